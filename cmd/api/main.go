@@ -1,13 +1,24 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
 	"vend/docs"
 	"vend/internal/delivery/http"
+	"vend/internal/domain"
+	"vend/internal/infrastructure/cache"
 	"vend/internal/infrastructure/chatgpt"
+	"vend/internal/infrastructure/fakellm"
+	"vend/internal/infrastructure/memoria"
 	"vend/internal/infrastructure/mongodb"
-	"vend/internal/repository"
+	"vend/internal/infrastructure/postgres"
+	"vend/internal/infrastructure/privacidade"
+	"vend/internal/infrastructure/resiliencia"
+	"vend/internal/infrastructure/vetorial"
 	"vend/internal/usecase"
 
 	_ "vend/docs"
@@ -16,6 +27,7 @@ import (
 	"github.com/joho/godotenv"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // @title           Vend API
@@ -41,27 +53,132 @@ func main() {
 		log.Printf("Aviso: Arquivo .env não encontrado")
 	}
 
-	// Inicializa o cliente MongoDB
-	mongoClient, err := mongodb.NewMongoClient()
-	if err != nil {
-		log.Fatalf("Erro ao conectar ao MongoDB: %v", err)
+	// Inicializa o repositório no armazenamento escolhido por STORAGE
+	var repo usecase.Repository
+	var mongoClient *mongo.Client
+	var err error
+	switch storage := os.Getenv("STORAGE"); storage {
+	case "", "mongo":
+		mongoClient, err = mongodb.NewMongoClient()
+		if err != nil {
+			log.Fatalf("Erro ao conectar ao MongoDB: %v", err)
+		}
+		defer mongoClient.Disconnect(nil)
+		mongoRepo := mongodb.NewRepository(mongodb.Database(mongoClient))
+		if err := mongoRepo.CriarIndices(); err != nil {
+			log.Fatalf("Erro ao criar os índices do MongoDB: %v", err)
+		}
+		if err := mongoRepo.MigrarReferencias(); err != nil {
+			log.Fatalf("Erro ao converter as relações copiadas do MongoDB em referências: %v", err)
+		}
+		repo = mongoRepo
+	case "postgres":
+		db, err := postgres.NewPostgresDB()
+		if err != nil {
+			log.Fatalf("Erro ao conectar ao PostgreSQL: %v", err)
+		}
+		postgresRepo := postgres.NewRepository(db)
+		if err := postgresRepo.Migrar(); err != nil {
+			log.Fatalf("Erro ao migrar as tabelas do PostgreSQL: %v", err)
+		}
+		repo = postgresRepo
+	case "memoria":
+		// Sem MEMORIA_ARQUIVO os dados somem ao encerrar a API
+		memoriaRepo, err := memoria.NewRepositoryArquivo(os.Getenv("MEMORIA_ARQUIVO"))
+		if err != nil {
+			log.Fatalf("Erro ao carregar o repositório em memória: %v", err)
+		}
+		repo = memoriaRepo
+	default:
+		log.Fatalf("Armazenamento desconhecido em STORAGE: %s", storage)
 	}
-	defer mongoClient.Disconnect(nil)
-
-	// Inicializa o repositório
-	pessoaRepo := repository.NewPessoaRepository(mongoClient)
 
 	// Inicializa os casos de uso
-	pessoaUseCase := usecase.NewPessoaUseCase(pessoaRepo)
-	telefoneUseCase := usecase.NewTelefoneUseCase(pessoaRepo)
-	contextoUseCase := usecase.NewContextoUseCase(pessoaRepo)
-	promptUseCase := usecase.NewPromptUseCase(pessoaRepo)
+	modelos := usecase.ParseModelos(os.Getenv("LLM_MODELOS_PERMITIDOS"))
+	pessoaUseCase := usecase.NewPessoaUseCase(repo)
+	telefoneUseCase := usecase.NewTelefoneUseCase(repo)
+	contextoUseCase := usecase.NewContextoUseCase(repo, modelos)
+	promptUseCase := usecase.NewPromptUseCase(repo, modelos)
+	regrasRemocao, err := usecase.ParseRegrasRemocao(os.Getenv("REMOCAO_REGRAS"))
+	if err != nil {
+		log.Fatalf("REMOCAO_REGRAS inválido: %v", err)
+	}
+	pessoaUseCase.UsarRegrasRemocao(regrasRemocao)
+	contextoUseCase.UsarRegrasRemocao(regrasRemocao)
 
-	// Inicializa o handler
-	handler := http.NewHandler(pessoaUseCase, telefoneUseCase, contextoUseCase, promptUseCase)
+	// Inicializa o provedor de LLM
+	llmProvider, err := newLLMProvider()
+	if err != nil {
+		log.Fatalf("Erro ao configurar o provedor de LLM: %v", err)
+	}
+	// Embeddings e moderação vêm direto do provedor, fora da resiliência e do cache das gerações
+	embeddings, _ := llmProvider.(domain.ProvedorEmbeddings)
+	moderador, _ := llmProvider.(domain.Moderador)
+	resilienciaConfig, err := newResilienciaConfig()
+	if err != nil {
+		log.Fatalf("Erro ao configurar a resiliência do provedor de LLM: %v", err)
+	}
+	llmProvider = resiliencia.NewProvider(llmProvider, resilienciaConfig)
+	llmProvider, err = newCacheProvider(llmProvider, mongoClient)
+	if err != nil {
+		log.Fatalf("Erro ao configurar o cache de respostas: %v", err)
+	}
+	// A privacidade envolve o cache para que ele guarde só mensagens protegidas
+	privacidadeModo := os.Getenv("PRIVACIDADE_MODO")
+	if err := usecase.ValidarPrivacidade(domain.PoliticaPrivacidade{Modo: privacidadeModo}); err != nil {
+		log.Fatalf("PRIVACIDADE_MODO inválido: %v", err)
+	}
+	llmProvider = privacidade.NewProvider(llmProvider, moderador, privacidadeModo)
+	if embeddings != nil {
+		embeddings = privacidade.NewEmbeddings(embeddings, privacidadeModo)
+	}
+	precos, err := usecase.ParsePrecos(os.Getenv("LLM_PRECOS"))
+	if err != nil {
+		log.Fatalf("Erro ao configurar os preços dos modelos: %v", err)
+	}
+	consumoUseCase := usecase.NewConsumoUseCase(repo, precos)
+	ferramentas := usecase.NewFerramentas(pessoaUseCase, telefoneUseCase, contextoUseCase)
+	documentoUseCase := usecase.NewDocumentoUseCase(repo, embeddings, consumoUseCase)
+	geracaoUseCase := usecase.NewGeracaoUseCase(repo, llmProvider, consumoUseCase, modelos, ferramentas, documentoUseCase)
+	respostaUseCase := usecase.NewRespostaUseCase(repo)
+	conversaUseCase := usecase.NewConversaUseCase(repo, llmProvider, consumoUseCase, modelos, ferramentas, documentoUseCase)
+	loteConfig, err := newLoteConfig()
+	if err != nil {
+		log.Fatalf("Erro ao configurar o processamento em lote: %v", err)
+	}
+	jobUseCase := usecase.NewJobUseCase(repo, geracaoUseCase, loteConfig)
+	go jobUseCase.Iniciar(context.Background())
+	buscaIntervalo := time.Minute
+	if valor := os.Getenv("BUSCA_INTERVALO"); valor != "" {
+		buscaIntervalo, err = time.ParseDuration(valor)
+		if err != nil {
+			log.Fatalf("BUSCA_INTERVALO inválido: %v", err)
+		}
+	}
+	buscaUseCase := usecase.NewBuscaUseCase(repo, embeddings, consumoUseCase, vetorial.NewMemoria(), buscaIntervalo)
+	go buscaUseCase.Iniciar(context.Background())
+	lixeiraConfig, err := newLixeiraConfig()
+	if err != nil {
+		log.Fatalf("Erro ao configurar a lixeira: %v", err)
+	}
+	lixeiraUseCase := usecase.NewLixeiraUseCase(repo, lixeiraConfig)
+	go lixeiraUseCase.Iniciar(context.Background())
 
-	// Inicializa o serviço do ChatGPT (será usado posteriormente)
-	_ = chatgpt.NewChatGPTService()
+	// Inicializa o handler
+	handler := http.NewHandler(
+		pessoaUseCase,
+		telefoneUseCase,
+		contextoUseCase,
+		promptUseCase,
+		geracaoUseCase,
+		respostaUseCase,
+		conversaUseCase,
+		consumoUseCase,
+		jobUseCase,
+		buscaUseCase,
+		documentoUseCase,
+		lixeiraUseCase,
+	)
 
 	// Configurar router
 	r := gin.Default()
@@ -70,7 +187,7 @@ func main() {
 	r.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Cache-Control")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
 			return
@@ -89,6 +206,9 @@ func main() {
 			pessoas.GET("/:id", handler.GetPessoa)
 			pessoas.PUT("/:id", handler.UpdatePessoa)
 			pessoas.DELETE("/:id", handler.DeletePessoa)
+			pessoas.GET("/:id/consumo", handler.ConsumoPessoa)
+			pessoas.GET("/:id/telefones", handler.ListTelefonesPessoa)
+			pessoas.POST("/:id/restaurar", handler.Restaurar(domain.EntidadePessoas))
 		}
 
 		// Rotas de Telefones
@@ -99,6 +219,7 @@ func main() {
 			telefones.GET("/:id", handler.GetTelefone)
 			telefones.PUT("/:id", handler.UpdateTelefone)
 			telefones.DELETE("/:id", handler.DeleteTelefone)
+			telefones.POST("/:id/restaurar", handler.Restaurar(domain.EntidadeTelefones))
 		}
 
 		// Rotas de Contextos
@@ -109,6 +230,16 @@ func main() {
 			contextos.GET("/:id", handler.GetContexto)
 			contextos.PUT("/:id", handler.UpdateContexto)
 			contextos.DELETE("/:id", handler.DeleteContexto)
+			contextos.POST("/:id/restaurar", handler.Restaurar(domain.EntidadeContextos))
+			contextos.GET("/:id/consumo", handler.ConsumoContexto)
+			contextos.POST("/:id/pessoas/:pessoaId", handler.AddPessoaContexto)
+			contextos.DELETE("/:id/pessoas/:pessoaId", handler.RemovePessoaContexto)
+			contextos.POST("/:id/prompts/:promptId/executar", handler.ExecutarPrompt)
+			contextos.POST("/:id/prompts/:promptId/executar/stream", handler.ExecutarPromptStream)
+			contextos.POST("/:id/prompts/:promptId/lote", handler.ExecutarLote)
+			contextos.GET("/:id/documentos", handler.ListDocumentos)
+			contextos.POST("/:id/documentos", handler.AdicionarDocumento)
+			contextos.DELETE("/:id/documentos/:documentoId", handler.DeleteDocumento)
 		}
 
 		// Rotas de Prompts
@@ -119,7 +250,45 @@ func main() {
 			prompts.GET("/:id", handler.GetPrompt)
 			prompts.PUT("/:id", handler.UpdatePrompt)
 			prompts.DELETE("/:id", handler.DeletePrompt)
+			prompts.POST("/:id/restaurar", handler.Restaurar(domain.EntidadePrompts))
+			prompts.POST("/:id/render", handler.RenderPrompt)
+			prompts.GET("/:id/versoes", handler.ListPromptVersoes)
+			prompts.GET("/:id/versoes/:numero", handler.GetPromptVersao)
+			prompts.POST("/:id/versoes/:numero/restaurar", handler.RestaurarPromptVersao)
+			prompts.GET("/:id/diff", handler.DiffPromptVersoes)
 		}
+
+		// Rotas de Respostas
+		respostas := v1.Group("/respostas")
+		{
+			respostas.GET("", handler.ListRespostas)
+			respostas.GET("/:id", handler.GetResposta)
+		}
+
+		// Rotas de Conversas
+		conversas := v1.Group("/conversas")
+		{
+			conversas.GET("", handler.ListConversas)
+			conversas.POST("", handler.IniciarConversa)
+			conversas.GET("/:id", handler.GetConversa)
+			conversas.POST("/:id/mensagens", handler.EnviarMensagem)
+		}
+
+		// Rotas de Busca
+		busca := v1.Group("/busca")
+		{
+			busca.GET("", handler.Busca)
+			busca.GET("/semantica", handler.BuscaSemantica)
+		}
+
+		// Rotas de Jobs
+		jobs := v1.Group("/jobs")
+		{
+			jobs.GET("/:id", handler.GetJob)
+		}
+
+		// Rotas da Lixeira
+		v1.GET("/lixeira", handler.ListLixeira)
 	}
 
 	// Configurar Swagger
@@ -137,3 +306,157 @@ func main() {
 		log.Fatalf("Erro ao iniciar servidor: %v", err)
 	}
 }
+
+// newLLMProvider escolhe o provedor pelo LLM_PROVIDER: "openai" (padrão),
+// "compativel" (API compatível em LLM_BASE_URL) ou "fake" (offline)
+func newLLMProvider() (domain.LLMProvider, error) {
+	modelo := os.Getenv("LLM_MODELO")
+
+	switch provider := os.Getenv("LLM_PROVIDER"); provider {
+	case "", "openai":
+		provider := chatgpt.NewOpenAIProvider(os.Getenv("OPENAI_API_KEY"), modelo)
+		provider.UsarModeloEmbeddings(os.Getenv("LLM_MODELO_EMBEDDINGS"))
+		return provider, nil
+	case "compativel":
+		baseURL := os.Getenv("LLM_BASE_URL")
+		if baseURL == "" {
+			return nil, fmt.Errorf("LLM_BASE_URL é obrigatório para o provedor compativel")
+		}
+		provider := chatgpt.NewCompatibleProvider(baseURL, os.Getenv("OPENAI_API_KEY"), modelo)
+		provider.UsarModeloEmbeddings(os.Getenv("LLM_MODELO_EMBEDDINGS"))
+		return provider, nil
+	case "fake":
+		return fakellm.NewProvider(os.Getenv("LLM_FAKE_TEMPLATE"))
+	default:
+		return nil, fmt.Errorf("provedor de LLM desconhecido: %s", provider)
+	}
+}
+
+// newCacheProvider coloca o cache de respostas escolhido por LLM_CACHE
+// ("memoria" ou "mongo"; vazio desativa) na frente do provedor. LLM_CACHE_TTL
+// define a validade (padrão 24h) e LLM_CACHE_TAMANHO a capacidade do cache em memória.
+func newCacheProvider(provider domain.LLMProvider, mongoClient *mongo.Client) (domain.LLMProvider, error) {
+	tipo := os.Getenv("LLM_CACHE")
+	if tipo == "" {
+		return provider, nil
+	}
+
+	ttl := 24 * time.Hour
+	if valor := os.Getenv("LLM_CACHE_TTL"); valor != "" {
+		d, err := time.ParseDuration(valor)
+		if err != nil {
+			return nil, fmt.Errorf("LLM_CACHE_TTL inválido: %w", err)
+		}
+		ttl = d
+	}
+
+	var cacheGeracao domain.CacheGeracao
+	switch tipo {
+	case "memoria":
+		tamanho := 1000
+		if valor := os.Getenv("LLM_CACHE_TAMANHO"); valor != "" {
+			n, err := strconv.Atoi(valor)
+			if err != nil {
+				return nil, fmt.Errorf("LLM_CACHE_TAMANHO inválido: %w", err)
+			}
+			tamanho = n
+		}
+		cacheGeracao = cache.NewLRU(tamanho)
+	case "mongo":
+		if mongoClient == nil {
+			return nil, fmt.Errorf("o cache mongo requer STORAGE=mongo")
+		}
+		cacheMongo, err := mongodb.NewCacheGeracao(mongoClient)
+		if err != nil {
+			return nil, err
+		}
+		cacheGeracao = cacheMongo
+	default:
+		return nil, fmt.Errorf("cache de respostas desconhecido: %s", tipo)
+	}
+
+	return cache.NewProvider(provider, cacheGeracao, ttl), nil
+}
+
+// newResilienciaConfig sobrepõe à configuração padrão as variáveis
+// LLM_TENTATIVAS, LLM_TIMEOUT, LLM_CIRCUITO_FALHAS e LLM_CIRCUITO_ESPERA
+func newResilienciaConfig() (resiliencia.Config, error) {
+	config := resiliencia.ConfigPadrao()
+
+	inteiros := map[string]*int{
+		"LLM_TENTATIVAS":      &config.Tentativas,
+		"LLM_CIRCUITO_FALHAS": &config.LimiteFalhas,
+	}
+	for nome, destino := range inteiros {
+		if valor := os.Getenv(nome); valor != "" {
+			n, err := strconv.Atoi(valor)
+			if err != nil {
+				return config, fmt.Errorf("%s inválido: %w", nome, err)
+			}
+			*destino = n
+		}
+	}
+
+	duracoes := map[string]*time.Duration{
+		"LLM_TIMEOUT":         &config.TimeoutChamada,
+		"LLM_CIRCUITO_ESPERA": &config.TempoAberto,
+	}
+	for nome, destino := range duracoes {
+		if valor := os.Getenv(nome); valor != "" {
+			d, err := time.ParseDuration(valor)
+			if err != nil {
+				return config, fmt.Errorf("%s inválido: %w", nome, err)
+			}
+			*destino = d
+		}
+	}
+
+	return config, nil
+}
+
+// newLoteConfig sobrepõe à configuração padrão as variáveis LOTE_WORKERS e
+// LOTE_INTERVALO
+func newLoteConfig() (usecase.ConfigLote, error) {
+	config := usecase.ConfigLotePadrao()
+
+	if valor := os.Getenv("LOTE_WORKERS"); valor != "" {
+		n, err := strconv.Atoi(valor)
+		if err != nil {
+			return config, fmt.Errorf("LOTE_WORKERS inválido: %w", err)
+		}
+		config.Workers = n
+	}
+	if valor := os.Getenv("LOTE_INTERVALO"); valor != "" {
+		d, err := time.ParseDuration(valor)
+		if err != nil {
+			return config, fmt.Errorf("LOTE_INTERVALO inválido: %w", err)
+		}
+		config.Intervalo = d
+	}
+
+	return config, nil
+}
+
+// newLixeiraConfig sobrepõe à configuração padrão as variáveis
+// LIXEIRA_RETENCAO (0 desliga a purga) e LIXEIRA_INTERVALO
+func newLixeiraConfig() (usecase.ConfigLixeira, error) {
+	config := usecase.ConfigLixeiraPadrao()
+
+	for nome, destino := range map[string]*time.Duration{
+		"LIXEIRA_RETENCAO":  &config.Retencao,
+		"LIXEIRA_INTERVALO": &config.Intervalo,
+	} {
+		if valor := os.Getenv(nome); valor != "" {
+			d, err := time.ParseDuration(valor)
+			if err != nil {
+				return config, fmt.Errorf("%s inválido: %w", nome, err)
+			}
+			*destino = d
+		}
+	}
+	if config.Intervalo <= 0 {
+		return config, fmt.Errorf("LIXEIRA_INTERVALO deve ser positivo")
+	}
+
+	return config, nil
+}
@@ -0,0 +1,73 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary     Busca textual
+// @Description Retorna as pessoas, contextos e prompts com as palavras do texto, dos mais relevantes aos menos, com os trechos encontrados entre <em> e </em>
+// @Tags        busca
+// @Accept      json
+// @Produce     json
+// @Param       q     query string true  "Texto da busca"
+// @Param       tipo  query string false "pessoa, contexto ou prompt; vazio busca em todos"
+// @Param       limit query int    false "Número de resultados (padrão 20, máximo 100)"
+// @Success     200 {array}  domain.ResultadoTexto
+// @Failure     400 {object} map[string]string
+// @Failure     500 {object} map[string]string
+// @Router      /busca [get]
+func (h *Handler) Busca(c *gin.Context) {
+	limite := 0
+	if valor := c.Query("limit"); valor != "" {
+		n, err := strconv.Atoi(valor)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"erro": "limit deve ser um número positivo"})
+			return
+		}
+		limite = n
+	}
+
+	resultados, err := h.buscaUseCase.BuscarTexto(c.Query("q"), c.Query("tipo"), limite)
+	if err != nil {
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resultados)
+}
+
+// @Summary     Busca semântica
+// @Description Retorna os prompts e respostas mais parecidos com o texto, pela similaridade de cosseno entre embeddings
+// @Tags        busca
+// @Accept      json
+// @Produce     json
+// @Param       q    query string true  "Texto da busca"
+// @Param       k    query int    false "Número de resultados (padrão 10, máximo 50)"
+// @Param       tipo query string false "prompt ou resposta; vazio busca em ambos"
+// @Success     200 {array}  domain.ResultadoBusca
+// @Failure     400 {object} map[string]string
+// @Failure     500 {object} map[string]string
+// @Failure     501 {object} map[string]string
+// @Router      /busca/semantica [get]
+func (h *Handler) BuscaSemantica(c *gin.Context) {
+	k := 0
+	if valor := c.Query("k"); valor != "" {
+		n, err := strconv.Atoi(valor)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"erro": "k deve ser um número positivo"})
+			return
+		}
+		k = n
+	}
+
+	resultados, err := h.buscaUseCase.BuscarSemantica(c.Request.Context(), c.Query("q"), k, c.Query("tipo"))
+	if err != nil {
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resultados)
+}
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// @Summary     Consumo do contexto
+// @Description Retorna os tokens, o custo estimado e o orçamento restante do contexto no mês
+// @Tags        contextos
+// @Accept      json
+// @Produce     json
+// @Param       id  path  string true  "ID do contexto"
+// @Param       mes query string false "Mês de referência (AAAA-MM); padrão é o mês corrente"
+// @Success     200 {object} domain.Consumo
+// @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
+// @Failure     500 {object} map[string]string
+// @Router      /contextos/{id}/consumo [get]
+func (h *Handler) ConsumoContexto(c *gin.Context) {
+	id := c.Param("id")
+	if !primitive.IsValidObjectID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return
+	}
+
+	referencia, ok := mesReferencia(c)
+	if !ok {
+		return
+	}
+
+	consumo, err := h.consumoUseCase.ConsumoContexto(id, referencia)
+	if err != nil {
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, consumo)
+}
+
+// @Summary     Consumo da pessoa
+// @Description Retorna os tokens e o custo estimado das gerações para a pessoa no mês
+// @Tags        pessoas
+// @Accept      json
+// @Produce     json
+// @Param       id  path  string true  "ID da pessoa"
+// @Param       mes query string false "Mês de referência (AAAA-MM); padrão é o mês corrente"
+// @Success     200 {object} domain.Consumo
+// @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
+// @Failure     500 {object} map[string]string
+// @Router      /pessoas/{id}/consumo [get]
+func (h *Handler) ConsumoPessoa(c *gin.Context) {
+	id := c.Param("id")
+	if !primitive.IsValidObjectID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return
+	}
+
+	referencia, ok := mesReferencia(c)
+	if !ok {
+		return
+	}
+
+	consumo, err := h.consumoUseCase.ConsumoPessoa(id, referencia)
+	if err != nil {
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, consumo)
+}
+
+// mesReferencia lê o parâmetro "mes" (AAAA-MM); responde 400 se for inválido
+func mesReferencia(c *gin.Context) (time.Time, bool) {
+	mes := c.Query("mes")
+	if mes == "" {
+		return time.Now(), true
+	}
+
+	referencia, err := time.Parse("2006-01", mes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "Mês inválido, use o formato AAAA-MM"})
+		return time.Time{}, false
+	}
+	return referencia, true
+}
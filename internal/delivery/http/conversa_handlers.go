@@ -0,0 +1,156 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"vend/internal/domain"
+	"vend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type iniciarConversaRequest struct {
+	PessoaID   string `json:"pessoa_id" binding:"required"`
+	ContextoID string `json:"contexto_id" binding:"required"`
+}
+
+type enviarMensagemRequest struct {
+	Conteudo string `json:"conteudo" binding:"required"`
+}
+
+// @Summary     Iniciar conversa
+// @Description Inicia uma conversa com o modelo de linguagem para uma pessoa em um contexto
+// @Tags        conversas
+// @Accept      json
+// @Produce     json
+// @Param       conversa body iniciarConversaRequest true "Pessoa e contexto da conversa"
+// @Success     201 {object} domain.Conversa
+// @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
+// @Failure     500 {object} map[string]string
+// @Router      /conversas [post]
+func (h *Handler) IniciarConversa(c *gin.Context) {
+	var req iniciarConversaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": err.Error()})
+		return
+	}
+	if !primitive.IsValidObjectID(req.PessoaID) || !primitive.IsValidObjectID(req.ContextoID) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return
+	}
+
+	conversa, err := h.conversaUseCase.IniciarConversa(req.PessoaID, req.ContextoID)
+	if err != nil {
+		c.JSON(statusConversa(err), gin.H{"erro": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, conversa)
+}
+
+// @Summary     Listar conversas
+// @Description Retorna as conversas, opcionalmente filtradas por pessoa ou contexto
+// @Tags        conversas
+// @Accept      json
+// @Produce     json
+// @Param       pessoa_id   query string false "ID da pessoa"
+// @Param       contexto_id query string false "ID do contexto"
+// @Success     200 {array} domain.Conversa
+// @Failure     400 {object} map[string]string
+// @Failure     500 {object} map[string]string
+// @Router      /conversas [get]
+func (h *Handler) ListConversas(c *gin.Context) {
+	filtro := domain.ConversaFiltro{
+		PessoaID:   c.Query("pessoa_id"),
+		ContextoID: c.Query("contexto_id"),
+	}
+	if (filtro.PessoaID != "" && !primitive.IsValidObjectID(filtro.PessoaID)) ||
+		(filtro.ContextoID != "" && !primitive.IsValidObjectID(filtro.ContextoID)) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return
+	}
+
+	conversas, err := h.conversaUseCase.ListConversas(filtro)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"erro": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, conversas)
+}
+
+// @Summary     Buscar conversa
+// @Description Retorna uma conversa com todo o seu histórico de mensagens
+// @Tags        conversas
+// @Accept      json
+// @Produce     json
+// @Param       id path string true "ID da conversa"
+// @Success     200 {object} domain.Conversa
+// @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
+// @Router      /conversas/{id} [get]
+func (h *Handler) GetConversa(c *gin.Context) {
+	id := c.Param("id")
+	if !primitive.IsValidObjectID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return
+	}
+
+	conversa, err := h.conversaUseCase.GetConversa(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"erro": "Conversa não encontrada"})
+		return
+	}
+
+	c.JSON(http.StatusOK, conversa)
+}
+
+// @Summary     Enviar mensagem
+// @Description Acrescenta uma mensagem do usuário à conversa e retorna a resposta do assistente
+// @Tags        conversas
+// @Accept      json
+// @Produce     json
+// @Param       id       path string                true "ID da conversa"
+// @Param       mensagem body enviarMensagemRequest true "Mensagem do usuário"
+// @Success     200 {object} domain.MensagemConversa
+// @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
+// @Failure     402 {object} map[string]string
+// @Failure     422 {object} map[string]string
+// @Failure     429 {object} map[string]string
+// @Failure     500 {object} map[string]string
+// @Failure     503 {object} map[string]string
+// @Failure     504 {object} map[string]string
+// @Router      /conversas/{id}/mensagens [post]
+func (h *Handler) EnviarMensagem(c *gin.Context) {
+	id := c.Param("id")
+	if !primitive.IsValidObjectID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return
+	}
+
+	var req enviarMensagemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": err.Error()})
+		return
+	}
+
+	mensagem, err := h.conversaUseCase.EnviarMensagem(c.Request.Context(), id, req.Conteudo)
+	if err != nil {
+		c.JSON(statusConversa(err), gin.H{"erro": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, mensagem)
+}
+
+func statusConversa(err error) int {
+	switch {
+	case errors.Is(err, usecase.ErrConversaNaoEncontrada):
+		return http.StatusNotFound
+	default:
+		return statusErro(err)
+	}
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// tamanhoMaximoDocumento limita o arquivo enviado a 10 MB
+const tamanhoMaximoDocumento = 10 << 20
+
+// @Summary     Enviar documento
+// @Description Envia um documento (.txt, .md ou .pdf) ao contexto; o texto é dividido em trechos consultados pelas gerações do contexto
+// @Tags        contextos
+// @Accept      multipart/form-data
+// @Produce     json
+// @Param       id      path     string true "ID do contexto"
+// @Param       arquivo formData file   true "Arquivo do documento"
+// @Success     201 {object} domain.Documento
+// @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
+// @Failure     413 {object} map[string]string
+// @Failure     500 {object} map[string]string
+// @Failure     501 {object} map[string]string
+// @Router      /contextos/{id}/documentos [post]
+func (h *Handler) AdicionarDocumento(c *gin.Context) {
+	id := c.Param("id")
+	if !primitive.IsValidObjectID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return
+	}
+
+	arquivo, err := c.FormFile("arquivo")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "Envie o documento no campo \"arquivo\""})
+		return
+	}
+	if arquivo.Size > tamanhoMaximoDocumento {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"erro": "O documento deve ter no máximo 10 MB"})
+		return
+	}
+
+	f, err := arquivo.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"erro": err.Error()})
+		return
+	}
+	defer f.Close()
+	dados, err := io.ReadAll(f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"erro": err.Error()})
+		return
+	}
+
+	documento, err := h.documentoUseCase.AdicionarDocumento(c.Request.Context(), id, arquivo.Filename, dados)
+	if err != nil {
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, documento)
+}
+
+// @Summary     Listar documentos
+// @Description Lista os documentos enviados ao contexto
+// @Tags        contextos
+// @Accept      json
+// @Produce     json
+// @Param       id path string true "ID do contexto"
+// @Success     200 {array}  domain.Documento
+// @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
+// @Failure     500 {object} map[string]string
+// @Router      /contextos/{id}/documentos [get]
+func (h *Handler) ListDocumentos(c *gin.Context) {
+	id := c.Param("id")
+	if !primitive.IsValidObjectID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return
+	}
+
+	documentos, err := h.documentoUseCase.ListDocumentos(id)
+	if err != nil {
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, documentos)
+}
+
+// @Summary     Remover documento
+// @Description Remove o documento e os seus trechos do contexto
+// @Tags        contextos
+// @Accept      json
+// @Produce     json
+// @Param       id          path string true "ID do contexto"
+// @Param       documentoId path string true "ID do documento"
+// @Success     200 {object} map[string]string
+// @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
+// @Failure     500 {object} map[string]string
+// @Router      /contextos/{id}/documentos/{documentoId} [delete]
+func (h *Handler) DeleteDocumento(c *gin.Context) {
+	id := c.Param("id")
+	documentoID := c.Param("documentoId")
+	if !primitive.IsValidObjectID(id) || !primitive.IsValidObjectID(documentoID) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return
+	}
+
+	if err := h.documentoUseCase.DeleteDocumento(id, documentoID); err != nil {
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mensagem": "Documento removido com sucesso"})
+}
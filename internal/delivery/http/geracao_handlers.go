@@ -0,0 +1,207 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"vend/internal/domain"
+	"vend/internal/extracao"
+	"vend/internal/jsonschema"
+	"vend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type execucaoRequest struct {
+	PessoaID   string                   `json:"pessoa_id"`
+	Variaveis  map[string]any           `json:"variaveis"`
+	Parametros domain.ParametrosGeracao `json:"parametros"`
+}
+
+// @Summary     Executar prompt
+// @Description Gera uma resposta do modelo de linguagem para o prompt usando os dados do contexto
+// @Tags        contextos
+// @Accept      json
+// @Produce     json
+// @Param       id       path string true "ID do contexto"
+// @Param       promptId path string true "ID do prompt"
+// @Param       execucao body execucaoRequest false "Pessoa e valores das variáveis do template"
+// @Param       Cache-Control header string false "no-cache gera uma nova resposta sem consultar o cache"
+// @Success     200 {object} domain.Resposta
+// @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
+// @Failure     402 {object} map[string]string
+// @Failure     422 {object} map[string]string
+// @Failure     429 {object} map[string]string
+// @Failure     500 {object} map[string]string
+// @Failure     503 {object} map[string]string
+// @Failure     504 {object} map[string]string
+// @Router      /contextos/{id}/prompts/{promptId}/executar [post]
+func (h *Handler) ExecutarPrompt(c *gin.Context) {
+	contextoID := c.Param("id")
+	promptID := c.Param("promptId")
+	if !primitive.IsValidObjectID(contextoID) || !primitive.IsValidObjectID(promptID) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return
+	}
+
+	opcoes, ok := bindExecucao(c)
+	if !ok {
+		return
+	}
+
+	resposta, err := h.geracaoUseCase.ExecutarPrompt(contextoGeracao(c), contextoID, promptID, opcoes)
+	if err != nil {
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resposta)
+}
+
+// @Summary     Executar prompt com streaming
+// @Description Gera a resposta do modelo de linguagem enviando os trechos como Server-Sent Events (eventos "token", "fim" e "erro")
+// @Tags        contextos
+// @Accept      json
+// @Produce     text/event-stream
+// @Param       id       path string true "ID do contexto"
+// @Param       promptId path string true "ID do prompt"
+// @Param       execucao body execucaoRequest false "Pessoa e valores das variáveis do template"
+// @Param       Cache-Control header string false "no-cache gera uma nova resposta sem consultar o cache"
+// @Success     200 {string} string "Stream de eventos"
+// @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
+// @Failure     402 {object} map[string]string
+// @Failure     422 {object} map[string]string
+// @Failure     429 {object} map[string]string
+// @Failure     500 {object} map[string]string
+// @Failure     503 {object} map[string]string
+// @Failure     504 {object} map[string]string
+// @Router      /contextos/{id}/prompts/{promptId}/executar/stream [post]
+func (h *Handler) ExecutarPromptStream(c *gin.Context) {
+	contextoID := c.Param("id")
+	promptID := c.Param("promptId")
+	if !primitive.IsValidObjectID(contextoID) || !primitive.IsValidObjectID(promptID) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return
+	}
+
+	opcoes, ok := bindExecucao(c)
+	if !ok {
+		return
+	}
+
+	ctx := contextoGeracao(c)
+	resposta, err := h.geracaoUseCase.ExecutarPromptStream(ctx, contextoID, promptID, opcoes, func(delta string) error {
+		if !c.Writer.Written() {
+			iniciarSSE(c)
+		}
+		c.SSEvent("token", delta)
+		c.Writer.Flush()
+		return ctx.Err()
+	})
+	if err != nil {
+		// Cliente desconectado: não há para quem responder
+		if ctx.Err() != nil {
+			return
+		}
+		if !c.Writer.Written() {
+			c.JSON(statusErro(err), gin.H{"erro": err.Error()})
+			return
+		}
+		c.SSEvent("erro", gin.H{"erro": err.Error()})
+		c.Writer.Flush()
+		return
+	}
+
+	if !c.Writer.Written() {
+		iniciarSSE(c)
+	}
+	c.SSEvent("fim", resposta)
+	c.Writer.Flush()
+}
+
+// bindExecucao lê o corpo opcional de uma execução; responde 400 se for inválido
+func bindExecucao(c *gin.Context) (usecase.OpcoesExecucao, bool) {
+	var req execucaoRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": err.Error()})
+		return usecase.OpcoesExecucao{}, false
+	}
+	if req.PessoaID != "" && !primitive.IsValidObjectID(req.PessoaID) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return usecase.OpcoesExecucao{}, false
+	}
+
+	return usecase.OpcoesExecucao{PessoaID: req.PessoaID, Variaveis: req.Variaveis, Parametros: req.Parametros}, true
+}
+
+// contextoGeracao repassa o contexto da requisição; com "Cache-Control:
+// no-cache" a geração ignora o cache de respostas
+func contextoGeracao(c *gin.Context) context.Context {
+	ctx := c.Request.Context()
+	for _, diretiva := range strings.Split(c.GetHeader("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(diretiva), "no-cache") {
+			return domain.IgnorarCache(ctx)
+		}
+	}
+	return ctx
+}
+
+func iniciarSSE(c *gin.Context) {
+	c.Writer.Header().Set("Content-Type", "text/event-stream")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+}
+
+func statusErro(err error) int {
+	switch {
+	case errors.Is(err, usecase.ErrContextoNaoEncontrado),
+		errors.Is(err, usecase.ErrPromptNaoEncontrado),
+		errors.Is(err, usecase.ErrPessoaNaoEncontrada),
+		errors.Is(err, usecase.ErrVersaoNaoEncontrada),
+		errors.Is(err, usecase.ErrJobNaoEncontrado),
+		errors.Is(err, usecase.ErrDocumentoNaoEncontrado),
+		errors.Is(err, domain.ErrNaoEncontrado):
+		return http.StatusNotFound
+	case errors.Is(err, usecase.ErrPromptForaDoContexto),
+		errors.Is(err, usecase.ErrParametroInvalido),
+		errors.Is(err, jsonschema.ErrSchemaInvalido),
+		errors.Is(err, usecase.ErrTemplateInvalido),
+		errors.Is(err, usecase.ErrVariavelInvalida),
+		errors.Is(err, usecase.ErrContextoSemPessoas),
+		errors.Is(err, usecase.ErrConsultaVazia),
+		errors.Is(err, extracao.ErrFormatoNaoSuportado),
+		errors.Is(err, extracao.ErrSemTexto),
+		errors.Is(err, usecase.ErrPrivacidadeInvalida),
+		errors.Is(err, usecase.ErrPaginacaoInvalida),
+		errors.Is(err, usecase.ErrEntidadeSemLixeira):
+		return http.StatusBadRequest
+	case errors.Is(err, usecase.ErrRemocaoRestrita),
+		errors.Is(err, usecase.ErrRestauracaoImpedida):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrConteudoBloqueado):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, usecase.ErrBuscaIndisponivel):
+		return http.StatusNotImplemented
+	case errors.Is(err, usecase.ErrOrcamentoExcedido):
+		return http.StatusPaymentRequired
+	case errors.Is(err, domain.ErrLLMLimiteRequisicoes):
+		return http.StatusTooManyRequests
+	case errors.Is(err, domain.ErrLLMTempoEsgotado):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, domain.ErrLLMIndisponivel),
+		errors.Is(err, domain.ErrLLMCircuitoAberto):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, domain.ErrLLMRespostaVazia),
+		errors.Is(err, usecase.ErrSaidaInvalida),
+		errors.Is(err, domain.ErrLLMRequisicaoInvalida):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -1,6 +1,8 @@
 package http
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"vend/internal/domain"
 	"vend/internal/usecase"
@@ -9,11 +11,23 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// headerUsuario identifica quem fez a alteração, registrado como autor das
+// versões e como deleted_by dos registros removidos
+const headerUsuario = "X-Usuario"
+
 type Handler struct {
-	pessoaUseCase   *usecase.PessoaUseCase
-	telefoneUseCase *usecase.TelefoneUseCase
-	contextoUseCase *usecase.ContextoUseCase
-	promptUseCase   *usecase.PromptUseCase
+	pessoaUseCase    *usecase.PessoaUseCase
+	telefoneUseCase  *usecase.TelefoneUseCase
+	contextoUseCase  *usecase.ContextoUseCase
+	promptUseCase    *usecase.PromptUseCase
+	geracaoUseCase   *usecase.GeracaoUseCase
+	respostaUseCase  *usecase.RespostaUseCase
+	conversaUseCase  *usecase.ConversaUseCase
+	consumoUseCase   *usecase.ConsumoUseCase
+	jobUseCase       *usecase.JobUseCase
+	buscaUseCase     *usecase.BuscaUseCase
+	documentoUseCase *usecase.DocumentoUseCase
+	lixeiraUseCase   *usecase.LixeiraUseCase
 }
 
 func NewHandler(
@@ -21,12 +35,28 @@ func NewHandler(
 	telefoneUseCase *usecase.TelefoneUseCase,
 	contextoUseCase *usecase.ContextoUseCase,
 	promptUseCase *usecase.PromptUseCase,
+	geracaoUseCase *usecase.GeracaoUseCase,
+	respostaUseCase *usecase.RespostaUseCase,
+	conversaUseCase *usecase.ConversaUseCase,
+	consumoUseCase *usecase.ConsumoUseCase,
+	jobUseCase *usecase.JobUseCase,
+	buscaUseCase *usecase.BuscaUseCase,
+	documentoUseCase *usecase.DocumentoUseCase,
+	lixeiraUseCase *usecase.LixeiraUseCase,
 ) *Handler {
 	return &Handler{
-		pessoaUseCase:   pessoaUseCase,
-		telefoneUseCase: telefoneUseCase,
-		contextoUseCase: contextoUseCase,
-		promptUseCase:   promptUseCase,
+		pessoaUseCase:    pessoaUseCase,
+		telefoneUseCase:  telefoneUseCase,
+		contextoUseCase:  contextoUseCase,
+		promptUseCase:    promptUseCase,
+		geracaoUseCase:   geracaoUseCase,
+		respostaUseCase:  respostaUseCase,
+		conversaUseCase:  conversaUseCase,
+		consumoUseCase:   consumoUseCase,
+		jobUseCase:       jobUseCase,
+		buscaUseCase:     buscaUseCase,
+		documentoUseCase: documentoUseCase,
+		lixeiraUseCase:   lixeiraUseCase,
 	}
 }
 
@@ -82,17 +112,35 @@ func (h *Handler) GetPessoa(c *gin.Context) {
 }
 
 // @Summary     Listar pessoas
-// @Description Retorna a lista de todas as pessoas cadastradas
+// @Description Retorna uma página das pessoas cadastradas, opcionalmente filtradas por nome ou email
 // @Tags        pessoas
 // @Accept      json
 // @Produce     json
-// @Success     200 {array} domain.Pessoa
+// @Param       nome   query string false "Parte do nome, sem diferenciar maiúsculas"
+// @Param       email  query string false "Parte do email, sem diferenciar maiúsculas"
+// @Param       limit  query int    false "Itens por página (padrão 50, máximo 200)"
+// @Param       offset query int    false "Itens a pular; não combina com cursor"
+// @Param       cursor query string false "next_cursor da página anterior"
+// @Param       total  query bool   false "Conta os registros aceitos pelos filtros; ignorado com cursor"
+// @Param       sort   query string false "created_at, updated_at, nome ou email; prefixo - para decrescente (padrão -created_at)"
+// @Param       expand query bool   false "Inclui os telefones e os contextos de cada pessoa"
+// @Success     200 {object} domain.Pagina[domain.Pessoa]
+// @Failure     400 {object} map[string]string
 // @Failure     500 {object} map[string]string
 // @Router      /pessoas [get]
 func (h *Handler) ListPessoas(c *gin.Context) {
-	pessoas, err := h.pessoaUseCase.ListPessoas()
+	pagina, ok := parametrosPagina(c)
+	if !ok {
+		return
+	}
+
+	filtro := domain.PessoaFiltro{Nome: c.Query("nome"), Email: c.Query("email")}
+	if filtro.Expandir, ok = expandir(c); !ok {
+		return
+	}
+	pessoas, err := h.pessoaUseCase.ListPessoas(filtro, pagina)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"erro": err.Error()})
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
 		return
 	}
 
@@ -108,6 +156,7 @@ func (h *Handler) ListPessoas(c *gin.Context) {
 // @Param       pessoa body domain.Pessoa true "Dados da pessoa"
 // @Success     200 {object} domain.Pessoa
 // @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
 // @Failure     500 {object} map[string]string
 // @Router      /pessoas/{id} [put]
 func (h *Handler) UpdatePessoa(c *gin.Context) {
@@ -131,21 +180,60 @@ func (h *Handler) UpdatePessoa(c *gin.Context) {
 
 	pessoa.ID = objectID
 	if err := h.pessoaUseCase.UpdatePessoa(&pessoa); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"erro": err.Error()})
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, pessoa)
 }
 
+// @Summary     Listar telefones da pessoa
+// @Description Retorna uma página dos telefones de uma pessoa
+// @Tags        pessoas
+// @Accept      json
+// @Produce     json
+// @Param       id     path  string true  "ID da pessoa"
+// @Param       limit  query int    false "Itens por página (padrão 50, máximo 200)"
+// @Param       offset query int    false "Itens a pular; não combina com cursor"
+// @Param       cursor query string false "next_cursor da página anterior"
+// @Param       total  query bool   false "Conta os registros aceitos pelos filtros; ignorado com cursor"
+// @Param       sort   query string false "created_at, updated_at, numero ou tipo; prefixo - para decrescente (padrão -created_at)"
+// @Success     200 {object} domain.Pagina[domain.Telefone]
+// @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
+// @Failure     500 {object} map[string]string
+// @Router      /pessoas/{id}/telefones [get]
+func (h *Handler) ListTelefonesPessoa(c *gin.Context) {
+	id := c.Param("id")
+	if !primitive.IsValidObjectID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return
+	}
+	pagina, ok := parametrosPagina(c)
+	if !ok {
+		return
+	}
+
+	telefones, err := h.pessoaUseCase.ListTelefones(id, pagina)
+	if err != nil {
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, telefones)
+}
+
 // @Summary     Deletar pessoa
-// @Description Remove uma pessoa do sistema
+// @Description Move uma pessoa para a lixeira, aplicando as regras de remoção aos seus telefones e contextos
 // @Tags        pessoas
 // @Accept      json
 // @Produce     json
-// @Param       id path string true "ID da pessoa"
+// @Param       id        path   string true  "ID da pessoa"
+// @Param       X-Usuario header string false "Quem removeu, gravado em deleted_by"
 // @Success     200 {object} map[string]string
 // @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
+// @Failure     409 {object} map[string]string
 // @Failure     500 {object} map[string]string
 // @Router      /pessoas/{id} [delete]
 func (h *Handler) DeletePessoa(c *gin.Context) {
@@ -155,8 +243,8 @@ func (h *Handler) DeletePessoa(c *gin.Context) {
 		return
 	}
 
-	if err := h.pessoaUseCase.DeletePessoa(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"erro": err.Error()})
+	if err := h.pessoaUseCase.DeletePessoa(id, c.GetHeader(headerUsuario)); err != nil {
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
 		return
 	}
 
@@ -164,17 +252,37 @@ func (h *Handler) DeletePessoa(c *gin.Context) {
 }
 
 // @Summary     Listar telefones
-// @Description Retorna a lista de todos os telefones cadastrados
+// @Description Retorna uma página dos telefones cadastrados, opcionalmente filtrados por número, tipo ou pessoa
 // @Tags        telefones
 // @Accept      json
 // @Produce     json
-// @Success     200 {array} domain.Telefone
+// @Param       numero    query string false "Parte do número"
+// @Param       tipo      query string false "Tipo do telefone"
+// @Param       pessoa_id query string false "ID da pessoa"
+// @Param       limit     query int    false "Itens por página (padrão 50, máximo 200)"
+// @Param       offset    query int    false "Itens a pular; não combina com cursor"
+// @Param       cursor    query string false "next_cursor da página anterior"
+// @Param       total     query bool   false "Conta os registros aceitos pelos filtros; ignorado com cursor"
+// @Param       sort      query string false "created_at, updated_at, numero ou tipo; prefixo - para decrescente (padrão -created_at)"
+// @Success     200 {object} domain.Pagina[domain.Telefone]
+// @Failure     400 {object} map[string]string
 // @Failure     500 {object} map[string]string
 // @Router      /telefones [get]
 func (h *Handler) ListTelefones(c *gin.Context) {
-	telefones, err := h.telefoneUseCase.ListTelefones()
+	pagina, ok := parametrosPagina(c)
+	if !ok {
+		return
+	}
+
+	filtro := domain.TelefoneFiltro{Numero: c.Query("numero"), Tipo: c.Query("tipo"), PessoaID: c.Query("pessoa_id")}
+	if filtro.PessoaID != "" && !primitive.IsValidObjectID(filtro.PessoaID) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return
+	}
+
+	telefones, err := h.telefoneUseCase.ListTelefones(filtro, pagina)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"erro": err.Error()})
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
 		return
 	}
 
@@ -241,6 +349,7 @@ func (h *Handler) GetTelefone(c *gin.Context) {
 // @Param       telefone body domain.Telefone true "Dados do telefone"
 // @Success     200 {object} domain.Telefone
 // @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
 // @Failure     500 {object} map[string]string
 // @Router      /telefones/{id} [put]
 func (h *Handler) UpdateTelefone(c *gin.Context) {
@@ -264,7 +373,7 @@ func (h *Handler) UpdateTelefone(c *gin.Context) {
 
 	telefone.ID = objectID
 	if err := h.telefoneUseCase.UpdateTelefone(&telefone); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"erro": err.Error()})
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
 		return
 	}
 
@@ -272,13 +381,15 @@ func (h *Handler) UpdateTelefone(c *gin.Context) {
 }
 
 // @Summary     Deletar telefone
-// @Description Remove um telefone do sistema
+// @Description Move um telefone para a lixeira
 // @Tags        telefones
 // @Accept      json
 // @Produce     json
-// @Param       id path string true "ID do telefone"
+// @Param       id        path   string true  "ID do telefone"
+// @Param       X-Usuario header string false "Quem removeu, gravado em deleted_by"
 // @Success     200 {object} map[string]string
 // @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
 // @Failure     500 {object} map[string]string
 // @Router      /telefones/{id} [delete]
 func (h *Handler) DeleteTelefone(c *gin.Context) {
@@ -288,8 +399,8 @@ func (h *Handler) DeleteTelefone(c *gin.Context) {
 		return
 	}
 
-	if err := h.telefoneUseCase.DeleteTelefone(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"erro": err.Error()})
+	if err := h.telefoneUseCase.DeleteTelefone(id, c.GetHeader(headerUsuario)); err != nil {
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
 		return
 	}
 
@@ -297,17 +408,47 @@ func (h *Handler) DeleteTelefone(c *gin.Context) {
 }
 
 // @Summary     Listar contextos
-// @Description Retorna a lista de todos os contextos cadastrados
+// @Description Retorna uma página dos contextos cadastrados, opcionalmente filtrados por nome ou data de início
 // @Tags        contextos
 // @Accept      json
 // @Produce     json
-// @Success     200 {array} domain.Contexto
+// @Param       nome            query string false "Parte do nome, sem diferenciar maiúsculas"
+// @Param       data_inicio_de  query string false "Data de início mínima (AAAA-MM-DD)"
+// @Param       data_inicio_ate query string false "Data de início máxima, inclusive (AAAA-MM-DD)"
+// @Param       limit           query int    false "Itens por página (padrão 50, máximo 200)"
+// @Param       offset          query int    false "Itens a pular; não combina com cursor"
+// @Param       cursor          query string false "next_cursor da página anterior"
+// @Param       total           query bool   false "Conta os registros aceitos pelos filtros; ignorado com cursor"
+// @Param       sort            query string false "created_at, updated_at, nome, data_inicio ou data_fim; prefixo - para decrescente (padrão -created_at)"
+// @Param       expand          query bool   false "Inclui as pessoas de cada contexto"
+// @Success     200 {object} domain.Pagina[domain.Contexto]
+// @Failure     400 {object} map[string]string
 // @Failure     500 {object} map[string]string
 // @Router      /contextos [get]
 func (h *Handler) ListContextos(c *gin.Context) {
-	contextos, err := h.contextoUseCase.ListContextos()
+	pagina, ok := parametrosPagina(c)
+	if !ok {
+		return
+	}
+
+	filtro := domain.ContextoFiltro{Nome: c.Query("nome")}
+	if filtro.Expandir, ok = expandir(c); !ok {
+		return
+	}
+	if filtro.DataInicioDe, ok = data(c, "data_inicio_de"); !ok {
+		return
+	}
+	if filtro.DataInicioAte, ok = data(c, "data_inicio_ate"); !ok {
+		return
+	}
+	if !filtro.DataInicioAte.IsZero() {
+		// O filtro é exclusivo; a data informada vale o dia inteiro
+		filtro.DataInicioAte = filtro.DataInicioAte.AddDate(0, 0, 1)
+	}
+
+	contextos, err := h.contextoUseCase.ListContextos(filtro, pagina)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"erro": err.Error()})
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
 		return
 	}
 
@@ -332,7 +473,7 @@ func (h *Handler) CreateContexto(c *gin.Context) {
 	}
 
 	if err := h.contextoUseCase.CreateContexto(&contexto); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"erro": err.Error()})
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
 		return
 	}
 
@@ -397,7 +538,7 @@ func (h *Handler) UpdateContexto(c *gin.Context) {
 
 	contexto.ID = objectID
 	if err := h.contextoUseCase.UpdateContexto(&contexto); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"erro": err.Error()})
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
 		return
 	}
 
@@ -405,13 +546,16 @@ func (h *Handler) UpdateContexto(c *gin.Context) {
 }
 
 // @Summary     Deletar contexto
-// @Description Remove um contexto do sistema
+// @Description Move um contexto para a lixeira, aplicando a regra de remoção aos seus prompts
 // @Tags        contextos
 // @Accept      json
 // @Produce     json
-// @Param       id path string true "ID do contexto"
+// @Param       id        path   string true  "ID do contexto"
+// @Param       X-Usuario header string false "Quem removeu, gravado em deleted_by"
 // @Success     200 {object} map[string]string
 // @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
+// @Failure     409 {object} map[string]string
 // @Failure     500 {object} map[string]string
 // @Router      /contextos/{id} [delete]
 func (h *Handler) DeleteContexto(c *gin.Context) {
@@ -421,26 +565,99 @@ func (h *Handler) DeleteContexto(c *gin.Context) {
 		return
 	}
 
-	if err := h.contextoUseCase.DeleteContexto(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"erro": err.Error()})
+	if err := h.contextoUseCase.DeleteContexto(id, c.GetHeader(headerUsuario)); err != nil {
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"mensagem": "Contexto deletado com sucesso"})
 }
 
+// @Summary     Adicionar pessoa ao contexto
+// @Description Põe uma pessoa cadastrada no fim do contexto; se ela já estiver nele, nada muda
+// @Tags        contextos
+// @Accept      json
+// @Produce     json
+// @Param       id       path string true "ID do contexto"
+// @Param       pessoaId path string true "ID da pessoa"
+// @Success     200 {object} domain.Contexto
+// @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
+// @Failure     500 {object} map[string]string
+// @Router      /contextos/{id}/pessoas/{pessoaId} [post]
+func (h *Handler) AddPessoaContexto(c *gin.Context) {
+	id, pessoaID := c.Param("id"), c.Param("pessoaId")
+	if !primitive.IsValidObjectID(id) || !primitive.IsValidObjectID(pessoaID) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return
+	}
+
+	contexto, err := h.contextoUseCase.AddPessoa(id, pessoaID)
+	if err != nil {
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, contexto)
+}
+
+// @Summary     Remover pessoa do contexto
+// @Description Tira uma pessoa do contexto; a pessoa continua cadastrada
+// @Tags        contextos
+// @Accept      json
+// @Produce     json
+// @Param       id       path string true "ID do contexto"
+// @Param       pessoaId path string true "ID da pessoa"
+// @Success     200 {object} map[string]string
+// @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
+// @Failure     500 {object} map[string]string
+// @Router      /contextos/{id}/pessoas/{pessoaId} [delete]
+func (h *Handler) RemovePessoaContexto(c *gin.Context) {
+	id, pessoaID := c.Param("id"), c.Param("pessoaId")
+	if !primitive.IsValidObjectID(id) || !primitive.IsValidObjectID(pessoaID) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return
+	}
+
+	if err := h.contextoUseCase.RemovePessoa(id, pessoaID); err != nil {
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mensagem": "Pessoa removida do contexto com sucesso"})
+}
+
 // @Summary     Listar prompts
-// @Description Retorna a lista de todos os prompts cadastrados
+// @Description Retorna uma página dos prompts cadastrados, opcionalmente filtrados por contexto
 // @Tags        prompts
 // @Accept      json
 // @Produce     json
-// @Success     200 {array} domain.Prompt
+// @Param       contexto_id query string false "ID do contexto"
+// @Param       limit       query int    false "Itens por página (padrão 50, máximo 200)"
+// @Param       offset      query int    false "Itens a pular; não combina com cursor"
+// @Param       cursor      query string false "next_cursor da página anterior"
+// @Param       total       query bool   false "Conta os registros aceitos pelos filtros; ignorado com cursor"
+// @Param       sort        query string false "created_at, updated_at ou versao; prefixo - para decrescente (padrão -created_at)"
+// @Success     200 {object} domain.Pagina[domain.Prompt]
+// @Failure     400 {object} map[string]string
 // @Failure     500 {object} map[string]string
 // @Router      /prompts [get]
 func (h *Handler) ListPrompts(c *gin.Context) {
-	prompts, err := h.promptUseCase.ListPrompts()
+	pagina, ok := parametrosPagina(c)
+	if !ok {
+		return
+	}
+
+	filtro := domain.PromptFiltro{ContextoID: c.Query("contexto_id")}
+	if filtro.ContextoID != "" && !primitive.IsValidObjectID(filtro.ContextoID) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return
+	}
+
+	prompts, err := h.promptUseCase.ListPrompts(filtro, pagina)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"erro": err.Error()})
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
 		return
 	}
 
@@ -452,7 +669,8 @@ func (h *Handler) ListPrompts(c *gin.Context) {
 // @Tags        prompts
 // @Accept      json
 // @Produce     json
-// @Param       prompt body domain.Prompt true "Dados do prompt"
+// @Param       prompt    body   domain.Prompt true  "Dados do prompt"
+// @Param       X-Usuario header string        false "Autor da versão"
 // @Success     201 {object} domain.Prompt
 // @Failure     400 {object} map[string]string
 // @Failure     500 {object} map[string]string
@@ -464,8 +682,8 @@ func (h *Handler) CreatePrompt(c *gin.Context) {
 		return
 	}
 
-	if err := h.promptUseCase.CreatePrompt(&prompt); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"erro": err.Error()})
+	if err := h.promptUseCase.CreatePrompt(&prompt, c.GetHeader(headerUsuario)); err != nil {
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
 		return
 	}
 
@@ -499,14 +717,16 @@ func (h *Handler) GetPrompt(c *gin.Context) {
 }
 
 // @Summary     Atualizar prompt
-// @Description Atualiza os dados de um prompt específico
+// @Description Atualiza os dados de um prompt específico, registrando uma nova versão se o texto mudar
 // @Tags        prompts
 // @Accept      json
 // @Produce     json
-// @Param       id path string true "ID do prompt"
-// @Param       prompt body domain.Prompt true "Dados do prompt"
+// @Param       id        path   string        true  "ID do prompt"
+// @Param       prompt    body   domain.Prompt true  "Dados do prompt"
+// @Param       X-Usuario header string        false "Autor da versão"
 // @Success     200 {object} domain.Prompt
 // @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
 // @Failure     500 {object} map[string]string
 // @Router      /prompts/{id} [put]
 func (h *Handler) UpdatePrompt(c *gin.Context) {
@@ -529,8 +749,8 @@ func (h *Handler) UpdatePrompt(c *gin.Context) {
 	}
 
 	prompt.ID = objectID
-	if err := h.promptUseCase.UpdatePrompt(&prompt); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"erro": err.Error()})
+	if err := h.promptUseCase.UpdatePrompt(&prompt, c.GetHeader(headerUsuario)); err != nil {
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
 		return
 	}
 
@@ -538,13 +758,15 @@ func (h *Handler) UpdatePrompt(c *gin.Context) {
 }
 
 // @Summary     Deletar prompt
-// @Description Remove um prompt do sistema
+// @Description Move um prompt para a lixeira
 // @Tags        prompts
 // @Accept      json
 // @Produce     json
-// @Param       id path string true "ID do prompt"
+// @Param       id        path   string true  "ID do prompt"
+// @Param       X-Usuario header string false "Quem removeu, gravado em deleted_by"
 // @Success     200 {object} map[string]string
 // @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
 // @Failure     500 {object} map[string]string
 // @Router      /prompts/{id} [delete]
 func (h *Handler) DeletePrompt(c *gin.Context) {
@@ -554,10 +776,111 @@ func (h *Handler) DeletePrompt(c *gin.Context) {
 		return
 	}
 
-	if err := h.promptUseCase.DeletePrompt(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"erro": err.Error()})
+	if err := h.promptUseCase.DeletePrompt(id, c.GetHeader(headerUsuario)); err != nil {
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"mensagem": "Prompt deletado com sucesso"})
 }
+
+type renderPromptRequest struct {
+	PessoaID   string         `json:"pessoa_id"`
+	ContextoID string         `json:"contexto_id"`
+	Variaveis  map[string]any `json:"variaveis"`
+}
+
+// @Summary     Pré-visualizar prompt
+// @Description Renderiza o template do prompt com os dados da pessoa, do contexto e das variáveis informadas
+// @Tags        prompts
+// @Accept      json
+// @Produce     json
+// @Param       id     path string              true  "ID do prompt"
+// @Param       render body renderPromptRequest false "Pessoa, contexto e valores das variáveis"
+// @Success     200 {object} map[string]string
+// @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
+// @Router      /prompts/{id}/render [post]
+func (h *Handler) RenderPrompt(c *gin.Context) {
+	id := c.Param("id")
+	if !primitive.IsValidObjectID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return
+	}
+
+	var req renderPromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": err.Error()})
+		return
+	}
+	if (req.PessoaID != "" && !primitive.IsValidObjectID(req.PessoaID)) ||
+		(req.ContextoID != "" && !primitive.IsValidObjectID(req.ContextoID)) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return
+	}
+
+	conteudo, err := h.promptUseCase.RenderPrompt(id, req.PessoaID, req.ContextoID, req.Variaveis)
+	if err != nil {
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"conteudo": conteudo})
+}
+
+// @Summary     Listar respostas
+// @Description Retorna o histórico de respostas geradas, opcionalmente filtrado por prompt ou contexto
+// @Tags        respostas
+// @Accept      json
+// @Produce     json
+// @Param       prompt_id   query string false "ID do prompt"
+// @Param       contexto_id query string false "ID do contexto"
+// @Success     200 {array} domain.Resposta
+// @Failure     400 {object} map[string]string
+// @Failure     500 {object} map[string]string
+// @Router      /respostas [get]
+func (h *Handler) ListRespostas(c *gin.Context) {
+	filtro := domain.RespostaFiltro{
+		PromptID:   c.Query("prompt_id"),
+		ContextoID: c.Query("contexto_id"),
+	}
+	if (filtro.PromptID != "" && !primitive.IsValidObjectID(filtro.PromptID)) ||
+		(filtro.ContextoID != "" && !primitive.IsValidObjectID(filtro.ContextoID)) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return
+	}
+
+	respostas, err := h.respostaUseCase.ListRespostas(filtro)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"erro": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, respostas)
+}
+
+// @Summary     Buscar resposta
+// @Description Retorna uma resposta gerada específica
+// @Tags        respostas
+// @Accept      json
+// @Produce     json
+// @Param       id path string true "ID da resposta"
+// @Success     200 {object} domain.Resposta
+// @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
+// @Router      /respostas/{id} [get]
+func (h *Handler) GetResposta(c *gin.Context) {
+	id := c.Param("id")
+	if !primitive.IsValidObjectID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return
+	}
+
+	resposta, err := h.respostaUseCase.GetResposta(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"erro": "Resposta não encontrada"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resposta)
+}
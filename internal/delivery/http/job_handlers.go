@@ -0,0 +1,79 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"vend/internal/domain"
+	"vend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type loteRequest struct {
+	Variaveis  map[string]any           `json:"variaveis"`
+	Parametros domain.ParametrosGeracao `json:"parametros"`
+}
+
+// @Summary     Executar prompt em lote
+// @Description Enfileira um job que executa o prompt para cada pessoa do contexto; acompanhe por GET /jobs/{id}
+// @Tags        contextos
+// @Accept      json
+// @Produce     json
+// @Param       id       path string true "ID do contexto"
+// @Param       promptId path string true "ID do prompt"
+// @Param       lote     body loteRequest false "Valores das variáveis do template e parâmetros de geração"
+// @Success     202 {object} domain.Job
+// @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
+// @Failure     500 {object} map[string]string
+// @Router      /contextos/{id}/prompts/{promptId}/lote [post]
+func (h *Handler) ExecutarLote(c *gin.Context) {
+	contextoID := c.Param("id")
+	promptID := c.Param("promptId")
+	if !primitive.IsValidObjectID(contextoID) || !primitive.IsValidObjectID(promptID) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return
+	}
+
+	var req loteRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": err.Error()})
+		return
+	}
+
+	job, err := h.jobUseCase.CriarLote(contextoID, promptID, usecase.OpcoesLote{Variaveis: req.Variaveis, Parametros: req.Parametros})
+	if err != nil {
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// @Summary     Buscar job
+// @Description Retorna o progresso do job, com o resultado ou o erro de cada pessoa
+// @Tags        jobs
+// @Accept      json
+// @Produce     json
+// @Param       id path string true "ID do job"
+// @Success     200 {object} domain.Job
+// @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
+// @Router      /jobs/{id} [get]
+func (h *Handler) GetJob(c *gin.Context) {
+	id := c.Param("id")
+	if !primitive.IsValidObjectID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return
+	}
+
+	job, err := h.jobUseCase.GetJob(id)
+	if err != nil {
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
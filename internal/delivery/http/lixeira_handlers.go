@@ -0,0 +1,72 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// @Summary     Listar lixeira
+// @Description Retorna uma página das pessoas, telefones, contextos e prompts removidos, dos mais recentes aos mais antigos
+// @Tags        lixeira
+// @Accept      json
+// @Produce     json
+// @Param       entidade query string false "pessoas, telefones, contextos ou prompts; vazio lista todas"
+// @Param       limit    query int    false "Itens por página (padrão 50, máximo 200)"
+// @Param       offset   query int    false "Itens a pular; não combina com cursor"
+// @Param       cursor   query string false "next_cursor da página anterior"
+// @Param       total    query bool   false "Conta os registros aceitos pelos filtros; ignorado com cursor"
+// @Param       sort     query string false "deleted_at; prefixo - para decrescente (padrão -deleted_at)"
+// @Success     200 {object} domain.Pagina[domain.ItemLixeira]
+// @Failure     400 {object} map[string]string
+// @Failure     500 {object} map[string]string
+// @Router      /lixeira [get]
+func (h *Handler) ListLixeira(c *gin.Context) {
+	pagina, ok := parametrosPagina(c)
+	if !ok {
+		return
+	}
+
+	resultado, err := h.lixeiraUseCase.ListLixeira(c.Query("entidade"), pagina)
+	if err != nil {
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resultado)
+}
+
+// Restaurar retorna o handler que tira da lixeira os registros da entidade
+//
+// @Summary     Restaurar da lixeira
+// @Description Tira o registro da lixeira com os telefones ou prompts removidos em cascata com ele; telefones e prompts só voltam se a pessoa ou o contexto não estiverem na lixeira
+// @Tags        lixeira
+// @Accept      json
+// @Produce     json
+// @Param       id path string true "ID do registro"
+// @Success     200 {object} map[string]string
+// @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
+// @Failure     409 {object} map[string]string
+// @Failure     500 {object} map[string]string
+// @Router      /pessoas/{id}/restaurar [post]
+// @Router      /telefones/{id}/restaurar [post]
+// @Router      /contextos/{id}/restaurar [post]
+// @Router      /prompts/{id}/restaurar [post]
+func (h *Handler) Restaurar(entidade string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		if !primitive.IsValidObjectID(id) {
+			c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+			return
+		}
+
+		if err := h.lixeiraUseCase.Restaurar(entidade, id); err != nil {
+			c.JSON(statusErro(err), gin.H{"erro": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"mensagem": "Registro restaurado com sucesso"})
+	}
+}
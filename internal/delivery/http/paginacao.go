@@ -0,0 +1,69 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"vend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parametrosPagina lê limit, offset, cursor, sort e total; responde 400 se
+// limit ou offset não forem números ou se total não for booleano. Os demais
+// limites são validados no caso de uso.
+func parametrosPagina(c *gin.Context) (usecase.ParametrosPagina, bool) {
+	pagina := usecase.ParametrosPagina{Cursor: c.Query("cursor"), Ordem: c.Query("sort")}
+	for nome, destino := range map[string]*int{"limit": &pagina.Limite, "offset": &pagina.Offset} {
+		valor := c.Query(nome)
+		if valor == "" {
+			continue
+		}
+		n, err := strconv.Atoi(valor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"erro": nome + " deve ser um número"})
+			return pagina, false
+		}
+		*destino = n
+	}
+	if valor := c.Query("total"); valor != "" {
+		total, err := strconv.ParseBool(valor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"erro": "total deve ser true ou false"})
+			return pagina, false
+		}
+		pagina.Total = total
+	}
+	return pagina, true
+}
+
+// data lê o parâmetro de data (AAAA-MM-DD); vazio retorna a data zero e
+// inválido responde 400
+func data(c *gin.Context, nome string) (time.Time, bool) {
+	valor := c.Query(nome)
+	if valor == "" {
+		return time.Time{}, true
+	}
+
+	t, err := time.Parse(time.DateOnly, valor)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": nome + " inválido, use o formato AAAA-MM-DD"})
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// expandir lê o parâmetro expand (true ou false); inválido responde 400
+func expandir(c *gin.Context) (bool, bool) {
+	valor := c.Query("expand")
+	if valor == "" {
+		return false, true
+	}
+
+	expandir, err := strconv.ParseBool(valor)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "expand deve ser true ou false"})
+		return false, false
+	}
+	return expandir, true
+}
@@ -0,0 +1,123 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// @Summary     Listar versões do prompt
+// @Description Retorna todas as versões do prompt, da mais antiga para a mais recente
+// @Tags        prompts
+// @Accept      json
+// @Produce     json
+// @Param       id path string true "ID do prompt"
+// @Success     200 {array} domain.PromptVersao
+// @Failure     400 {object} map[string]string
+// @Failure     500 {object} map[string]string
+// @Router      /prompts/{id}/versoes [get]
+func (h *Handler) ListPromptVersoes(c *gin.Context) {
+	id := c.Param("id")
+	if !primitive.IsValidObjectID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID inválido"})
+		return
+	}
+
+	versoes, err := h.promptUseCase.ListVersoes(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"erro": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, versoes)
+}
+
+// @Summary     Buscar versão do prompt
+// @Description Retorna uma versão específica do prompt
+// @Tags        prompts
+// @Accept      json
+// @Produce     json
+// @Param       id     path string true "ID do prompt"
+// @Param       numero path int    true "Número da versão"
+// @Success     200 {object} domain.PromptVersao
+// @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
+// @Router      /prompts/{id}/versoes/{numero} [get]
+func (h *Handler) GetPromptVersao(c *gin.Context) {
+	id := c.Param("id")
+	numero, err := strconv.Atoi(c.Param("numero"))
+	if !primitive.IsValidObjectID(id) || err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID ou número de versão inválido"})
+		return
+	}
+
+	versao, err := h.promptUseCase.GetVersao(id, numero)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"erro": "Versão não encontrada"})
+		return
+	}
+
+	c.JSON(http.StatusOK, versao)
+}
+
+// @Summary     Comparar versões do prompt
+// @Description Retorna o diff linha a linha entre duas versões do prompt
+// @Tags        prompts
+// @Accept      json
+// @Produce     json
+// @Param       id   path  string true "ID do prompt"
+// @Param       de   query int    true "Versão de origem"
+// @Param       para query int    true "Versão de destino"
+// @Success     200 {object} map[string]string
+// @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
+// @Router      /prompts/{id}/diff [get]
+func (h *Handler) DiffPromptVersoes(c *gin.Context) {
+	id := c.Param("id")
+	de, errDe := strconv.Atoi(c.Query("de"))
+	para, errPara := strconv.Atoi(c.Query("para"))
+	if !primitive.IsValidObjectID(id) || errDe != nil || errPara != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID ou números de versão inválidos"})
+		return
+	}
+
+	diff, err := h.promptUseCase.DiffVersoes(id, de, para)
+	if err != nil {
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"diff": diff})
+}
+
+// @Summary     Restaurar versão do prompt
+// @Description Volta o prompt ao texto de uma versão anterior, registrando a restauração como nova versão
+// @Tags        prompts
+// @Accept      json
+// @Produce     json
+// @Param       id        path   string true  "ID do prompt"
+// @Param       numero    path   int    true  "Número da versão"
+// @Param       X-Usuario header string false "Autor da restauração"
+// @Success     200 {object} domain.Prompt
+// @Failure     400 {object} map[string]string
+// @Failure     404 {object} map[string]string
+// @Failure     500 {object} map[string]string
+// @Router      /prompts/{id}/versoes/{numero}/restaurar [post]
+func (h *Handler) RestaurarPromptVersao(c *gin.Context) {
+	id := c.Param("id")
+	numero, err := strconv.Atoi(c.Param("numero"))
+	if !primitive.IsValidObjectID(id) || err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "ID ou número de versão inválido"})
+		return
+	}
+
+	prompt, err := h.promptUseCase.RestaurarVersao(id, numero, c.GetHeader(headerUsuario))
+	if err != nil {
+		c.JSON(statusErro(err), gin.H{"erro": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, prompt)
+}
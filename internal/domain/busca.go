@@ -0,0 +1,159 @@
+package domain
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Embedding é o vetor de um documento; Hash identifica o texto e o modelo
+// usados, para que o vetor seja recalculado quando um deles mudar
+type Embedding struct {
+	Modelo string    `bson:"modelo"`
+	Hash   string    `bson:"hash"`
+	Vetor  []float32 `bson:"vetor"`
+}
+
+const (
+	TipoBuscaPrompt   = "prompt"
+	TipoBuscaResposta = "resposta"
+	TipoBuscaPessoa   = "pessoa"
+	TipoBuscaContexto = "contexto"
+)
+
+// DocumentoVetorial é um documento indexado para busca semântica
+type DocumentoVetorial struct {
+	Tipo  string
+	ID    string
+	Texto string
+	Vetor []float32
+}
+
+// ResultadoBusca é um documento encontrado, com a similaridade de cosseno
+// entre o seu vetor e o da consulta
+type ResultadoBusca struct {
+	Tipo   string  `json:"tipo"`
+	ID     string  `json:"id"`
+	Trecho string  `json:"trecho"`
+	Score  float64 `json:"score"`
+}
+
+// IndiceVetorial guarda os vetores dos documentos e retorna os mais próximos
+// de uma consulta; tipos vazio busca em todos os tipos
+type IndiceVetorial interface {
+	Indexar(ctx context.Context, docs ...DocumentoVetorial) error
+	Remover(ctx context.Context, tipo string, ids ...string) error
+	Buscar(ctx context.Context, vetor []float32, k int, tipos ...string) ([]ResultadoBusca, error)
+}
+
+// Similaridade é o cosseno entre dois vetores; falso se as dimensões forem
+// diferentes (modelos distintos) ou um dos vetores for nulo
+func Similaridade(a, b []float32) (float64, bool) {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0, false
+	}
+
+	var produto, normaA, normaB float64
+	for i := range a {
+		produto += float64(a[i]) * float64(b[i])
+		normaA += float64(a[i]) * float64(a[i])
+		normaB += float64(b[i]) * float64(b[i])
+	}
+	if normaA == 0 || normaB == 0 {
+		return 0, false
+	}
+	return produto / (math.Sqrt(normaA) * math.Sqrt(normaB)), true
+}
+
+// CamposBuscaTexto são os campos (nomes no banco) pesquisados pela busca
+// textual em cada tipo, na ordem dos destaques; o primeiro é o título
+var CamposBuscaTexto = map[string][]string{
+	TipoBuscaPessoa:   {"nome", "email"},
+	TipoBuscaContexto: {"nome", "descricao"},
+	TipoBuscaPrompt:   {"conteudo"},
+}
+
+// BuscaTextoFiltro pede os registros com qualquer um dos termos (já
+// normalizados) nos tipos pedidos, até Limite por tipo, dos mais relevantes
+// aos menos
+type BuscaTextoFiltro struct {
+	Termos []string
+	Tipos  []string
+	Limite int
+}
+
+// ResultadoTexto é um registro encontrado pela busca textual. Score é a
+// relevância calculada pelo armazenamento; Destaques trazem os trechos dos
+// campos com os termos encontrados entre <em> e </em>.
+type ResultadoTexto struct {
+	Tipo      string     `json:"tipo"`
+	ID        string     `json:"id"`
+	Titulo    string     `json:"titulo"`
+	Score     float64    `json:"score"`
+	Destaques []Destaque `json:"destaques"`
+	// Campos são os textos pesquisados, por campo, de onde saem os destaques
+	Campos map[string]string `json:"-"`
+}
+
+type Destaque struct {
+	Campo  string `json:"campo"`
+	Trecho string `json:"trecho"`
+}
+
+// NormalizarTexto passa o texto para minúsculas e retira os acentos
+func NormalizarTexto(texto string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(texto)) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// TermosBusca separa o texto da busca em palavras normalizadas, sem repetir
+func TermosBusca(texto string) []string {
+	var termos []string
+	for _, palavra := range strings.FieldsFunc(NormalizarTexto(texto), separador) {
+		if !slices.Contains(termos, palavra) {
+			termos = append(termos, palavra)
+		}
+	}
+	return termos
+}
+
+// PalavrasComTermo retorna as posições (início e fim, em bytes) das palavras
+// do texto que correspondem a algum dos termos: a palavra começa pelo termo
+// ou, com ao menos 4 letras, é o começo dele (desconto para descontos)
+func PalavrasComTermo(texto string, termos []string) [][2]int {
+	var posicoes [][2]int
+	inicio := -1
+	for i, r := range texto + " " {
+		if !separador(r) {
+			if inicio < 0 {
+				inicio = i
+			}
+			continue
+		}
+		if inicio < 0 {
+			continue
+		}
+		palavra := NormalizarTexto(texto[inicio:i])
+		for _, termo := range termos {
+			if strings.HasPrefix(palavra, termo) || (len(palavra) >= 4 && strings.HasPrefix(termo, palavra)) {
+				posicoes = append(posicoes, [2]int{inicio, i})
+				break
+			}
+		}
+		inicio = -1
+	}
+	return posicoes
+}
+
+func separador(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
@@ -1,26 +1,41 @@
 package domain
 
 import (
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// ErrNaoEncontrado é retornado pelos repositórios quando o registro não existe
+var ErrNaoEncontrado = errors.New("registro não encontrado")
+
+// Pessoa.Telefones e Pessoa.Contextos não são gravados com a pessoa: vêm dos
+// telefones com o seu PessoaID e dos contextos com o seu ID em PessoaIDs,
+// preenchidos ao buscar a pessoa ou ao expandir a listagem
 type Pessoa struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Nome      string             `bson:"nome" json:"nome" binding:"required"`
 	Email     string             `bson:"email" json:"email" binding:"required"`
-	Telefones []Telefone         `bson:"telefones,omitempty" json:"telefones,omitempty"`
-	Contextos []Contexto         `bson:"contextos,omitempty" json:"contextos,omitempty"`
+	Telefones []Telefone         `bson:"-" json:"telefones,omitempty"`
+	Contextos []Contexto         `bson:"-" json:"contextos,omitempty"`
 	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	// DeletedAt e DeletedBy marcam o registro que está na lixeira, fora das
+	// leituras; aparecem só na listagem da lixeira
+	DeletedAt *time.Time `bson:"deleted_at,omitempty" json:"-"`
+	DeletedBy string     `bson:"deleted_by,omitempty" json:"-"`
 }
 
 type Telefone struct {
-	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Numero   string             `bson:"numero" json:"numero" binding:"required"`
-	Tipo     string             `bson:"tipo" json:"tipo" binding:"required"`
-	PessoaID primitive.ObjectID `bson:"pessoa_id" json:"pessoa_id"`
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Numero    string             `bson:"numero" json:"numero" binding:"required"`
+	Tipo      string             `bson:"tipo" json:"tipo" binding:"required"`
+	PessoaID  primitive.ObjectID `bson:"pessoa_id" json:"pessoa_id"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	DeletedAt *time.Time         `bson:"deleted_at,omitempty" json:"-"`
+	DeletedBy string             `bson:"deleted_by,omitempty" json:"-"`
 }
 
 type Contexto struct {
@@ -29,40 +44,301 @@ type Contexto struct {
 	Descricao  string             `bson:"descricao" json:"descricao"`
 	DataInicio time.Time          `bson:"data_inicio" json:"data_inicio"`
 	DataFim    time.Time          `bson:"data_fim" json:"data_fim"`
-	Pessoas    []Pessoa           `bson:"pessoas,omitempty" json:"pessoas,omitempty"`
-	Prompts    []Prompt           `bson:"prompts,omitempty" json:"prompts,omitempty"`
+	// OrcamentoTokensMensal limita os tokens gerados no contexto por mês; 0 é ilimitado
+	OrcamentoTokensMensal int64 `bson:"orcamento_tokens_mensal,omitempty" json:"orcamento_tokens_mensal,omitempty"`
+	// Parametros são os padrões de geração dos prompts do contexto
+	Parametros ParametrosGeracao `bson:"parametros,omitempty" json:"parametros,omitempty"`
+	// Privacidade define o tratamento dos dados pessoais enviados ao provedor
+	Privacidade PoliticaPrivacidade `bson:"privacidade,omitempty" json:"privacidade,omitempty"`
+	// PessoaIDs são as pessoas envolvidas, na ordem em que entraram; informadas
+	// na criação e depois alteradas só por AddPessoaContexto e RemovePessoaContexto
+	PessoaIDs []primitive.ObjectID `bson:"pessoa_ids,omitempty" json:"pessoa_ids,omitempty"`
+	// Pessoas não é gravado: vem de PessoaIDs ao buscar o contexto ou ao
+	// expandir a listagem
+	Pessoas   []Pessoa   `bson:"-" json:"pessoas,omitempty"`
+	Prompts   []Prompt   `bson:"prompts,omitempty" json:"prompts,omitempty"`
+	CreatedAt time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time  `bson:"updated_at" json:"updated_at"`
+	DeletedAt *time.Time `bson:"deleted_at,omitempty" json:"-"`
+	DeletedBy string     `bson:"deleted_by,omitempty" json:"-"`
 }
 
+// Prompt.Conteudo é um template (text/template) que pode usar .Pessoa,
+// .Contexto e as variáveis declaradas em .Vars
 type Prompt struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Conteudo  string             `bson:"conteudo" json:"conteudo" binding:"required"`
+	Variaveis []VariavelPrompt   `bson:"variaveis,omitempty" json:"variaveis,omitempty"`
+	// Parametros sobrepõem os parâmetros de geração do contexto
+	Parametros ParametrosGeracao `bson:"parametros,omitempty" json:"parametros,omitempty"`
+	// SchemaSaida é um JSON Schema (objeto na raiz); quando presente, a geração
+	// pede saída estruturada e a resposta é validada contra ele
+	SchemaSaida map[string]any     `bson:"schema_saida,omitempty" json:"schema_saida,omitempty"`
+	ContextoID  primitive.ObjectID `bson:"contexto_id" json:"contexto_id"`
+	Versao      int                `bson:"versao" json:"versao"`
+	// Embedding do conteúdo, calculado em segundo plano para a busca semântica
+	Embedding *Embedding `bson:"embedding,omitempty" json:"-"`
+	CreatedAt time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time  `bson:"updated_at" json:"updated_at"`
+	DeletedAt *time.Time `bson:"deleted_at,omitempty" json:"-"`
+	DeletedBy string     `bson:"deleted_by,omitempty" json:"-"`
+}
+
+// ParametrosGeracao configura a chamada ao modelo. Campos vazios (nil) herdam
+// o valor do nível anterior: padrão do sistema, contexto, prompt e requisição.
+type ParametrosGeracao struct {
+	Modelo      string   `bson:"modelo,omitempty" json:"modelo,omitempty"`
+	Temperatura *float32 `bson:"temperatura,omitempty" json:"temperatura,omitempty"`
+	MaxTokens   *int     `bson:"max_tokens,omitempty" json:"max_tokens,omitempty"`
+	TopP        *float32 `bson:"top_p,omitempty" json:"top_p,omitempty"`
+	Stop        []string `bson:"stop,omitempty" json:"stop,omitempty"`
+	// Ferramentas lista as ferramentas que o modelo pode chamar, como buscar_pessoa
+	Ferramentas []string `bson:"ferramentas,omitempty" json:"ferramentas,omitempty"`
+	// PrefixoSistema é acrescentado no início da mensagem de sistema
+	PrefixoSistema string `bson:"prefixo_sistema,omitempty" json:"prefixo_sistema,omitempty"`
+}
+
+// Sobrepor retorna os parâmetros com os campos preenchidos em outro
+// substituindo os atuais
+func (p ParametrosGeracao) Sobrepor(outro ParametrosGeracao) ParametrosGeracao {
+	if outro.Modelo != "" {
+		p.Modelo = outro.Modelo
+	}
+	if outro.Temperatura != nil {
+		p.Temperatura = outro.Temperatura
+	}
+	if outro.MaxTokens != nil {
+		p.MaxTokens = outro.MaxTokens
+	}
+	if outro.TopP != nil {
+		p.TopP = outro.TopP
+	}
+	if outro.Stop != nil {
+		p.Stop = outro.Stop
+	}
+	if outro.Ferramentas != nil {
+		p.Ferramentas = outro.Ferramentas
+	}
+	if outro.PrefixoSistema != "" {
+		p.PrefixoSistema = outro.PrefixoSistema
+	}
+	return p
+}
+
+const (
+	TipoVariavelTexto    = "texto"
+	TipoVariavelNumero   = "numero"
+	TipoVariavelData     = "data"
+	TipoVariavelBooleano = "booleano"
+)
+
+type VariavelPrompt struct {
+	Nome        string `bson:"nome" json:"nome"`
+	Tipo        string `bson:"tipo" json:"tipo"`
+	Obrigatoria bool   `bson:"obrigatoria" json:"obrigatoria"`
+	Padrao      string `bson:"padrao,omitempty" json:"padrao,omitempty"`
+	Descricao   string `bson:"descricao,omitempty" json:"descricao,omitempty"`
+}
+
+// PromptVersao é uma revisão imutável do prompt; Diff compara com a revisão anterior
+type PromptVersao struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PromptID  primitive.ObjectID `bson:"prompt_id" json:"prompt_id"`
+	Numero    int                `bson:"numero" json:"numero"`
+	Conteudo  string             `bson:"conteudo" json:"conteudo"`
+	Variaveis []VariavelPrompt   `bson:"variaveis,omitempty" json:"variaveis,omitempty"`
+	Autor     string             `bson:"autor" json:"autor"`
+	Diff      string             `bson:"diff" json:"diff"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+type Mensagem struct {
+	Papel    string `bson:"papel" json:"papel"`
+	Conteudo string `bson:"conteudo" json:"conteudo"`
+	// ChamadasFerramenta são as ferramentas pedidas pelo assistente nesta mensagem
+	ChamadasFerramenta []ChamadaFerramenta `bson:"chamadas_ferramenta,omitempty" json:"chamadas_ferramenta,omitempty"`
+	// ChamadaID liga a mensagem de papel "tool" à chamada que ela responde
+	ChamadaID string `bson:"chamada_id,omitempty" json:"chamada_id,omitempty"`
+}
+
+// ChamadaFerramenta é um pedido do modelo para executar uma ferramenta;
+// Argumentos é um objeto JSON
+type ChamadaFerramenta struct {
+	ID         string `bson:"id" json:"id"`
+	Nome       string `bson:"nome" json:"nome"`
+	Argumentos string `bson:"argumentos" json:"argumentos"`
+}
+
+type UsoTokens struct {
+	PromptTokens     int `bson:"prompt_tokens" json:"prompt_tokens"`
+	CompletionTokens int `bson:"completion_tokens" json:"completion_tokens"`
+	TotalTokens      int `bson:"total_tokens" json:"total_tokens"`
+}
+
+type Resposta struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PromptID     primitive.ObjectID `bson:"prompt_id" json:"prompt_id"`
+	PromptVersao int                `bson:"prompt_versao,omitempty" json:"prompt_versao,omitempty"`
+	ContextoID   primitive.ObjectID `bson:"contexto_id" json:"contexto_id"`
+	PessoaID     primitive.ObjectID `bson:"pessoa_id,omitempty" json:"pessoa_id,omitempty"`
+	ConversaID   primitive.ObjectID `bson:"conversa_id,omitempty" json:"conversa_id,omitempty"`
+	Modelo       string             `bson:"modelo" json:"modelo"`
+	Temperatura  float32            `bson:"temperatura" json:"temperatura"`
+	Mensagens    []Mensagem         `bson:"mensagens" json:"mensagens"`
+	Conteudo     string             `bson:"conteudo" json:"conteudo"`
+	// Saida é o objeto JSON da resposta, para prompts com SchemaSaida
+	Saida         any       `bson:"saida,omitempty" json:"saida,omitempty"`
+	Uso           UsoTokens `bson:"uso" json:"uso"`
+	CustoEstimado float64   `bson:"custo_estimado" json:"custo_estimado"`
+	DoCache       bool      `bson:"do_cache,omitempty" json:"do_cache,omitempty"`
+	LatenciaMs    int64     `bson:"latencia_ms" json:"latencia_ms"`
+	// Fontes são os trechos de documentos do contexto enviados ao modelo, na
+	// numeração usada nas citações ([1], [2]...)
+	Fontes []Fonte `bson:"fontes,omitempty" json:"fontes,omitempty"`
+	// Embedding do conteúdo, calculado em segundo plano para a busca semântica
+	Embedding *Embedding `bson:"embedding,omitempty" json:"-"`
+	CreatedAt time.Time  `bson:"created_at" json:"created_at"`
+}
+
+// RespostaFiltro restringe a listagem de respostas; campos vazios são
+// ignorados e, sem Limite, a listagem traz todas as respostas
+type RespostaFiltro struct {
+	PromptID   string
+	ContextoID string
+	Paginacao
+}
+
+// UsoEmbeddings registra os tokens de uma chamada de embeddings feita para um
+// contexto (trechos de documentos, prompts, respostas e consultas dos
+// documentos); soma ao consumo do contexto sem contar como geração
+type UsoEmbeddings struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ContextoID    primitive.ObjectID `bson:"contexto_id" json:"contexto_id"`
+	Modelo        string             `bson:"modelo" json:"modelo"`
+	Uso           UsoTokens          `bson:"uso" json:"uso"`
+	CustoEstimado float64            `bson:"custo_estimado" json:"custo_estimado"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// ConsumoFiltro seleciona as respostas e os usos de embeddings somados no
+// consumo; IDs vazios são ignorados. Os embeddings não têm pessoa, então ficam
+// de fora do consumo filtrado por PessoaID.
+type ConsumoFiltro struct {
+	ContextoID string
+	PessoaID   string
+	Inicio     time.Time
+	Fim        time.Time
+}
+
+// Consumo é o total de tokens e custo estimado (em US$) das respostas e dos
+// embeddings de um período; EmbeddingTokens é a parte do total gasta em
+// embeddings
+type Consumo struct {
+	Inicio           time.Time `json:"inicio"`
+	Fim              time.Time `json:"fim"`
+	Geracoes         int64     `json:"geracoes"`
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	TotalTokens      int64     `json:"total_tokens"`
+	EmbeddingTokens  int64     `json:"embedding_tokens"`
+	CustoEstimado    float64   `json:"custo_estimado"`
+	OrcamentoTokens  int64     `json:"orcamento_tokens,omitempty"`
+	TokensRestantes  *int64    `json:"tokens_restantes,omitempty"`
+}
+
+type MensagemConversa struct {
+	Papel    string `bson:"papel" json:"papel"`
+	Conteudo string `bson:"conteudo" json:"conteudo"`
+	// Fontes são os trechos de documentos do contexto usados na resposta
+	Fontes    []Fonte   `bson:"fontes,omitempty" json:"fontes,omitempty"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+type Conversa struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PessoaID   primitive.ObjectID `bson:"pessoa_id" json:"pessoa_id"`
+	ContextoID primitive.ObjectID `bson:"contexto_id" json:"contexto_id"`
+	Mensagens  []MensagemConversa `bson:"mensagens" json:"mensagens"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// ConversaFiltro restringe a listagem de conversas; campos vazios são ignorados
+type ConversaFiltro struct {
+	PessoaID   string
+	ContextoID string
+}
+
+const (
+	StatusJobPendente   = "pendente"
+	StatusJobExecutando = "executando"
+	StatusJobConcluido  = "concluido"
+
+	StatusItemPendente  = "pendente"
+	StatusItemConcluido = "concluido"
+	StatusItemFalhou    = "falhou"
+)
+
+const (
+	FormatoTexto    = "texto"
+	FormatoMarkdown = "markdown"
+	FormatoPDF      = "pdf"
+)
+
+// Documento é um arquivo enviado a um contexto (fichas de produto, tabelas de
+// preço), dividido em trechos que as gerações do contexto podem consultar
+type Documento struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ContextoID primitive.ObjectID `bson:"contexto_id" json:"contexto_id"`
+	Nome       string             `bson:"nome" json:"nome"`
+	Formato    string             `bson:"formato" json:"formato"`
+	Caracteres int                `bson:"caracteres" json:"caracteres"`
+	Trechos    int                `bson:"trechos" json:"trechos"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// TrechoDocumento é uma parte do texto de um documento, com o seu embedding
+type TrechoDocumento struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	DocumentoID primitive.ObjectID `bson:"documento_id" json:"documento_id"`
+	ContextoID  primitive.ObjectID `bson:"contexto_id" json:"contexto_id"`
+	Documento   string             `bson:"documento" json:"documento"`
+	Indice      int                `bson:"indice" json:"indice"`
+	Conteudo    string             `bson:"conteudo" json:"conteudo"`
+	Embedding   *Embedding         `bson:"embedding,omitempty" json:"-"`
+}
+
+// Fonte identifica um trecho de documento citado em uma resposta
+type Fonte struct {
+	Numero      int                `bson:"numero" json:"numero"`
+	DocumentoID primitive.ObjectID `bson:"documento_id" json:"documento_id"`
+	Documento   string             `bson:"documento" json:"documento"`
+	Trecho      int                `bson:"trecho" json:"trecho"`
+	Score       float64            `bson:"score" json:"score"`
+}
+
+// Job é a execução de um prompt para cada pessoa de um contexto, processada
+// em segundo plano; o estado fica no banco para sobreviver a reinícios
+type Job struct {
 	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Conteudo   string             `bson:"conteudo" json:"conteudo" binding:"required"`
 	ContextoID primitive.ObjectID `bson:"contexto_id" json:"contexto_id"`
+	PromptID   primitive.ObjectID `bson:"prompt_id" json:"prompt_id"`
+	Variaveis  map[string]any     `bson:"variaveis,omitempty" json:"variaveis,omitempty"`
+	Parametros ParametrosGeracao  `bson:"parametros,omitempty" json:"parametros,omitempty"`
+	Status     string             `bson:"status" json:"status"`
+	Total      int                `bson:"total" json:"total"`
+	Concluidos int                `bson:"concluidos" json:"concluidos"`
+	Falhas     int                `bson:"falhas" json:"falhas"`
+	Itens      []ItemJob          `bson:"itens" json:"itens"`
 	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
 }
 
-type Repository interface {
-	CreatePessoa(pessoa *Pessoa) error
-	GetPessoa(id uint) (*Pessoa, error)
-	ListPessoas() ([]Pessoa, error)
-	UpdatePessoa(pessoa *Pessoa) error
-	DeletePessoa(id uint) error
-
-	CreateTelefone(telefone *Telefone) error
-	GetTelefone(id uint) (*Telefone, error)
-	ListTelefones() ([]Telefone, error)
-	UpdateTelefone(telefone *Telefone) error
-	DeleteTelefone(id uint) error
-
-	CreateContexto(contexto *Contexto) error
-	GetContexto(id uint) (*Contexto, error)
-	ListContextos() ([]Contexto, error)
-	UpdateContexto(contexto *Contexto) error
-	DeleteContexto(id uint) error
-
-	CreatePrompt(prompt *Prompt) error
-	GetPrompt(id uint) (*Prompt, error)
-	ListPrompts() ([]Prompt, error)
-	UpdatePrompt(prompt *Prompt) error
-	DeletePrompt(id uint) error
+// ItemJob é a geração para uma pessoa do job
+type ItemJob struct {
+	PessoaID   primitive.ObjectID `bson:"pessoa_id" json:"pessoa_id"`
+	Status     string             `bson:"status" json:"status"`
+	RespostaID primitive.ObjectID `bson:"resposta_id,omitempty" json:"resposta_id,omitempty"`
+	Conteudo   string             `bson:"conteudo,omitempty" json:"conteudo,omitempty"`
+	Erro       string             `bson:"erro,omitempty" json:"erro,omitempty"`
 }
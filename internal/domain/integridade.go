@@ -0,0 +1,29 @@
+package domain
+
+// Relacao é uma referência de uma entidade a outra, seguida ao remover a
+// entidade referenciada
+type Relacao string
+
+const (
+	// RelacaoPessoaTelefones são os telefones com o PessoaID da pessoa
+	RelacaoPessoaTelefones Relacao = "pessoa_telefones"
+	// RelacaoPessoaContextos são os contextos com a pessoa em PessoaIDs
+	RelacaoPessoaContextos Relacao = "pessoa_contextos"
+	// RelacaoContextoPrompts são os prompts com o ContextoID do contexto
+	RelacaoContextoPrompts Relacao = "contexto_prompts"
+)
+
+// Relacoes lista as relações na ordem em que são aplicadas
+var Relacoes = []Relacao{RelacaoPessoaTelefones, RelacaoPessoaContextos, RelacaoContextoPrompts}
+
+// Regras de remoção: o que acontece com os registros que apontam para o
+// registro removido
+const (
+	// RegraCascata remove os registros; nos contextos de uma pessoa, remove só
+	// a pessoa do contexto
+	RegraCascata = "cascade"
+	// RegraRestringir impede a remoção enquanto houver algum registro
+	RegraRestringir = "restrict"
+	// RegraAnular mantém os registros sem a referência
+	RegraAnular = "nullify"
+)
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Entidades com lixeira: remover um registro grava deleted_at e deleted_by, e
+// ele some das leituras até ser restaurado ou purgado
+const (
+	EntidadePessoas   = "pessoas"
+	EntidadeTelefones = "telefones"
+	EntidadeContextos = "contextos"
+	EntidadePrompts   = "prompts"
+)
+
+// EntidadesLixeira lista as entidades com lixeira
+var EntidadesLixeira = []string{EntidadePessoas, EntidadeTelefones, EntidadeContextos, EntidadePrompts}
+
+// CamposTituloLixeira é o campo que identifica os registros de cada entidade
+// na listagem da lixeira
+var CamposTituloLixeira = map[string]string{
+	EntidadePessoas:   "nome",
+	EntidadeTelefones: "numero",
+	EntidadeContextos: "nome",
+	EntidadePrompts:   "conteudo",
+}
+
+// CamposReferenciaLixeira é o campo que aponta para o registro a que os
+// telefones e os prompts pertencem
+var CamposReferenciaLixeira = map[string]string{
+	EntidadeTelefones: "pessoa_id",
+	EntidadePrompts:   "contexto_id",
+}
+
+// Remocao registra quando e por quem um registro foi para a lixeira
+type Remocao struct {
+	Em  time.Time
+	Por string
+}
+
+// ItemLixeira é um registro removido
+type ItemLixeira struct {
+	Entidade  string             `json:"entidade"`
+	ID        primitive.ObjectID `json:"id"`
+	Titulo    string             `json:"titulo"`
+	DeletedAt time.Time          `json:"deleted_at"`
+	DeletedBy string             `json:"deleted_by,omitempty"`
+	// ReferenciaID é a pessoa do telefone ou o contexto do prompt; zerado nas
+	// outras entidades e nas referências anuladas
+	ReferenciaID primitive.ObjectID `json:"-"`
+}
+
+// LixeiraFiltro restringe a listagem da lixeira; Entidade vazia lista todas,
+// ordenadas por deleted_at
+type LixeiraFiltro struct {
+	Entidade string
+	Paginacao
+}
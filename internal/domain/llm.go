@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// RequisicaoGeracao descreve uma chamada ao modelo de linguagem.
+// Modelo vazio faz o provedor usar o seu modelo padrão.
+type RequisicaoGeracao struct {
+	Modelo      string
+	Temperatura float32
+	// MaxTokens e TopP zerados usam o padrão do provedor
+	MaxTokens int
+	TopP      float32
+	Stop      []string
+	// SchemaSaida pede ao provedor uma resposta JSON que siga o schema
+	SchemaSaida map[string]any
+	Ferramentas []Ferramenta
+	Mensagens   []Mensagem
+}
+
+// Ferramenta descreve uma função que o modelo pode chamar; Parametros é o
+// JSON Schema dos argumentos
+type Ferramenta struct {
+	Nome       string
+	Descricao  string
+	Parametros map[string]any
+}
+
+// ResultadoGeracao traz o texto gerado ou, se o modelo pediu ferramentas,
+// as chamadas a executar antes de uma nova geração
+type ResultadoGeracao struct {
+	Modelo             string              `bson:"modelo"`
+	Conteudo           string              `bson:"conteudo"`
+	ChamadasFerramenta []ChamadaFerramenta `bson:"chamadas_ferramenta,omitempty"`
+	Uso                UsoTokens           `bson:"uso"`
+	// DoCache indica que o resultado veio do cache, sem chamada ao provedor
+	DoCache bool `bson:"-"`
+}
+
+// LLMProvider abstrai o provedor de modelo de linguagem (OpenAI, APIs
+// compatíveis ou o fake usado em testes)
+type LLMProvider interface {
+	Generate(ctx context.Context, req RequisicaoGeracao) (*ResultadoGeracao, error)
+	GenerateStream(ctx context.Context, req RequisicaoGeracao, onDelta func(string) error) (*ResultadoGeracao, error)
+}
+
+// ProvedorEmbeddings é implementado pelos provedores que também geram
+// embeddings; os vetores voltam na ordem dos textos recebidos
+type ProvedorEmbeddings interface {
+	Embeddings(ctx context.Context, textos []string) (*ResultadoEmbeddings, error)
+}
+
+type ResultadoEmbeddings struct {
+	Modelo  string
+	Vetores [][]float32
+	Uso     UsoTokens
+}
+
+// CacheGeracao guarda resultados de gerações pela chave da requisição
+type CacheGeracao interface {
+	Get(ctx context.Context, chave string) (*ResultadoGeracao, bool, error)
+	Set(ctx context.Context, chave string, resultado *ResultadoGeracao, ttl time.Duration) error
+}
+
+type chaveIgnorarCache struct{}
+
+// IgnorarCache marca o contexto para que a geração sempre chame o provedor;
+// o resultado novo ainda é gravado no cache
+func IgnorarCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, chaveIgnorarCache{}, true)
+}
+
+func CacheIgnorado(ctx context.Context) bool {
+	ignorar, _ := ctx.Value(chaveIgnorarCache{}).(bool)
+	return ignorar
+}
+
+// Erros tipados dos provedores de LLM; os provedores os envolvem com
+// fmt.Errorf("%w: ...") para que a camada HTTP escolha o status adequado
+var (
+	ErrLLMLimiteRequisicoes  = errors.New("limite de requisições do provedor de LLM atingido")
+	ErrLLMIndisponivel       = errors.New("provedor de LLM indisponível")
+	ErrLLMTempoEsgotado      = errors.New("tempo limite da chamada ao provedor de LLM esgotado")
+	ErrLLMRespostaVazia      = errors.New("provedor de LLM retornou uma resposta vazia")
+	ErrLLMRequisicaoInvalida = errors.New("requisição recusada pelo provedor de LLM")
+	ErrLLMCircuitoAberto     = errors.New("provedor de LLM temporariamente desativado após falhas consecutivas")
+)
+
+// ErroLLMTransitorio indica se vale a pena repetir a chamada que falhou com err
+func ErroLLMTransitorio(err error) bool {
+	return errors.Is(err, ErrLLMLimiteRequisicoes) ||
+		errors.Is(err, ErrLLMIndisponivel) ||
+		errors.Is(err, ErrLLMTempoEsgotado)
+}
+
+const (
+	PapelSistema    = "system"
+	PapelUsuario    = "user"
+	PapelAssistente = "assistant"
+	PapelFerramenta = "tool"
+)
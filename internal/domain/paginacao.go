@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Paginacao restringe uma listagem a uma página. Com Apos a página começa
+// depois do marcador (keyset), sem percorrer os registros anteriores; Offset
+// pula registros e serve a saltos para páginas distantes.
+type Paginacao struct {
+	// Limite é o número máximo de itens; 0 retorna todos
+	Limite int
+	Offset int
+	// Ordem vazia ordena dos mais novos aos mais antigos
+	Ordem Ordem
+	Apos  *Marcador
+	// ComTotal pede a contagem dos registros aceitos pelos filtros; sem ele o
+	// repositório pode retornar total 0 sem contar
+	ComTotal bool
+}
+
+// Ordem ordena pelo campo (nome do campo no banco) e desempata pelo ID, na
+// mesma direção
+type Ordem struct {
+	Campo       string
+	Decrescente bool
+}
+
+// OrdemRecentes é a ordem padrão das listagens
+var OrdemRecentes = Ordem{Campo: "created_at", Decrescente: true}
+
+// Marcador é a posição do último item de uma página na ordem da listagem: o
+// valor do campo ordenado (string, int ou time.Time) e o ID
+type Marcador struct {
+	Valor any
+	ID    primitive.ObjectID
+}
+
+// Pagina é o resultado de uma listagem paginada; Total conta os registros
+// aceitos pelos filtros em todas as páginas e só vem quando pedido
+type Pagina[T any] struct {
+	Itens         []T    `json:"itens"`
+	Total         *int64 `json:"total,omitempty"`
+	ProximoCursor string `json:"next_cursor,omitempty"`
+}
+
+// PessoaFiltro restringe a listagem de pessoas; Nome e Email buscam por parte
+// do texto, sem diferenciar maiúsculas. Campos vazios são ignorados.
+type PessoaFiltro struct {
+	Nome  string
+	Email string
+	// Expandir preenche os telefones e os contextos de cada pessoa
+	Expandir bool
+	Paginacao
+}
+
+// TelefoneFiltro restringe a listagem de telefones; Numero busca por parte do
+// número. Campos vazios são ignorados.
+type TelefoneFiltro struct {
+	Numero   string
+	Tipo     string
+	PessoaID string
+	Paginacao
+}
+
+// ContextoFiltro restringe a listagem de contextos; Nome busca por parte do
+// nome, sem diferenciar maiúsculas, e a data de início fica no intervalo
+// [DataInicioDe, DataInicioAte). Campos vazios são ignorados.
+type ContextoFiltro struct {
+	Nome          string
+	DataInicioDe  time.Time
+	DataInicioAte time.Time
+	// Expandir preenche as pessoas de cada contexto
+	Expandir bool
+	Paginacao
+}
+
+// PromptFiltro restringe a listagem de prompts; campos vazios são ignorados
+type PromptFiltro struct {
+	ContextoID string
+	Paginacao
+}
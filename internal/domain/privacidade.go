@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+// Modos de tratamento de dados pessoais antes do envio ao provedor de LLM
+const (
+	PrivacidadeNenhuma = "nenhuma"
+	// PrivacidadeMascarar troca cada dado por um marcador fixo ([EMAIL]); a
+	// resposta não recebe os dados de volta
+	PrivacidadeMascarar = "mascarar"
+	// PrivacidadePseudonimizar troca cada dado por um marcador numerado
+	// (<EMAIL_1>) e devolve os dados originais na resposta
+	PrivacidadePseudonimizar = "pseudonimizar"
+)
+
+// Tipos de dado pessoal reconhecidos
+const (
+	DadoEmail    = "email"
+	DadoTelefone = "telefone"
+	DadoCPF      = "cpf"
+	DadoCNPJ     = "cnpj"
+	// DadoNome cobre os nomes das pessoas do contexto
+	DadoNome = "nome"
+)
+
+var DadosPessoais = []string{DadoEmail, DadoTelefone, DadoCPF, DadoCNPJ, DadoNome}
+
+// PoliticaPrivacidade define, por contexto, como os dados pessoais são
+// tratados antes de chegar ao provedor. Modo vazio usa o padrão do sistema e
+// Dados vazio aplica o modo a todos os tipos.
+type PoliticaPrivacidade struct {
+	Modo  string   `bson:"modo,omitempty" json:"modo,omitempty"`
+	Dados []string `bson:"dados,omitempty" json:"dados,omitempty"`
+	// Moderar submete o texto enviado à moderação do provedor
+	Moderar bool `bson:"moderar,omitempty" json:"moderar,omitempty"`
+}
+
+// Privacidade é a política de uma geração, com os nomes a proteger
+type Privacidade struct {
+	Politica PoliticaPrivacidade
+	Nomes    []string
+}
+
+type chavePrivacidade struct{}
+
+// ComPrivacidade marca o contexto da geração com a política a aplicar
+func ComPrivacidade(ctx context.Context, privacidade Privacidade) context.Context {
+	return context.WithValue(ctx, chavePrivacidade{}, privacidade)
+}
+
+func PrivacidadeDe(ctx context.Context) (Privacidade, bool) {
+	privacidade, ok := ctx.Value(chavePrivacidade{}).(Privacidade)
+	return privacidade, ok
+}
+
+// Moderador classifica textos quanto a conteúdo impróprio
+type Moderador interface {
+	Moderar(ctx context.Context, texto string) (*ResultadoModeracao, error)
+}
+
+type ResultadoModeracao struct {
+	Sinalizado bool
+	Categorias []string
+}
+
+var ErrConteudoBloqueado = errors.New("conteúdo bloqueado pela moderação")
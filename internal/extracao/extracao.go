@@ -0,0 +1,54 @@
+// Package extracao obtém o texto de documentos enviados aos contextos
+package extracao
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+	"vend/internal/domain"
+)
+
+var (
+	ErrFormatoNaoSuportado = errors.New("formato de documento não suportado (use .txt, .md ou .pdf)")
+	ErrSemTexto            = errors.New("documento sem texto extraível")
+)
+
+// Formato deduz o formato pela extensão do nome do arquivo
+func Formato(nome string) (string, error) {
+	switch strings.ToLower(filepath.Ext(nome)) {
+	case ".txt", ".text", ".csv":
+		return domain.FormatoTexto, nil
+	case ".md", ".markdown":
+		return domain.FormatoMarkdown, nil
+	case ".pdf":
+		return domain.FormatoPDF, nil
+	default:
+		return "", ErrFormatoNaoSuportado
+	}
+}
+
+// Extrair retorna o texto do documento no formato informado. Texto e
+// Markdown devem estar em UTF-8; de PDFs só se extrai o texto das páginas,
+// então arquivos digitalizados (imagens) resultam em ErrSemTexto.
+func Extrair(formato string, dados []byte) (string, error) {
+	var texto string
+	switch formato {
+	case domain.FormatoTexto, domain.FormatoMarkdown:
+		if !utf8.Valid(dados) {
+			return "", fmt.Errorf("%w: o arquivo deve estar em UTF-8", ErrFormatoNaoSuportado)
+		}
+		texto = string(dados)
+	case domain.FormatoPDF:
+		texto = textoPDF(dados)
+	default:
+		return "", ErrFormatoNaoSuportado
+	}
+
+	texto = strings.ReplaceAll(texto, "\r\n", "\n")
+	if strings.TrimSpace(texto) == "" {
+		return "", ErrSemTexto
+	}
+	return texto, nil
+}
@@ -0,0 +1,359 @@
+package extracao
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// deslocamentoEspaco é o ajuste de posição em um array TJ (em milésimos de
+// em) a partir do qual o deslocamento é tratado como espaço entre palavras
+const deslocamentoEspaco = 200
+
+// conteudoMaximo limita o conteúdo decodificado dos streams de um PDF a
+// quatro vezes o tamanho máximo do upload (10 MB): poucos bytes compactados
+// podem se expandir em gigabytes. O que passar do limite é descartado.
+const conteudoMaximo = 40 << 20
+
+// textoPDF extrai o texto dos operadores de texto (Tj, TJ, ' e ") dos streams
+// de conteúdo do PDF. São lidos streams sem filtro ou com FlateDecode e
+// fontes de um byte por caractere; fontes CID, que dependem do mapa
+// ToUnicode, não são decodificadas.
+func textoPDF(dados []byte) string {
+	var saida strings.Builder
+	for _, conteudo := range streamsPDF(dados) {
+		if bytes.Contains(conteudo, []byte("BT")) {
+			extrairTexto(conteudo, &saida)
+		}
+	}
+	return saida.String()
+}
+
+// streamsPDF retorna o conteúdo decodificado de cada stream do arquivo, até
+// conteudoMaximo bytes no total
+func streamsPDF(dados []byte) [][]byte {
+	var streams [][]byte
+	restante := conteudoMaximo
+	resto := dados
+	for restante > 0 {
+		i := bytes.Index(resto, []byte("stream"))
+		if i < 0 {
+			return streams
+		}
+		// "endstream" também contém "stream"
+		if i >= 3 && string(resto[i-3:i]) == "end" {
+			resto = resto[i+len("stream"):]
+			continue
+		}
+
+		cabecalho := resto[:i]
+		if j := bytes.LastIndex(cabecalho, []byte("obj")); j >= 0 {
+			cabecalho = cabecalho[j:]
+		}
+
+		inicio := i + len("stream")
+		if inicio < len(resto) && resto[inicio] == '\r' {
+			inicio++
+		}
+		if inicio < len(resto) && resto[inicio] == '\n' {
+			inicio++
+		}
+		fim := bytes.Index(resto[inicio:], []byte("endstream"))
+		if fim < 0 {
+			return streams
+		}
+		bruto := resto[inicio : inicio+fim]
+		resto = resto[inicio+fim+len("endstream"):]
+
+		switch {
+		case bytes.Contains(cabecalho, []byte("/FlateDecode")):
+			leitor, err := zlib.NewReader(bytes.NewReader(bruto))
+			if err != nil {
+				continue
+			}
+			// Um final truncado ainda deixa o conteúdo lido aproveitável
+			decodificado, _ := io.ReadAll(io.LimitReader(leitor, int64(restante)))
+			if len(decodificado) > 0 {
+				streams = append(streams, decodificado)
+				restante -= len(decodificado)
+			}
+		case !bytes.Contains(cabecalho, []byte("/Filter")):
+			bruto = bruto[:min(len(bruto), restante)]
+			streams = append(streams, bruto)
+			restante -= len(bruto)
+		}
+	}
+	return streams
+}
+
+const (
+	tokOperador = iota
+	tokNumero
+	tokTexto
+	tokNome
+	tokInicioArray
+	tokFimArray
+	tokOutro
+)
+
+type tokenPDF struct {
+	tipo  int
+	valor string
+}
+
+// extrairTexto interpreta os operadores de texto de um stream de conteúdo,
+// quebrando linhas nas mudanças de linha e no fim de cada bloco de texto
+func extrairTexto(conteudo []byte, saida *strings.Builder) {
+	l := &lexerPDF{dados: conteudo}
+	quebrada := true
+	escrever := func(texto string) {
+		if texto != "" {
+			saida.WriteString(texto)
+			quebrada = false
+		}
+	}
+	quebrar := func() {
+		if !quebrada {
+			saida.WriteByte('\n')
+			quebrada = true
+		}
+	}
+
+	var operandos []tokenPDF
+	var array []tokenPDF
+	emArray := false
+	for {
+		tok, ok := l.proximo()
+		if !ok {
+			quebrar()
+			return
+		}
+
+		switch tok.tipo {
+		case tokInicioArray:
+			emArray, array = true, nil
+		case tokFimArray:
+			emArray = false
+		case tokOperador:
+			switch tok.valor {
+			case "Tj":
+				if n := len(operandos); n > 0 && operandos[n-1].tipo == tokTexto {
+					escrever(operandos[n-1].valor)
+				}
+			case "'", "\"":
+				quebrar()
+				if n := len(operandos); n > 0 && operandos[n-1].tipo == tokTexto {
+					escrever(operandos[n-1].valor)
+				}
+			case "TJ":
+				for _, item := range array {
+					switch item.tipo {
+					case tokTexto:
+						escrever(item.valor)
+					case tokNumero:
+						if n, _ := strconv.ParseFloat(item.valor, 64); n < -deslocamentoEspaco {
+							escrever(" ")
+						}
+					}
+				}
+			case "Td", "TD":
+				if n := len(operandos); n >= 2 {
+					if ty, _ := strconv.ParseFloat(operandos[n-1].valor, 64); ty != 0 {
+						quebrar()
+					}
+				}
+			case "T*", "ET":
+				quebrar()
+			case "ID":
+				l.pularImagem()
+			}
+			operandos, array = operandos[:0], nil
+		default:
+			if emArray {
+				array = append(array, tok)
+			} else {
+				operandos = append(operandos, tok)
+			}
+		}
+	}
+}
+
+type lexerPDF struct {
+	dados []byte
+	pos   int
+}
+
+func (l *lexerPDF) proximo() (tokenPDF, bool) {
+	for l.pos < len(l.dados) {
+		c := l.dados[l.pos]
+		switch {
+		case espacoPDF(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.dados) && l.dados[l.pos] != '\n' && l.dados[l.pos] != '\r' {
+				l.pos++
+			}
+		case c == '(':
+			return tokenPDF{tipo: tokTexto, valor: l.literal()}, true
+		case c == '<':
+			if l.pos+1 < len(l.dados) && l.dados[l.pos+1] == '<' {
+				l.pos += 2
+				return tokenPDF{tipo: tokOutro}, true
+			}
+			return tokenPDF{tipo: tokTexto, valor: l.hexadecimal()}, true
+		case c == '>':
+			l.pos++
+			if l.pos < len(l.dados) && l.dados[l.pos] == '>' {
+				l.pos++
+			}
+			return tokenPDF{tipo: tokOutro}, true
+		case c == '[':
+			l.pos++
+			return tokenPDF{tipo: tokInicioArray}, true
+		case c == ']':
+			l.pos++
+			return tokenPDF{tipo: tokFimArray}, true
+		case c == '{' || c == '}' || c == ')':
+			l.pos++
+			return tokenPDF{tipo: tokOutro}, true
+		case c == '/':
+			l.pos++
+			return tokenPDF{tipo: tokNome, valor: l.palavra()}, true
+		default:
+			palavra := l.palavra()
+			if _, err := strconv.ParseFloat(palavra, 64); err == nil {
+				return tokenPDF{tipo: tokNumero, valor: palavra}, true
+			}
+			return tokenPDF{tipo: tokOperador, valor: palavra}, true
+		}
+	}
+	return tokenPDF{}, false
+}
+
+func (l *lexerPDF) palavra() string {
+	inicio := l.pos
+	for l.pos < len(l.dados) && !delimitadorPDF(l.dados[l.pos]) {
+		l.pos++
+	}
+	return string(l.dados[inicio:l.pos])
+}
+
+// literal lê uma string entre parênteses, com parênteses aninhados e escapes
+func (l *lexerPDF) literal() string {
+	var b []byte
+	nivel := 0
+	for l.pos < len(l.dados) {
+		c := l.dados[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			nivel++
+			if nivel == 1 {
+				continue
+			}
+		case ')':
+			nivel--
+			if nivel == 0 {
+				return latin1(b)
+			}
+		case '\\':
+			if l.pos >= len(l.dados) {
+				return latin1(b)
+			}
+			e := l.dados[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r', '\n':
+				// Continuação de linha
+				if e == '\r' && l.pos < len(l.dados) && l.dados[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					n := int(e - '0')
+					for k := 0; k < 2 && l.pos < len(l.dados) && l.dados[l.pos] >= '0' && l.dados[l.pos] <= '7'; k++ {
+						n = n*8 + int(l.dados[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(n)
+				} else {
+					c = e
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return latin1(b)
+}
+
+// hexadecimal lê uma string <...>; valores com bytes de controle indicam
+// fontes CID e são descartados
+func (l *lexerPDF) hexadecimal() string {
+	l.pos++
+	var digitos []byte
+	for l.pos < len(l.dados) && l.dados[l.pos] != '>' {
+		if c := l.dados[l.pos]; strings.IndexByte("0123456789abcdefABCDEF", c) >= 0 {
+			digitos = append(digitos, c)
+		}
+		l.pos++
+	}
+	l.pos++
+	if len(digitos)%2 == 1 {
+		digitos = append(digitos, '0')
+	}
+
+	b := make([]byte, 0, len(digitos)/2)
+	for i := 0; i < len(digitos); i += 2 {
+		n, _ := strconv.ParseUint(string(digitos[i:i+2]), 16, 8)
+		if n < 0x20 && !espacoPDF(byte(n)) {
+			return ""
+		}
+		b = append(b, byte(n))
+	}
+	return latin1(b)
+}
+
+// pularImagem avança sobre os dados binários de uma imagem embutida (BI ... ID dados EI)
+func (l *lexerPDF) pularImagem() {
+	for l.pos < len(l.dados) {
+		i := bytes.Index(l.dados[l.pos:], []byte("EI"))
+		if i < 0 {
+			l.pos = len(l.dados)
+			return
+		}
+		inicio := l.pos + i
+		l.pos = inicio + 2
+		if inicio > 0 && espacoPDF(l.dados[inicio-1]) && (l.pos == len(l.dados) || delimitadorPDF(l.dados[l.pos])) {
+			return
+		}
+	}
+}
+
+func latin1(b []byte) string {
+	runas := make([]rune, len(b))
+	for i, c := range b {
+		runas[i] = rune(c)
+	}
+	return string(runas)
+}
+
+func espacoPDF(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func delimitadorPDF(c byte) bool {
+	return espacoPDF(c) || strings.IndexByte("()<>[]{}/%", c) >= 0
+}
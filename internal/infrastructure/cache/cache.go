@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"time"
+	"vend/internal/domain"
+)
+
+// Provider consulta o cache antes de chamar o provedor envolvido. Resultados
+// do cache voltam com DoCache e sem uso de tokens, pois nada foi consumido.
+type Provider struct {
+	provider domain.LLMProvider
+	cache    domain.CacheGeracao
+	ttl      time.Duration
+}
+
+func NewProvider(provider domain.LLMProvider, cache domain.CacheGeracao, ttl time.Duration) *Provider {
+	return &Provider{provider: provider, cache: cache, ttl: ttl}
+}
+
+func (p *Provider) Generate(ctx context.Context, req domain.RequisicaoGeracao) (*domain.ResultadoGeracao, error) {
+	chave := Chave(req)
+	if resultado, ok := p.buscar(ctx, chave); ok {
+		return resultado, nil
+	}
+
+	resultado, err := p.provider.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	p.guardar(ctx, chave, resultado)
+	return resultado, nil
+}
+
+// GenerateStream entrega um resultado do cache como um único trecho
+func (p *Provider) GenerateStream(ctx context.Context, req domain.RequisicaoGeracao, onDelta func(string) error) (*domain.ResultadoGeracao, error) {
+	chave := Chave(req)
+	if resultado, ok := p.buscar(ctx, chave); ok {
+		if err := onDelta(resultado.Conteudo); err != nil {
+			return nil, err
+		}
+		return resultado, nil
+	}
+
+	resultado, err := p.provider.GenerateStream(ctx, req, onDelta)
+	if err != nil {
+		return nil, err
+	}
+	p.guardar(ctx, chave, resultado)
+	return resultado, nil
+}
+
+// Chave é o hash SHA-256 do modelo, dos parâmetros e das mensagens da requisição
+func Chave(req domain.RequisicaoGeracao) string {
+	dados, _ := json.Marshal(req)
+	soma := sha256.Sum256(dados)
+	return hex.EncodeToString(soma[:])
+}
+
+// buscar trata falhas do cache como ausência, para que o cache nunca impeça uma geração
+func (p *Provider) buscar(ctx context.Context, chave string) (*domain.ResultadoGeracao, bool) {
+	if domain.CacheIgnorado(ctx) {
+		return nil, false
+	}
+
+	resultado, ok, err := p.cache.Get(ctx, chave)
+	if err != nil {
+		log.Printf("Erro ao consultar o cache de gerações: %v", err)
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+
+	return &domain.ResultadoGeracao{
+		Modelo:             resultado.Modelo,
+		Conteudo:           resultado.Conteudo,
+		ChamadasFerramenta: resultado.ChamadasFerramenta,
+		DoCache:            true,
+	}, true
+}
+
+func (p *Provider) guardar(ctx context.Context, chave string, resultado *domain.ResultadoGeracao) {
+	if err := p.cache.Set(ctx, chave, resultado, p.ttl); err != nil {
+		log.Printf("Erro ao gravar no cache de gerações: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+	"vend/internal/domain"
+)
+
+// LRU é um cache em memória que descarta o item usado há mais tempo quando
+// atinge a capacidade; itens expirados são removidos ao serem consultados
+type LRU struct {
+	mu         sync.Mutex
+	capacidade int
+	itens      map[string]*list.Element
+	ordem      *list.List
+}
+
+type itemLRU struct {
+	chave     string
+	resultado domain.ResultadoGeracao
+	expiraEm  time.Time
+}
+
+func NewLRU(capacidade int) *LRU {
+	if capacidade < 1 {
+		capacidade = 1
+	}
+	return &LRU{
+		capacidade: capacidade,
+		itens:      make(map[string]*list.Element),
+		ordem:      list.New(),
+	}
+}
+
+func (c *LRU) Get(ctx context.Context, chave string) (*domain.ResultadoGeracao, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elemento, ok := c.itens[chave]
+	if !ok {
+		return nil, false, nil
+	}
+
+	item := elemento.Value.(*itemLRU)
+	if !item.expiraEm.IsZero() && time.Now().After(item.expiraEm) {
+		c.ordem.Remove(elemento)
+		delete(c.itens, chave)
+		return nil, false, nil
+	}
+
+	c.ordem.MoveToFront(elemento)
+	resultado := item.resultado
+	return &resultado, true, nil
+}
+
+// Set grava o resultado; ttl zero mantém o item até ser descartado pela capacidade
+func (c *LRU) Set(ctx context.Context, chave string, resultado *domain.ResultadoGeracao, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item := &itemLRU{chave: chave, resultado: *resultado}
+	if ttl > 0 {
+		item.expiraEm = time.Now().Add(ttl)
+	}
+
+	if elemento, ok := c.itens[chave]; ok {
+		elemento.Value = item
+		c.ordem.MoveToFront(elemento)
+		return nil
+	}
+
+	c.itens[chave] = c.ordem.PushFront(item)
+	if c.ordem.Len() > c.capacidade {
+		antigo := c.ordem.Back()
+		c.ordem.Remove(antigo)
+		delete(c.itens, antigo.Value.(*itemLRU).chave)
+	}
+	return nil
+}
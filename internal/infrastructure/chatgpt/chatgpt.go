@@ -2,37 +2,284 @@ package chatgpt
 
 import (
 	"context"
-	"os"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"vend/internal/domain"
 
 	"github.com/sashabaranov/go-openai"
 )
 
-type ChatGPTService struct {
-	client *openai.Client
+type Provider struct {
+	client           *openai.Client
+	modelo           string
+	modeloEmbeddings string
 }
 
-func NewChatGPTService() *ChatGPTService {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	client := openai.NewClient(apiKey)
-	return &ChatGPTService{client: client}
+// NewOpenAIProvider cria um provedor que usa a API da OpenAI
+func NewOpenAIProvider(apiKey, modelo string) *Provider {
+	return NewCompatibleProvider("", apiKey, modelo)
 }
 
-func (s *ChatGPTService) GenerateResponse(ctx context.Context, prompt string) (string, error) {
-	resp, err := s.client.CreateChatCompletion(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model: openai.GPT3Dot5Turbo,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: prompt,
-				},
-			},
-		},
-	)
+// NewCompatibleProvider cria um provedor para qualquer API compatível com a
+// OpenAI (Ollama, vLLM, LocalAI). baseURL vazio usa a API da OpenAI.
+func NewCompatibleProvider(baseURL, apiKey, modelo string) *Provider {
+	config := openai.DefaultConfig(apiKey)
+	if baseURL != "" {
+		config.BaseURL = baseURL
+	}
+	if modelo == "" {
+		modelo = openai.GPT3Dot5Turbo
+	}
+	return &Provider{client: openai.NewClientWithConfig(config), modelo: modelo, modeloEmbeddings: string(openai.SmallEmbedding3)}
+}
+
+// UsarModeloEmbeddings troca o modelo de embeddings (padrão text-embedding-3-small)
+func (p *Provider) UsarModeloEmbeddings(modelo string) {
+	if modelo != "" {
+		p.modeloEmbeddings = modelo
+	}
+}
+
+func (p *Provider) Embeddings(ctx context.Context, textos []string) (*domain.ResultadoEmbeddings, error) {
+	resp, err := p.client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+		Input: textos,
+		Model: openai.EmbeddingModel(p.modeloEmbeddings),
+	})
+	if err != nil {
+		return nil, classificarErro(ctx, err)
+	}
+	if len(resp.Data) != len(textos) {
+		return nil, fmt.Errorf("%w: %d embeddings para %d textos", domain.ErrLLMRespostaVazia, len(resp.Data), len(textos))
+	}
+
+	vetores := make([][]float32, len(textos))
+	for _, dado := range resp.Data {
+		if dado.Index < 0 || dado.Index >= len(vetores) {
+			return nil, fmt.Errorf("%w: índice de embedding inválido", domain.ErrLLMRespostaVazia)
+		}
+		vetores[dado.Index] = dado.Embedding
+	}
+
+	return &domain.ResultadoEmbeddings{
+		Modelo:  p.modeloEmbeddings,
+		Vetores: vetores,
+		Uso:     usoTokens(resp.Usage),
+	}, nil
+}
+
+// Moderar usa a moderação da OpenAI; as categorias sinalizadas vêm em ordem
+// alfabética
+func (p *Provider) Moderar(ctx context.Context, texto string) (*domain.ResultadoModeracao, error) {
+	resp, err := p.client.Moderations(ctx, openai.ModerationRequest{Input: texto})
+	if err != nil {
+		return nil, classificarErro(ctx, err)
+	}
+
+	resultado := &domain.ResultadoModeracao{}
+	for _, r := range resp.Results {
+		if !r.Flagged {
+			continue
+		}
+		resultado.Sinalizado = true
+
+		// As categorias só têm nome nas tags json
+		dados, err := json.Marshal(r.Categories)
+		if err != nil {
+			return nil, err
+		}
+		var categorias map[string]bool
+		if err := json.Unmarshal(dados, &categorias); err != nil {
+			return nil, err
+		}
+		for categoria, sinalizada := range categorias {
+			if sinalizada {
+				resultado.Categorias = append(resultado.Categorias, categoria)
+			}
+		}
+	}
+	sort.Strings(resultado.Categorias)
+	return resultado, nil
+}
+
+func (p *Provider) Generate(ctx context.Context, req domain.RequisicaoGeracao) (*domain.ResultadoGeracao, error) {
+	chatReq := p.chatRequest(req)
+	resp, err := p.client.CreateChatCompletion(ctx, chatReq)
+	if err != nil {
+		return nil, classificarErro(ctx, err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, domain.ErrLLMRespostaVazia
+	}
+
+	mensagem := resp.Choices[0].Message
+	chamadas := make([]domain.ChamadaFerramenta, 0, len(mensagem.ToolCalls))
+	for _, call := range mensagem.ToolCalls {
+		chamadas = append(chamadas, domain.ChamadaFerramenta{ID: call.ID, Nome: call.Function.Name, Argumentos: call.Function.Arguments})
+	}
+	if mensagem.Content == "" && len(chamadas) == 0 {
+		return nil, domain.ErrLLMRespostaVazia
+	}
+
+	return &domain.ResultadoGeracao{
+		Modelo:             chatReq.Model,
+		Conteudo:           mensagem.Content,
+		ChamadasFerramenta: chamadas,
+		Uso:                usoTokens(resp.Usage),
+	}, nil
+}
+
+func (p *Provider) GenerateStream(ctx context.Context, req domain.RequisicaoGeracao, onDelta func(string) error) (*domain.ResultadoGeracao, error) {
+	chatReq := p.chatRequest(req)
+	chatReq.Stream = true
+	chatReq.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+
+	stream, err := p.client.CreateChatCompletionStream(ctx, chatReq)
 	if err != nil {
-		return "", err
+		return nil, classificarErro(ctx, err)
 	}
+	defer stream.Close()
 
-	return resp.Choices[0].Message.Content, nil
+	var conteudo strings.Builder
+	var uso domain.UsoTokens
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, classificarErro(ctx, err)
+		}
+
+		if chunk.Usage != nil {
+			uso = usoTokens(*chunk.Usage)
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+
+		delta := chunk.Choices[0].Delta.Content
+		conteudo.WriteString(delta)
+		if err := onDelta(delta); err != nil {
+			return nil, err
+		}
+	}
+
+	if conteudo.Len() == 0 {
+		return nil, domain.ErrLLMRespostaVazia
+	}
+
+	return &domain.ResultadoGeracao{
+		Modelo:   chatReq.Model,
+		Conteudo: conteudo.String(),
+		Uso:      uso,
+	}, nil
+}
+
+func (p *Provider) chatRequest(req domain.RequisicaoGeracao) openai.ChatCompletionRequest {
+	modelo := req.Modelo
+	if modelo == "" {
+		modelo = p.modelo
+	}
+
+	messages := make([]openai.ChatCompletionMessage, 0, len(req.Mensagens))
+	for _, m := range req.Mensagens {
+		message := openai.ChatCompletionMessage{Role: m.Papel, Content: m.Conteudo, ToolCallID: m.ChamadaID}
+		for _, chamada := range m.ChamadasFerramenta {
+			message.ToolCalls = append(message.ToolCalls, openai.ToolCall{
+				ID:       chamada.ID,
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: chamada.Nome, Arguments: chamada.Argumentos},
+			})
+		}
+		messages = append(messages, message)
+	}
+
+	// O cliente omite temperatura zero do JSON, o que faria a API usar o
+	// padrão 1; o menor float positivo equivale a zero
+	temperatura := req.Temperatura
+	if temperatura == 0 {
+		temperatura = math.SmallestNonzeroFloat32
+	}
+
+	chatReq := openai.ChatCompletionRequest{
+		Model:       modelo,
+		Messages:    messages,
+		Temperature: temperatura,
+		MaxTokens:   req.MaxTokens,
+		TopP:        req.TopP,
+		Stop:        req.Stop,
+	}
+	for _, ferramenta := range req.Ferramentas {
+		chatReq.Tools = append(chatReq.Tools, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        ferramenta.Nome,
+				Description: ferramenta.Descricao,
+				Parameters:  ferramenta.Parametros,
+			},
+		})
+	}
+	if req.SchemaSaida != nil {
+		chatReq.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:   "saida",
+				Schema: schemaJSON(req.SchemaSaida),
+			},
+		}
+	}
+	return chatReq
+}
+
+// schemaJSON adapta o schema ao json.Marshaler exigido pelo cliente
+type schemaJSON map[string]any
+
+func (s schemaJSON) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any(s))
+}
+
+func usoTokens(usage openai.Usage) domain.UsoTokens {
+	return domain.UsoTokens{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+}
+
+// classificarErro converte os erros do cliente da OpenAI nos erros tipados do domínio
+func classificarErro(ctx context.Context, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %v", domain.ErrLLMTempoEsgotado, err)
+	}
+	if errors.Is(err, context.Canceled) || ctx.Err() != nil {
+		return err
+	}
+
+	status := 0
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	switch {
+	case errors.As(err, &apiErr):
+		status = apiErr.HTTPStatusCode
+	case errors.As(err, &reqErr):
+		status = reqErr.HTTPStatusCode
+	}
+
+	switch {
+	case status == http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %v", domain.ErrLLMLimiteRequisicoes, err)
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		return fmt.Errorf("%w: %v", domain.ErrLLMTempoEsgotado, err)
+	case status >= 500 || status == 0:
+		// status 0 são falhas de rede, antes de qualquer resposta do servidor
+		return fmt.Errorf("%w: %v", domain.ErrLLMIndisponivel, err)
+	default:
+		return fmt.Errorf("%w: %v", domain.ErrLLMRequisicaoInvalida, err)
+	}
 }
@@ -0,0 +1,169 @@
+package fakellm
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"text/template"
+	"unicode"
+	"vend/internal/domain"
+)
+
+const (
+	ModeloPadrao   = "fake"
+	TemplatePadrao = "Resposta simulada para: {{.Ultima}}"
+	// DimensaoEmbeddings é o tamanho dos vetores gerados por Embeddings
+	DimensaoEmbeddings = 256
+)
+
+// Provider é um provedor determinístico que não acessa a rede: a resposta é
+// o template aplicado às mensagens recebidas
+type Provider struct {
+	tmpl *template.Template
+}
+
+// Dados disponíveis no template
+type Dados struct {
+	Modelo    string
+	Sistema   string
+	Ultima    string
+	Mensagens []domain.Mensagem
+}
+
+// NewProvider cria o fake com o template informado; vazio usa TemplatePadrao
+func NewProvider(tmpl string) (*Provider, error) {
+	if tmpl == "" {
+		tmpl = TemplatePadrao
+	}
+	t, err := template.New("fakellm").Parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	return &Provider{tmpl: t}, nil
+}
+
+func (p *Provider) Generate(ctx context.Context, req domain.RequisicaoGeracao) (*domain.ResultadoGeracao, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	modelo := req.Modelo
+	if modelo == "" {
+		modelo = ModeloPadrao
+	}
+
+	dados := Dados{Modelo: modelo, Mensagens: req.Mensagens}
+	for _, m := range req.Mensagens {
+		switch m.Papel {
+		case domain.PapelSistema:
+			dados.Sistema = m.Conteudo
+		case domain.PapelUsuario:
+			dados.Ultima = m.Conteudo
+		}
+	}
+
+	var conteudo strings.Builder
+	if err := p.tmpl.Execute(&conteudo, dados); err != nil {
+		return nil, err
+	}
+
+	promptTokens := 0
+	for _, m := range req.Mensagens {
+		promptTokens += contarTokens(m.Conteudo)
+	}
+	completionTokens := contarTokens(conteudo.String())
+
+	return &domain.ResultadoGeracao{
+		Modelo:   modelo,
+		Conteudo: conteudo.String(),
+		Uso: domain.UsoTokens{
+			PromptTokens:     promptTokens,
+			CompletionTokens: completionTokens,
+			TotalTokens:      promptTokens + completionTokens,
+		},
+	}, nil
+}
+
+// GenerateStream entrega a mesma resposta de Generate, uma palavra por vez
+func (p *Provider) GenerateStream(ctx context.Context, req domain.RequisicaoGeracao, onDelta func(string) error) (*domain.ResultadoGeracao, error) {
+	resultado, err := p.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, delta := range strings.SplitAfter(resultado.Conteudo, " ") {
+		if delta == "" {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := onDelta(delta); err != nil {
+			return nil, err
+		}
+	}
+
+	return resultado, nil
+}
+
+// Embeddings gera vetores determinísticos pelo truque do hashing: cada palavra
+// (sem diferenciar maiúsculas) soma ±1 em uma posição do vetor, que é
+// normalizado. Textos com palavras em comum ficam próximos.
+func (p *Provider) Embeddings(ctx context.Context, textos []string) (*domain.ResultadoEmbeddings, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	vetores := make([][]float32, len(textos))
+	tokens := 0
+	for i, texto := range textos {
+		vetor := make([]float32, DimensaoEmbeddings)
+		palavras := strings.FieldsFunc(strings.ToLower(texto), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		})
+		for _, palavra := range palavras {
+			h := fnv.New32a()
+			h.Write([]byte(palavra))
+			soma := h.Sum32()
+			if soma&1 == 0 {
+				vetor[(soma>>1)%DimensaoEmbeddings]++
+			} else {
+				vetor[(soma>>1)%DimensaoEmbeddings]--
+			}
+		}
+
+		var norma float64
+		for _, v := range vetor {
+			norma += float64(v) * float64(v)
+		}
+		if norma > 0 {
+			norma = math.Sqrt(norma)
+			for j := range vetor {
+				vetor[j] = float32(float64(vetor[j]) / norma)
+			}
+		}
+
+		vetores[i] = vetor
+		tokens += contarTokens(texto)
+	}
+
+	return &domain.ResultadoEmbeddings{
+		Modelo:  ModeloPadrao,
+		Vetores: vetores,
+		Uso:     domain.UsoTokens{PromptTokens: tokens, TotalTokens: tokens},
+	}, nil
+}
+
+// Moderar nunca sinaliza o texto
+func (p *Provider) Moderar(ctx context.Context, texto string) (*domain.ResultadoModeracao, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &domain.ResultadoModeracao{}, nil
+}
+
+// contarTokens aproxima a contagem de tokens pelo número de palavras
+func contarTokens(texto string) int {
+	return len(strings.Fields(texto))
+}
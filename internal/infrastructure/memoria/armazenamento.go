@@ -0,0 +1,529 @@
+// Package memoria implementa o repositório em memória, para rodar a API e os
+// testes sem banco de dados
+package memoria
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"vend/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// armazenamento guarda os registros como documentos BSON, uma coleção por
+// entidade, como no MongoDB: cada leitura decodifica uma cópia, então quem
+// recebe um registro não altera o que está guardado, e os campos seguem as
+// mesmas tags (omitempty, datas com precisão de milissegundos). Documentos
+// com deleted_at estão na lixeira: as leituras e alterações os ignoram.
+type armazenamento struct {
+	mu       sync.RWMutex
+	colecoes map[string]map[primitive.ObjectID]bson.Raw
+	arquivo  string
+}
+
+// carregar lê as coleções gravadas no arquivo; sem arquivo, ou com um arquivo
+// inexistente, o armazenamento começa vazio
+func (a *armazenamento) carregar() error {
+	if a.arquivo == "" {
+		return nil
+	}
+	dados, err := os.ReadFile(a.arquivo)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var colecoes map[string][]bson.Raw
+	if err := bson.UnmarshalExtJSON(dados, false, &colecoes); err != nil {
+		return fmt.Errorf("arquivo %s inválido: %w", a.arquivo, err)
+	}
+	for nome, docs := range colecoes {
+		colecao := a.colecao(nome)
+		for _, doc := range docs {
+			id, ok := doc.Lookup("_id").ObjectIDOK()
+			if !ok {
+				return fmt.Errorf("arquivo %s inválido: registro sem _id em %s", a.arquivo, nome)
+			}
+			colecao[id] = doc
+		}
+	}
+	return nil
+}
+
+// gravar regrava o arquivo inteiro com todas as coleções, em JSON estendido;
+// chamado com o lock de escrita a cada alteração, então serve a volumes de
+// desenvolvimento. Se a gravação falhar, a alteração fica só na memória.
+func (a *armazenamento) gravar() error {
+	if a.arquivo == "" {
+		return nil
+	}
+
+	nomes := make([]string, 0, len(a.colecoes))
+	for nome := range a.colecoes {
+		nomes = append(nomes, nome)
+	}
+	sort.Strings(nomes)
+
+	conteudo := bson.D{}
+	for _, nome := range nomes {
+		conteudo = append(conteudo, bson.E{Key: nome, Value: a.ordenar(nome, nil)})
+	}
+	dados, err := bson.MarshalExtJSONIndent(conteudo, false, false, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Grava em um arquivo temporário e renomeia, para não deixar o arquivo pela metade
+	temporario := a.arquivo + ".tmp"
+	if err := os.WriteFile(temporario, dados, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(temporario, a.arquivo)
+}
+
+func (a *armazenamento) colecao(nome string) map[primitive.ObjectID]bson.Raw {
+	colecao, ok := a.colecoes[nome]
+	if !ok {
+		colecao = make(map[primitive.ObjectID]bson.Raw)
+		a.colecoes[nome] = colecao
+	}
+	return colecao
+}
+
+// ordenar retorna os documentos da coleção na ordem pedida, desempatando (e,
+// sem ordem, ordenando) pelo _id crescente, que segue a ordem de inserção
+func (a *armazenamento) ordenar(nome string, ordem bson.D) []bson.Raw {
+	docs := make([]bson.Raw, 0, len(a.colecoes[nome]))
+	for _, doc := range a.colecoes[nome] {
+		docs = append(docs, doc)
+	}
+	campos := append(append(bson.D{}, ordem...), bson.E{Key: "_id", Value: 1})
+	sort.Slice(docs, func(i, j int) bool {
+		for _, campo := range campos {
+			c := compararValores(docs[i].Lookup(campo.Key), docs[j].Lookup(campo.Key))
+			if c != 0 {
+				return c*campo.Value.(int) < 0
+			}
+		}
+		return false
+	})
+	return docs
+}
+
+// compararValores compara os campos usados nas ordenações; campos ausentes
+// vêm antes dos preenchidos, como no MongoDB
+func compararValores(a, b bson.RawValue) int {
+	if a.Type != b.Type {
+		if a.Type == 0 {
+			return -1
+		}
+		if b.Type == 0 {
+			return 1
+		}
+	}
+	switch a.Type {
+	case bsontype.DateTime:
+		return comparar(a.DateTime(), b.DateTime())
+	case bsontype.Int32, bsontype.Int64:
+		return comparar(a.AsInt64(), b.AsInt64())
+	case bsontype.ObjectID:
+		idA, idB := a.ObjectID(), b.ObjectID()
+		return bytes.Compare(idA[:], idB[:])
+	case bsontype.String:
+		return strings.Compare(a.StringValue(), b.StringValue())
+	}
+	return 0
+}
+
+func comparar(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// inserir grava doc na coleção, gerando o ID quando ele está zerado
+func (a *armazenamento) inserir(nome string, doc interface{}, id *primitive.ObjectID) error {
+	return a.inserirTodos(nome, 1, func(int) (interface{}, *primitive.ObjectID) { return doc, id })
+}
+
+// inserirTodos grava os n documentos retornados por doc de uma só vez, sem
+// regravar o arquivo a cada um; se algum falhar, nenhum é gravado
+func (a *armazenamento) inserirTodos(nome string, n int, doc func(i int) (interface{}, *primitive.ObjectID)) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	colecao := a.colecao(nome)
+	novos := make(map[primitive.ObjectID]bson.Raw, n)
+	for i := 0; i < n; i++ {
+		d, id := doc(i)
+		if id.IsZero() {
+			*id = primitive.NewObjectID()
+		}
+		if _, ok := colecao[*id]; ok {
+			return fmt.Errorf("registro duplicado em %s: %s", nome, id.Hex())
+		}
+		raw, err := bson.Marshal(d)
+		if err != nil {
+			return err
+		}
+		novos[*id] = raw
+	}
+
+	for id, raw := range novos {
+		colecao[id] = raw
+	}
+	return a.gravar()
+}
+
+func (a *armazenamento) buscar(nome, id string, destino interface{}) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.ErrNaoEncontrado
+	}
+	raw, ok := a.colecoes[nome][objectID]
+	if !ok || naLixeira(raw) {
+		return domain.ErrNaoEncontrado
+	}
+	return bson.Unmarshal(raw, destino)
+}
+
+// listar decodifica os documentos da coleção aceitos pelo filtro (nil aceita
+// todos), na ordem pedida; a lista vazia não é nil
+func listar[T any](a *armazenamento, nome string, ordem bson.D, filtro func(*T) bool) ([]T, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	lista := []T{}
+	for _, raw := range a.ordenar(nome, ordem) {
+		if naLixeira(raw) {
+			continue
+		}
+		var doc T
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			return nil, err
+		}
+		if filtro == nil || filtro(&doc) {
+			lista = append(lista, doc)
+		}
+	}
+	return lista, nil
+}
+
+// listarPagina decodifica a página dos documentos aceitos pelo filtro e
+// retorna o total aceito por ele, com a mesma ordem e o mesmo marcador das
+// listagens paginadas dos outros repositórios
+func listarPagina[T any](a *armazenamento, nome string, paginacao domain.Paginacao, filtro func(*T) bool) ([]T, int64, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	ordem := paginacao.Ordem
+	if ordem.Campo == "" {
+		ordem = domain.OrdemRecentes
+	}
+	direcao := 1
+	if ordem.Decrescente {
+		direcao = -1
+	}
+
+	var apos bson.RawValue
+	if paginacao.Apos != nil {
+		tipo, valor, err := bson.MarshalValue(paginacao.Apos.Valor)
+		if err != nil {
+			return nil, 0, err
+		}
+		apos = bson.RawValue{Type: tipo, Value: valor}
+	}
+
+	lista := []T{}
+	var total int64
+	pulados := 0
+	docs := a.ordenar(nome, bson.D{{Key: ordem.Campo, Value: direcao}, {Key: "_id", Value: direcao}})
+	for _, raw := range docs {
+		if naLixeira(raw) {
+			continue
+		}
+		var doc T
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			return nil, 0, err
+		}
+		if filtro != nil && !filtro(&doc) {
+			continue
+		}
+		total++
+
+		if paginacao.Apos != nil {
+			c := compararValores(raw.Lookup(ordem.Campo), apos)
+			if c == 0 {
+				id := raw.Lookup("_id").ObjectID()
+				c = bytes.Compare(id[:], paginacao.Apos.ID[:])
+			}
+			if c*direcao <= 0 {
+				continue
+			}
+		}
+		if pulados < paginacao.Offset {
+			pulados++
+			continue
+		}
+		if paginacao.Limite == 0 || len(lista) < paginacao.Limite {
+			lista = append(lista, doc)
+		}
+	}
+	return lista, total, nil
+}
+
+// pesquisar pontua os documentos da coleção pelo número de palavras dos
+// campos que correspondem aos termos e retorna até limite, dos mais relevantes
+// aos menos; sem radicais, mas com os termos como prefixos, como no PostgreSQL
+func (a *armazenamento) pesquisar(nome, tipo string, termos []string, limite int) []domain.ResultadoTexto {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	resultados := []domain.ResultadoTexto{}
+	for _, raw := range a.ordenar(nome, nil) {
+		if naLixeira(raw) {
+			continue
+		}
+		resultado := domain.ResultadoTexto{Tipo: tipo, ID: raw.Lookup("_id").ObjectID().Hex(), Campos: map[string]string{}}
+		for _, campo := range domain.CamposBuscaTexto[tipo] {
+			texto, _ := raw.Lookup(campo).StringValueOK()
+			resultado.Campos[campo] = texto
+			resultado.Score += float64(len(domain.PalavrasComTermo(texto, termos)))
+		}
+		if resultado.Score > 0 {
+			resultados = append(resultados, resultado)
+		}
+	}
+	sort.SliceStable(resultados, func(i, j int) bool { return resultados[i].Score > resultados[j].Score })
+	if len(resultados) > limite {
+		resultados = resultados[:limite]
+	}
+	return resultados
+}
+
+// atualizar substitui o documento guardado por doc, como o PostgreSQL grava
+// todas as colunas: os campos vazios omitidos pelo omitempty deixam o
+// documento. _id, created_at, os campos da lixeira e os preservados (gravados
+// por outras operações) continuam como estão. Devolve em doc o documento
+// gravado.
+func (a *armazenamento) atualizar(nome string, id primitive.ObjectID, doc interface{}, preservados ...string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	atual, ok := a.colecoes[nome][id]
+	if !ok || naLixeira(atual) {
+		return domain.ErrNaoEncontrado
+	}
+
+	novo, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	var gravado bson.D
+	if err := bson.Unmarshal(novo, &gravado); err != nil {
+		return err
+	}
+	preservados = append([]string{"_id", "created_at", "deleted_at", "deleted_by"}, preservados...)
+	for _, campo := range preservados {
+		if valor, err := atual.LookupErr(campo); err == nil {
+			gravado = definirCampo(gravado, bson.E{Key: campo, Value: valor})
+		} else {
+			gravado = removerCampo(gravado, campo)
+		}
+	}
+
+	raw, err := bson.Marshal(gravado)
+	if err != nil {
+		return err
+	}
+	a.colecoes[nome][id] = raw
+	if err := a.gravar(); err != nil {
+		return err
+	}
+	return bson.Unmarshal(raw, doc)
+}
+
+// alterar decodifica o documento em doc, aplica alteracao e grava o resultado
+func (a *armazenamento) alterar(nome, id string, doc interface{}, alteracao func()) error {
+	return a.alterarDocumento(nome, id, false, doc, alteracao)
+}
+
+// alterarInclusiveLixeira é alterar também para os documentos na lixeira,
+// como ao anular as referências a um registro removido
+func (a *armazenamento) alterarInclusiveLixeira(nome, id string, doc interface{}, alteracao func()) error {
+	return a.alterarDocumento(nome, id, true, doc, alteracao)
+}
+
+func (a *armazenamento) alterarDocumento(nome, id string, lixeira bool, doc interface{}, alteracao func()) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	atual, ok := a.colecoes[nome][objectID]
+	if !ok || (naLixeira(atual) && !lixeira) {
+		return domain.ErrNaoEncontrado
+	}
+	if err := bson.Unmarshal(atual, doc); err != nil {
+		return err
+	}
+
+	alteracao()
+
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	a.colecoes[nome][objectID] = raw
+	return a.gravar()
+}
+
+func (a *armazenamento) remover(nome, id string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.ErrNaoEncontrado
+	}
+	if _, ok := a.colecoes[nome][objectID]; !ok {
+		return domain.ErrNaoEncontrado
+	}
+	delete(a.colecoes[nome], objectID)
+	return a.gravar()
+}
+
+// removerOnde remove os documentos da coleção em que campo vale valor
+func (a *armazenamento) removerOnde(nome, campo string, valor primitive.ObjectID) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for id, raw := range a.colecoes[nome] {
+		if v, ok := raw.Lookup(campo).ObjectIDOK(); ok && v == valor {
+			delete(a.colecoes[nome], id)
+		}
+	}
+	return a.gravar()
+}
+
+// comReferencia retorna os IDs dos documentos da coleção em que campo vale
+// valor ou, sendo uma lista, contém valor; com lixeira, inclui os documentos
+// na lixeira
+func (a *armazenamento) comReferencia(nome, campo string, valor primitive.ObjectID, lixeira bool) []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	var ids []string
+	for id, raw := range a.colecoes[nome] {
+		if naLixeira(raw) && !lixeira {
+			continue
+		}
+		referencia := raw.Lookup(campo)
+		if v, ok := referencia.ObjectIDOK(); ok && v == valor {
+			ids = append(ids, id.Hex())
+			continue
+		}
+		lista, ok := referencia.ArrayOK()
+		if !ok {
+			continue
+		}
+		valores, err := lista.Values()
+		if err != nil {
+			continue
+		}
+		for _, item := range valores {
+			if v, ok := item.ObjectIDOK(); ok && v == valor {
+				ids = append(ids, id.Hex())
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// copiar retorna um armazenamento sem arquivo com as coleções atuais; os
+// documentos são imutáveis, então só os mapas são copiados
+func (a *armazenamento) copiar() *armazenamento {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	copia := &armazenamento{colecoes: make(map[string]map[primitive.ObjectID]bson.Raw, len(a.colecoes))}
+	for nome, colecao := range a.colecoes {
+		copia.colecoes[nome] = maps.Clone(colecao)
+	}
+	return copia
+}
+
+// aplicar grava as alterações feitas na cópia desde que ela era igual a base:
+// os documentos inseridos, alterados e removidos nela. Os documentos que ela
+// não alterou ficam como estão, mesmo que tenham mudado depois da cópia.
+func (a *armazenamento) aplicar(base, copia *armazenamento) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for nome, colecao := range copia.colecoes {
+		anteriores := base.colecoes[nome]
+		for id, raw := range colecao {
+			if anterior, ok := anteriores[id]; !ok || !bytes.Equal(anterior, raw) {
+				a.colecao(nome)[id] = raw
+			}
+		}
+		for id := range anteriores {
+			if _, ok := colecao[id]; !ok {
+				delete(a.colecoes[nome], id)
+			}
+		}
+	}
+	return a.gravar()
+}
+
+// naLixeira diz se o documento foi removido
+func naLixeira(raw bson.Raw) bool {
+	return raw.Lookup("deleted_at").Type != 0
+}
+
+func definirCampo(doc bson.D, campo bson.E) bson.D {
+	for i := range doc {
+		if doc[i].Key == campo.Key {
+			doc[i].Value = campo.Value
+			return doc
+		}
+	}
+	return append(doc, campo)
+}
+
+func removerCampo(doc bson.D, chave string) bson.D {
+	for i := range doc {
+		if doc[i].Key == chave {
+			return append(doc[:i], doc[i+1:]...)
+		}
+	}
+	return doc
+}
+
+// filtroID converte o ID de um filtro; o ID vazio fica zerado e é ignorado
+func filtroID(id string) (primitive.ObjectID, error) {
+	if id == "" {
+		return primitive.NilObjectID, nil
+	}
+	return primitive.ObjectIDFromHex(id)
+}
@@ -0,0 +1,110 @@
+package memoria
+
+import (
+	"fmt"
+	"slices"
+	"time"
+	"vend/internal/domain"
+	"vend/internal/usecase"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Transacao executa fn sobre uma cópia dos dados e, se fn não retornar erro,
+// aplica as alterações dela de uma só vez (e regrava o arquivo uma vez)
+func (r *Repository) Transacao(fn func(repo usecase.Repository) error) error {
+	base := r.dados.copiar()
+	copia := base.copiar()
+	if err := fn(&Repository{dados: copia}); err != nil {
+		return err
+	}
+	return r.dados.aplicar(base, copia)
+}
+
+// referencias dá a coleção e o campo que apontam para o registro em cada
+// relação, como no MongoDB
+func referencias(relacao domain.Relacao) (colecao, campo string, err error) {
+	switch relacao {
+	case domain.RelacaoPessoaTelefones:
+		return "telefones", "pessoa_id", nil
+	case domain.RelacaoPessoaContextos:
+		return "contextos", "pessoa_ids", nil
+	case domain.RelacaoContextoPrompts:
+		return "prompts", "contexto_id", nil
+	}
+	return "", "", fmt.Errorf("relação desconhecida: %s", relacao)
+}
+
+func (r *Repository) ContarReferencias(relacao domain.Relacao, id string) (int64, error) {
+	colecao, campo, err := referencias(relacao)
+	if err != nil {
+		return 0, err
+	}
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, nil
+	}
+	return int64(len(r.dados.comReferencia(colecao, campo, objectID, false))), nil
+}
+
+func (r *Repository) RemoverReferencias(relacao domain.Relacao, id string, remocao domain.Remocao) error {
+	if relacao == domain.RelacaoPessoaContextos {
+		return r.AnularReferencias(relacao, id)
+	}
+
+	colecao, campo, err := referencias(relacao)
+	if err != nil {
+		return err
+	}
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil
+	}
+	_, err = r.dados.editar(colecao, func(_ primitive.ObjectID, raw bson.Raw) bool {
+		referencia, ok := raw.Lookup(campo).ObjectIDOK()
+		return ok && referencia == objectID && !naLixeira(raw)
+	}, marcarRemocao(remocao))
+	return err
+}
+
+// AnularReferencias zera o ID nos telefones e nos prompts, ou tira a pessoa
+// dos contextos, inclusive nos que estão na lixeira, como nos outros
+// repositórios
+func (r *Repository) AnularReferencias(relacao domain.Relacao, id string) error {
+	colecao, campo, err := referencias(relacao)
+	if err != nil {
+		return err
+	}
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil
+	}
+
+	for _, referencia := range r.dados.comReferencia(colecao, campo, objectID, true) {
+		switch relacao {
+		case domain.RelacaoPessoaTelefones:
+			var telefone domain.Telefone
+			err = r.dados.alterarInclusiveLixeira(colecao, referencia, &telefone, func() {
+				telefone.PessoaID = primitive.NilObjectID
+				telefone.UpdatedAt = time.Now()
+			})
+		case domain.RelacaoPessoaContextos:
+			var contexto domain.Contexto
+			err = r.dados.alterarInclusiveLixeira(colecao, referencia, &contexto, func() {
+				contexto.PessoaIDs = slices.DeleteFunc(contexto.PessoaIDs, func(pessoa primitive.ObjectID) bool { return pessoa == objectID })
+				contexto.UpdatedAt = time.Now()
+			})
+		case domain.RelacaoContextoPrompts:
+			var prompt domain.Prompt
+			err = r.dados.alterarInclusiveLixeira(colecao, referencia, &prompt, func() {
+				prompt.ContextoID = primitive.NilObjectID
+				prompt.UpdatedAt = time.Now()
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
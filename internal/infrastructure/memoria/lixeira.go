@@ -0,0 +1,274 @@
+package memoria
+
+import (
+	"bytes"
+	"sort"
+	"time"
+	"vend/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// editar aplica edicao aos documentos da coleção aceitos por aceitar, com
+// os documentos na lixeira, e regrava o arquivo uma vez; retorna quantos
+// foram editados
+func (a *armazenamento) editar(nome string, aceitar func(id primitive.ObjectID, raw bson.Raw) bool, edicao func(doc bson.D) bson.D) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	n := 0
+	for id, raw := range a.colecoes[nome] {
+		if !aceitar(id, raw) {
+			continue
+		}
+		var doc bson.D
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			return n, err
+		}
+		novo, err := bson.Marshal(edicao(doc))
+		if err != nil {
+			return n, err
+		}
+		a.colecoes[nome][id] = novo
+		n++
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, a.gravar()
+}
+
+// itensLixeira retorna os documentos da coleção que estão na lixeira
+func (a *armazenamento) itensLixeira(nome string) []domain.ItemLixeira {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	var itens []domain.ItemLixeira
+	for id, raw := range a.colecoes[nome] {
+		if naLixeira(raw) {
+			itens = append(itens, itemLixeira(nome, id, raw))
+		}
+	}
+	return itens
+}
+
+// buscarLixeira retorna o documento da coleção que está na lixeira
+func (a *armazenamento) buscarLixeira(nome, id string) (*domain.ItemLixeira, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, domain.ErrNaoEncontrado
+	}
+	raw, ok := a.colecoes[nome][objectID]
+	if !ok || !naLixeira(raw) {
+		return nil, domain.ErrNaoEncontrado
+	}
+	item := itemLixeira(nome, objectID, raw)
+	return &item, nil
+}
+
+func itemLixeira(nome string, id primitive.ObjectID, raw bson.Raw) domain.ItemLixeira {
+	remocao := lerRemocao(raw)
+	item := domain.ItemLixeira{Entidade: nome, ID: id, DeletedAt: remocao.Em, DeletedBy: remocao.Por}
+	item.Titulo, _ = raw.Lookup(domain.CamposTituloLixeira[nome]).StringValueOK()
+	if campo, ok := domain.CamposReferenciaLixeira[nome]; ok {
+		item.ReferenciaID, _ = raw.Lookup(campo).ObjectIDOK()
+	}
+	return item
+}
+
+// apagarLixeira apaga de vez os documentos da coleção removidos até ate e
+// retorna os IDs deles
+func (a *armazenamento) apagarLixeira(nome string, ate time.Time) ([]primitive.ObjectID, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var ids []primitive.ObjectID
+	for id, raw := range a.colecoes[nome] {
+		if naLixeira(raw) && !lerRemocao(raw).Em.After(ate) {
+			ids = append(ids, id)
+			delete(a.colecoes[nome], id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return ids, a.gravar()
+}
+
+func lerRemocao(raw bson.Raw) domain.Remocao {
+	remocao := domain.Remocao{}
+	if em, ok := raw.Lookup("deleted_at").DateTimeOK(); ok {
+		remocao.Em = time.UnixMilli(em).UTC()
+	}
+	remocao.Por, _ = raw.Lookup("deleted_by").StringValueOK()
+	return remocao
+}
+
+func marcarRemocao(remocao domain.Remocao) func(doc bson.D) bson.D {
+	return func(doc bson.D) bson.D {
+		doc = definirCampo(doc, bson.E{Key: "deleted_at", Value: primitive.NewDateTimeFromTime(remocao.Em)})
+		return definirCampo(doc, bson.E{Key: "deleted_by", Value: remocao.Por})
+	}
+}
+
+func restauracao(doc bson.D) bson.D {
+	restaurado := bson.D{}
+	for _, campo := range doc {
+		if campo.Key != "deleted_at" && campo.Key != "deleted_by" {
+			restaurado = append(restaurado, campo)
+		}
+	}
+	return restaurado
+}
+
+// moverParaLixeira marca o registro como removido
+func (r *Repository) moverParaLixeira(nome, id string, remocao domain.Remocao) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.ErrNaoEncontrado
+	}
+	n, err := r.dados.editar(nome, func(docID primitive.ObjectID, raw bson.Raw) bool {
+		return docID == objectID && !naLixeira(raw)
+	}, marcarRemocao(remocao))
+	if err == nil && n == 0 {
+		return domain.ErrNaoEncontrado
+	}
+	return err
+}
+
+// ListLixeira junta os registros removidos das coleções e os pagina como
+// listarPagina
+func (r *Repository) ListLixeira(filtro domain.LixeiraFiltro) ([]domain.ItemLixeira, int64, error) {
+	entidades := domain.EntidadesLixeira
+	if filtro.Entidade != "" {
+		entidades = []string{filtro.Entidade}
+	}
+	var todos []domain.ItemLixeira
+	for _, entidade := range entidades {
+		todos = append(todos, r.dados.itensLixeira(entidade)...)
+	}
+
+	direcao := 1
+	if filtro.Ordem.Decrescente {
+		direcao = -1
+	}
+	comparar := func(em time.Time, id primitive.ObjectID, outroEm time.Time, outroID primitive.ObjectID) int {
+		if c := em.Compare(outroEm); c != 0 {
+			return c * direcao
+		}
+		return bytes.Compare(id[:], outroID[:]) * direcao
+	}
+	sort.Slice(todos, func(i, j int) bool {
+		return comparar(todos[i].DeletedAt, todos[i].ID, todos[j].DeletedAt, todos[j].ID) < 0
+	})
+
+	itens := []domain.ItemLixeira{}
+	pulados := 0
+	for _, item := range todos {
+		if apos := filtro.Apos; apos != nil {
+			if em, ok := apos.Valor.(time.Time); ok && comparar(item.DeletedAt, item.ID, em, apos.ID) <= 0 {
+				continue
+			}
+		}
+		if pulados < filtro.Offset {
+			pulados++
+			continue
+		}
+		if filtro.Limite == 0 || len(itens) < filtro.Limite {
+			itens = append(itens, item)
+		}
+	}
+	return itens, int64(len(todos)), nil
+}
+
+func (r *Repository) GetLixeira(entidade, id string) (*domain.ItemLixeira, error) {
+	return r.dados.buscarLixeira(entidade, id)
+}
+
+func (r *Repository) Restaurar(entidade, id string) (*domain.Remocao, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, domain.ErrNaoEncontrado
+	}
+	var remocao *domain.Remocao
+	_, err = r.dados.editar(entidade, func(docID primitive.ObjectID, raw bson.Raw) bool {
+		if docID != objectID || !naLixeira(raw) {
+			return false
+		}
+		anterior := lerRemocao(raw)
+		remocao = &anterior
+		return true
+	}, restauracao)
+	if err != nil {
+		return nil, err
+	}
+	if remocao == nil {
+		return nil, domain.ErrNaoEncontrado
+	}
+	return remocao, nil
+}
+
+func (r *Repository) RestaurarReferencias(relacao domain.Relacao, id string, em time.Time) error {
+	if relacao == domain.RelacaoPessoaContextos {
+		return nil
+	}
+
+	colecao, campo, err := referencias(relacao)
+	if err != nil {
+		return err
+	}
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil
+	}
+	_, err = r.dados.editar(colecao, func(_ primitive.ObjectID, raw bson.Raw) bool {
+		referencia, ok := raw.Lookup(campo).ObjectIDOK()
+		return ok && referencia == objectID && naLixeira(raw) && lerRemocao(raw).Em.Equal(em)
+	}, restauracao)
+	return err
+}
+
+// PurgarLixeira apaga os registros e faz nas coleções relacionadas o que as
+// chaves estrangeiras fazem no PostgreSQL, como o repositório do MongoDB
+func (r *Repository) PurgarLixeira(ate time.Time) (int64, error) {
+	var total int64
+	for _, entidade := range domain.EntidadesLixeira {
+		ids, err := r.dados.apagarLixeira(entidade, ate)
+		if err != nil {
+			return total, err
+		}
+		total += int64(len(ids))
+
+		for _, id := range ids {
+			if err := r.purgarRelacionados(entidade, id); err != nil {
+				return total, err
+			}
+		}
+	}
+	return total, nil
+}
+
+func (r *Repository) purgarRelacionados(entidade string, id primitive.ObjectID) error {
+	switch entidade {
+	case domain.EntidadePessoas:
+		if err := r.AnularReferencias(domain.RelacaoPessoaTelefones, id.Hex()); err != nil {
+			return err
+		}
+		return r.AnularReferencias(domain.RelacaoPessoaContextos, id.Hex())
+	case domain.EntidadeContextos:
+		if err := r.AnularReferencias(domain.RelacaoContextoPrompts, id.Hex()); err != nil {
+			return err
+		}
+		if err := r.dados.removerOnde("trechos_documento", "contexto_id", id); err != nil {
+			return err
+		}
+		return r.dados.removerOnde("documentos", "contexto_id", id)
+	case domain.EntidadePrompts:
+		return r.dados.removerOnde("prompt_versoes", "prompt_id", id)
+	}
+	return nil
+}
@@ -0,0 +1,589 @@
+package memoria
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"vend/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Repository implementa usecase.Repository em memória, com a mesma semântica
+// dos repositórios MongoDB e PostgreSQL: listagens de pessoas, telefones,
+// contextos e prompts dos mais novos aos mais antigos, e
+// domain.ErrNaoEncontrado para os registros inexistentes ou na lixeira. Os
+// dados somem ao encerrar a API, a menos que o repositório tenha um arquivo.
+type Repository struct {
+	dados *armazenamento
+}
+
+func NewRepository() *Repository {
+	return &Repository{dados: &armazenamento{colecoes: make(map[string]map[primitive.ObjectID]bson.Raw)}}
+}
+
+// NewRepositoryArquivo carrega os dados do arquivo, se ele existir, e o
+// regrava a cada alteração; com arquivo vazio equivale a NewRepository
+func NewRepositoryArquivo(arquivo string) (*Repository, error) {
+	r := NewRepository()
+	r.dados.arquivo = arquivo
+	if err := r.dados.carregar(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+var ordemCriacao = bson.D{{Key: "created_at", Value: 1}}
+
+// Métodos de Pessoa
+func (r *Repository) CreatePessoa(pessoa *domain.Pessoa) error {
+	pessoa.CreatedAt = time.Now()
+	pessoa.UpdatedAt = pessoa.CreatedAt
+	return r.dados.inserir("pessoas", pessoa, &pessoa.ID)
+}
+
+func (r *Repository) GetPessoa(id string) (*domain.Pessoa, error) {
+	var pessoa domain.Pessoa
+	if err := r.dados.buscar("pessoas", id, &pessoa); err != nil {
+		return nil, err
+	}
+	if err := r.expandirPessoa(&pessoa); err != nil {
+		return nil, err
+	}
+	return &pessoa, nil
+}
+
+func (r *Repository) ListPessoas(filtro domain.PessoaFiltro) ([]domain.Pessoa, int64, error) {
+	pessoas, total, err := listarPagina(r.dados, "pessoas", filtro.Paginacao, func(pessoa *domain.Pessoa) bool {
+		return contem(pessoa.Nome, filtro.Nome) && contem(pessoa.Email, filtro.Email)
+	})
+	if err != nil || !filtro.Expandir {
+		return pessoas, total, err
+	}
+	for i := range pessoas {
+		if err := r.expandirPessoa(&pessoas[i]); err != nil {
+			return nil, 0, err
+		}
+	}
+	return pessoas, total, nil
+}
+
+func (r *Repository) UpdatePessoa(pessoa *domain.Pessoa) error {
+	pessoa.UpdatedAt = time.Now()
+	return r.dados.atualizar("pessoas", pessoa.ID, pessoa)
+}
+
+func (r *Repository) DeletePessoa(id string, remocao domain.Remocao) error {
+	return r.moverParaLixeira("pessoas", id, remocao)
+}
+
+// Métodos de Telefone
+func (r *Repository) CreateTelefone(telefone *domain.Telefone) error {
+	telefone.CreatedAt = time.Now()
+	telefone.UpdatedAt = telefone.CreatedAt
+	return r.dados.inserir("telefones", telefone, &telefone.ID)
+}
+
+func (r *Repository) GetTelefone(id string) (*domain.Telefone, error) {
+	var telefone domain.Telefone
+	if err := r.dados.buscar("telefones", id, &telefone); err != nil {
+		return nil, err
+	}
+	return &telefone, nil
+}
+
+func (r *Repository) ListTelefones(filtro domain.TelefoneFiltro) ([]domain.Telefone, int64, error) {
+	pessoaID, err := filtroID(filtro.PessoaID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return listarPagina(r.dados, "telefones", filtro.Paginacao, func(telefone *domain.Telefone) bool {
+		return contem(telefone.Numero, filtro.Numero) &&
+			(filtro.Tipo == "" || telefone.Tipo == filtro.Tipo) &&
+			(pessoaID.IsZero() || telefone.PessoaID == pessoaID)
+	})
+}
+
+func (r *Repository) UpdateTelefone(telefone *domain.Telefone) error {
+	telefone.UpdatedAt = time.Now()
+	return r.dados.atualizar("telefones", telefone.ID, telefone)
+}
+
+func (r *Repository) DeleteTelefone(id string, remocao domain.Remocao) error {
+	return r.moverParaLixeira("telefones", id, remocao)
+}
+
+// Métodos de Contexto
+func (r *Repository) CreateContexto(contexto *domain.Contexto) error {
+	contexto.CreatedAt = time.Now()
+	contexto.UpdatedAt = contexto.CreatedAt
+	return r.dados.inserir("contextos", contexto, &contexto.ID)
+}
+
+func (r *Repository) GetContexto(id string) (*domain.Contexto, error) {
+	var contexto domain.Contexto
+	if err := r.dados.buscar("contextos", id, &contexto); err != nil {
+		return nil, err
+	}
+	if err := r.expandirContexto(&contexto); err != nil {
+		return nil, err
+	}
+	return &contexto, nil
+}
+
+func (r *Repository) ListContextos(filtro domain.ContextoFiltro) ([]domain.Contexto, int64, error) {
+	contextos, total, err := listarPagina(r.dados, "contextos", filtro.Paginacao, func(contexto *domain.Contexto) bool {
+		return contem(contexto.Nome, filtro.Nome) &&
+			(filtro.DataInicioDe.IsZero() || !contexto.DataInicio.Before(filtro.DataInicioDe)) &&
+			(filtro.DataInicioAte.IsZero() || contexto.DataInicio.Before(filtro.DataInicioAte))
+	})
+	if err != nil || !filtro.Expandir {
+		return contextos, total, err
+	}
+	for i := range contextos {
+		if err := r.expandirContexto(&contextos[i]); err != nil {
+			return nil, 0, err
+		}
+	}
+	return contextos, total, nil
+}
+
+// UpdateContexto não altera as pessoas do contexto; veja AddPessoaContexto
+func (r *Repository) UpdateContexto(contexto *domain.Contexto) error {
+	contexto.UpdatedAt = time.Now()
+	contexto.PessoaIDs = nil
+	contexto.Pessoas = nil
+	return r.dados.atualizar("contextos", contexto.ID, contexto, "pessoa_ids")
+}
+
+func (r *Repository) DeleteContexto(id string, remocao domain.Remocao) error {
+	return r.moverParaLixeira("contextos", id, remocao)
+}
+
+// Métodos de Prompt
+func (r *Repository) CreatePrompt(prompt *domain.Prompt) error {
+	prompt.CreatedAt = time.Now()
+	prompt.UpdatedAt = prompt.CreatedAt
+	return r.dados.inserir("prompts", prompt, &prompt.ID)
+}
+
+func (r *Repository) GetPrompt(id string) (*domain.Prompt, error) {
+	var prompt domain.Prompt
+	if err := r.dados.buscar("prompts", id, &prompt); err != nil {
+		return nil, err
+	}
+	return &prompt, nil
+}
+
+func (r *Repository) ListPrompts(filtro domain.PromptFiltro) ([]domain.Prompt, int64, error) {
+	contextoID, err := filtroID(filtro.ContextoID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return listarPagina(r.dados, "prompts", filtro.Paginacao, func(prompt *domain.Prompt) bool {
+		return contextoID.IsZero() || prompt.ContextoID == contextoID
+	})
+}
+
+func (r *Repository) UpdatePrompt(prompt *domain.Prompt) error {
+	prompt.UpdatedAt = time.Now()
+	return r.dados.atualizar("prompts", prompt.ID, prompt, "embedding")
+}
+
+func (r *Repository) UpdatePromptEmbedding(id string, embedding *domain.Embedding) error {
+	var prompt domain.Prompt
+	return ignorarNaoEncontrado(r.dados.alterar("prompts", id, &prompt, func() {
+		prompt.Embedding = embedding
+	}))
+}
+
+func (r *Repository) DeletePrompt(id string, remocao domain.Remocao) error {
+	return r.moverParaLixeira("prompts", id, remocao)
+}
+
+// Métodos de PromptVersao. As versões são imutáveis: não há update nem delete.
+// Como o índice único dos bancos, cada prompt tem uma só versão com cada número.
+func (r *Repository) CreatePromptVersao(versao *domain.PromptVersao) error {
+	if _, err := r.GetPromptVersao(versao.PromptID.Hex(), versao.Numero); err == nil {
+		return fmt.Errorf("versão %d do prompt %s já existe", versao.Numero, versao.PromptID.Hex())
+	}
+	versao.CreatedAt = time.Now()
+	return r.dados.inserir("prompt_versoes", versao, &versao.ID)
+}
+
+func (r *Repository) GetPromptVersao(promptID string, numero int) (*domain.PromptVersao, error) {
+	versoes, err := r.ListPromptVersoes(promptID)
+	if err != nil {
+		return nil, err
+	}
+	for _, versao := range versoes {
+		if versao.Numero == numero {
+			return &versao, nil
+		}
+	}
+	return nil, domain.ErrNaoEncontrado
+}
+
+func (r *Repository) ListPromptVersoes(promptID string) ([]domain.PromptVersao, error) {
+	objectID, err := primitive.ObjectIDFromHex(promptID)
+	if err != nil {
+		return nil, err
+	}
+
+	return listar(r.dados, "prompt_versoes", bson.D{{Key: "numero", Value: 1}}, func(v *domain.PromptVersao) bool {
+		return v.PromptID == objectID
+	})
+}
+
+// Métodos de Resposta
+func (r *Repository) CreateResposta(resposta *domain.Resposta) error {
+	resposta.CreatedAt = time.Now()
+	return r.dados.inserir("respostas", resposta, &resposta.ID)
+}
+
+func (r *Repository) GetResposta(id string) (*domain.Resposta, error) {
+	var resposta domain.Resposta
+	if err := r.dados.buscar("respostas", id, &resposta); err != nil {
+		return nil, err
+	}
+	return &resposta, nil
+}
+
+func (r *Repository) ListRespostas(filtro domain.RespostaFiltro) ([]domain.Resposta, error) {
+	promptID, err := filtroID(filtro.PromptID)
+	if err != nil {
+		return nil, err
+	}
+	contextoID, err := filtroID(filtro.ContextoID)
+	if err != nil {
+		return nil, err
+	}
+
+	respostas, _, err := listarPagina(r.dados, "respostas", filtro.Paginacao, func(resposta *domain.Resposta) bool {
+		return (promptID.IsZero() || resposta.PromptID == promptID) &&
+			(contextoID.IsZero() || resposta.ContextoID == contextoID)
+	})
+	return respostas, err
+}
+
+// SomarConsumo agrega tokens e custo das respostas e dos embeddings do período
+func (r *Repository) SomarConsumo(filtro domain.ConsumoFiltro) (*domain.Consumo, error) {
+	contextoID, err := filtroID(filtro.ContextoID)
+	if err != nil {
+		return nil, err
+	}
+	pessoaID, err := filtroID(filtro.PessoaID)
+	if err != nil {
+		return nil, err
+	}
+
+	respostas, err := listar(r.dados, "respostas", nil, func(resposta *domain.Resposta) bool {
+		return !resposta.CreatedAt.Before(filtro.Inicio) && resposta.CreatedAt.Before(filtro.Fim) &&
+			(contextoID.IsZero() || resposta.ContextoID == contextoID) &&
+			(pessoaID.IsZero() || resposta.PessoaID == pessoaID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	consumo := &domain.Consumo{Inicio: filtro.Inicio, Fim: filtro.Fim}
+	somar := func(uso domain.UsoTokens, custo float64) {
+		consumo.PromptTokens += int64(uso.PromptTokens)
+		consumo.CompletionTokens += int64(uso.CompletionTokens)
+		consumo.TotalTokens += int64(uso.TotalTokens)
+		consumo.CustoEstimado += custo
+	}
+	for _, resposta := range respostas {
+		consumo.Geracoes++
+		somar(resposta.Uso, resposta.CustoEstimado)
+	}
+	if !pessoaID.IsZero() {
+		return consumo, nil
+	}
+
+	usos, err := listar(r.dados, "usos_embeddings", nil, func(uso *domain.UsoEmbeddings) bool {
+		return !uso.CreatedAt.Before(filtro.Inicio) && uso.CreatedAt.Before(filtro.Fim) &&
+			(contextoID.IsZero() || uso.ContextoID == contextoID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, uso := range usos {
+		consumo.EmbeddingTokens += int64(uso.Uso.TotalTokens)
+		somar(uso.Uso, uso.CustoEstimado)
+	}
+	return consumo, nil
+}
+
+func (r *Repository) CreateUsoEmbeddings(uso *domain.UsoEmbeddings) error {
+	uso.CreatedAt = time.Now()
+	return r.dados.inserir("usos_embeddings", uso, &uso.ID)
+}
+
+func (r *Repository) UpdateRespostaEmbedding(id string, embedding *domain.Embedding) error {
+	var resposta domain.Resposta
+	return ignorarNaoEncontrado(r.dados.alterar("respostas", id, &resposta, func() {
+		resposta.Embedding = embedding
+	}))
+}
+
+// Métodos de Conversa
+func (r *Repository) CreateConversa(conversa *domain.Conversa) error {
+	conversa.CreatedAt = time.Now()
+	conversa.UpdatedAt = conversa.CreatedAt
+	if conversa.Mensagens == nil {
+		conversa.Mensagens = []domain.MensagemConversa{}
+	}
+	return r.dados.inserir("conversas", conversa, &conversa.ID)
+}
+
+func (r *Repository) GetConversa(id string) (*domain.Conversa, error) {
+	var conversa domain.Conversa
+	if err := r.dados.buscar("conversas", id, &conversa); err != nil {
+		return nil, err
+	}
+	return &conversa, nil
+}
+
+func (r *Repository) ListConversas(filtro domain.ConversaFiltro) ([]domain.Conversa, error) {
+	pessoaID, err := filtroID(filtro.PessoaID)
+	if err != nil {
+		return nil, err
+	}
+	contextoID, err := filtroID(filtro.ContextoID)
+	if err != nil {
+		return nil, err
+	}
+
+	return listar(r.dados, "conversas", bson.D{{Key: "updated_at", Value: -1}}, func(conversa *domain.Conversa) bool {
+		return (pessoaID.IsZero() || conversa.PessoaID == pessoaID) &&
+			(contextoID.IsZero() || conversa.ContextoID == contextoID)
+	})
+}
+
+func (r *Repository) AppendMensagensConversa(id string, mensagens []domain.MensagemConversa) error {
+	var conversa domain.Conversa
+	return r.dados.alterar("conversas", id, &conversa, func() {
+		conversa.Mensagens = append(conversa.Mensagens, mensagens...)
+		conversa.UpdatedAt = time.Now()
+	})
+}
+
+// Métodos de Documento
+func (r *Repository) CreateDocumento(documento *domain.Documento, trechos []domain.TrechoDocumento) error {
+	documento.CreatedAt = time.Now()
+	documento.Trechos = len(trechos)
+	if err := r.dados.inserir("documentos", documento, &documento.ID); err != nil {
+		return err
+	}
+
+	err := r.dados.inserirTodos("trechos_documento", len(trechos), func(i int) (interface{}, *primitive.ObjectID) {
+		trechos[i].DocumentoID = documento.ID
+		trechos[i].ContextoID = documento.ContextoID
+		return &trechos[i], &trechos[i].ID
+	})
+	if err != nil {
+		// Sem os trechos o documento não serve às gerações
+		r.DeleteDocumento(documento.ID.Hex())
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) GetDocumento(id string) (*domain.Documento, error) {
+	var documento domain.Documento
+	if err := r.dados.buscar("documentos", id, &documento); err != nil {
+		return nil, err
+	}
+	return &documento, nil
+}
+
+func (r *Repository) ListDocumentos(contextoID string) ([]domain.Documento, error) {
+	objectID, err := primitive.ObjectIDFromHex(contextoID)
+	if err != nil {
+		return nil, err
+	}
+
+	return listar(r.dados, "documentos", ordemCriacao, func(documento *domain.Documento) bool {
+		return documento.ContextoID == objectID
+	})
+}
+
+func (r *Repository) ListTrechosDocumento(contextoID string) ([]domain.TrechoDocumento, error) {
+	objectID, err := primitive.ObjectIDFromHex(contextoID)
+	if err != nil {
+		return nil, err
+	}
+
+	return listar(r.dados, "trechos_documento", nil, func(trecho *domain.TrechoDocumento) bool {
+		return trecho.ContextoID == objectID
+	})
+}
+
+func (r *Repository) DeleteDocumento(id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	if err := r.dados.removerOnde("trechos_documento", "documento_id", objectID); err != nil {
+		return err
+	}
+	return ignorarNaoEncontrado(r.dados.remover("documentos", id))
+}
+
+// Métodos de Job
+func (r *Repository) CreateJob(job *domain.Job) error {
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = job.CreatedAt
+	return r.dados.inserir("jobs", job, &job.ID)
+}
+
+func (r *Repository) GetJob(id string) (*domain.Job, error) {
+	var job domain.Job
+	if err := r.dados.buscar("jobs", id, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListJobsPorStatus retorna os jobs nos status informados, dos mais antigos aos mais novos
+func (r *Repository) ListJobsPorStatus(status ...string) ([]domain.Job, error) {
+	return listar(r.dados, "jobs", ordemCriacao, func(job *domain.Job) bool {
+		return slices.Contains(status, job.Status)
+	})
+}
+
+func (r *Repository) UpdateJobStatus(id string, status string) error {
+	var job domain.Job
+	return ignorarNaoEncontrado(r.dados.alterar("jobs", id, &job, func() {
+		job.Status = status
+		job.UpdatedAt = time.Now()
+	}))
+}
+
+// Métodos de Busca
+func (r *Repository) BuscarTexto(filtro domain.BuscaTextoFiltro) ([]domain.ResultadoTexto, error) {
+	colecoes := map[string]string{
+		domain.TipoBuscaPessoa:   "pessoas",
+		domain.TipoBuscaContexto: "contextos",
+		domain.TipoBuscaPrompt:   "prompts",
+	}
+
+	resultados := []domain.ResultadoTexto{}
+	for _, tipo := range filtro.Tipos {
+		resultados = append(resultados, r.dados.pesquisar(colecoes[tipo], tipo, filtro.Termos, filtro.Limite)...)
+	}
+	return resultados, nil
+}
+
+// UpdateJobItem altera o job sob o lock do armazenamento, então os workers do
+// mesmo job não sobrescrevem o trabalho uns dos outros
+func (r *Repository) UpdateJobItem(id string, i int, item domain.ItemJob) error {
+	var job domain.Job
+	return ignorarNaoEncontrado(r.dados.alterar("jobs", id, &job, func() {
+		for len(job.Itens) <= i {
+			job.Itens = append(job.Itens, domain.ItemJob{})
+		}
+		job.Itens[i] = item
+		if item.Status == domain.StatusItemFalhou {
+			job.Falhas++
+		} else {
+			job.Concluidos++
+		}
+		job.UpdatedAt = time.Now()
+	}))
+}
+
+// Métodos das relações
+func (r *Repository) AddPessoaContexto(contextoID, pessoaID string) error {
+	pessoa, err := primitive.ObjectIDFromHex(pessoaID)
+	if err != nil {
+		return domain.ErrNaoEncontrado
+	}
+	var contexto domain.Contexto
+	return r.dados.alterar("contextos", contextoID, &contexto, func() {
+		if !slices.Contains(contexto.PessoaIDs, pessoa) {
+			contexto.PessoaIDs = append(contexto.PessoaIDs, pessoa)
+			contexto.UpdatedAt = time.Now()
+		}
+	})
+}
+
+// RemovePessoaContexto retorna domain.ErrNaoEncontrado também quando a
+// pessoa não está no contexto
+func (r *Repository) RemovePessoaContexto(contextoID, pessoaID string) error {
+	pessoa, err := primitive.ObjectIDFromHex(pessoaID)
+	if err != nil {
+		return domain.ErrNaoEncontrado
+	}
+	var contexto domain.Contexto
+	removida := false
+	err = r.dados.alterar("contextos", contextoID, &contexto, func() {
+		if i := slices.Index(contexto.PessoaIDs, pessoa); i >= 0 {
+			contexto.PessoaIDs = slices.Delete(contexto.PessoaIDs, i, i+1)
+			contexto.UpdatedAt = time.Now()
+			removida = true
+		}
+	})
+	if err == nil && !removida {
+		return domain.ErrNaoEncontrado
+	}
+	return err
+}
+
+// expandirPessoa preenche os telefones e os contextos da pessoa, na ordem do
+// PostgreSQL
+func (r *Repository) expandirPessoa(pessoa *domain.Pessoa) error {
+	telefones, err := listar(r.dados, "telefones", ordemCriacao, func(telefone *domain.Telefone) bool {
+		return telefone.PessoaID == pessoa.ID
+	})
+	if err != nil {
+		return err
+	}
+	contextos, err := listar(r.dados, "contextos", bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}, func(contexto *domain.Contexto) bool {
+		return slices.Contains(contexto.PessoaIDs, pessoa.ID)
+	})
+	if err != nil {
+		return err
+	}
+	pessoa.Telefones, pessoa.Contextos = telefones, contextos
+	return nil
+}
+
+// expandirContexto preenche as pessoas do contexto na ordem de PessoaIDs,
+// ignorando as que não existem mais
+func (r *Repository) expandirContexto(contexto *domain.Contexto) error {
+	contexto.Pessoas = nil
+	for _, id := range contexto.PessoaIDs {
+		var pessoa domain.Pessoa
+		err := r.dados.buscar("pessoas", id.Hex(), &pessoa)
+		if errors.Is(err, domain.ErrNaoEncontrado) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		contexto.Pessoas = append(contexto.Pessoas, pessoa)
+	}
+	return nil
+}
+
+// contem busca o trecho em qualquer parte do texto, sem diferenciar maiúsculas
+func contem(texto, trecho string) bool {
+	return strings.Contains(strings.ToLower(texto), strings.ToLower(trecho))
+}
+
+// ignorarNaoEncontrado segue os outros repositórios, em que as atualizações
+// feitas em segundo plano não falham quando o registro já foi removido
+func ignorarNaoEncontrado(err error) error {
+	if errors.Is(err, domain.ErrNaoEncontrado) {
+		return nil
+	}
+	return err
+}
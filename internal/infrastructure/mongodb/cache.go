@@ -0,0 +1,75 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+	"vend/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CacheGeracao guarda resultados de gerações na coleção "cache_geracoes"; um
+// índice TTL em expira_em faz o MongoDB remover os itens vencidos
+type CacheGeracao struct {
+	collection *mongo.Collection
+}
+
+type itemCache struct {
+	Chave     string                  `bson:"_id"`
+	Resultado domain.ResultadoGeracao `bson:"resultado"`
+	ExpiraEm  time.Time               `bson:"expira_em"`
+}
+
+func NewCacheGeracao(client *mongo.Client) (*CacheGeracao, error) {
+	collection := Database(client).Collection("cache_geracoes")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expira_em", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CacheGeracao{collection: collection}, nil
+}
+
+// Get ignora itens vencidos que o MongoDB ainda não removeu (a limpeza do
+// índice TTL roda a cada minuto)
+func (c *CacheGeracao) Get(ctx context.Context, chave string) (*domain.ResultadoGeracao, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var item itemCache
+	filtro := bson.M{"_id": chave, "expira_em": bson.M{"$gt": time.Now()}}
+	err := c.collection.FindOne(ctx, filtro).Decode(&item)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &item.Resultado, true, nil
+}
+
+// Set grava o resultado; ttl zero usa um prazo de 24 horas, já que o índice
+// TTL exige uma data de expiração
+func (c *CacheGeracao) Set(ctx context.Context, chave string, resultado *domain.ResultadoGeracao, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	item := itemCache{Chave: chave, Resultado: *resultado, ExpiraEm: time.Now().Add(ttl)}
+
+	_, err := c.collection.ReplaceOne(ctx, bson.M{"_id": chave}, item, options.Replace().SetUpsert(true))
+	return err
+}
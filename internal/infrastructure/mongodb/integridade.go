@@ -0,0 +1,128 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"vend/internal/domain"
+	"vend/internal/usecase"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// codigoOperacaoIlegal é o erro de um servidor standalone ao receber uma
+// operação de transação
+const codigoOperacaoIlegal = 20
+
+// contexto é a base dos prazos das operações: a sessão da transação no
+// repositório passado por Transacao
+func (r *Repository) contexto() context.Context {
+	if r.sessao != nil {
+		return r.sessao
+	}
+	return context.Background()
+}
+
+// Transacao executa fn em uma transação do MongoDB, que o driver repete em
+// erros transitórios. Transações exigem um replica set (o docker-compose
+// sobe um de um nó só) ou um mongos; um servidor standalone recusa a
+// primeira operação antes de gravar qualquer coisa, e Transacao retorna erro
+// em vez de executar fn sem transação.
+func (r *Repository) Transacao(fn func(repo usecase.Repository) error) error {
+	if r.sessao != nil {
+		return fn(r)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	sessao, err := r.db.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer sessao.EndSession(ctx)
+
+	_, err = sessao.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+		return nil, fn(&Repository{db: r.db, sessao: sc})
+	})
+	var comando mongo.CommandError
+	if errors.As(err, &comando) && comando.Code == codigoOperacaoIlegal {
+		return fmt.Errorf("o MongoDB precisa ser um replica set ou um mongos para executar transações (use ?replicaSet= no MONGODB_URI): %w", err)
+	}
+	return err
+}
+
+// referencias dá a coleção e o campo que apontam para o registro em cada
+// relação
+func referencias(relacao domain.Relacao) (colecao, campo string, err error) {
+	switch relacao {
+	case domain.RelacaoPessoaTelefones:
+		return "telefones", "pessoa_id", nil
+	case domain.RelacaoPessoaContextos:
+		return "contextos", "pessoa_ids", nil
+	case domain.RelacaoContextoPrompts:
+		return "prompts", "contexto_id", nil
+	}
+	return "", "", fmt.Errorf("relação desconhecida: %s", relacao)
+}
+
+func (r *Repository) ContarReferencias(relacao domain.Relacao, id string) (int64, error) {
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	colecao, campo, err := referencias(relacao)
+	if err != nil {
+		return 0, err
+	}
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, nil
+	}
+	return r.db.Collection(colecao).CountDocuments(ctx, bson.M{campo: objectID, "deleted_at": ativo})
+}
+
+func (r *Repository) RemoverReferencias(relacao domain.Relacao, id string, remocao domain.Remocao) error {
+	if relacao == domain.RelacaoPessoaContextos {
+		return r.AnularReferencias(relacao, id)
+	}
+
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	colecao, campo, err := referencias(relacao)
+	if err != nil {
+		return err
+	}
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil
+	}
+	_, err = r.db.Collection(colecao).UpdateMany(ctx, bson.M{campo: objectID, "deleted_at": ativo}, marcarRemocao(remocao))
+	return err
+}
+
+// AnularReferencias grava null no campo, ou tira o ID da lista de pessoas
+// dos contextos
+func (r *Repository) AnularReferencias(relacao domain.Relacao, id string) error {
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	colecao, campo, err := referencias(relacao)
+	if err != nil {
+		return err
+	}
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil
+	}
+
+	alteracao := bson.M{"$set": bson.M{campo: nil, "updated_at": time.Now()}}
+	if relacao == domain.RelacaoPessoaContextos {
+		alteracao = bson.M{"$pull": bson.M{campo: objectID}, "$set": bson.M{"updated_at": time.Now()}}
+	}
+	_, err = r.db.Collection(colecao).UpdateMany(ctx, bson.M{campo: objectID}, alteracao)
+	return err
+}
@@ -0,0 +1,238 @@
+package mongodb
+
+import (
+	"context"
+	"sort"
+	"time"
+	"vend/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var naLixeira = bson.M{"$exists": true}
+
+// ListLixeira busca a página em cada coleção, já na ordem de deleted_at, e
+// junta os resultados; cada coleção contribui no máximo offset + limite
+// documentos
+func (r *Repository) ListLixeira(filtro domain.LixeiraFiltro) ([]domain.ItemLixeira, int64, error) {
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	entidades := domain.EntidadesLixeira
+	if filtro.Entidade != "" {
+		entidades = []string{filtro.Entidade}
+	}
+	ordem := filtro.Ordem
+	direcao, operador := 1, "$gt"
+	if ordem.Decrescente {
+		direcao, operador = -1, "$lt"
+	}
+
+	query := bson.M{"deleted_at": naLixeira}
+	if apos := filtro.Apos; apos != nil {
+		query["$or"] = bson.A{
+			bson.M{"deleted_at": bson.M{operador: apos.Valor}},
+			bson.M{"deleted_at": apos.Valor, "_id": bson.M{operador: apos.ID}},
+		}
+	}
+
+	itens := []domain.ItemLixeira{}
+	var total int64
+	for _, entidade := range entidades {
+		collection := r.db.Collection(entidade)
+		if filtro.ComTotal {
+			n, err := collection.CountDocuments(ctx, bson.M{"deleted_at": naLixeira})
+			if err != nil {
+				return nil, 0, err
+			}
+			total += n
+		}
+
+		opts := options.Find().
+			SetProjection(projecaoLixeira(entidade)).
+			SetSort(bson.D{{Key: "deleted_at", Value: direcao}, {Key: "_id", Value: direcao}})
+		if filtro.Limite > 0 {
+			opts.SetLimit(int64(filtro.Offset + filtro.Limite))
+		}
+		cursor, err := collection.Find(ctx, query, opts)
+		if err != nil {
+			return nil, 0, err
+		}
+		var docs []bson.M
+		if err := cursor.All(ctx, &docs); err != nil {
+			return nil, 0, err
+		}
+		for _, doc := range docs {
+			itens = append(itens, itemLixeira(entidade, doc))
+		}
+	}
+
+	sort.Slice(itens, func(i, j int) bool {
+		a, b := itens[i], itens[j]
+		if !a.DeletedAt.Equal(b.DeletedAt) {
+			return a.DeletedAt.Before(b.DeletedAt) == (direcao == 1)
+		}
+		return (a.ID.Hex() < b.ID.Hex()) == (direcao == 1)
+	})
+	if filtro.Offset >= len(itens) {
+		return []domain.ItemLixeira{}, total, nil
+	}
+	itens = itens[filtro.Offset:]
+	if filtro.Limite > 0 && len(itens) > filtro.Limite {
+		itens = itens[:filtro.Limite]
+	}
+	return itens, total, nil
+}
+
+func (r *Repository) GetLixeira(entidade, id string) (*domain.ItemLixeira, error) {
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, domain.ErrNaoEncontrado
+	}
+
+	var doc bson.M
+	err = r.db.Collection(entidade).FindOne(ctx,
+		bson.M{"_id": objectID, "deleted_at": naLixeira},
+		options.FindOne().SetProjection(projecaoLixeira(entidade)),
+	).Decode(&doc)
+	if err != nil {
+		return nil, naoEncontrado(err)
+	}
+	item := itemLixeira(entidade, doc)
+	return &item, nil
+}
+
+// projecaoLixeira traz os campos de ItemLixeira dos documentos da entidade
+func projecaoLixeira(entidade string) bson.M {
+	projecao := bson.M{domain.CamposTituloLixeira[entidade]: 1, "deleted_at": 1, "deleted_by": 1}
+	if campo, ok := domain.CamposReferenciaLixeira[entidade]; ok {
+		projecao[campo] = 1
+	}
+	return projecao
+}
+
+func itemLixeira(entidade string, doc bson.M) domain.ItemLixeira {
+	item := domain.ItemLixeira{Entidade: entidade, ID: doc["_id"].(primitive.ObjectID)}
+	item.Titulo, _ = doc[domain.CamposTituloLixeira[entidade]].(string)
+	if em, ok := doc["deleted_at"].(primitive.DateTime); ok {
+		item.DeletedAt = em.Time().UTC()
+	}
+	item.DeletedBy, _ = doc["deleted_by"].(string)
+	item.ReferenciaID, _ = doc[domain.CamposReferenciaLixeira[entidade]].(primitive.ObjectID)
+	return item
+}
+
+func (r *Repository) Restaurar(entidade, id string) (*domain.Remocao, error) {
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, domain.ErrNaoEncontrado
+	}
+
+	var anterior struct {
+		DeletedAt time.Time `bson:"deleted_at"`
+		DeletedBy string    `bson:"deleted_by"`
+	}
+	err = r.db.Collection(entidade).FindOneAndUpdate(ctx,
+		bson.M{"_id": objectID, "deleted_at": naLixeira},
+		bson.M{"$unset": bson.M{"deleted_at": "", "deleted_by": ""}},
+		options.FindOneAndUpdate().SetProjection(bson.M{"deleted_at": 1, "deleted_by": 1}),
+	).Decode(&anterior)
+	if err != nil {
+		return nil, naoEncontrado(err)
+	}
+	return &domain.Remocao{Em: anterior.DeletedAt, Por: anterior.DeletedBy}, nil
+}
+
+func (r *Repository) RestaurarReferencias(relacao domain.Relacao, id string, em time.Time) error {
+	if relacao == domain.RelacaoPessoaContextos {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	colecao, campo, err := referencias(relacao)
+	if err != nil {
+		return err
+	}
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil
+	}
+	_, err = r.db.Collection(colecao).UpdateMany(ctx,
+		bson.M{campo: objectID, "deleted_at": em},
+		bson.M{"$unset": bson.M{"deleted_at": "", "deleted_by": ""}},
+	)
+	return err
+}
+
+// PurgarLixeira apaga os documentos e faz nas coleções relacionadas o que as
+// chaves estrangeiras fazem no PostgreSQL: as versões dos prompts e os
+// documentos dos contextos vão junto, as pessoas saem dos contextos e os
+// telefones e prompts restantes ficam sem a referência
+func (r *Repository) PurgarLixeira(ate time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Minute)
+	defer cancel()
+
+	var total int64
+	for _, entidade := range domain.EntidadesLixeira {
+		collection := r.db.Collection(entidade)
+		cursor, err := collection.Find(ctx, bson.M{"deleted_at": bson.M{"$lte": ate}}, options.Find().SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			return total, err
+		}
+		var docs []struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.All(ctx, &docs); err != nil {
+			return total, err
+		}
+		if len(docs) == 0 {
+			continue
+		}
+		ids := make([]primitive.ObjectID, len(docs))
+		for i, doc := range docs {
+			ids[i] = doc.ID
+		}
+
+		if err := r.purgarRelacionados(ctx, entidade, ids); err != nil {
+			return total, err
+		}
+		result, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+		if err != nil {
+			return total, err
+		}
+		total += result.DeletedCount
+	}
+	return total, nil
+}
+
+func (r *Repository) purgarRelacionados(ctx context.Context, entidade string, ids []primitive.ObjectID) error {
+	var err error
+	switch entidade {
+	case domain.EntidadePessoas:
+		if _, err = r.db.Collection("telefones").UpdateMany(ctx, bson.M{"pessoa_id": bson.M{"$in": ids}}, bson.M{"$set": bson.M{"pessoa_id": nil}}); err != nil {
+			return err
+		}
+		_, err = r.db.Collection("contextos").UpdateMany(ctx, bson.M{"pessoa_ids": bson.M{"$in": ids}}, bson.M{"$pull": bson.M{"pessoa_ids": bson.M{"$in": ids}}})
+	case domain.EntidadeContextos:
+		if _, err = r.db.Collection("prompts").UpdateMany(ctx, bson.M{"contexto_id": bson.M{"$in": ids}}, bson.M{"$set": bson.M{"contexto_id": nil}}); err != nil {
+			return err
+		}
+		if _, err = r.db.Collection("trechos_documento").DeleteMany(ctx, bson.M{"contexto_id": bson.M{"$in": ids}}); err != nil {
+			return err
+		}
+		_, err = r.db.Collection("documentos").DeleteMany(ctx, bson.M{"contexto_id": bson.M{"$in": ids}})
+	case domain.EntidadePrompts:
+		_, err = r.db.Collection("prompt_versoes").DeleteMany(ctx, bson.M{"prompt_id": bson.M{"$in": ids}})
+	}
+	return err
+}
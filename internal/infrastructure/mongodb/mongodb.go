@@ -32,3 +32,12 @@ func NewMongoClient() (*mongo.Client, error) {
 
 	return client, nil
 }
+
+// Database retorna o banco da aplicação, definido por MONGODB_DATABASE (padrão "vend")
+func Database(client *mongo.Client) *mongo.Database {
+	dbName := "vend"
+	if dbNameEnv := os.Getenv("MONGODB_DATABASE"); dbNameEnv != "" {
+		dbName = dbNameEnv
+	}
+	return client.Database(dbName)
+}
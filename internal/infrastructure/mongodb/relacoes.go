@@ -0,0 +1,244 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+	"vend/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// As relações entre pessoas, telefones e contextos são referências: o
+// telefone guarda pessoa_id e o contexto guarda pessoa_ids. Os campos
+// Pessoa.Telefones, Pessoa.Contextos e Contexto.Pessoas não são gravados
+// (bson:"-") e vêm dos $lookup abaixo, decodificados nos tipos expandidos.
+
+func lookup(colecao, campoLocal, campoEstrangeiro, destino string) bson.D {
+	return bson.D{{Key: "$lookup", Value: bson.M{
+		"from":         colecao,
+		"localField":   campoLocal,
+		"foreignField": campoEstrangeiro,
+		"as":           destino,
+	}}}
+}
+
+var (
+	etapasPessoa = []bson.D{
+		lookup("telefones", "_id", "pessoa_id", "telefones"),
+		lookup("contextos", "_id", "pessoa_ids", "contextos"),
+	}
+	etapasContexto = []bson.D{
+		lookup("pessoas", "pessoa_ids", "_id", "pessoas"),
+	}
+)
+
+type pessoaExpandida struct {
+	domain.Pessoa `bson:",inline"`
+	Telefones     []domain.Telefone `bson:"telefones"`
+	Contextos     []domain.Contexto `bson:"contextos"`
+}
+
+// dominio deixa de fora as relações na lixeira e as ordena como no
+// PostgreSQL: telefones dos mais antigos aos mais novos e contextos dos mais
+// novos aos mais antigos
+func (p pessoaExpandida) dominio() domain.Pessoa {
+	pessoa := p.Pessoa
+	pessoa.Telefones = ativos(p.Telefones, func(t domain.Telefone) bool { return t.DeletedAt == nil })
+	sort.Slice(pessoa.Telefones, func(i, j int) bool {
+		a, b := pessoa.Telefones[i], pessoa.Telefones[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID.Hex() < b.ID.Hex()
+	})
+	pessoa.Contextos = ativos(p.Contextos, func(c domain.Contexto) bool { return c.DeletedAt == nil })
+	sort.Slice(pessoa.Contextos, func(i, j int) bool {
+		a, b := pessoa.Contextos[i], pessoa.Contextos[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID.Hex() > b.ID.Hex()
+	})
+	return pessoa
+}
+
+type contextoExpandido struct {
+	domain.Contexto `bson:",inline"`
+	Pessoas         []domain.Pessoa `bson:"pessoas"`
+}
+
+// dominio deixa de fora as pessoas na lixeira e as põe na ordem de
+// PessoaIDs, que o $lookup não preserva
+func (c contextoExpandido) dominio() domain.Contexto {
+	contexto := c.Contexto
+	contexto.Pessoas = ativos(c.Pessoas, func(p domain.Pessoa) bool { return p.DeletedAt == nil })
+	sort.SliceStable(contexto.Pessoas, func(i, j int) bool {
+		return slices.Index(contexto.PessoaIDs, contexto.Pessoas[i].ID) < slices.Index(contexto.PessoaIDs, contexto.Pessoas[j].ID)
+	})
+	return contexto
+}
+
+// ativos retorna os itens aceitos por ativo, mantendo nil quando não há
+// relações, como no PostgreSQL
+func ativos[T any](itens []T, ativo func(T) bool) []T {
+	var resultado []T
+	for _, item := range itens {
+		if ativo(item) {
+			resultado = append(resultado, item)
+		}
+	}
+	return resultado
+}
+
+// Métodos das relações
+func (r *Repository) AddPessoaContexto(contextoID, pessoaID string) error {
+	return r.alterarPessoasContexto(contextoID, pessoaID, false)
+}
+
+// RemovePessoaContexto retorna domain.ErrNaoEncontrado também quando a
+// pessoa não está no contexto
+func (r *Repository) RemovePessoaContexto(contextoID, pessoaID string) error {
+	return r.alterarPessoasContexto(contextoID, pessoaID, true)
+}
+
+func (r *Repository) alterarPessoasContexto(contextoID, pessoaID string, remover bool) error {
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	contexto, err := primitive.ObjectIDFromHex(contextoID)
+	if err != nil {
+		return domain.ErrNaoEncontrado
+	}
+	pessoa, err := primitive.ObjectIDFromHex(pessoaID)
+	if err != nil {
+		return domain.ErrNaoEncontrado
+	}
+
+	filtro := bson.M{"_id": contexto, "deleted_at": ativo}
+	alteracao := bson.M{"$addToSet": bson.M{"pessoa_ids": pessoa}, "$set": bson.M{"updated_at": time.Now()}}
+	if remover {
+		filtro["pessoa_ids"] = pessoa
+		alteracao = bson.M{"$pull": bson.M{"pessoa_ids": pessoa}, "$set": bson.M{"updated_at": time.Now()}}
+	}
+	result, err := r.db.Collection("contextos").UpdateOne(ctx, filtro, alteracao)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrNaoEncontrado
+	}
+	return nil
+}
+
+// MigrarReferencias converte os documentos gravados com as relações
+// copiadas: as pessoas embutidas nos contextos viram pessoa_ids, os telefones
+// embutidos nas pessoas são gravados na coleção telefones (os que já existem
+// ficam como estão) e os contextos embutidos nelas recebem a pessoa em
+// pessoa_ids. Só percorre os documentos que ainda têm cópias, então pode rodar
+// a cada inicialização.
+func (r *Repository) MigrarReferencias() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	contextos := r.db.Collection("contextos")
+	cursor, err := contextos.Find(ctx, bson.M{"pessoas": bson.M{"$exists": true}}, options.Find().SetProjection(bson.M{"pessoa_ids": 1, "pessoas._id": 1}))
+	if err != nil {
+		return err
+	}
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID        primitive.ObjectID   `bson:"_id"`
+			PessoaIDs []primitive.ObjectID `bson:"pessoa_ids"`
+			Pessoas   []struct {
+				ID primitive.ObjectID `bson:"_id"`
+			} `bson:"pessoas"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			cursor.Close(ctx)
+			return err
+		}
+		// Nunca nulo: $addToSet não altera um campo nulo
+		ids := append([]primitive.ObjectID{}, doc.PessoaIDs...)
+		for _, p := range doc.Pessoas {
+			if !p.ID.IsZero() && !slices.Contains(ids, p.ID) {
+				ids = append(ids, p.ID)
+			}
+		}
+		alteracao := bson.M{"$set": bson.M{"pessoa_ids": ids}, "$unset": bson.M{"pessoas": ""}}
+		if _, err := contextos.UpdateOne(ctx, bson.M{"_id": doc.ID}, alteracao); err != nil {
+			cursor.Close(ctx)
+			return fmt.Errorf("contexto %s: %w", doc.ID.Hex(), err)
+		}
+	}
+	cursor.Close(ctx)
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	pessoas := r.db.Collection("pessoas")
+	cursor, err = pessoas.Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"telefones": bson.M{"$exists": true}},
+		bson.M{"contextos": bson.M{"$exists": true}},
+	}}, options.Find().SetProjection(bson.M{"telefones": 1, "contextos._id": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID        primitive.ObjectID `bson:"_id"`
+			Telefones []domain.Telefone  `bson:"telefones"`
+			Contextos []struct {
+				ID primitive.ObjectID `bson:"_id"`
+			} `bson:"contextos"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		contextoIDs := make([]primitive.ObjectID, len(doc.Contextos))
+		for i, c := range doc.Contextos {
+			contextoIDs[i] = c.ID
+		}
+		if err := r.migrarPessoa(ctx, doc.ID, doc.Telefones, contextoIDs); err != nil {
+			return fmt.Errorf("pessoa %s: %w", doc.ID.Hex(), err)
+		}
+	}
+	return cursor.Err()
+}
+
+func (r *Repository) migrarPessoa(ctx context.Context, id primitive.ObjectID, telefones []domain.Telefone, contextoIDs []primitive.ObjectID) error {
+	for _, telefone := range telefones {
+		if telefone.ID.IsZero() {
+			telefone.ID = primitive.NewObjectID()
+		}
+		telefone.PessoaID = id
+		dados, err := bson.Marshal(telefone)
+		if err != nil {
+			return err
+		}
+		var campos bson.M
+		if err := bson.Unmarshal(dados, &campos); err != nil {
+			return err
+		}
+		delete(campos, "_id")
+		_, err = r.db.Collection("telefones").UpdateOne(ctx, bson.M{"_id": telefone.ID}, bson.M{"$setOnInsert": campos}, options.Update().SetUpsert(true))
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(contextoIDs) > 0 {
+		_, err := r.db.Collection("contextos").UpdateMany(ctx, bson.M{"_id": bson.M{"$in": contextoIDs}}, bson.M{"$addToSet": bson.M{"pessoa_ids": id}})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := r.db.Collection("pessoas").UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$unset": bson.M{"telefones": "", "contextos": ""}})
+	return err
+}
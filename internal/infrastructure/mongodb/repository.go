@@ -2,6 +2,12 @@ package mongodb
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 	"vend/internal/domain"
 
@@ -11,85 +17,1126 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Repository implementa usecase.Repository no MongoDB, com uma coleção por
+// entidade. As listagens de pessoas, telefones, contextos e prompts vêm dos
+// mais novos aos mais antigos, e os registros inexistentes retornam
+// domain.ErrNaoEncontrado. Os documentos com deleted_at estão na lixeira e
+// ficam de fora de todas as leituras e alterações, exceto as da lixeira.
 type Repository struct {
 	db *mongo.Database
+	// sessao é a transação em andamento no repositório passado por Transacao
+	sessao mongo.SessionContext
 }
 
 func NewRepository(db *mongo.Database) *Repository {
 	return &Repository{db: db}
 }
 
-// Pessoas
+// indices atendem as listagens paginadas: um por campo ordenável, com o _id
+// que desempata (o MongoDB percorre o índice nas duas direções), e os filtros
+// e os $lookup por ID
+var indices = map[string][]string{
+	"pessoas":   {"created_at", "updated_at", "nome", "email", "deleted_at"},
+	"telefones": {"created_at", "updated_at", "numero", "tipo", "pessoa_id", "deleted_at"},
+	"contextos": {"created_at", "updated_at", "nome", "data_inicio", "data_fim", "pessoa_ids", "deleted_at"},
+	"prompts":   {"created_at", "updated_at", "versao", "contexto_id", "deleted_at"},
+	"respostas": {"created_at"},
+}
+
+// ativo aceita os documentos fora da lixeira; as coleções sem lixeira não
+// têm deleted_at, então aceita todos os documentos delas
+var ativo = bson.M{"$exists": false}
+
+// colecoesBusca são as coleções de cada tipo da busca textual
+var colecoesBusca = map[string]string{
+	domain.TipoBuscaPessoa:   "pessoas",
+	domain.TipoBuscaContexto: "contextos",
+	domain.TipoBuscaPrompt:   "prompts",
+}
+
+// CriarIndices cria os índices das listagens e da busca textual que ainda não
+// existirem
+func (r *Repository) CriarIndices() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	for colecao, campos := range indices {
+		modelos := make([]mongo.IndexModel, len(campos))
+		for i, campo := range campos {
+			modelos[i] = mongo.IndexModel{Keys: bson.D{{Key: campo, Value: 1}, {Key: "_id", Value: 1}}}
+		}
+		if _, err := r.db.Collection(colecao).Indexes().CreateMany(ctx, modelos); err != nil {
+			return fmt.Errorf("índices de %s: %w", colecao, err)
+		}
+	}
+
+	// Cada prompt tem uma só versão com cada número
+	versoes := mongo.IndexModel{
+		Keys:    bson.D{{Key: "prompt_id", Value: 1}, {Key: "numero", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	if _, err := r.db.Collection("prompt_versoes").Indexes().CreateOne(ctx, versoes); err != nil {
+		return fmt.Errorf("índice de prompt_versoes: %w", err)
+	}
+
+	// A coleção só pode ter um índice de texto, com todos os campos da busca
+	for tipo, colecao := range colecoesBusca {
+		chaves := bson.D{}
+		for _, campo := range domain.CamposBuscaTexto[tipo] {
+			chaves = append(chaves, bson.E{Key: campo, Value: "text"})
+		}
+		modelo := mongo.IndexModel{Keys: chaves, Options: options.Index().SetName("busca_texto").SetDefaultLanguage("portuguese")}
+		if _, err := r.db.Collection(colecao).Indexes().CreateOne(ctx, modelo); err != nil {
+			return fmt.Errorf("índice de texto de %s: %w", colecao, err)
+		}
+	}
+	return nil
+}
+
+// Métodos de Pessoa
 func (r *Repository) CreatePessoa(pessoa *domain.Pessoa) error {
 	pessoa.CreatedAt = time.Now()
+	pessoa.UpdatedAt = pessoa.CreatedAt
+	return r.inserir("pessoas", pessoa, &pessoa.ID)
+}
+
+func (r *Repository) GetPessoa(id string) (*domain.Pessoa, error) {
+	var expandida pessoaExpandida
+	if err := r.buscarExpandido("pessoas", id, &expandida, etapasPessoa...); err != nil {
+		return nil, err
+	}
+	pessoa := expandida.dominio()
+	return &pessoa, nil
+}
+
+func (r *Repository) ListPessoas(filtro domain.PessoaFiltro) ([]domain.Pessoa, int64, error) {
+	query := bson.M{}
+	if filtro.Nome != "" {
+		query["nome"] = contem(filtro.Nome)
+	}
+	if filtro.Email != "" {
+		query["email"] = contem(filtro.Email)
+	}
+
+	var etapas []bson.D
+	if filtro.Expandir {
+		etapas = etapasPessoa
+	}
+	expandidas := []pessoaExpandida{}
+	total, err := r.listarPagina("pessoas", query, filtro.Paginacao, &expandidas, etapas...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pessoas := make([]domain.Pessoa, len(expandidas))
+	for i, p := range expandidas {
+		pessoas[i] = p.dominio()
+	}
+	return pessoas, total, nil
+}
+
+func (r *Repository) UpdatePessoa(pessoa *domain.Pessoa) error {
 	pessoa.UpdatedAt = time.Now()
+	return r.atualizar("pessoas", pessoa.ID, pessoa)
+}
+
+func (r *Repository) DeletePessoa(id string, remocao domain.Remocao) error {
+	return r.moverParaLixeira("pessoas", id, remocao)
+}
+
+// Métodos de Telefone
+func (r *Repository) CreateTelefone(telefone *domain.Telefone) error {
+	telefone.CreatedAt = time.Now()
+	telefone.UpdatedAt = telefone.CreatedAt
+	return r.inserir("telefones", telefone, &telefone.ID)
+}
+
+func (r *Repository) GetTelefone(id string) (*domain.Telefone, error) {
+	var telefone domain.Telefone
+	if err := r.buscar("telefones", id, &telefone); err != nil {
+		return nil, err
+	}
+	return &telefone, nil
+}
+
+func (r *Repository) ListTelefones(filtro domain.TelefoneFiltro) ([]domain.Telefone, int64, error) {
+	query := bson.M{}
+	if filtro.Numero != "" {
+		query["numero"] = contem(filtro.Numero)
+	}
+	if filtro.Tipo != "" {
+		query["tipo"] = filtro.Tipo
+	}
+	if filtro.PessoaID != "" {
+		pessoaID, err := primitive.ObjectIDFromHex(filtro.PessoaID)
+		if err != nil {
+			return nil, 0, err
+		}
+		query["pessoa_id"] = pessoaID
+	}
+
+	telefones := []domain.Telefone{}
+	total, err := r.listarPagina("telefones", query, filtro.Paginacao, &telefones)
+	if err != nil {
+		return nil, 0, err
+	}
+	return telefones, total, nil
+}
+
+func (r *Repository) UpdateTelefone(telefone *domain.Telefone) error {
+	telefone.UpdatedAt = time.Now()
+	return r.atualizar("telefones", telefone.ID, telefone)
+}
+
+func (r *Repository) DeleteTelefone(id string, remocao domain.Remocao) error {
+	return r.moverParaLixeira("telefones", id, remocao)
+}
+
+// Métodos de Contexto
+func (r *Repository) CreateContexto(contexto *domain.Contexto) error {
+	contexto.CreatedAt = time.Now()
+	contexto.UpdatedAt = contexto.CreatedAt
+	return r.inserir("contextos", contexto, &contexto.ID)
+}
+
+func (r *Repository) GetContexto(id string) (*domain.Contexto, error) {
+	var expandido contextoExpandido
+	if err := r.buscarExpandido("contextos", id, &expandido, etapasContexto...); err != nil {
+		return nil, err
+	}
+	contexto := expandido.dominio()
+	return &contexto, nil
+}
+
+func (r *Repository) ListContextos(filtro domain.ContextoFiltro) ([]domain.Contexto, int64, error) {
+	query := bson.M{}
+	if filtro.Nome != "" {
+		query["nome"] = contem(filtro.Nome)
+	}
+	periodo := bson.M{}
+	if !filtro.DataInicioDe.IsZero() {
+		periodo["$gte"] = filtro.DataInicioDe
+	}
+	if !filtro.DataInicioAte.IsZero() {
+		periodo["$lt"] = filtro.DataInicioAte
+	}
+	if len(periodo) > 0 {
+		query["data_inicio"] = periodo
+	}
+
+	var etapas []bson.D
+	if filtro.Expandir {
+		etapas = etapasContexto
+	}
+	expandidos := []contextoExpandido{}
+	total, err := r.listarPagina("contextos", query, filtro.Paginacao, &expandidos, etapas...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	contextos := make([]domain.Contexto, len(expandidos))
+	for i, c := range expandidos {
+		contextos[i] = c.dominio()
+	}
+	return contextos, total, nil
+}
+
+// UpdateContexto não altera as pessoas do contexto; veja AddPessoaContexto
+func (r *Repository) UpdateContexto(contexto *domain.Contexto) error {
+	contexto.UpdatedAt = time.Now()
+	contexto.PessoaIDs = nil
+	contexto.Pessoas = nil
+	return r.atualizar("contextos", contexto.ID, contexto, "pessoa_ids")
+}
+
+func (r *Repository) DeleteContexto(id string, remocao domain.Remocao) error {
+	return r.moverParaLixeira("contextos", id, remocao)
+}
+
+// Métodos de Prompt
+func (r *Repository) CreatePrompt(prompt *domain.Prompt) error {
+	prompt.CreatedAt = time.Now()
+	prompt.UpdatedAt = prompt.CreatedAt
+	return r.inserir("prompts", prompt, &prompt.ID)
+}
+
+func (r *Repository) GetPrompt(id string) (*domain.Prompt, error) {
+	var prompt domain.Prompt
+	if err := r.buscar("prompts", id, &prompt); err != nil {
+		return nil, err
+	}
+	return &prompt, nil
+}
+
+func (r *Repository) ListPrompts(filtro domain.PromptFiltro) ([]domain.Prompt, int64, error) {
+	query := bson.M{}
+	if filtro.ContextoID != "" {
+		contextoID, err := primitive.ObjectIDFromHex(filtro.ContextoID)
+		if err != nil {
+			return nil, 0, err
+		}
+		query["contexto_id"] = contextoID
+	}
+
+	prompts := []domain.Prompt{}
+	total, err := r.listarPagina("prompts", query, filtro.Paginacao, &prompts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return prompts, total, nil
+}
+
+func (r *Repository) UpdatePrompt(prompt *domain.Prompt) error {
+	prompt.UpdatedAt = time.Now()
+	return r.atualizar("prompts", prompt.ID, prompt, "embedding")
+}
+
+func (r *Repository) UpdatePromptEmbedding(id string, embedding *domain.Embedding) error {
+	return r.updateEmbedding("prompts", id, embedding)
+}
+
+func (r *Repository) DeletePrompt(id string, remocao domain.Remocao) error {
+	return r.moverParaLixeira("prompts", id, remocao)
+}
+
+// Métodos de PromptVersao. As versões são imutáveis: não há update nem delete.
+func (r *Repository) CreatePromptVersao(versao *domain.PromptVersao) error {
+	collection := r.db.Collection("prompt_versoes")
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	versao.CreatedAt = time.Now()
 
-	result, err := r.db.Collection("pessoas").InsertOne(context.Background(), pessoa)
+	result, err := collection.InsertOne(ctx, versao)
 	if err != nil {
 		return err
 	}
 
-	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
-		pessoa.ID = oid
+	versao.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *Repository) GetPromptVersao(promptID string, numero int) (*domain.PromptVersao, error) {
+	collection := r.db.Collection("prompt_versoes")
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(promptID)
+	if err != nil {
+		return nil, domain.ErrNaoEncontrado
+	}
+
+	var versao domain.PromptVersao
+	err = collection.FindOne(ctx, bson.M{"prompt_id": objectID, "numero": numero}).Decode(&versao)
+	if err != nil {
+		return nil, naoEncontrado(err)
+	}
+
+	return &versao, nil
+}
+
+func (r *Repository) ListPromptVersoes(promptID string) ([]domain.PromptVersao, error) {
+	collection := r.db.Collection("prompt_versoes")
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(promptID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "numero", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{"prompt_id": objectID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var versoes []domain.PromptVersao
+	if err = cursor.All(ctx, &versoes); err != nil {
+		return nil, err
+	}
+
+	return versoes, nil
+}
+
+// Métodos de Resposta
+func (r *Repository) CreateResposta(resposta *domain.Resposta) error {
+	collection := r.db.Collection("respostas")
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	resposta.CreatedAt = time.Now()
+
+	result, err := collection.InsertOne(ctx, resposta)
+	if err != nil {
+		return err
 	}
+
+	resposta.ID = result.InsertedID.(primitive.ObjectID)
 	return nil
 }
 
-func (r *Repository) GetPessoa(id string) (*domain.Pessoa, error) {
+func (r *Repository) GetResposta(id string) (*domain.Resposta, error) {
+	var resposta domain.Resposta
+	if err := r.buscar("respostas", id, &resposta); err != nil {
+		return nil, err
+	}
+	return &resposta, nil
+}
+
+func (r *Repository) ListRespostas(filtro domain.RespostaFiltro) ([]domain.Resposta, error) {
+	query := bson.M{}
+	if filtro.PromptID != "" {
+		promptID, err := primitive.ObjectIDFromHex(filtro.PromptID)
+		if err != nil {
+			return nil, err
+		}
+		query["prompt_id"] = promptID
+	}
+	if filtro.ContextoID != "" {
+		contextoID, err := primitive.ObjectIDFromHex(filtro.ContextoID)
+		if err != nil {
+			return nil, err
+		}
+		query["contexto_id"] = contextoID
+	}
+
+	var respostas []domain.Resposta
+	if _, err := r.listarPagina("respostas", query, filtro.Paginacao, &respostas); err != nil {
+		return nil, err
+	}
+	return respostas, nil
+}
+
+// SomarConsumo agrega tokens e custo das respostas e dos embeddings do período
+func (r *Repository) SomarConsumo(filtro domain.ConsumoFiltro) (*domain.Consumo, error) {
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	match := bson.M{"created_at": bson.M{"$gte": filtro.Inicio, "$lt": filtro.Fim}}
+	if filtro.ContextoID != "" {
+		contextoID, err := primitive.ObjectIDFromHex(filtro.ContextoID)
+		if err != nil {
+			return nil, err
+		}
+		match["contexto_id"] = contextoID
+	}
+
+	var embeddings totaisConsumo
+	if filtro.PessoaID == "" {
+		var err error
+		if embeddings, err = r.somarUso(ctx, "usos_embeddings", match); err != nil {
+			return nil, err
+		}
+	} else {
+		pessoaID, err := primitive.ObjectIDFromHex(filtro.PessoaID)
+		if err != nil {
+			return nil, err
+		}
+		match["pessoa_id"] = pessoaID
+	}
+	respostas, err := r.somarUso(ctx, "respostas", match)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.Consumo{
+		Inicio:           filtro.Inicio,
+		Fim:              filtro.Fim,
+		Geracoes:         respostas.Registros,
+		PromptTokens:     respostas.PromptTokens + embeddings.PromptTokens,
+		CompletionTokens: respostas.CompletionTokens + embeddings.CompletionTokens,
+		TotalTokens:      respostas.TotalTokens + embeddings.TotalTokens,
+		EmbeddingTokens:  embeddings.TotalTokens,
+		CustoEstimado:    respostas.CustoEstimado + embeddings.CustoEstimado,
+	}, nil
+}
+
+type totaisConsumo struct {
+	Registros        int64   `bson:"registros"`
+	PromptTokens     int64   `bson:"prompt_tokens"`
+	CompletionTokens int64   `bson:"completion_tokens"`
+	TotalTokens      int64   `bson:"total_tokens"`
+	CustoEstimado    float64 `bson:"custo_estimado"`
+}
+
+// somarUso soma o uso e o custo dos documentos da coleção aceitos por match
+func (r *Repository) somarUso(ctx context.Context, colecao string, match bson.M) (totaisConsumo, error) {
+	var totais totaisConsumo
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":               nil,
+			"registros":         bson.M{"$sum": 1},
+			"prompt_tokens":     bson.M{"$sum": "$uso.prompt_tokens"},
+			"completion_tokens": bson.M{"$sum": "$uso.completion_tokens"},
+			"total_tokens":      bson.M{"$sum": "$uso.total_tokens"},
+			"custo_estimado":    bson.M{"$sum": "$custo_estimado"},
+		}}},
+	}
+
+	cursor, err := r.db.Collection(colecao).Aggregate(ctx, pipeline)
+	if err != nil {
+		return totais, err
+	}
+	defer cursor.Close(ctx)
+
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&totais); err != nil {
+			return totais, err
+		}
+	}
+	return totais, cursor.Err()
+}
+
+func (r *Repository) CreateUsoEmbeddings(uso *domain.UsoEmbeddings) error {
+	uso.CreatedAt = time.Now()
+	return r.inserir("usos_embeddings", uso, &uso.ID)
+}
+
+// Métodos de Conversa
+func (r *Repository) CreateConversa(conversa *domain.Conversa) error {
+	collection := r.db.Collection("conversas")
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	conversa.CreatedAt = time.Now()
+	conversa.UpdatedAt = time.Now()
+	if conversa.Mensagens == nil {
+		conversa.Mensagens = []domain.MensagemConversa{}
+	}
+
+	result, err := collection.InsertOne(ctx, conversa)
+	if err != nil {
+		return err
+	}
+
+	conversa.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *Repository) GetConversa(id string) (*domain.Conversa, error) {
+	var conversa domain.Conversa
+	if err := r.buscar("conversas", id, &conversa); err != nil {
+		return nil, err
+	}
+	return &conversa, nil
+}
+
+func (r *Repository) ListConversas(filtro domain.ConversaFiltro) ([]domain.Conversa, error) {
+	collection := r.db.Collection("conversas")
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	query := bson.M{}
+	if filtro.PessoaID != "" {
+		pessoaID, err := primitive.ObjectIDFromHex(filtro.PessoaID)
+		if err != nil {
+			return nil, err
+		}
+		query["pessoa_id"] = pessoaID
+	}
+	if filtro.ContextoID != "" {
+		contextoID, err := primitive.ObjectIDFromHex(filtro.ContextoID)
+		if err != nil {
+			return nil, err
+		}
+		query["contexto_id"] = contextoID
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}})
+	cursor, err := collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var conversas []domain.Conversa
+	if err = cursor.All(ctx, &conversas); err != nil {
+		return nil, err
+	}
+
+	return conversas, nil
+}
+
+// AppendMensagensConversa acrescenta as mensagens ao fim da conversa com $push,
+// sem regravar o histórico existente
+func (r *Repository) AppendMensagensConversa(id string, mensagens []domain.MensagemConversa) error {
+	collection := r.db.Collection("conversas")
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
+		return err
+	}
+
+	result, err := collection.UpdateOne(
+		ctx,
+		bson.M{"_id": objectID},
+		bson.M{
+			"$push": bson.M{"mensagens": bson.M{"$each": mensagens}},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrNaoEncontrado
+	}
+	return nil
+}
+
+// Métodos de Documento
+func (r *Repository) CreateDocumento(documento *domain.Documento, trechos []domain.TrechoDocumento) error {
+	ctx, cancel := context.WithTimeout(r.contexto(), 30*time.Second)
+	defer cancel()
+
+	documento.CreatedAt = time.Now()
+	documento.Trechos = len(trechos)
+
+	result, err := r.db.Collection("documentos").InsertOne(ctx, documento)
+	if err != nil {
+		return err
+	}
+	documento.ID = result.InsertedID.(primitive.ObjectID)
+
+	if len(trechos) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, len(trechos))
+	for i := range trechos {
+		trechos[i].DocumentoID = documento.ID
+		trechos[i].ContextoID = documento.ContextoID
+		docs[i] = trechos[i]
+	}
+	if _, err := r.db.Collection("trechos_documento").InsertMany(ctx, docs); err != nil {
+		// Sem os trechos o documento não serve às gerações
+		r.db.Collection("documentos").DeleteOne(ctx, bson.M{"_id": documento.ID})
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) GetDocumento(id string) (*domain.Documento, error) {
+	var documento domain.Documento
+	if err := r.buscar("documentos", id, &documento); err != nil {
 		return nil, err
 	}
+	return &documento, nil
+}
+
+func (r *Repository) ListDocumentos(contextoID string) ([]domain.Documento, error) {
+	collection := r.db.Collection("documentos")
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
 
-	var pessoa domain.Pessoa
-	err = r.db.Collection("pessoas").FindOne(context.Background(), bson.M{"_id": objectID}).Decode(&pessoa)
+	objectID, err := primitive.ObjectIDFromHex(contextoID)
 	if err != nil {
 		return nil, err
 	}
-	return &pessoa, nil
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{"contexto_id": objectID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	documentos := []domain.Documento{}
+	if err = cursor.All(ctx, &documentos); err != nil {
+		return nil, err
+	}
+
+	return documentos, nil
 }
 
-func (r *Repository) ListPessoas() ([]domain.Pessoa, error) {
-	var pessoas []domain.Pessoa
-	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+func (r *Repository) ListTrechosDocumento(contextoID string) ([]domain.TrechoDocumento, error) {
+	collection := r.db.Collection("trechos_documento")
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(contextoID)
+	if err != nil {
+		return nil, err
+	}
 
-	cursor, err := r.db.Collection("pessoas").Find(context.Background(), bson.M{}, opts)
+	cursor, err := collection.Find(ctx, bson.M{"contexto_id": objectID})
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(context.Background())
+	defer cursor.Close(ctx)
 
-	if err = cursor.All(context.Background(), &pessoas); err != nil {
+	var trechos []domain.TrechoDocumento
+	if err = cursor.All(ctx, &trechos); err != nil {
 		return nil, err
 	}
-	return pessoas, nil
+
+	return trechos, nil
 }
 
-func (r *Repository) UpdatePessoa(pessoa *domain.Pessoa) error {
-	pessoa.UpdatedAt = time.Now()
+func (r *Repository) DeleteDocumento(id string) error {
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.db.Collection("trechos_documento").DeleteMany(ctx, bson.M{"documento_id": objectID}); err != nil {
+		return err
+	}
+	_, err = r.db.Collection("documentos").DeleteOne(ctx, bson.M{"_id": objectID})
+	return err
+}
+
+// Métodos de Job
+func (r *Repository) CreateJob(job *domain.Job) error {
+	collection := r.db.Collection("jobs")
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = time.Now()
+
+	result, err := collection.InsertOne(ctx, job)
+	if err != nil {
+		return err
+	}
+
+	job.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *Repository) GetJob(id string) (*domain.Job, error) {
+	var job domain.Job
+	if err := r.buscar("jobs", id, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListJobsPorStatus retorna os jobs nos status informados, dos mais antigos aos mais novos
+func (r *Repository) ListJobsPorStatus(status ...string) ([]domain.Job, error) {
+	collection := r.db.Collection("jobs")
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{"status": bson.M{"$in": status}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var jobs []domain.Job
+	if err = cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
 
-	_, err := r.db.Collection("pessoas").UpdateOne(
-		context.Background(),
-		bson.M{"_id": pessoa.ID},
-		bson.M{"$set": bson.M{
-			"nome":       pessoa.Nome,
-			"email":      pessoa.Email,
-			"telefones":  pessoa.Telefones,
-			"contextos":  pessoa.Contextos,
-			"updated_at": pessoa.UpdatedAt,
-		}},
+func (r *Repository) UpdateJobStatus(id string, status string) error {
+	collection := r.db.Collection("jobs")
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(
+		ctx,
+		bson.M{"_id": objectID},
+		bson.M{"$set": bson.M{"status": status, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+// UpdateJobItem grava só o item alterado, para que os workers do mesmo job
+// não sobrescrevam o trabalho uns dos outros
+func (r *Repository) UpdateJobItem(id string, i int, item domain.ItemJob) error {
+	collection := r.db.Collection("jobs")
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	contador := "concluidos"
+	if item.Status == domain.StatusItemFalhou {
+		contador = "falhas"
+	}
+
+	_, err = collection.UpdateOne(
+		ctx,
+		bson.M{"_id": objectID},
+		bson.M{
+			"$set": bson.M{fmt.Sprintf("itens.%d", i): item, "updated_at": time.Now()},
+			"$inc": bson.M{contador: 1},
+		},
 	)
 	return err
 }
 
-func (r *Repository) DeletePessoa(id string) error {
+// Métodos de Busca
+// BuscarTexto usa os índices de texto de CriarIndices, que comparam os
+// radicais das palavras em português sem diferenciar acentos; o score é o
+// textScore do MongoDB. Como o índice de texto não compara prefixos (silv não
+// encontra Silva), as vagas que sobram no limite são preenchidas pelos
+// documentos com palavras que começam por algum termo, com o score da busca
+// em memória: o número dessas palavras.
+func (r *Repository) BuscarTexto(filtro domain.BuscaTextoFiltro) ([]domain.ResultadoTexto, error) {
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	resultados := []domain.ResultadoTexto{}
+	for _, tipo := range filtro.Tipos {
+		campos := domain.CamposBuscaTexto[tipo]
+		colecao := r.db.Collection(colecoesBusca[tipo])
+		projecao := bson.M{}
+		projecaoTexto := bson.M{"score": bson.M{"$meta": "textScore"}}
+		for _, campo := range campos {
+			projecao[campo] = 1
+			projecaoTexto[campo] = 1
+		}
+		opts := options.Find().
+			SetProjection(projecaoTexto).
+			SetSort(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}}).
+			SetLimit(int64(filtro.Limite))
+
+		consulta := bson.M{"$text": bson.M{"$search": strings.Join(filtro.Termos, " ")}, "deleted_at": ativo}
+		cursor, err := colecao.Find(ctx, consulta, opts)
+		if err != nil {
+			return nil, err
+		}
+		var docs []bson.M
+		if err := cursor.All(ctx, &docs); err != nil {
+			return nil, err
+		}
+
+		encontrados := []primitive.ObjectID{}
+		for _, doc := range docs {
+			encontrados = append(encontrados, doc["_id"].(primitive.ObjectID))
+		}
+		if len(docs) < filtro.Limite {
+			var prefixos bson.A
+			for _, campo := range campos {
+				for _, termo := range filtro.Termos {
+					prefixos = append(prefixos, bson.M{campo: prefixoPalavra(termo)})
+				}
+			}
+			consulta := bson.M{"$or": prefixos, "_id": bson.M{"$nin": encontrados}, "deleted_at": ativo}
+			opts := options.Find().SetProjection(projecao).SetLimit(int64(filtro.Limite - len(docs)))
+			cursor, err := colecao.Find(ctx, consulta, opts)
+			if err != nil {
+				return nil, err
+			}
+			var porPrefixo []bson.M
+			if err := cursor.All(ctx, &porPrefixo); err != nil {
+				return nil, err
+			}
+			docs = append(docs, porPrefixo...)
+		}
+
+		for _, doc := range docs {
+			resultado := domain.ResultadoTexto{Tipo: tipo, Campos: map[string]string{}}
+			resultado.ID = doc["_id"].(primitive.ObjectID).Hex()
+			for _, campo := range campos {
+				resultado.Campos[campo], _ = doc[campo].(string)
+			}
+			if score, ok := doc["score"].(float64); ok {
+				resultado.Score = score
+			} else {
+				for _, texto := range resultado.Campos {
+					resultado.Score += float64(len(domain.PalavrasComTermo(texto, filtro.Termos)))
+				}
+			}
+			resultados = append(resultados, resultado)
+		}
+	}
+	return resultados, nil
+}
+
+func (r *Repository) UpdateRespostaEmbedding(id string, embedding *domain.Embedding) error {
+	return r.updateEmbedding("respostas", id, embedding)
+}
+
+// updateEmbedding grava só o embedding, sem alterar updated_at, pois o
+// documento em si não mudou
+func (r *Repository) updateEmbedding(colecao, id string, embedding *domain.Embedding) error {
+	collection := r.db.Collection(colecao)
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return err
 	}
 
-	_, err = r.db.Collection("pessoas").DeleteOne(context.Background(), bson.M{"_id": objectID})
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": bson.M{"embedding": embedding}})
 	return err
 }
 
-// Implementar métodos similares para Telefone, Contexto e Prompt...
+func (r *Repository) inserir(colecao string, doc interface{}, id *primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.db.Collection(colecao).InsertOne(ctx, doc)
+	if err != nil {
+		return err
+	}
+
+	*id = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *Repository) buscar(colecao, id string, destino interface{}) error {
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.ErrNaoEncontrado
+	}
+
+	return naoEncontrado(r.db.Collection(colecao).FindOne(ctx, bson.M{"_id": objectID, "deleted_at": ativo}).Decode(destino))
+}
+
+// listarPagina decodifica em destino a página dos documentos aceitos pela
+// consulta e, com ComTotal, retorna o total aceito por ela. A ordem desempata
+// pelo _id, que também ordena os documentos gravados antes de terem
+// created_at; depois de um marcador, a consulta continua do par (campo, _id)
+// em diante, usando os índices de CriarIndices em vez de pular os documentos
+// anteriores. As etapas (os $lookup das expansões) são aplicadas só aos
+// documentos da página.
+func (r *Repository) listarPagina(colecao string, query bson.M, paginacao domain.Paginacao, destino interface{}, etapas ...bson.D) (int64, error) {
+	collection := r.db.Collection(colecao)
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	var total int64
+	if paginacao.ComTotal {
+		var err error
+		if total, err = contar(ctx, collection, query); err != nil {
+			return 0, err
+		}
+	}
+	query["deleted_at"] = ativo
+
+	ordem := paginacao.Ordem
+	if ordem.Campo == "" {
+		ordem = domain.OrdemRecentes
+	}
+	direcao, operador := 1, "$gt"
+	if ordem.Decrescente {
+		direcao, operador = -1, "$lt"
+	}
+
+	if apos := paginacao.Apos; apos != nil {
+		query = bson.M{"$and": bson.A{query, bson.M{"$or": bson.A{
+			bson.M{ordem.Campo: bson.M{operador: apos.Valor}},
+			bson.M{ordem.Campo: apos.Valor, "_id": bson.M{operador: apos.ID}},
+		}}}}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: query}},
+		{{Key: "$sort", Value: bson.D{{Key: ordem.Campo, Value: direcao}, {Key: "_id", Value: direcao}}}},
+	}
+	if paginacao.Offset > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$skip", Value: paginacao.Offset}})
+	}
+	if paginacao.Limite > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: paginacao.Limite}})
+	}
+	pipeline = append(pipeline, etapas...)
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	return total, cursor.All(ctx, destino)
+}
+
+// contar conta os documentos ativos aceitos pela consulta. Sem filtros, usa a
+// contagem estimada dos metadados da coleção, descontados os documentos da
+// lixeira pelo índice de deleted_at, em vez de percorrer a coleção.
+func contar(ctx context.Context, collection *mongo.Collection, query bson.M) (int64, error) {
+	if len(query) > 0 {
+		filtro := bson.M{"deleted_at": ativo}
+		for campo, valor := range query {
+			filtro[campo] = valor
+		}
+		return collection.CountDocuments(ctx, filtro)
+	}
+
+	estimado, err := collection.EstimatedDocumentCount(ctx)
+	if err != nil {
+		return 0, err
+	}
+	removidos, err := collection.CountDocuments(ctx, bson.M{"deleted_at": naLixeira})
+	if err != nil {
+		return 0, err
+	}
+	return max(estimado-removidos, 0), nil
+}
+
+// buscarExpandido decodifica em destino o documento com o ID, depois das
+// etapas (os $lookup das relações)
+func (r *Repository) buscarExpandido(colecao, id string, destino interface{}, etapas ...bson.D) error {
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.ErrNaoEncontrado
+	}
+
+	pipeline := append(mongo.Pipeline{{{Key: "$match", Value: bson.M{"_id": objectID, "deleted_at": ativo}}}}, etapas...)
+	cursor, err := r.db.Collection(colecao).Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	if !cursor.Next(ctx) {
+		if err := cursor.Err(); err != nil {
+			return err
+		}
+		return domain.ErrNaoEncontrado
+	}
+	return cursor.Decode(destino)
+}
+
+// atualizar substitui os campos de doc, como o PostgreSQL grava todas as
+// colunas: os campos vazios omitidos pelo omitempty são removidos do
+// documento. _id, created_at, os campos da lixeira e os preservados (gravados
+// por outras operações) continuam como estão. Devolve em doc o documento
+// gravado.
+func (r *Repository) atualizar(colecao string, id primitive.ObjectID, doc interface{}, preservados ...string) error {
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	dados, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	var campos bson.M
+	if err := bson.Unmarshal(dados, &campos); err != nil {
+		return err
+	}
+
+	preservados = append([]string{"_id", "created_at", "deleted_at", "deleted_by"}, preservados...)
+	for _, campo := range preservados {
+		delete(campos, campo)
+	}
+	vazios := bson.M{}
+	for _, campo := range camposBSON(reflect.TypeOf(doc)) {
+		if _, ok := campos[campo]; !ok && !slices.Contains(preservados, campo) {
+			vazios[campo] = ""
+		}
+	}
+
+	update := bson.M{"$set": campos}
+	if len(vazios) > 0 {
+		update["$unset"] = vazios
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	return naoEncontrado(r.db.Collection(colecao).FindOneAndUpdate(ctx, bson.M{"_id": id, "deleted_at": ativo}, update, opts).Decode(doc))
+}
+
+// camposBSON retorna os nomes no documento dos campos gravados do tipo
+func camposBSON(tipo reflect.Type) []string {
+	if tipo.Kind() == reflect.Pointer {
+		tipo = tipo.Elem()
+	}
+	var campos []string
+	for i := 0; i < tipo.NumField(); i++ {
+		campo := tipo.Field(i)
+		if !campo.IsExported() {
+			continue
+		}
+		nome, _, _ := strings.Cut(campo.Tag.Get("bson"), ",")
+		switch nome {
+		case "-":
+			continue
+		case "":
+			nome = strings.ToLower(campo.Name)
+		}
+		campos = append(campos, nome)
+	}
+	return campos
+}
+
+// moverParaLixeira grava a remoção no documento; o documento que já está na
+// lixeira retorna domain.ErrNaoEncontrado, como nas leituras
+func (r *Repository) moverParaLixeira(colecao, id string, remocao domain.Remocao) error {
+	ctx, cancel := context.WithTimeout(r.contexto(), 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.ErrNaoEncontrado
+	}
+
+	result, err := r.db.Collection(colecao).UpdateOne(ctx, bson.M{"_id": objectID, "deleted_at": ativo}, marcarRemocao(remocao))
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrNaoEncontrado
+	}
+	return nil
+}
+
+func marcarRemocao(remocao domain.Remocao) bson.M {
+	return bson.M{"$set": bson.M{"deleted_at": remocao.Em, "deleted_by": remocao.Por}}
+}
+
+// contem busca o texto em qualquer parte do campo, sem diferenciar maiúsculas
+func contem(texto string) primitive.Regex {
+	return primitive.Regex{Pattern: regexp.QuoteMeta(texto), Options: "i"}
+}
+
+// acentos são as letras que o índice de texto iguala às suas versões acentuadas
+var acentos = map[rune]string{
+	'a': "aáàâãä",
+	'e': "eéèêë",
+	'i': "iíìîï",
+	'o': "oóòôõö",
+	'u': "uúùûü",
+	'c': "cç",
+	'n': "nñ",
+}
+
+// prefixoPalavra aceita os textos com uma palavra que começa pelo termo
+// normalizado, sem diferenciar maiúsculas nem acentos
+func prefixoPalavra(termo string) primitive.Regex {
+	var padrao strings.Builder
+	padrao.WriteString(`(^|[^\p{L}\p{N}])`)
+	for _, letra := range termo {
+		if variantes, ok := acentos[letra]; ok {
+			padrao.WriteString("[" + variantes + "]")
+		} else {
+			padrao.WriteString(regexp.QuoteMeta(string(letra)))
+		}
+	}
+	return primitive.Regex{Pattern: padrao.String(), Options: "i"}
+}
+
+// naoEncontrado traduz o erro do driver para o erro do domínio
+func naoEncontrado(err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return domain.ErrNaoEncontrado
+	}
+	return err
+}
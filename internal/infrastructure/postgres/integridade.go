@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"fmt"
+	"time"
+	"vend/internal/domain"
+	"vend/internal/usecase"
+
+	"gorm.io/gorm"
+)
+
+// Transacao executa fn em uma transação do banco; as operações que já usam
+// transações viram savepoints dentro dela
+func (r *Repository) Transacao(fn func(repo usecase.Repository) error) error {
+	db, cancel := r.sessao(30 * time.Second)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		return fn(&Repository{db: tx})
+	})
+}
+
+// referencias dá a tabela e a coluna que apontam para o registro em cada
+// relação; as pessoas dos contextos ficam em contexto_pessoas
+func referencias(relacao domain.Relacao) (tabela, coluna string, err error) {
+	switch relacao {
+	case domain.RelacaoPessoaTelefones:
+		return "telefones", "pessoa_id", nil
+	case domain.RelacaoPessoaContextos:
+		return "contexto_pessoas", "pessoa_id", nil
+	case domain.RelacaoContextoPrompts:
+		return "prompts", "contexto_id", nil
+	}
+	return "", "", fmt.Errorf("relação desconhecida: %s", relacao)
+}
+
+func (r *Repository) ContarReferencias(relacao domain.Relacao, id string) (int64, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	tabela, coluna, err := referencias(relacao)
+	if err != nil {
+		return 0, err
+	}
+	consulta := db.Table(tabela).Where(coluna+" = ?", id)
+	if relacao != domain.RelacaoPessoaContextos {
+		consulta = consulta.Where("deleted_at IS NULL")
+	}
+	var total int64
+	err = consulta.Count(&total).Error
+	return total, err
+}
+
+func (r *Repository) RemoverReferencias(relacao domain.Relacao, id string, remocao domain.Remocao) error {
+	if relacao == domain.RelacaoPessoaContextos {
+		return r.AnularReferencias(relacao, id)
+	}
+
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	tabela, coluna, err := referencias(relacao)
+	if err != nil {
+		return err
+	}
+	return db.Table(tabela).Where(coluna+" = ? AND deleted_at IS NULL", id).UpdateColumns(marcarRemocao(remocao)).Error
+}
+
+// AnularReferencias grava NULL na coluna, ou apaga as ligações da pessoa com
+// os contextos
+func (r *Repository) AnularReferencias(relacao domain.Relacao, id string) error {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	tabela, coluna, err := referencias(relacao)
+	if err != nil {
+		return err
+	}
+	if relacao != domain.RelacaoPessoaContextos {
+		return db.Table(tabela).Where(coluna+" = ?", id).Updates(map[string]any{coluna: nil, "updated_at": time.Now()}).Error
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		contextos := tx.Model(&contextoPessoaModel{}).Select("contexto_id").Where("pessoa_id = ?", id)
+		if err := tx.Model(&contextoModel{}).Where("id IN (?)", contextos).Update("updated_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Where("pessoa_id = ?", id).Delete(&contextoPessoaModel{}).Error
+	})
+}
@@ -0,0 +1,170 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"vend/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// itemLixeiraModel é uma linha da união das tabelas com lixeira; as tabelas
+// têm os mesmos nomes das entidades
+type itemLixeiraModel struct {
+	Entidade  string
+	ID        string
+	Titulo    string
+	DeletedAt time.Time
+	DeletedBy string
+	// Referencia é a coluna de domain.CamposReferenciaLixeira, nula nas outras
+	// entidades
+	Referencia *string
+}
+
+// selecaoLixeira seleciona as colunas de itemLixeiraModel dos registros da
+// entidade que estão na lixeira
+func selecaoLixeira(entidade string) string {
+	referencia := "NULL"
+	if coluna, ok := domain.CamposReferenciaLixeira[entidade]; ok {
+		referencia = coluna
+	}
+	return fmt.Sprintf("SELECT '%s' AS entidade, id, %s AS titulo, deleted_at, deleted_by, %s AS referencia FROM %s WHERE deleted_at IS NOT NULL",
+		entidade, domain.CamposTituloLixeira[entidade], referencia, entidade)
+}
+
+func (l itemLixeiraModel) dominio() domain.ItemLixeira {
+	return domain.ItemLixeira{
+		Entidade:     l.Entidade,
+		ID:           objectID(l.ID),
+		Titulo:       l.Titulo,
+		DeletedAt:    l.DeletedAt.UTC(),
+		DeletedBy:    l.DeletedBy,
+		ReferenciaID: objectIDNulo(l.Referencia),
+	}
+}
+
+// ListLixeira pagina a união das tabelas, como listarPagina pagina uma tabela
+func (r *Repository) ListLixeira(filtro domain.LixeiraFiltro) ([]domain.ItemLixeira, int64, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	entidades := domain.EntidadesLixeira
+	if filtro.Entidade != "" {
+		entidades = []string{filtro.Entidade}
+	}
+	selecoes := make([]string, len(entidades))
+	for i, entidade := range entidades {
+		selecoes[i] = selecaoLixeira(entidade)
+	}
+	consulta := db.Table("(" + strings.Join(selecoes, " UNION ALL ") + ") AS lixeira").Session(&gorm.Session{})
+
+	var total int64
+	if filtro.ComTotal {
+		if err := consulta.Count(&total).Error; err != nil {
+			return nil, 0, err
+		}
+	}
+
+	ordem := filtro.Ordem
+	consulta = consulta.Order(clause.OrderBy{Columns: []clause.OrderByColumn{
+		{Column: clause.Column{Name: "deleted_at"}, Desc: ordem.Decrescente},
+		{Column: clause.Column{Name: "id"}, Desc: ordem.Decrescente},
+	}})
+	if apos := filtro.Apos; apos != nil {
+		operador := ">"
+		if ordem.Decrescente {
+			operador = "<"
+		}
+		consulta = consulta.Where("(deleted_at, id) "+operador+" (?, ?)", apos.Valor, apos.ID.Hex())
+	}
+	if filtro.Limite > 0 {
+		consulta = consulta.Limit(filtro.Limite)
+	}
+	if filtro.Offset > 0 {
+		consulta = consulta.Offset(filtro.Offset)
+	}
+
+	var linhas []itemLixeiraModel
+	if err := consulta.Find(&linhas).Error; err != nil {
+		return nil, 0, err
+	}
+	itens := make([]domain.ItemLixeira, len(linhas))
+	for i, l := range linhas {
+		itens[i] = l.dominio()
+	}
+	return itens, total, nil
+}
+
+func (r *Repository) GetLixeira(entidade, id string) (*domain.ItemLixeira, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	var linha itemLixeiraModel
+	if err := db.Table("("+selecaoLixeira(entidade)+") AS lixeira").Where("id = ?", id).Take(&linha).Error; err != nil {
+		return nil, naoEncontrado(err)
+	}
+	item := linha.dominio()
+	return &item, nil
+}
+
+func (r *Repository) Restaurar(entidade, id string) (*domain.Remocao, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	var remocao *domain.Remocao
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var anterior itemLixeiraModel
+		err := tx.Table(entidade).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("deleted_at, deleted_by").
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Take(&anterior).Error
+		if err != nil {
+			return naoEncontrado(err)
+		}
+		if err := tx.Table(entidade).Where("id = ?", id).UpdateColumns(restauracao()).Error; err != nil {
+			return err
+		}
+		remocao = &domain.Remocao{Em: anterior.DeletedAt.UTC(), Por: anterior.DeletedBy}
+		return nil
+	})
+	return remocao, err
+}
+
+func (r *Repository) RestaurarReferencias(relacao domain.Relacao, id string, em time.Time) error {
+	if relacao == domain.RelacaoPessoaContextos {
+		return nil
+	}
+
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	tabela, coluna, err := referencias(relacao)
+	if err != nil {
+		return err
+	}
+	return db.Table(tabela).Where(coluna+" = ? AND deleted_at = ?", id, em).UpdateColumns(restauracao()).Error
+}
+
+// PurgarLixeira apaga as linhas; as chaves estrangeiras levam junto as
+// versões dos prompts e os documentos dos contextos e anulam as referências
+// dos registros restantes
+func (r *Repository) PurgarLixeira(ate time.Time) (int64, error) {
+	db, cancel := r.sessao(5 * time.Minute)
+	defer cancel()
+
+	var total int64
+	for _, entidade := range domain.EntidadesLixeira {
+		result := db.Exec(fmt.Sprintf("DELETE FROM %s WHERE deleted_at <= ?", entidade), ate)
+		if result.Error != nil {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+	}
+	return total, nil
+}
+
+func restauracao() map[string]any {
+	return map[string]any{"deleted_at": nil, "deleted_by": ""}
+}
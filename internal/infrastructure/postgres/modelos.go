@@ -0,0 +1,589 @@
+package postgres
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"vend/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// As tabelas usam os mesmos IDs de 24 caracteres hexadecimais do MongoDB,
+// para que a API e os clientes não dependam do armazenamento escolhido. Os
+// objetos de valor (parâmetros, variáveis, mensagens, embeddings) ficam em
+// colunas jsonb; as relações entre entidades, em chaves estrangeiras.
+
+// jsonb grava o valor como JSON em uma coluna jsonb
+type jsonb[T any] struct {
+	Valor T
+}
+
+func (j jsonb[T]) GormDataType() string {
+	return "jsonb"
+}
+
+func (j jsonb[T]) Value() (driver.Value, error) {
+	dados, err := json.Marshal(j.Valor)
+	if err != nil {
+		return nil, err
+	}
+	return string(dados), nil
+}
+
+func (j *jsonb[T]) Scan(valor any) error {
+	var dados []byte
+	switch v := valor.(type) {
+	case nil:
+		return nil
+	case []byte:
+		dados = v
+	case string:
+		dados = []byte(v)
+	default:
+		return fmt.Errorf("jsonb: tipo %T não suportado", valor)
+	}
+	return json.Unmarshal(dados, &j.Valor)
+}
+
+type pessoaModel struct {
+	ID        string    `gorm:"primaryKey;type:char(24)"`
+	Nome      string    `gorm:"not null;index"`
+	Email     string    `gorm:"not null;index"`
+	CreatedAt time.Time `gorm:"index"`
+	UpdatedAt time.Time
+	DeletedAt *time.Time `gorm:"index"`
+	DeletedBy string
+}
+
+func (pessoaModel) TableName() string { return "pessoas" }
+
+type telefoneModel struct {
+	ID        string       `gorm:"primaryKey;type:char(24)"`
+	Numero    string       `gorm:"not null"`
+	Tipo      string       `gorm:"not null"`
+	PessoaID  *string      `gorm:"type:char(24);index"`
+	Pessoa    *pessoaModel `gorm:"constraint:OnDelete:SET NULL"`
+	CreatedAt time.Time    `gorm:"index"`
+	UpdatedAt time.Time
+	DeletedAt *time.Time `gorm:"index"`
+	DeletedBy string
+}
+
+func (telefoneModel) TableName() string { return "telefones" }
+
+type contextoModel struct {
+	ID                    string `gorm:"primaryKey;type:char(24)"`
+	Nome                  string `gorm:"not null;index"`
+	Descricao             string
+	DataInicio            time.Time `gorm:"index"`
+	DataFim               time.Time
+	OrcamentoTokensMensal int64
+	Parametros            jsonb[domain.ParametrosGeracao]
+	Privacidade           jsonb[domain.PoliticaPrivacidade]
+	CreatedAt             time.Time `gorm:"index"`
+	UpdatedAt             time.Time
+	DeletedAt             *time.Time `gorm:"index"`
+	DeletedBy             string
+}
+
+func (contextoModel) TableName() string { return "contextos" }
+
+// contextoPessoaModel é a tabela de junção entre contextos e pessoas; Posicao
+// preserva a ordem em que as pessoas foram informadas
+type contextoPessoaModel struct {
+	ContextoID string         `gorm:"primaryKey;type:char(24)"`
+	PessoaID   string         `gorm:"primaryKey;type:char(24);index"`
+	Posicao    int            `gorm:"not null"`
+	Contexto   *contextoModel `gorm:"constraint:OnDelete:CASCADE"`
+	Pessoa     *pessoaModel   `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+func (contextoPessoaModel) TableName() string { return "contexto_pessoas" }
+
+type promptModel struct {
+	ID          string `gorm:"primaryKey;type:char(24)"`
+	Conteudo    string `gorm:"type:text;not null"`
+	Variaveis   jsonb[[]domain.VariavelPrompt]
+	Parametros  jsonb[domain.ParametrosGeracao]
+	SchemaSaida jsonb[map[string]any]
+	ContextoID  *string        `gorm:"type:char(24);index"`
+	Contexto    *contextoModel `gorm:"constraint:OnDelete:SET NULL"`
+	Versao      int
+	Embedding   jsonb[*domain.Embedding]
+	CreatedAt   time.Time `gorm:"index"`
+	UpdatedAt   time.Time
+	DeletedAt   *time.Time `gorm:"index"`
+	DeletedBy   string
+}
+
+func (promptModel) TableName() string { return "prompts" }
+
+type promptVersaoModel struct {
+	ID        string       `gorm:"primaryKey;type:char(24)"`
+	PromptID  string       `gorm:"type:char(24);not null;uniqueIndex:idx_prompt_versoes_numero"`
+	Numero    int          `gorm:"not null;uniqueIndex:idx_prompt_versoes_numero"`
+	Prompt    *promptModel `gorm:"constraint:OnDelete:CASCADE"`
+	Conteudo  string       `gorm:"type:text;not null"`
+	Variaveis jsonb[[]domain.VariavelPrompt]
+	Autor     string
+	Diff      string `gorm:"type:text"`
+	CreatedAt time.Time
+}
+
+func (promptVersaoModel) TableName() string { return "prompt_versoes" }
+
+// respostaModel não tem chaves estrangeiras: as respostas são o histórico de
+// consumo e permanecem quando o prompt, o contexto ou a pessoa são removidos
+type respostaModel struct {
+	ID               string  `gorm:"primaryKey;type:char(24)"`
+	PromptID         *string `gorm:"type:char(24);index"`
+	PromptVersao     int
+	ContextoID       *string `gorm:"type:char(24);index"`
+	PessoaID         *string `gorm:"type:char(24);index"`
+	ConversaID       *string `gorm:"type:char(24)"`
+	Modelo           string
+	Temperatura      float32
+	Mensagens        jsonb[[]domain.Mensagem]
+	Conteudo         string `gorm:"type:text"`
+	Saida            jsonb[any]
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	CustoEstimado    float64
+	DoCache          bool
+	LatenciaMs       int64
+	Fontes           jsonb[[]domain.Fonte]
+	Embedding        jsonb[*domain.Embedding]
+	CreatedAt        time.Time `gorm:"index"`
+}
+
+func (respostaModel) TableName() string { return "respostas" }
+
+type usoEmbeddingsModel struct {
+	ID               string `gorm:"primaryKey;type:char(24)"`
+	ContextoID       string `gorm:"type:char(24);index"`
+	Modelo           string
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	CustoEstimado    float64
+	CreatedAt        time.Time `gorm:"index"`
+}
+
+func (usoEmbeddingsModel) TableName() string { return "usos_embeddings" }
+
+type conversaModel struct {
+	ID         string  `gorm:"primaryKey;type:char(24)"`
+	PessoaID   *string `gorm:"type:char(24);index"`
+	ContextoID *string `gorm:"type:char(24);index"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (conversaModel) TableName() string { return "conversas" }
+
+type mensagemConversaModel struct {
+	ID         uint           `gorm:"primaryKey"`
+	ConversaID string         `gorm:"type:char(24);not null;index"`
+	Conversa   *conversaModel `gorm:"constraint:OnDelete:CASCADE"`
+	Papel      string         `gorm:"not null"`
+	Conteudo   string         `gorm:"type:text"`
+	Fontes     jsonb[[]domain.Fonte]
+	CreatedAt  time.Time
+}
+
+func (mensagemConversaModel) TableName() string { return "mensagens_conversa" }
+
+type documentoModel struct {
+	ID         string         `gorm:"primaryKey;type:char(24)"`
+	ContextoID string         `gorm:"type:char(24);not null;index"`
+	Contexto   *contextoModel `gorm:"constraint:OnDelete:CASCADE"`
+	Nome       string         `gorm:"not null"`
+	Formato    string         `gorm:"not null"`
+	Caracteres int
+	Trechos    int
+	CreatedAt  time.Time
+}
+
+func (documentoModel) TableName() string { return "documentos" }
+
+type trechoDocumentoModel struct {
+	ID          string          `gorm:"primaryKey;type:char(24)"`
+	DocumentoID string          `gorm:"type:char(24);not null;index"`
+	Origem      *documentoModel `gorm:"foreignKey:DocumentoID;constraint:OnDelete:CASCADE"`
+	ContextoID  string          `gorm:"type:char(24);not null;index"`
+	// NomeDocumento repete o nome do documento para as citações
+	NomeDocumento string `gorm:"column:documento"`
+	Indice        int
+	Conteudo      string `gorm:"type:text"`
+	Embedding     jsonb[*domain.Embedding]
+}
+
+func (trechoDocumentoModel) TableName() string { return "trechos_documento" }
+
+type jobModel struct {
+	ID         string `gorm:"primaryKey;type:char(24)"`
+	ContextoID string `gorm:"type:char(24)"`
+	PromptID   string `gorm:"type:char(24)"`
+	Variaveis  jsonb[map[string]any]
+	Parametros jsonb[domain.ParametrosGeracao]
+	Status     string `gorm:"not null;index"`
+	Total      int
+	Concluidos int
+	Falhas     int
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (jobModel) TableName() string { return "jobs" }
+
+type itemJobModel struct {
+	JobID      string    `gorm:"primaryKey;type:char(24)"`
+	Indice     int       `gorm:"primaryKey"`
+	Job        *jobModel `gorm:"constraint:OnDelete:CASCADE"`
+	PessoaID   string    `gorm:"type:char(24)"`
+	Status     string    `gorm:"not null"`
+	RespostaID *string   `gorm:"type:char(24)"`
+	Conteudo   string    `gorm:"type:text"`
+	Erro       string    `gorm:"type:text"`
+}
+
+func (itemJobModel) TableName() string { return "itens_job" }
+
+// tabelasBusca são as tabelas de cada tipo da busca textual
+var tabelasBusca = map[string]string{
+	domain.TipoBuscaPessoa:   "pessoas",
+	domain.TipoBuscaContexto: "contextos",
+	domain.TipoBuscaPrompt:   "prompts",
+}
+
+// vetorBusca é a expressão do tsvector dos campos: em minúsculas, sem acentos
+// (translate, ao contrário de unaccent, pode ser usado em colunas geradas) e
+// com @ e . separando as partes dos emails
+func vetorBusca(campos []string) string {
+	colunas := make([]string, len(campos))
+	for i, campo := range campos {
+		colunas[i] = fmt.Sprintf("coalesce(%s, '')", campo)
+	}
+	return fmt.Sprintf("to_tsvector('portuguese', translate(lower(%s), 'áàâãäéèêëíìîïóòôõöúùûüç@.', 'aaaaaeeeeiiiiooooouuuuc  '))", strings.Join(colunas, " || ' ' || "))
+}
+
+// modelos lista as tabelas na ordem de criação
+var modelos = []any{
+	&pessoaModel{},
+	&telefoneModel{},
+	&contextoModel{},
+	&contextoPessoaModel{},
+	&promptModel{},
+	&promptVersaoModel{},
+	&respostaModel{},
+	&usoEmbeddingsModel{},
+	&conversaModel{},
+	&mensagemConversaModel{},
+	&documentoModel{},
+	&trechoDocumentoModel{},
+	&jobModel{},
+	&itemJobModel{},
+}
+
+// novoID gera um ID quando a entidade ainda não tem um
+func novoID(id *primitive.ObjectID) string {
+	if id.IsZero() {
+		*id = primitive.NewObjectID()
+	}
+	return id.Hex()
+}
+
+func objectID(id string) primitive.ObjectID {
+	oid, _ := primitive.ObjectIDFromHex(id)
+	return oid
+}
+
+// idNulo grava o ID vazio como NULL
+func idNulo(id primitive.ObjectID) *string {
+	if id.IsZero() {
+		return nil
+	}
+	hex := id.Hex()
+	return &hex
+}
+
+func objectIDNulo(id *string) primitive.ObjectID {
+	if id == nil {
+		return primitive.NilObjectID
+	}
+	return objectID(*id)
+}
+
+func paraPessoaModel(p *domain.Pessoa) pessoaModel {
+	return pessoaModel{ID: novoID(&p.ID), Nome: p.Nome, Email: p.Email, CreatedAt: p.CreatedAt, UpdatedAt: p.UpdatedAt}
+}
+
+func (m pessoaModel) dominio() domain.Pessoa {
+	return domain.Pessoa{ID: objectID(m.ID), Nome: m.Nome, Email: m.Email, CreatedAt: m.CreatedAt, UpdatedAt: m.UpdatedAt}
+}
+
+func paraTelefoneModel(t *domain.Telefone) telefoneModel {
+	return telefoneModel{
+		ID:        novoID(&t.ID),
+		Numero:    t.Numero,
+		Tipo:      t.Tipo,
+		PessoaID:  idNulo(t.PessoaID),
+		CreatedAt: t.CreatedAt,
+		UpdatedAt: t.UpdatedAt,
+	}
+}
+
+func (m telefoneModel) dominio() domain.Telefone {
+	return domain.Telefone{
+		ID:        objectID(m.ID),
+		Numero:    m.Numero,
+		Tipo:      m.Tipo,
+		PessoaID:  objectIDNulo(m.PessoaID),
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+}
+
+func paraContextoModel(c *domain.Contexto) contextoModel {
+	return contextoModel{
+		ID:                    novoID(&c.ID),
+		Nome:                  c.Nome,
+		Descricao:             c.Descricao,
+		DataInicio:            c.DataInicio,
+		DataFim:               c.DataFim,
+		OrcamentoTokensMensal: c.OrcamentoTokensMensal,
+		Parametros:            jsonb[domain.ParametrosGeracao]{c.Parametros},
+		Privacidade:           jsonb[domain.PoliticaPrivacidade]{c.Privacidade},
+		CreatedAt:             c.CreatedAt,
+		UpdatedAt:             c.UpdatedAt,
+	}
+}
+
+func (m contextoModel) dominio() domain.Contexto {
+	return domain.Contexto{
+		ID:                    objectID(m.ID),
+		Nome:                  m.Nome,
+		Descricao:             m.Descricao,
+		DataInicio:            m.DataInicio,
+		DataFim:               m.DataFim,
+		OrcamentoTokensMensal: m.OrcamentoTokensMensal,
+		Parametros:            m.Parametros.Valor,
+		Privacidade:           m.Privacidade.Valor,
+		CreatedAt:             m.CreatedAt,
+		UpdatedAt:             m.UpdatedAt,
+	}
+}
+
+func paraPromptModel(p *domain.Prompt) promptModel {
+	return promptModel{
+		ID:          novoID(&p.ID),
+		Conteudo:    p.Conteudo,
+		Variaveis:   jsonb[[]domain.VariavelPrompt]{p.Variaveis},
+		Parametros:  jsonb[domain.ParametrosGeracao]{p.Parametros},
+		SchemaSaida: jsonb[map[string]any]{p.SchemaSaida},
+		ContextoID:  idNulo(p.ContextoID),
+		Versao:      p.Versao,
+		Embedding:   jsonb[*domain.Embedding]{p.Embedding},
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+	}
+}
+
+func (m promptModel) dominio() domain.Prompt {
+	return domain.Prompt{
+		ID:          objectID(m.ID),
+		Conteudo:    m.Conteudo,
+		Variaveis:   m.Variaveis.Valor,
+		Parametros:  m.Parametros.Valor,
+		SchemaSaida: m.SchemaSaida.Valor,
+		ContextoID:  objectIDNulo(m.ContextoID),
+		Versao:      m.Versao,
+		Embedding:   m.Embedding.Valor,
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+	}
+}
+
+func paraPromptVersaoModel(v *domain.PromptVersao) promptVersaoModel {
+	return promptVersaoModel{
+		ID:        novoID(&v.ID),
+		PromptID:  v.PromptID.Hex(),
+		Numero:    v.Numero,
+		Conteudo:  v.Conteudo,
+		Variaveis: jsonb[[]domain.VariavelPrompt]{v.Variaveis},
+		Autor:     v.Autor,
+		Diff:      v.Diff,
+		CreatedAt: v.CreatedAt,
+	}
+}
+
+func (m promptVersaoModel) dominio() domain.PromptVersao {
+	return domain.PromptVersao{
+		ID:        objectID(m.ID),
+		PromptID:  objectID(m.PromptID),
+		Numero:    m.Numero,
+		Conteudo:  m.Conteudo,
+		Variaveis: m.Variaveis.Valor,
+		Autor:     m.Autor,
+		Diff:      m.Diff,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+func paraUsoEmbeddingsModel(u *domain.UsoEmbeddings) usoEmbeddingsModel {
+	return usoEmbeddingsModel{
+		ID:               novoID(&u.ID),
+		ContextoID:       u.ContextoID.Hex(),
+		Modelo:           u.Modelo,
+		PromptTokens:     u.Uso.PromptTokens,
+		CompletionTokens: u.Uso.CompletionTokens,
+		TotalTokens:      u.Uso.TotalTokens,
+		CustoEstimado:    u.CustoEstimado,
+		CreatedAt:        u.CreatedAt,
+	}
+}
+
+func paraRespostaModel(r *domain.Resposta) respostaModel {
+	return respostaModel{
+		ID:               novoID(&r.ID),
+		PromptID:         idNulo(r.PromptID),
+		PromptVersao:     r.PromptVersao,
+		ContextoID:       idNulo(r.ContextoID),
+		PessoaID:         idNulo(r.PessoaID),
+		ConversaID:       idNulo(r.ConversaID),
+		Modelo:           r.Modelo,
+		Temperatura:      r.Temperatura,
+		Mensagens:        jsonb[[]domain.Mensagem]{r.Mensagens},
+		Conteudo:         r.Conteudo,
+		Saida:            jsonb[any]{r.Saida},
+		PromptTokens:     r.Uso.PromptTokens,
+		CompletionTokens: r.Uso.CompletionTokens,
+		TotalTokens:      r.Uso.TotalTokens,
+		CustoEstimado:    r.CustoEstimado,
+		DoCache:          r.DoCache,
+		LatenciaMs:       r.LatenciaMs,
+		Fontes:           jsonb[[]domain.Fonte]{r.Fontes},
+		Embedding:        jsonb[*domain.Embedding]{r.Embedding},
+		CreatedAt:        r.CreatedAt,
+	}
+}
+
+func (m respostaModel) dominio() domain.Resposta {
+	return domain.Resposta{
+		ID:           objectID(m.ID),
+		PromptID:     objectIDNulo(m.PromptID),
+		PromptVersao: m.PromptVersao,
+		ContextoID:   objectIDNulo(m.ContextoID),
+		PessoaID:     objectIDNulo(m.PessoaID),
+		ConversaID:   objectIDNulo(m.ConversaID),
+		Modelo:       m.Modelo,
+		Temperatura:  m.Temperatura,
+		Mensagens:    m.Mensagens.Valor,
+		Conteudo:     m.Conteudo,
+		Saida:        m.Saida.Valor,
+		Uso: domain.UsoTokens{
+			PromptTokens:     m.PromptTokens,
+			CompletionTokens: m.CompletionTokens,
+			TotalTokens:      m.TotalTokens,
+		},
+		CustoEstimado: m.CustoEstimado,
+		DoCache:       m.DoCache,
+		LatenciaMs:    m.LatenciaMs,
+		Fontes:        m.Fontes.Valor,
+		Embedding:     m.Embedding.Valor,
+		CreatedAt:     m.CreatedAt,
+	}
+}
+
+func paraMensagemConversaModel(conversaID string, m domain.MensagemConversa) mensagemConversaModel {
+	return mensagemConversaModel{
+		ConversaID: conversaID,
+		Papel:      m.Papel,
+		Conteudo:   m.Conteudo,
+		Fontes:     jsonb[[]domain.Fonte]{m.Fontes},
+		CreatedAt:  m.CreatedAt,
+	}
+}
+
+func (m mensagemConversaModel) dominio() domain.MensagemConversa {
+	return domain.MensagemConversa{Papel: m.Papel, Conteudo: m.Conteudo, Fontes: m.Fontes.Valor, CreatedAt: m.CreatedAt}
+}
+
+func (m documentoModel) dominio() domain.Documento {
+	return domain.Documento{
+		ID:         objectID(m.ID),
+		ContextoID: objectID(m.ContextoID),
+		Nome:       m.Nome,
+		Formato:    m.Formato,
+		Caracteres: m.Caracteres,
+		Trechos:    m.Trechos,
+		CreatedAt:  m.CreatedAt,
+	}
+}
+
+func paraTrechoDocumentoModel(t *domain.TrechoDocumento) trechoDocumentoModel {
+	return trechoDocumentoModel{
+		ID:            novoID(&t.ID),
+		DocumentoID:   t.DocumentoID.Hex(),
+		ContextoID:    t.ContextoID.Hex(),
+		NomeDocumento: t.Documento,
+		Indice:        t.Indice,
+		Conteudo:      t.Conteudo,
+		Embedding:     jsonb[*domain.Embedding]{t.Embedding},
+	}
+}
+
+func (m trechoDocumentoModel) dominio() domain.TrechoDocumento {
+	return domain.TrechoDocumento{
+		ID:          objectID(m.ID),
+		DocumentoID: objectID(m.DocumentoID),
+		ContextoID:  objectID(m.ContextoID),
+		Documento:   m.NomeDocumento,
+		Indice:      m.Indice,
+		Conteudo:    m.Conteudo,
+		Embedding:   m.Embedding.Valor,
+	}
+}
+
+func paraItemJobModel(jobID string, indice int, item domain.ItemJob) itemJobModel {
+	return itemJobModel{
+		JobID:      jobID,
+		Indice:     indice,
+		PessoaID:   item.PessoaID.Hex(),
+		Status:     item.Status,
+		RespostaID: idNulo(item.RespostaID),
+		Conteudo:   item.Conteudo,
+		Erro:       item.Erro,
+	}
+}
+
+func (m itemJobModel) dominio() domain.ItemJob {
+	return domain.ItemJob{
+		PessoaID:   objectID(m.PessoaID),
+		Status:     m.Status,
+		RespostaID: objectIDNulo(m.RespostaID),
+		Conteudo:   m.Conteudo,
+		Erro:       m.Erro,
+	}
+}
+
+func (m jobModel) dominio(itens []domain.ItemJob) domain.Job {
+	return domain.Job{
+		ID:         objectID(m.ID),
+		ContextoID: objectID(m.ContextoID),
+		PromptID:   objectID(m.PromptID),
+		Variaveis:  m.Variaveis.Valor,
+		Parametros: m.Parametros.Valor,
+		Status:     m.Status,
+		Total:      m.Total,
+		Concluidos: m.Concluidos,
+		Falhas:     m.Falhas,
+		Itens:      itens,
+		CreatedAt:  m.CreatedAt,
+		UpdatedAt:  m.UpdatedAt,
+	}
+}
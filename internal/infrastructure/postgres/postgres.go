@@ -0,0 +1,21 @@
+package postgres
+
+import (
+	"os"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func NewPostgresDB() (*gorm.DB, error) {
+	dsn := os.Getenv("POSTGRES_DSN")
+	if dsn == "" {
+		dsn = "host=localhost user=postgres password=postgres dbname=vend port=5432 sslmode=disable"
+	}
+
+	// gorm.Open já verifica a conexão com um ping
+	return gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Warn),
+	})
+}
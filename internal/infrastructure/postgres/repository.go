@@ -1,11 +1,25 @@
 package postgres
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 	"vend/internal/domain"
 
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// Repository implementa usecase.Repository no PostgreSQL. As pessoas de um
+// contexto ficam na tabela de junção contexto_pessoas; os telefones e
+// contextos de uma pessoa e os prompts de um contexto são lidos das tabelas
+// relacionadas e ignorados na gravação. Assim como no MongoDB, as listagens
+// vêm dos mais novos aos mais antigos e os registros inexistentes retornam
+// domain.ErrNaoEncontrado. Pessoas, telefones, contextos e prompts removidos
+// continuam nas tabelas com deleted_at preenchido e ficam fora das leituras.
 type Repository struct {
 	db *gorm.DB
 }
@@ -14,98 +28,1130 @@ func NewRepository(db *gorm.DB) *Repository {
 	return &Repository{db: db}
 }
 
+// Migrar cria ou atualiza as tabelas, índices e chaves estrangeiras, e a
+// coluna busca das tabelas da busca textual: um tsvector gerado pelo banco a
+// partir dos campos de domain.CamposBuscaTexto, com índice GIN
+func (r *Repository) Migrar() error {
+	if err := r.db.AutoMigrate(modelos...); err != nil {
+		return err
+	}
+	for tipo, tabela := range tabelasBusca {
+		comandos := []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS busca tsvector GENERATED ALWAYS AS (%s) STORED", tabela, vetorBusca(domain.CamposBuscaTexto[tipo])),
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_busca ON %s USING gin (busca)", tabela, tabela),
+		}
+		for _, comando := range comandos {
+			if err := r.db.Exec(comando).Error; err != nil {
+				return fmt.Errorf("busca textual em %s: %w", tabela, err)
+			}
+		}
+	}
+	return nil
+}
+
+// sessao limita cada operação a 5 segundos, como no repositório do MongoDB
+func (r *Repository) sessao(prazo time.Duration) (*gorm.DB, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), prazo)
+	return r.db.WithContext(ctx), cancel
+}
+
+// Métodos de Pessoa
 func (r *Repository) CreatePessoa(pessoa *domain.Pessoa) error {
-	return r.db.Create(pessoa).Error
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	pessoa.CreatedAt = time.Now()
+	pessoa.UpdatedAt = pessoa.CreatedAt
+	modelo := paraPessoaModel(pessoa)
+	return db.Create(&modelo).Error
 }
 
-func (r *Repository) GetPessoa(id uint) (*domain.Pessoa, error) {
-	var pessoa domain.Pessoa
-	err := r.db.Preload("Telefones").Preload("Contextos").First(&pessoa, id).Error
-	return &pessoa, err
+func (r *Repository) GetPessoa(id string) (*domain.Pessoa, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	var modelo pessoaModel
+	if err := db.Where("id = ? AND deleted_at IS NULL", id).Take(&modelo).Error; err != nil {
+		return nil, naoEncontrado(err)
+	}
+
+	pessoas := []domain.Pessoa{modelo.dominio()}
+	if err := carregarPessoas(db, pessoas); err != nil {
+		return nil, err
+	}
+	return &pessoas[0], nil
 }
 
-func (r *Repository) ListPessoas() ([]domain.Pessoa, error) {
-	var pessoas []domain.Pessoa
-	err := r.db.Preload("Telefones").Preload("Contextos").Find(&pessoas).Error
-	return pessoas, err
+func (r *Repository) ListPessoas(filtro domain.PessoaFiltro) ([]domain.Pessoa, int64, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	consulta := db.Model(&pessoaModel{}).Where("deleted_at IS NULL")
+	if filtro.Nome != "" {
+		consulta = consulta.Where("nome ILIKE ?", contem(filtro.Nome))
+	}
+	if filtro.Email != "" {
+		consulta = consulta.Where("email ILIKE ?", contem(filtro.Email))
+	}
+
+	var modelos []pessoaModel
+	total, err := listarPagina(consulta, filtro.Paginacao, &modelos)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pessoas := make([]domain.Pessoa, len(modelos))
+	for i, m := range modelos {
+		pessoas[i] = m.dominio()
+	}
+	if filtro.Expandir {
+		if err := carregarPessoas(db, pessoas); err != nil {
+			return nil, 0, err
+		}
+	}
+	return pessoas, total, nil
 }
 
 func (r *Repository) UpdatePessoa(pessoa *domain.Pessoa) error {
-	return r.db.Save(pessoa).Error
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	pessoa.UpdatedAt = time.Now()
+	modelo := paraPessoaModel(pessoa)
+	if err := atualizar(db, &modelo); err != nil {
+		return err
+	}
+	pessoa.CreatedAt = modelo.CreatedAt
+	return nil
 }
 
-func (r *Repository) DeletePessoa(id uint) error {
-	return r.db.Delete(&domain.Pessoa{}, id).Error
+func (r *Repository) DeletePessoa(id string, remocao domain.Remocao) error {
+	return r.moverParaLixeira(&pessoaModel{}, id, remocao)
 }
 
+// carregarPessoas preenche os telefones e os contextos das pessoas
+func carregarPessoas(db *gorm.DB, pessoas []domain.Pessoa) error {
+	if len(pessoas) == 0 {
+		return nil
+	}
+	ids := make([]string, len(pessoas))
+	indices := make(map[string]int, len(pessoas))
+	for i, p := range pessoas {
+		ids[i] = p.ID.Hex()
+		indices[ids[i]] = i
+	}
+
+	var telefones []telefoneModel
+	if err := db.Where("pessoa_id IN ? AND deleted_at IS NULL", ids).Order("created_at, id").Find(&telefones).Error; err != nil {
+		return err
+	}
+	for _, t := range telefones {
+		i := indices[*t.PessoaID]
+		pessoas[i].Telefones = append(pessoas[i].Telefones, t.dominio())
+	}
+
+	var contextos []struct {
+		contextoModel
+		PessoaID string
+	}
+	err := db.Table("contextos").
+		Select("contextos.*, contexto_pessoas.pessoa_id").
+		Joins("JOIN contexto_pessoas ON contexto_pessoas.contexto_id = contextos.id").
+		Where("contexto_pessoas.pessoa_id IN ? AND contextos.deleted_at IS NULL", ids).
+		Order("contextos.created_at DESC, contextos.id DESC").
+		Find(&contextos).Error
+	if err != nil {
+		return err
+	}
+	for _, c := range contextos {
+		i := indices[c.PessoaID]
+		pessoas[i].Contextos = append(pessoas[i].Contextos, c.contextoModel.dominio())
+	}
+	return nil
+}
+
+// Métodos de Telefone
 func (r *Repository) CreateTelefone(telefone *domain.Telefone) error {
-	return r.db.Create(telefone).Error
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	telefone.CreatedAt = time.Now()
+	telefone.UpdatedAt = telefone.CreatedAt
+	modelo := paraTelefoneModel(telefone)
+	return db.Create(&modelo).Error
 }
 
-func (r *Repository) GetTelefone(id uint) (*domain.Telefone, error) {
-	var telefone domain.Telefone
-	err := r.db.First(&telefone, id).Error
-	return &telefone, err
+func (r *Repository) GetTelefone(id string) (*domain.Telefone, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	var modelo telefoneModel
+	if err := db.Where("id = ? AND deleted_at IS NULL", id).Take(&modelo).Error; err != nil {
+		return nil, naoEncontrado(err)
+	}
+	telefone := modelo.dominio()
+	return &telefone, nil
 }
 
-func (r *Repository) ListTelefones() ([]domain.Telefone, error) {
-	var telefones []domain.Telefone
-	err := r.db.Find(&telefones).Error
-	return telefones, err
+func (r *Repository) ListTelefones(filtro domain.TelefoneFiltro) ([]domain.Telefone, int64, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	consulta := db.Model(&telefoneModel{}).Where("deleted_at IS NULL")
+	if filtro.Numero != "" {
+		consulta = consulta.Where("numero LIKE ?", contem(filtro.Numero))
+	}
+	if filtro.Tipo != "" {
+		consulta = consulta.Where("tipo = ?", filtro.Tipo)
+	}
+	if filtro.PessoaID != "" {
+		consulta = consulta.Where("pessoa_id = ?", filtro.PessoaID)
+	}
+
+	var modelos []telefoneModel
+	total, err := listarPagina(consulta, filtro.Paginacao, &modelos)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	telefones := make([]domain.Telefone, len(modelos))
+	for i, m := range modelos {
+		telefones[i] = m.dominio()
+	}
+	return telefones, total, nil
 }
 
 func (r *Repository) UpdateTelefone(telefone *domain.Telefone) error {
-	return r.db.Save(telefone).Error
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	telefone.UpdatedAt = time.Now()
+	modelo := paraTelefoneModel(telefone)
+	if err := atualizar(db, &modelo); err != nil {
+		return err
+	}
+	telefone.CreatedAt = modelo.CreatedAt
+	return nil
 }
 
-func (r *Repository) DeleteTelefone(id uint) error {
-	return r.db.Delete(&domain.Telefone{}, id).Error
+func (r *Repository) DeleteTelefone(id string, remocao domain.Remocao) error {
+	return r.moverParaLixeira(&telefoneModel{}, id, remocao)
 }
 
+// Métodos de Contexto
 func (r *Repository) CreateContexto(contexto *domain.Contexto) error {
-	return r.db.Create(contexto).Error
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	contexto.CreatedAt = time.Now()
+	contexto.UpdatedAt = contexto.CreatedAt
+	modelo := paraContextoModel(contexto)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&modelo).Error; err != nil {
+			return err
+		}
+		return gravarPessoasContexto(tx, modelo.ID, contexto.PessoaIDs)
+	})
 }
 
-func (r *Repository) GetContexto(id uint) (*domain.Contexto, error) {
-	var contexto domain.Contexto
-	err := r.db.Preload("Pessoas").Preload("Prompts").First(&contexto, id).Error
-	return &contexto, err
+func (r *Repository) GetContexto(id string) (*domain.Contexto, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	var modelo contextoModel
+	if err := db.Where("id = ? AND deleted_at IS NULL", id).Take(&modelo).Error; err != nil {
+		return nil, naoEncontrado(err)
+	}
+
+	contextos := []domain.Contexto{modelo.dominio()}
+	if err := carregarContextos(db, contextos, true); err != nil {
+		return nil, err
+	}
+	return &contextos[0], nil
 }
 
-func (r *Repository) ListContextos() ([]domain.Contexto, error) {
-	var contextos []domain.Contexto
-	err := r.db.Preload("Pessoas").Preload("Prompts").Find(&contextos).Error
-	return contextos, err
+func (r *Repository) ListContextos(filtro domain.ContextoFiltro) ([]domain.Contexto, int64, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	consulta := db.Model(&contextoModel{}).Where("deleted_at IS NULL")
+	if filtro.Nome != "" {
+		consulta = consulta.Where("nome ILIKE ?", contem(filtro.Nome))
+	}
+	if !filtro.DataInicioDe.IsZero() {
+		consulta = consulta.Where("data_inicio >= ?", filtro.DataInicioDe)
+	}
+	if !filtro.DataInicioAte.IsZero() {
+		consulta = consulta.Where("data_inicio < ?", filtro.DataInicioAte)
+	}
+
+	var modelos []contextoModel
+	total, err := listarPagina(consulta, filtro.Paginacao, &modelos)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	contextos := make([]domain.Contexto, len(modelos))
+	for i, m := range modelos {
+		contextos[i] = m.dominio()
+	}
+	if err := carregarContextos(db, contextos, filtro.Expandir); err != nil {
+		return nil, 0, err
+	}
+	return contextos, total, nil
 }
 
+// UpdateContexto não altera as pessoas do contexto; veja AddPessoaContexto
 func (r *Repository) UpdateContexto(contexto *domain.Contexto) error {
-	return r.db.Save(contexto).Error
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	contexto.UpdatedAt = time.Now()
+	modelo := paraContextoModel(contexto)
+	if err := atualizar(db, &modelo); err != nil {
+		return err
+	}
+	contexto.CreatedAt = modelo.CreatedAt
+	return nil
+}
+
+func (r *Repository) DeleteContexto(id string, remocao domain.Remocao) error {
+	return r.moverParaLixeira(&contextoModel{}, id, remocao)
 }
 
-func (r *Repository) DeleteContexto(id uint) error {
-	return r.db.Delete(&domain.Contexto{}, id).Error
+// gravarPessoasContexto liga as pessoas ao contexto na ordem informada; as
+// pessoas precisam existir
+func gravarPessoasContexto(tx *gorm.DB, contextoID string, pessoaIDs []primitive.ObjectID) error {
+	ligacoes := make([]contextoPessoaModel, 0, len(pessoaIDs))
+	vistas := make(map[string]bool, len(pessoaIDs))
+	for _, pessoaID := range pessoaIDs {
+		id := pessoaID.Hex()
+		if vistas[id] {
+			continue
+		}
+		vistas[id] = true
+		ligacoes = append(ligacoes, contextoPessoaModel{ContextoID: contextoID, PessoaID: id, Posicao: len(ligacoes)})
+	}
+	if len(ligacoes) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(ligacoes))
+	for i, l := range ligacoes {
+		ids[i] = l.PessoaID
+	}
+	var existentes int64
+	if err := tx.Model(&pessoaModel{}).Where("id IN ? AND deleted_at IS NULL", ids).Count(&existentes).Error; err != nil {
+		return err
+	}
+	if existentes != int64(len(ids)) {
+		return fmt.Errorf("%w: pessoa do contexto", domain.ErrNaoEncontrado)
+	}
+
+	return tx.Create(&ligacoes).Error
+}
+
+// Métodos das relações
+// AddPessoaContexto põe a pessoa no fim do contexto; se ela já estiver nele,
+// nada muda
+func (r *Repository) AddPessoaContexto(contextoID, pessoaID string) error {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// O lock do contexto serializa as posições das pessoas adicionadas juntas
+		var contexto contextoModel
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ? AND deleted_at IS NULL", contextoID).Take(&contexto).Error
+		if err != nil {
+			return naoEncontrado(err)
+		}
+		var pessoas int64
+		if err := tx.Model(&pessoaModel{}).Where("id = ? AND deleted_at IS NULL", pessoaID).Count(&pessoas).Error; err != nil {
+			return err
+		}
+		if pessoas == 0 {
+			return fmt.Errorf("%w: pessoa do contexto", domain.ErrNaoEncontrado)
+		}
+
+		var posicao int
+		err = tx.Model(&contextoPessoaModel{}).
+			Select("coalesce(max(posicao) + 1, 0)").
+			Where("contexto_id = ?", contextoID).
+			Scan(&posicao).Error
+		if err != nil {
+			return err
+		}
+		ligacao := contextoPessoaModel{ContextoID: contextoID, PessoaID: pessoaID, Posicao: posicao}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&ligacao).Error; err != nil {
+			return err
+		}
+		return tx.Model(&contextoModel{}).Where("id = ?", contextoID).Update("updated_at", time.Now()).Error
+	})
+}
+
+// RemovePessoaContexto retorna domain.ErrNaoEncontrado também quando a
+// pessoa não está no contexto
+func (r *Repository) RemovePessoaContexto(contextoID, pessoaID string) error {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		ativos := tx.Model(&contextoModel{}).Select("id").Where("deleted_at IS NULL")
+		result := tx.Where("contexto_id = ? AND pessoa_id = ? AND contexto_id IN (?)", contextoID, pessoaID, ativos).Delete(&contextoPessoaModel{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrNaoEncontrado
+		}
+		return tx.Model(&contextoModel{}).Where("id = ?", contextoID).Update("updated_at", time.Now()).Error
+	})
+}
+
+// carregarContextos preenche os IDs das pessoas e os prompts dos contextos e,
+// com expandir, as pessoas
+func carregarContextos(db *gorm.DB, contextos []domain.Contexto, expandir bool) error {
+	if len(contextos) == 0 {
+		return nil
+	}
+	ids := make([]string, len(contextos))
+	indices := make(map[string]int, len(contextos))
+	for i, c := range contextos {
+		ids[i] = c.ID.Hex()
+		indices[ids[i]] = i
+	}
+
+	var ligacoes []contextoPessoaModel
+	if err := db.Where("contexto_id IN ?", ids).Order("posicao").Find(&ligacoes).Error; err != nil {
+		return err
+	}
+	for _, l := range ligacoes {
+		i := indices[l.ContextoID]
+		contextos[i].PessoaIDs = append(contextos[i].PessoaIDs, objectID(l.PessoaID))
+	}
+
+	if expandir {
+		var pessoas []struct {
+			pessoaModel
+			ContextoID string
+		}
+		err := db.Table("pessoas").
+			Select("pessoas.*, contexto_pessoas.contexto_id").
+			Joins("JOIN contexto_pessoas ON contexto_pessoas.pessoa_id = pessoas.id").
+			Where("contexto_pessoas.contexto_id IN ? AND pessoas.deleted_at IS NULL", ids).
+			Order("contexto_pessoas.posicao").
+			Find(&pessoas).Error
+		if err != nil {
+			return err
+		}
+		for _, p := range pessoas {
+			i := indices[p.ContextoID]
+			contextos[i].Pessoas = append(contextos[i].Pessoas, p.pessoaModel.dominio())
+		}
+	}
+
+	var prompts []promptModel
+	if err := db.Where("contexto_id IN ? AND deleted_at IS NULL", ids).Order("created_at, id").Find(&prompts).Error; err != nil {
+		return err
+	}
+	for _, p := range prompts {
+		i := indices[*p.ContextoID]
+		contextos[i].Prompts = append(contextos[i].Prompts, p.dominio())
+	}
+	return nil
 }
 
+// Métodos de Prompt
 func (r *Repository) CreatePrompt(prompt *domain.Prompt) error {
-	return r.db.Create(prompt).Error
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	prompt.CreatedAt = time.Now()
+	prompt.UpdatedAt = prompt.CreatedAt
+	modelo := paraPromptModel(prompt)
+	return db.Create(&modelo).Error
 }
 
-func (r *Repository) GetPrompt(id uint) (*domain.Prompt, error) {
-	var prompt domain.Prompt
-	err := r.db.First(&prompt, id).Error
-	return &prompt, err
+func (r *Repository) GetPrompt(id string) (*domain.Prompt, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	var modelo promptModel
+	if err := db.Where("id = ? AND deleted_at IS NULL", id).Take(&modelo).Error; err != nil {
+		return nil, naoEncontrado(err)
+	}
+	prompt := modelo.dominio()
+	return &prompt, nil
 }
 
-func (r *Repository) ListPrompts() ([]domain.Prompt, error) {
-	var prompts []domain.Prompt
-	err := r.db.Find(&prompts).Error
-	return prompts, err
+func (r *Repository) ListPrompts(filtro domain.PromptFiltro) ([]domain.Prompt, int64, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	consulta := db.Model(&promptModel{}).Where("deleted_at IS NULL")
+	if filtro.ContextoID != "" {
+		consulta = consulta.Where("contexto_id = ?", filtro.ContextoID)
+	}
+
+	var modelos []promptModel
+	total, err := listarPagina(consulta, filtro.Paginacao, &modelos)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	prompts := make([]domain.Prompt, len(modelos))
+	for i, m := range modelos {
+		prompts[i] = m.dominio()
+	}
+	return prompts, total, nil
 }
 
+// UpdatePrompt preserva o embedding gravado; ele é recalculado em segundo plano
 func (r *Repository) UpdatePrompt(prompt *domain.Prompt) error {
-	return r.db.Save(prompt).Error
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	prompt.UpdatedAt = time.Now()
+	modelo := paraPromptModel(prompt)
+	if err := atualizar(db, &modelo, "embedding"); err != nil {
+		return err
+	}
+	prompt.CreatedAt = modelo.CreatedAt
+	return nil
+}
+
+func (r *Repository) UpdatePromptEmbedding(id string, embedding *domain.Embedding) error {
+	return r.updateEmbedding(&promptModel{}, id, embedding)
+}
+
+func (r *Repository) DeletePrompt(id string, remocao domain.Remocao) error {
+	return r.moverParaLixeira(&promptModel{}, id, remocao)
+}
+
+// Métodos de PromptVersao. As versões são imutáveis: não há update nem delete.
+func (r *Repository) CreatePromptVersao(versao *domain.PromptVersao) error {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	versao.CreatedAt = time.Now()
+	modelo := paraPromptVersaoModel(versao)
+	return db.Create(&modelo).Error
+}
+
+func (r *Repository) GetPromptVersao(promptID string, numero int) (*domain.PromptVersao, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	var modelo promptVersaoModel
+	if err := db.Where("prompt_id = ? AND numero = ?", promptID, numero).Take(&modelo).Error; err != nil {
+		return nil, naoEncontrado(err)
+	}
+	versao := modelo.dominio()
+	return &versao, nil
+}
+
+func (r *Repository) ListPromptVersoes(promptID string) ([]domain.PromptVersao, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	var modelos []promptVersaoModel
+	if err := db.Where("prompt_id = ?", promptID).Order("numero").Find(&modelos).Error; err != nil {
+		return nil, err
+	}
+
+	versoes := make([]domain.PromptVersao, len(modelos))
+	for i, m := range modelos {
+		versoes[i] = m.dominio()
+	}
+	return versoes, nil
+}
+
+// Métodos de Resposta
+func (r *Repository) CreateResposta(resposta *domain.Resposta) error {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	resposta.CreatedAt = time.Now()
+	modelo := paraRespostaModel(resposta)
+	return db.Create(&modelo).Error
+}
+
+func (r *Repository) GetResposta(id string) (*domain.Resposta, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	var modelo respostaModel
+	if err := db.Where("id = ?", id).Take(&modelo).Error; err != nil {
+		return nil, naoEncontrado(err)
+	}
+	resposta := modelo.dominio()
+	return &resposta, nil
+}
+
+func (r *Repository) ListRespostas(filtro domain.RespostaFiltro) ([]domain.Resposta, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	consulta := db.Model(&respostaModel{})
+	if filtro.PromptID != "" {
+		consulta = consulta.Where("prompt_id = ?", filtro.PromptID)
+	}
+	if filtro.ContextoID != "" {
+		consulta = consulta.Where("contexto_id = ?", filtro.ContextoID)
+	}
+
+	var modelos []respostaModel
+	if _, err := listarPagina(consulta, filtro.Paginacao, &modelos); err != nil {
+		return nil, err
+	}
+
+	respostas := make([]domain.Resposta, len(modelos))
+	for i, m := range modelos {
+		respostas[i] = m.dominio()
+	}
+	return respostas, nil
+}
+
+// SomarConsumo agrega tokens e custo das respostas e dos embeddings do período
+func (r *Repository) SomarConsumo(filtro domain.ConsumoFiltro) (*domain.Consumo, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	somar := func(modelo any) *gorm.DB {
+		query := db.Model(modelo).
+			Select(`COUNT(*) AS registros,
+				COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens,
+				COALESCE(SUM(completion_tokens), 0) AS completion_tokens,
+				COALESCE(SUM(total_tokens), 0) AS total_tokens,
+				COALESCE(SUM(custo_estimado), 0) AS custo_estimado`).
+			Where("created_at >= ? AND created_at < ?", filtro.Inicio, filtro.Fim)
+		if filtro.ContextoID != "" {
+			query = query.Where("contexto_id = ?", filtro.ContextoID)
+		}
+		return query
+	}
+
+	var respostas, embeddings totaisConsumo
+	query := somar(&respostaModel{})
+	if filtro.PessoaID != "" {
+		query = query.Where("pessoa_id = ?", filtro.PessoaID)
+	} else if err := somar(&usoEmbeddingsModel{}).Scan(&embeddings).Error; err != nil {
+		return nil, err
+	}
+	if err := query.Scan(&respostas).Error; err != nil {
+		return nil, err
+	}
+
+	return &domain.Consumo{
+		Inicio:           filtro.Inicio,
+		Fim:              filtro.Fim,
+		Geracoes:         respostas.Registros,
+		PromptTokens:     respostas.PromptTokens + embeddings.PromptTokens,
+		CompletionTokens: respostas.CompletionTokens + embeddings.CompletionTokens,
+		TotalTokens:      respostas.TotalTokens + embeddings.TotalTokens,
+		EmbeddingTokens:  embeddings.TotalTokens,
+		CustoEstimado:    respostas.CustoEstimado + embeddings.CustoEstimado,
+	}, nil
+}
+
+type totaisConsumo struct {
+	Registros        int64
+	PromptTokens     int64
+	CompletionTokens int64
+	TotalTokens      int64
+	CustoEstimado    float64
+}
+
+func (r *Repository) CreateUsoEmbeddings(uso *domain.UsoEmbeddings) error {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	uso.CreatedAt = time.Now()
+	modelo := paraUsoEmbeddingsModel(uso)
+	return db.Create(&modelo).Error
+}
+
+func (r *Repository) UpdateRespostaEmbedding(id string, embedding *domain.Embedding) error {
+	return r.updateEmbedding(&respostaModel{}, id, embedding)
+}
+
+// Métodos de Conversa
+func (r *Repository) CreateConversa(conversa *domain.Conversa) error {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	conversa.CreatedAt = time.Now()
+	conversa.UpdatedAt = conversa.CreatedAt
+	if conversa.Mensagens == nil {
+		conversa.Mensagens = []domain.MensagemConversa{}
+	}
+
+	modelo := conversaModel{
+		ID:         novoID(&conversa.ID),
+		PessoaID:   idNulo(conversa.PessoaID),
+		ContextoID: idNulo(conversa.ContextoID),
+		CreatedAt:  conversa.CreatedAt,
+		UpdatedAt:  conversa.UpdatedAt,
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&modelo).Error; err != nil {
+			return err
+		}
+		return gravarMensagens(tx, modelo.ID, conversa.Mensagens)
+	})
+}
+
+func (r *Repository) GetConversa(id string) (*domain.Conversa, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	var modelo conversaModel
+	if err := db.Where("id = ?", id).Take(&modelo).Error; err != nil {
+		return nil, naoEncontrado(err)
+	}
+
+	var mensagens []mensagemConversaModel
+	if err := db.Where("conversa_id = ?", id).Order("id").Find(&mensagens).Error; err != nil {
+		return nil, err
+	}
+
+	conversa := &domain.Conversa{
+		ID:         objectID(modelo.ID),
+		PessoaID:   objectIDNulo(modelo.PessoaID),
+		ContextoID: objectIDNulo(modelo.ContextoID),
+		Mensagens:  make([]domain.MensagemConversa, len(mensagens)),
+		CreatedAt:  modelo.CreatedAt,
+		UpdatedAt:  modelo.UpdatedAt,
+	}
+	for i, m := range mensagens {
+		conversa.Mensagens[i] = m.dominio()
+	}
+	return conversa, nil
+}
+
+// ListConversas retorna as conversas sem as mensagens, como resumo
+func (r *Repository) ListConversas(filtro domain.ConversaFiltro) ([]domain.Conversa, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	query := db.Order("updated_at DESC")
+	if filtro.PessoaID != "" {
+		query = query.Where("pessoa_id = ?", filtro.PessoaID)
+	}
+	if filtro.ContextoID != "" {
+		query = query.Where("contexto_id = ?", filtro.ContextoID)
+	}
+
+	var modelos []conversaModel
+	if err := query.Find(&modelos).Error; err != nil {
+		return nil, err
+	}
+
+	conversas := make([]domain.Conversa, len(modelos))
+	for i, m := range modelos {
+		conversas[i] = domain.Conversa{
+			ID:         objectID(m.ID),
+			PessoaID:   objectIDNulo(m.PessoaID),
+			ContextoID: objectIDNulo(m.ContextoID),
+			Mensagens:  []domain.MensagemConversa{},
+			CreatedAt:  m.CreatedAt,
+			UpdatedAt:  m.UpdatedAt,
+		}
+	}
+	return conversas, nil
+}
+
+// AppendMensagensConversa acrescenta as mensagens ao fim da conversa, sem
+// regravar o histórico existente
+func (r *Repository) AppendMensagensConversa(id string, mensagens []domain.MensagemConversa) error {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&conversaModel{}).Where("id = ?", id).UpdateColumn("updated_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrNaoEncontrado
+		}
+		return gravarMensagens(tx, id, mensagens)
+	})
+}
+
+func gravarMensagens(tx *gorm.DB, conversaID string, mensagens []domain.MensagemConversa) error {
+	if len(mensagens) == 0 {
+		return nil
+	}
+	modelos := make([]mensagemConversaModel, len(mensagens))
+	for i, m := range mensagens {
+		modelos[i] = paraMensagemConversaModel(conversaID, m)
+	}
+	return tx.Create(&modelos).Error
+}
+
+// Métodos de Documento
+func (r *Repository) CreateDocumento(documento *domain.Documento, trechos []domain.TrechoDocumento) error {
+	db, cancel := r.sessao(30 * time.Second)
+	defer cancel()
+
+	documento.CreatedAt = time.Now()
+	documento.Trechos = len(trechos)
+	modelo := documentoModel{
+		ID:         novoID(&documento.ID),
+		ContextoID: documento.ContextoID.Hex(),
+		Nome:       documento.Nome,
+		Formato:    documento.Formato,
+		Caracteres: documento.Caracteres,
+		Trechos:    documento.Trechos,
+		CreatedAt:  documento.CreatedAt,
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&modelo).Error; err != nil {
+			return err
+		}
+		if len(trechos) == 0 {
+			return nil
+		}
+		modelos := make([]trechoDocumentoModel, len(trechos))
+		for i := range trechos {
+			trechos[i].DocumentoID = documento.ID
+			trechos[i].ContextoID = documento.ContextoID
+			modelos[i] = paraTrechoDocumentoModel(&trechos[i])
+		}
+		return tx.CreateInBatches(&modelos, 100).Error
+	})
+}
+
+func (r *Repository) GetDocumento(id string) (*domain.Documento, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	var modelo documentoModel
+	if err := db.Where("id = ?", id).Take(&modelo).Error; err != nil {
+		return nil, naoEncontrado(err)
+	}
+	documento := modelo.dominio()
+	return &documento, nil
+}
+
+func (r *Repository) ListDocumentos(contextoID string) ([]domain.Documento, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	var modelos []documentoModel
+	if err := db.Where("contexto_id = ?", contextoID).Order("created_at").Find(&modelos).Error; err != nil {
+		return nil, err
+	}
+
+	documentos := make([]domain.Documento, len(modelos))
+	for i, m := range modelos {
+		documentos[i] = m.dominio()
+	}
+	return documentos, nil
+}
+
+func (r *Repository) ListTrechosDocumento(contextoID string) ([]domain.TrechoDocumento, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	var modelos []trechoDocumentoModel
+	if err := db.Where("contexto_id = ?", contextoID).Find(&modelos).Error; err != nil {
+		return nil, err
+	}
+
+	trechos := make([]domain.TrechoDocumento, len(modelos))
+	for i, m := range modelos {
+		trechos[i] = m.dominio()
+	}
+	return trechos, nil
+}
+
+// DeleteDocumento remove o documento; os trechos saem em cascata
+func (r *Repository) DeleteDocumento(id string) error {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	return db.Where("id = ?", id).Delete(&documentoModel{}).Error
+}
+
+// Métodos de Job
+func (r *Repository) CreateJob(job *domain.Job) error {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = job.CreatedAt
+	modelo := jobModel{
+		ID:         novoID(&job.ID),
+		ContextoID: job.ContextoID.Hex(),
+		PromptID:   job.PromptID.Hex(),
+		Variaveis:  jsonb[map[string]any]{job.Variaveis},
+		Parametros: jsonb[domain.ParametrosGeracao]{job.Parametros},
+		Status:     job.Status,
+		Total:      job.Total,
+		Concluidos: job.Concluidos,
+		Falhas:     job.Falhas,
+		CreatedAt:  job.CreatedAt,
+		UpdatedAt:  job.UpdatedAt,
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&modelo).Error; err != nil {
+			return err
+		}
+		if len(job.Itens) == 0 {
+			return nil
+		}
+		itens := make([]itemJobModel, len(job.Itens))
+		for i, item := range job.Itens {
+			itens[i] = paraItemJobModel(modelo.ID, i, item)
+		}
+		return tx.CreateInBatches(&itens, 500).Error
+	})
+}
+
+func (r *Repository) GetJob(id string) (*domain.Job, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	var modelo jobModel
+	if err := db.Where("id = ?", id).Take(&modelo).Error; err != nil {
+		return nil, naoEncontrado(err)
+	}
+
+	jobs, err := carregarJobs(db, []jobModel{modelo})
+	if err != nil {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+// ListJobsPorStatus retorna os jobs nos status informados, dos mais antigos aos mais novos
+func (r *Repository) ListJobsPorStatus(status ...string) ([]domain.Job, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	var modelos []jobModel
+	if err := db.Where("status IN ?", status).Order("created_at").Find(&modelos).Error; err != nil {
+		return nil, err
+	}
+	return carregarJobs(db, modelos)
+}
+
+func (r *Repository) UpdateJobStatus(id string, status string) error {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	return db.Model(&jobModel{}).Where("id = ?", id).Updates(map[string]any{"status": status, "updated_at": time.Now()}).Error
+}
+
+// UpdateJobItem grava só o item alterado, para que os workers do mesmo job
+// não sobrescrevam o trabalho uns dos outros
+func (r *Repository) UpdateJobItem(id string, i int, item domain.ItemJob) error {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	contador := "concluidos"
+	if item.Status == domain.StatusItemFalhou {
+		contador = "falhas"
+	}
+
+	modelo := paraItemJobModel(id, i, item)
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&itemJobModel{}).
+			Where("job_id = ? AND indice = ?", id, i).
+			Select("*").
+			Updates(&modelo).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&jobModel{}).Where("id = ?", id).Updates(map[string]any{
+			contador:     gorm.Expr(contador + " + 1"),
+			"updated_at": time.Now(),
+		}).Error
+	})
+}
+
+// Métodos de Busca
+// BuscarTexto procura os termos como prefixos dos radicais em português, sem
+// diferenciar acentos; o score é o ts_rank da coluna busca
+func (r *Repository) BuscarTexto(filtro domain.BuscaTextoFiltro) ([]domain.ResultadoTexto, error) {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	prefixos := make([]string, len(filtro.Termos))
+	for i, termo := range filtro.Termos {
+		prefixos[i] = termo + ":*"
+	}
+	consulta := strings.Join(prefixos, " | ")
+
+	resultados := []domain.ResultadoTexto{}
+	for _, tipo := range filtro.Tipos {
+		campos := domain.CamposBuscaTexto[tipo]
+		colunas := make([]string, len(campos))
+		for i, campo := range campos {
+			colunas[i] = fmt.Sprintf("coalesce(%s, '')", campo)
+		}
+
+		linhas, err := db.Table(tabelasBusca[tipo]).
+			Select("id, "+strings.Join(colunas, ", ")+", ts_rank(busca, to_tsquery('portuguese', ?)) AS score", consulta).
+			Where("busca @@ to_tsquery('portuguese', ?) AND deleted_at IS NULL", consulta).
+			Order("score DESC, id").
+			Limit(filtro.Limite).
+			Rows()
+		if err != nil {
+			return nil, err
+		}
+		for linhas.Next() {
+			resultado := domain.ResultadoTexto{Tipo: tipo, Campos: map[string]string{}}
+			textos := make([]string, len(campos))
+			destinos := []any{&resultado.ID}
+			for i := range textos {
+				destinos = append(destinos, &textos[i])
+			}
+			destinos = append(destinos, &resultado.Score)
+			if err := linhas.Scan(destinos...); err != nil {
+				linhas.Close()
+				return nil, err
+			}
+			for i, campo := range campos {
+				resultado.Campos[campo] = textos[i]
+			}
+			resultados = append(resultados, resultado)
+		}
+		linhas.Close()
+		if err := linhas.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return resultados, nil
+}
+
+func carregarJobs(db *gorm.DB, modelos []jobModel) ([]domain.Job, error) {
+	jobs := make([]domain.Job, len(modelos))
+	if len(modelos) == 0 {
+		return jobs, nil
+	}
+	ids := make([]string, len(modelos))
+	indices := make(map[string]int, len(modelos))
+	for i, m := range modelos {
+		ids[i] = m.ID
+		indices[m.ID] = i
+	}
+
+	var itens []itemJobModel
+	if err := db.Where("job_id IN ?", ids).Order("job_id, indice").Find(&itens).Error; err != nil {
+		return nil, err
+	}
+	porJob := make([][]domain.ItemJob, len(modelos))
+	for _, item := range itens {
+		i := indices[item.JobID]
+		porJob[i] = append(porJob[i], item.dominio())
+	}
+
+	for i, m := range modelos {
+		jobs[i] = m.dominio(porJob[i])
+	}
+	return jobs, nil
+}
+
+// updateEmbedding grava só o embedding, sem alterar updated_at, pois o
+// registro em si não mudou
+func (r *Repository) updateEmbedding(modelo any, id string, embedding *domain.Embedding) error {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	return db.Model(modelo).Where("id = ?", id).UpdateColumn("embedding", jsonb[*domain.Embedding]{embedding}).Error
+}
+
+// listarPagina decodifica em destino a página das linhas aceitas pela
+// consulta e, com ComTotal, retorna o total aceito por ela. A ordem desempata pelo id; depois
+// de um marcador, a consulta continua do par (campo, id) em diante, sem
+// percorrer as linhas anteriores.
+func listarPagina(consulta *gorm.DB, paginacao domain.Paginacao, destino any) (int64, error) {
+	consulta = consulta.Session(&gorm.Session{})
+
+	var total int64
+	if paginacao.ComTotal {
+		if err := consulta.Count(&total).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	ordem := paginacao.Ordem
+	if ordem.Campo == "" {
+		ordem = domain.OrdemRecentes
+	}
+	coluna := clause.Column{Name: ordem.Campo}
+	consulta = consulta.Order(clause.OrderBy{Columns: []clause.OrderByColumn{
+		{Column: coluna, Desc: ordem.Decrescente},
+		{Column: clause.Column{Name: "id"}, Desc: ordem.Decrescente},
+	}})
+	if apos := paginacao.Apos; apos != nil {
+		operador := ">"
+		if ordem.Decrescente {
+			operador = "<"
+		}
+		consulta = consulta.Where("(?, id) "+operador+" (?, ?)", coluna, apos.Valor, apos.ID.Hex())
+	}
+	if paginacao.Limite > 0 {
+		consulta = consulta.Limit(paginacao.Limite)
+	}
+	if paginacao.Offset > 0 {
+		consulta = consulta.Offset(paginacao.Offset)
+	}
+	return total, consulta.Find(destino).Error
+}
+
+// contem monta o padrão do LIKE que busca o texto em qualquer parte do campo
+func contem(texto string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(texto) + "%"
+}
+
+// atualizar grava todas as colunas do modelo, menos created_at, as da
+// lixeira e as omitidas, e devolve no modelo o created_at gravado; registros
+// na lixeira não são alterados
+func atualizar(db *gorm.DB, modelo any, omitidas ...string) error {
+	omitidas = append([]string{"id", "created_at", "deleted_at", "deleted_by"}, omitidas...)
+	result := db.Model(modelo).Where("deleted_at IS NULL").Select("*").Omit(omitidas...).Updates(modelo)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrNaoEncontrado
+	}
+	return db.Select("created_at").Take(modelo).Error
+}
+
+// moverParaLixeira marca o registro como removido; ele continua na tabela
+// até ser restaurado ou purgado
+func (r *Repository) moverParaLixeira(modelo any, id string, remocao domain.Remocao) error {
+	db, cancel := r.sessao(5 * time.Second)
+	defer cancel()
+
+	if !primitive.IsValidObjectID(id) {
+		return domain.ErrNaoEncontrado
+	}
+	result := db.Model(modelo).Where("id = ? AND deleted_at IS NULL", id).UpdateColumns(marcarRemocao(remocao))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrNaoEncontrado
+	}
+	return nil
+}
+
+func marcarRemocao(remocao domain.Remocao) map[string]any {
+	return map[string]any{"deleted_at": remocao.Em, "deleted_by": remocao.Por}
 }
 
-func (r *Repository) DeletePrompt(id uint) error {
-	return r.db.Delete(&domain.Prompt{}, id).Error
+// naoEncontrado traduz o erro do GORM para o erro do domínio
+func naoEncontrado(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.ErrNaoEncontrado
+	}
+	return err
 }
@@ -0,0 +1,153 @@
+package privacidade
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"vend/internal/domain"
+)
+
+// tamanhoMaximoMarcador limita quanto texto o stream segura esperando o
+// fim de um marcador ("<TELEFONE_123>")
+const tamanhoMaximoMarcador = 24
+
+// Padrões de dados pessoais no formato brasileiro; CNPJ e CPF vêm antes do
+// telefone para que os seus dígitos não sejam lidos como número de telefone
+var padroes = []struct {
+	dado  string
+	regex *regexp.Regexp
+}{
+	{domain.DadoEmail, regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	{domain.DadoCNPJ, regexp.MustCompile(`\b\d{2}\.?\d{3}\.?\d{3}/?\d{4}-?\d{2}\b`)},
+	{domain.DadoCPF, regexp.MustCompile(`\b\d{3}\.?\d{3}\.?\d{3}-?\d{2}\b`)},
+	{domain.DadoTelefone, regexp.MustCompile(`(?:\+?55[\s-]?)?(?:\(\d{2}\)|\b\d{2})[\s-]?9?\d{4}[\s-]?\d{4}\b`)},
+}
+
+var rotulos = map[string]string{
+	domain.DadoEmail:    "EMAIL",
+	domain.DadoTelefone: "TELEFONE",
+	domain.DadoCPF:      "CPF",
+	domain.DadoCNPJ:     "CNPJ",
+	domain.DadoNome:     "PESSOA",
+}
+
+// anonimizador troca os dados pessoais de uma geração por marcadores. O
+// mesmo dado recebe sempre o mesmo marcador, para que o modelo possa se
+// referir a ele e a resposta seja restaurada.
+type anonimizador struct {
+	modo       string
+	dados      map[string]bool
+	nomes      []string
+	marcadores map[string]string
+	originais  map[string]string
+	contagem   map[string]int
+}
+
+func novoAnonimizador(modo string, dados, nomes []string) *anonimizador {
+	if len(dados) == 0 {
+		dados = domain.DadosPessoais
+	}
+	a := &anonimizador{
+		modo:       modo,
+		dados:      make(map[string]bool, len(dados)),
+		marcadores: make(map[string]string),
+		originais:  make(map[string]string),
+		contagem:   make(map[string]int),
+	}
+	for _, dado := range dados {
+		a.dados[dado] = true
+	}
+
+	if a.dados[domain.DadoNome] {
+		vistos := make(map[string]bool, len(nomes))
+		for _, nome := range nomes {
+			nome = strings.TrimSpace(nome)
+			if nome != "" && !vistos[nome] {
+				vistos[nome] = true
+				a.nomes = append(a.nomes, nome)
+			}
+		}
+		// Nomes mais longos primeiro, para "Ana Maria" não virar "<PESSOA_1> Maria"
+		sort.SliceStable(a.nomes, func(i, j int) bool { return len(a.nomes[i]) > len(a.nomes[j]) })
+	}
+	return a
+}
+
+func (a *anonimizador) aplicar(texto string) string {
+	if a == nil || texto == "" {
+		return texto
+	}
+	for _, p := range padroes {
+		if !a.dados[p.dado] {
+			continue
+		}
+		dado := p.dado
+		texto = p.regex.ReplaceAllStringFunc(texto, func(original string) string {
+			return a.marcador(dado, original)
+		})
+	}
+	for _, nome := range a.nomes {
+		if strings.Contains(texto, nome) {
+			texto = strings.ReplaceAll(texto, nome, a.marcador(domain.DadoNome, nome))
+		}
+	}
+	return texto
+}
+
+func (a *anonimizador) marcador(dado, original string) string {
+	if a.modo == domain.PrivacidadeMascarar {
+		return "[" + rotulos[dado] + "]"
+	}
+	if marcador, ok := a.marcadores[original]; ok {
+		return marcador
+	}
+	a.contagem[dado]++
+	marcador := "<" + rotulos[dado] + "_" + strconv.Itoa(a.contagem[dado]) + ">"
+	a.marcadores[original] = marcador
+	a.originais[marcador] = original
+	return marcador
+}
+
+// restaurar devolve os dados originais no lugar dos marcadores; só se aplica
+// à pseudonimização
+func (a *anonimizador) restaurar(texto string) string {
+	if a == nil || len(a.originais) == 0 || !strings.Contains(texto, "<") {
+		return texto
+	}
+	pares := make([]string, 0, 2*len(a.originais))
+	for marcador, original := range a.originais {
+		pares = append(pares, marcador, original)
+	}
+	return strings.NewReplacer(pares...).Replace(texto)
+}
+
+// restauradorStream restaura os trechos do stream, segurando o fim de um
+// trecho que pode ser o início de um marcador até que ele se complete
+type restauradorStream struct {
+	a        *anonimizador
+	onDelta  func(string) error
+	pendente string
+}
+
+func (r *restauradorStream) escrever(delta string) error {
+	texto := r.pendente + delta
+	r.pendente = ""
+	if i := strings.LastIndex(texto, "<"); i >= 0 && !strings.Contains(texto[i:], ">") && len(texto)-i < tamanhoMaximoMarcador {
+		r.pendente = texto[i:]
+		texto = texto[:i]
+	}
+	if texto == "" {
+		return nil
+	}
+	return r.onDelta(r.a.restaurar(texto))
+}
+
+func (r *restauradorStream) finalizar() error {
+	if r.pendente == "" {
+		return nil
+	}
+	texto := r.pendente
+	r.pendente = ""
+	return r.onDelta(r.a.restaurar(texto))
+}
@@ -0,0 +1,163 @@
+// Package privacidade protege os dados pessoais enviados ao provedor de LLM,
+// seguindo a política marcada no contexto da geração (domain.ComPrivacidade)
+package privacidade
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"vend/internal/domain"
+)
+
+// Provider aplica a política de privacidade antes de chamar o provedor
+// envolvido: troca os dados pessoais das mensagens por marcadores, submete a
+// mensagem do usuário à moderação e, na pseudonimização, devolve os dados
+// originais na resposta. Os marcadores também chegam ao cache, então
+// gerações que só diferem nos dados pessoais compartilham o resultado.
+type Provider struct {
+	provider  domain.LLMProvider
+	moderador domain.Moderador
+	padrao    string
+}
+
+// NewProvider recebe o modo usado quando o contexto não define um e o
+// moderador do provedor, que pode ser nil
+func NewProvider(provider domain.LLMProvider, moderador domain.Moderador, padrao string) *Provider {
+	return &Provider{provider: provider, moderador: moderador, padrao: padrao}
+}
+
+func (p *Provider) Generate(ctx context.Context, req domain.RequisicaoGeracao) (*domain.ResultadoGeracao, error) {
+	a, err := p.preparar(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	resultado, err := p.provider.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return restaurarResultado(a, resultado), nil
+}
+
+func (p *Provider) GenerateStream(ctx context.Context, req domain.RequisicaoGeracao, onDelta func(string) error) (*domain.ResultadoGeracao, error) {
+	a, err := p.preparar(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil || a.modo != domain.PrivacidadePseudonimizar {
+		return p.provider.GenerateStream(ctx, req, onDelta)
+	}
+
+	restaurador := &restauradorStream{a: a, onDelta: onDelta}
+	resultado, err := p.provider.GenerateStream(ctx, req, restaurador.escrever)
+	if err != nil {
+		return nil, err
+	}
+	if err := restaurador.finalizar(); err != nil {
+		return nil, err
+	}
+	return restaurarResultado(a, resultado), nil
+}
+
+// preparar substitui as mensagens da requisição por cópias protegidas e
+// modera a última mensagem do usuário
+func (p *Provider) preparar(ctx context.Context, req *domain.RequisicaoGeracao) (*anonimizador, error) {
+	privacidade, _ := domain.PrivacidadeDe(ctx)
+	a := p.anonimizador(privacidade, "")
+
+	if a != nil {
+		mensagens := make([]domain.Mensagem, len(req.Mensagens))
+		for i, m := range req.Mensagens {
+			m.Conteudo = a.aplicar(m.Conteudo)
+			if len(m.ChamadasFerramenta) > 0 {
+				chamadas := make([]domain.ChamadaFerramenta, len(m.ChamadasFerramenta))
+				for j, chamada := range m.ChamadasFerramenta {
+					chamada.Argumentos = a.aplicar(chamada.Argumentos)
+					chamadas[j] = chamada
+				}
+				m.ChamadasFerramenta = chamadas
+			}
+			mensagens[i] = m
+		}
+		req.Mensagens = mensagens
+	}
+
+	if privacidade.Politica.Moderar && p.moderador != nil {
+		for i := len(req.Mensagens) - 1; i >= 0; i-- {
+			if req.Mensagens[i].Papel != domain.PapelUsuario {
+				continue
+			}
+			resultado, err := p.moderador.Moderar(ctx, req.Mensagens[i].Conteudo)
+			if err != nil {
+				return nil, err
+			}
+			if resultado.Sinalizado {
+				return nil, fmt.Errorf("%w: %s", domain.ErrConteudoBloqueado, strings.Join(resultado.Categorias, ", "))
+			}
+			break
+		}
+	}
+
+	return a, nil
+}
+
+// anonimizador retorna nil quando nenhum dado deve ser protegido; modo
+// sobrepõe o modo da política quando informado
+func (p *Provider) anonimizador(privacidade domain.Privacidade, modo string) *anonimizador {
+	if modo == "" {
+		modo = privacidade.Politica.Modo
+		if modo == "" {
+			modo = p.padrao
+		}
+	}
+	if modo == "" || modo == domain.PrivacidadeNenhuma {
+		return nil
+	}
+	return novoAnonimizador(modo, privacidade.Politica.Dados, privacidade.Nomes)
+}
+
+// restaurarResultado devolve uma cópia com os dados originais; o resultado
+// recebido pode estar guardado no cache com os marcadores
+func restaurarResultado(a *anonimizador, resultado *domain.ResultadoGeracao) *domain.ResultadoGeracao {
+	if a == nil || a.modo != domain.PrivacidadePseudonimizar {
+		return resultado
+	}
+
+	restaurado := *resultado
+	restaurado.Conteudo = a.restaurar(resultado.Conteudo)
+	if len(resultado.ChamadasFerramenta) > 0 {
+		restaurado.ChamadasFerramenta = make([]domain.ChamadaFerramenta, len(resultado.ChamadasFerramenta))
+		for i, chamada := range resultado.ChamadasFerramenta {
+			chamada.Argumentos = a.restaurar(chamada.Argumentos)
+			restaurado.ChamadasFerramenta[i] = chamada
+		}
+	}
+	return &restaurado
+}
+
+// Embeddings mascara os dados pessoais dos textos antes de gerar os
+// embeddings, com a política do contexto ou, na indexação em segundo plano,
+// com o modo padrão
+type Embeddings struct {
+	embeddings domain.ProvedorEmbeddings
+	provider   *Provider
+}
+
+func NewEmbeddings(embeddings domain.ProvedorEmbeddings, padrao string) *Embeddings {
+	return &Embeddings{embeddings: embeddings, provider: &Provider{padrao: padrao}}
+}
+
+func (e *Embeddings) Embeddings(ctx context.Context, textos []string) (*domain.ResultadoEmbeddings, error) {
+	privacidade, _ := domain.PrivacidadeDe(ctx)
+	// Marcadores numerados variam entre chamadas; para os vetores basta a máscara
+	a := e.provider.anonimizador(privacidade, "")
+	if a != nil {
+		a = novoAnonimizador(domain.PrivacidadeMascarar, privacidade.Politica.Dados, privacidade.Nomes)
+		protegidos := make([]string, len(textos))
+		for i, texto := range textos {
+			protegidos[i] = a.aplicar(texto)
+		}
+		textos = protegidos
+	}
+	return e.embeddings.Embeddings(ctx, textos)
+}
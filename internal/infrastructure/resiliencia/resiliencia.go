@@ -0,0 +1,188 @@
+package resiliencia
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+	"vend/internal/domain"
+)
+
+// Config define a política aplicada às chamadas ao provedor de LLM
+type Config struct {
+	// Tentativas é o número máximo de chamadas, incluindo a primeira
+	Tentativas int
+	// BackoffInicial é a espera antes da segunda tentativa; dobra a cada nova falha até BackoffMaximo
+	BackoffInicial time.Duration
+	BackoffMaximo  time.Duration
+	// TimeoutChamada limita cada tentativa; zero deixa só o prazo do contexto da requisição
+	TimeoutChamada time.Duration
+	// LimiteFalhas é o número de falhas consecutivas que abre o circuito; zero desativa o circuito
+	LimiteFalhas int
+	// TempoAberto é quanto tempo o circuito fica aberto antes de liberar uma chamada de teste
+	TempoAberto time.Duration
+}
+
+func ConfigPadrao() Config {
+	return Config{
+		Tentativas:     3,
+		BackoffInicial: 500 * time.Millisecond,
+		BackoffMaximo:  8 * time.Second,
+		TimeoutChamada: 60 * time.Second,
+		LimiteFalhas:   5,
+		TempoAberto:    30 * time.Second,
+	}
+}
+
+// Provider envolve outro domain.LLMProvider com repetição das falhas
+// transitórias (backoff exponencial com jitter), prazo por chamada e um
+// disjuntor (circuit breaker) que falha rápido enquanto o provedor está fora
+type Provider struct {
+	provider domain.LLMProvider
+	config   Config
+
+	mu        sync.Mutex
+	falhas    int
+	abertoAte time.Time
+	testando  bool
+}
+
+func NewProvider(provider domain.LLMProvider, config Config) *Provider {
+	if config.Tentativas < 1 {
+		config.Tentativas = 1
+	}
+	return &Provider{provider: provider, config: config}
+}
+
+func (p *Provider) Generate(ctx context.Context, req domain.RequisicaoGeracao) (*domain.ResultadoGeracao, error) {
+	return p.executar(ctx, func(ctx context.Context) (*domain.ResultadoGeracao, bool, error) {
+		resultado, err := p.provider.Generate(ctx, req)
+		return resultado, true, err
+	})
+}
+
+// GenerateStream só repete a chamada se nenhum trecho tiver sido entregue,
+// para não duplicar texto já enviado ao cliente
+func (p *Provider) GenerateStream(ctx context.Context, req domain.RequisicaoGeracao, onDelta func(string) error) (*domain.ResultadoGeracao, error) {
+	return p.executar(ctx, func(ctx context.Context) (*domain.ResultadoGeracao, bool, error) {
+		entregue := false
+		resultado, err := p.provider.GenerateStream(ctx, req, func(delta string) error {
+			entregue = true
+			return onDelta(delta)
+		})
+		return resultado, !entregue, err
+	})
+}
+
+// executar roda a chamada com a política configurada; a chamada informa se
+// ainda pode ser repetida
+func (p *Provider) executar(ctx context.Context, chamada func(context.Context) (*domain.ResultadoGeracao, bool, error)) (*domain.ResultadoGeracao, error) {
+	var err error
+	for tentativa := 0; tentativa < p.config.Tentativas; tentativa++ {
+		if tentativa > 0 {
+			if esperaErr := esperar(ctx, p.backoff(tentativa)); esperaErr != nil {
+				return nil, err
+			}
+		}
+
+		if err := p.permitir(); err != nil {
+			return nil, err
+		}
+
+		var resultado *domain.ResultadoGeracao
+		var repetivel bool
+		resultado, repetivel, err = p.tentar(ctx, chamada)
+		p.registrar(err)
+		if err == nil {
+			return resultado, nil
+		}
+		if !repetivel || !domain.ErroLLMTransitorio(err) || ctx.Err() != nil {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+func (p *Provider) tentar(ctx context.Context, chamada func(context.Context) (*domain.ResultadoGeracao, bool, error)) (*domain.ResultadoGeracao, bool, error) {
+	if p.config.TimeoutChamada <= 0 {
+		return chamada(ctx)
+	}
+
+	ctxChamada, cancel := context.WithTimeout(ctx, p.config.TimeoutChamada)
+	defer cancel()
+
+	resultado, repetivel, err := chamada(ctxChamada)
+	if err != nil && ctx.Err() == nil && ctxChamada.Err() == context.DeadlineExceeded && !domain.ErroLLMTransitorio(err) {
+		err = fmt.Errorf("%w: %v", domain.ErrLLMTempoEsgotado, err)
+	}
+	return resultado, repetivel, err
+}
+
+// backoff retorna a espera antes da tentativa (a partir de 1), com jitter
+// completo: um valor aleatório entre zero e o limite exponencial
+func (p *Provider) backoff(tentativa int) time.Duration {
+	limite := p.config.BackoffInicial << min(tentativa-1, 30)
+	if limite <= 0 || (p.config.BackoffMaximo > 0 && limite > p.config.BackoffMaximo) {
+		limite = p.config.BackoffMaximo
+	}
+	if limite <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(limite) + 1))
+}
+
+// permitir recusa a chamada enquanto o circuito estiver aberto; passado
+// TempoAberto, libera uma única chamada de teste (meio aberto)
+func (p *Provider) permitir() error {
+	if p.config.LimiteFalhas <= 0 {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.falhas < p.config.LimiteFalhas {
+		return nil
+	}
+	if time.Now().Before(p.abertoAte) || p.testando {
+		return domain.ErrLLMCircuitoAberto
+	}
+	p.testando = true
+	return nil
+}
+
+// registrar atualiza o circuito; só indisponibilidade e tempo esgotado contam,
+// não limites de requisição, erros da requisição nem cancelamentos do cliente
+func (p *Provider) registrar(err error) {
+	if p.config.LimiteFalhas <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.testando = false
+	switch {
+	case err == nil:
+		p.falhas = 0
+	case errors.Is(err, domain.ErrLLMIndisponivel), errors.Is(err, domain.ErrLLMTempoEsgotado):
+		p.falhas++
+		if p.falhas >= p.config.LimiteFalhas {
+			p.abertoAte = time.Now().Add(p.config.TempoAberto)
+		}
+	}
+}
+
+func esperar(ctx context.Context, espera time.Duration) error {
+	timer := time.NewTimer(espera)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
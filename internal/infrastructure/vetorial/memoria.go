@@ -0,0 +1,87 @@
+package vetorial
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"vend/internal/domain"
+)
+
+// tamanhoTrecho é o número máximo de caracteres do texto nos resultados
+const tamanhoTrecho = 200
+
+// Memoria é um índice vetorial em memória que compara a consulta com todos os
+// documentos (força bruta); atende bem até algumas dezenas de milhares de
+// documentos, acima disso convém um banco vetorial que implemente
+// domain.IndiceVetorial
+type Memoria struct {
+	mu   sync.RWMutex
+	docs map[string]domain.DocumentoVetorial
+}
+
+func NewMemoria() *Memoria {
+	return &Memoria{docs: make(map[string]domain.DocumentoVetorial)}
+}
+
+func (m *Memoria) Indexar(ctx context.Context, docs ...domain.DocumentoVetorial) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, doc := range docs {
+		m.docs[chave(doc.Tipo, doc.ID)] = doc
+	}
+	return nil
+}
+
+func (m *Memoria) Remover(ctx context.Context, tipo string, ids ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, id := range ids {
+		delete(m.docs, chave(tipo, id))
+	}
+	return nil
+}
+
+// Buscar ordena os documentos pela similaridade de cosseno com o vetor; vetores
+// de dimensões diferentes (outro modelo) são ignorados
+func (m *Memoria) Buscar(ctx context.Context, vetor []float32, k int, tipos ...string) ([]domain.ResultadoBusca, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	resultados := []domain.ResultadoBusca{}
+	for _, doc := range m.docs {
+		if len(tipos) > 0 && !slices.Contains(tipos, doc.Tipo) {
+			continue
+		}
+		score, ok := domain.Similaridade(vetor, doc.Vetor)
+		if !ok {
+			continue
+		}
+		resultados = append(resultados, domain.ResultadoBusca{Tipo: doc.Tipo, ID: doc.ID, Trecho: trecho(doc.Texto), Score: score})
+	}
+
+	sort.Slice(resultados, func(i, j int) bool {
+		if resultados[i].Score != resultados[j].Score {
+			return resultados[i].Score > resultados[j].Score
+		}
+		return resultados[i].ID < resultados[j].ID
+	})
+	if k > 0 && len(resultados) > k {
+		resultados = resultados[:k]
+	}
+	return resultados, nil
+}
+
+func trecho(texto string) string {
+	runas := []rune(texto)
+	if len(runas) <= tamanhoTrecho {
+		return texto
+	}
+	return string(runas[:tamanhoTrecho]) + "…"
+}
+
+func chave(tipo, id string) string {
+	return tipo + "/" + id
+}
@@ -0,0 +1,306 @@
+// Package jsonschema valida documentos JSON contra o subconjunto do JSON
+// Schema usado nas saídas estruturadas: type, properties, required,
+// additionalProperties, items, enum, minimum, maximum, minLength, maxLength,
+// minItems, maxItems e pattern.
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrSchemaInvalido = errors.New("schema inválido")
+
+var tipos = map[string]bool{
+	"string": true, "number": true, "integer": true, "boolean": true,
+	"object": true, "array": true, "null": true,
+}
+
+// Erro descreve a primeira violação encontrada e onde ela ocorreu
+type Erro struct {
+	Caminho  string
+	Mensagem string
+}
+
+func (e *Erro) Error() string {
+	return e.Caminho + ": " + e.Mensagem
+}
+
+// ValidarSchema confere se o próprio schema usa as palavras-chave suportadas
+// com valores do tipo correto
+func ValidarSchema(schema map[string]any) error {
+	schema, err := normalizar(schema)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSchemaInvalido, err)
+	}
+	return validarSchema(schema, "$")
+}
+
+func validarSchema(schema map[string]any, caminho string) error {
+	invalido := func(formato string, args ...any) error {
+		return fmt.Errorf("%w: %s: %s", ErrSchemaInvalido, caminho, fmt.Sprintf(formato, args...))
+	}
+
+	if tipo, ok := schema["type"]; ok {
+		for _, t := range listaTipos(tipo) {
+			if !tipos[t] {
+				return invalido("tipo %q desconhecido", t)
+			}
+		}
+		if len(listaTipos(tipo)) == 0 {
+			return invalido("type deve ser um texto ou uma lista de textos")
+		}
+	}
+
+	if propriedades, ok := schema["properties"]; ok {
+		mapa, ok := propriedades.(map[string]any)
+		if !ok {
+			return invalido("properties deve ser um objeto")
+		}
+		for nome, sub := range mapa {
+			subSchema, ok := sub.(map[string]any)
+			if !ok {
+				return invalido("a propriedade %s deve ser um schema", nome)
+			}
+			if err := validarSchema(subSchema, caminho+"."+nome); err != nil {
+				return err
+			}
+		}
+	}
+
+	if itens, ok := schema["items"]; ok {
+		subSchema, ok := itens.(map[string]any)
+		if !ok {
+			return invalido("items deve ser um schema")
+		}
+		if err := validarSchema(subSchema, caminho+"[]"); err != nil {
+			return err
+		}
+	}
+
+	if required, ok := schema["required"]; ok {
+		lista, ok := required.([]any)
+		if !ok {
+			return invalido("required deve ser uma lista de textos")
+		}
+		for _, nome := range lista {
+			if _, ok := nome.(string); !ok {
+				return invalido("required deve ser uma lista de textos")
+			}
+		}
+	}
+
+	if adicionais, ok := schema["additionalProperties"]; ok {
+		if _, ok := adicionais.(bool); !ok {
+			return invalido("additionalProperties deve ser booleano")
+		}
+	}
+
+	if enum, ok := schema["enum"]; ok {
+		if _, ok := enum.([]any); !ok {
+			return invalido("enum deve ser uma lista")
+		}
+	}
+
+	for _, chave := range []string{"minimum", "maximum", "minLength", "maxLength", "minItems", "maxItems"} {
+		if valor, ok := schema[chave]; ok {
+			if _, ok := valor.(float64); !ok {
+				return invalido("%s deve ser um número", chave)
+			}
+		}
+	}
+
+	if pattern, ok := schema["pattern"]; ok {
+		texto, ok := pattern.(string)
+		if !ok {
+			return invalido("pattern deve ser um texto")
+		}
+		if _, err := regexp.Compile(texto); err != nil {
+			return invalido("pattern inválido: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Validar confere o valor, decodificado com encoding/json (objetos como
+// map[string]any e números como float64), contra o schema
+func Validar(schema map[string]any, valor any) error {
+	schema, err := normalizar(schema)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSchemaInvalido, err)
+	}
+	return validar(schema, valor, "$")
+}
+
+// normalizar refaz o schema pelo encoding/json, pois schemas lidos do banco
+// trazem listas e números em outros tipos (primitive.A, int32)
+func normalizar(schema map[string]any) (map[string]any, error) {
+	dados, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	var normalizado map[string]any
+	if err := json.Unmarshal(dados, &normalizado); err != nil {
+		return nil, err
+	}
+	return normalizado, nil
+}
+
+func validar(schema map[string]any, valor any, caminho string) error {
+	erro := func(formato string, args ...any) error {
+		return &Erro{Caminho: caminho, Mensagem: fmt.Sprintf(formato, args...)}
+	}
+
+	if tipo, ok := schema["type"]; ok {
+		esperados := listaTipos(tipo)
+		if !contemTipo(esperados, valor) {
+			return erro("esperado %s, recebido %s", strings.Join(esperados, " ou "), tipoDe(valor))
+		}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok {
+		permitido := false
+		for _, opcao := range enum {
+			if reflect.DeepEqual(opcao, valor) {
+				permitido = true
+				break
+			}
+		}
+		if !permitido {
+			return erro("valor %v fora de enum %v", valor, enum)
+		}
+	}
+
+	switch v := valor.(type) {
+	case string:
+		tamanho := float64(utf8.RuneCountInString(v))
+		if min, ok := schema["minLength"].(float64); ok && tamanho < min {
+			return erro("texto menor que %v caracteres", min)
+		}
+		if max, ok := schema["maxLength"].(float64); ok && tamanho > max {
+			return erro("texto maior que %v caracteres", max)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(v) {
+				return erro("texto não corresponde a %s", pattern)
+			}
+		}
+
+	case float64:
+		if min, ok := schema["minimum"].(float64); ok && v < min {
+			return erro("valor %v menor que %v", v, min)
+		}
+		if max, ok := schema["maximum"].(float64); ok && v > max {
+			return erro("valor %v maior que %v", v, max)
+		}
+
+	case []any:
+		if min, ok := schema["minItems"].(float64); ok && float64(len(v)) < min {
+			return erro("lista com menos de %v itens", min)
+		}
+		if max, ok := schema["maxItems"].(float64); ok && float64(len(v)) > max {
+			return erro("lista com mais de %v itens", max)
+		}
+		if itens, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := validar(itens, item, fmt.Sprintf("%s[%d]", caminho, i)); err != nil {
+					return err
+				}
+			}
+		}
+
+	case map[string]any:
+		if required, ok := schema["required"].([]any); ok {
+			for _, nome := range required {
+				if _, presente := v[nome.(string)]; !presente {
+					return erro("propriedade obrigatória %s ausente", nome)
+				}
+			}
+		}
+
+		propriedades, _ := schema["properties"].(map[string]any)
+		nomes := make([]string, 0, len(v))
+		for nome := range v {
+			nomes = append(nomes, nome)
+		}
+		sort.Strings(nomes)
+		for _, nome := range nomes {
+			sub, declarada := propriedades[nome].(map[string]any)
+			if !declarada {
+				if adicionais, ok := schema["additionalProperties"].(bool); ok && !adicionais {
+					return erro("propriedade %s não permitida", nome)
+				}
+				continue
+			}
+			if err := validar(sub, v[nome], caminho+"."+nome); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func listaTipos(tipo any) []string {
+	switch t := tipo.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		lista := make([]string, 0, len(t))
+		for _, item := range t {
+			texto, ok := item.(string)
+			if !ok {
+				return nil
+			}
+			lista = append(lista, texto)
+		}
+		return lista
+	default:
+		return nil
+	}
+}
+
+func contemTipo(esperados []string, valor any) bool {
+	atual := tipoDe(valor)
+	for _, esperado := range esperados {
+		if esperado == atual {
+			return true
+		}
+		// Todo inteiro também é um number
+		if esperado == "number" && atual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+func tipoDe(valor any) string {
+	switch v := valor.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", valor)
+	}
+}
@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"vend/internal/domain"
+)
+
+const (
+	limiteBuscaTextoPadrao = 20
+	limiteBuscaTextoMaximo = 100
+	// margemDestaque é quantos bytes de cada lado da primeira palavra
+	// encontrada entram no trecho destacado
+	margemDestaque = 60
+	tamanhoTitulo  = 80
+)
+
+// BuscarTexto retorna os registros com as palavras de q, dos mais relevantes
+// aos menos; tipo vazio busca em pessoas, contextos e prompts
+func (u *BuscaUseCase) BuscarTexto(q, tipo string, limite int) ([]domain.ResultadoTexto, error) {
+	termos := domain.TermosBusca(q)
+	if len(termos) == 0 {
+		return nil, ErrConsultaVazia
+	}
+	if limite <= 0 {
+		limite = limiteBuscaTextoPadrao
+	}
+	if limite > limiteBuscaTextoMaximo {
+		limite = limiteBuscaTextoMaximo
+	}
+
+	tipos := []string{domain.TipoBuscaPessoa, domain.TipoBuscaContexto, domain.TipoBuscaPrompt}
+	if tipo != "" {
+		if _, ok := domain.CamposBuscaTexto[tipo]; !ok {
+			return nil, fmt.Errorf("%w: tipo %q (use %s)", ErrParametroInvalido, tipo, strings.Join(tipos, ", "))
+		}
+		tipos = []string{tipo}
+	}
+
+	resultados, err := u.repo.BuscarTexto(domain.BuscaTextoFiltro{Termos: termos, Tipos: tipos, Limite: limite})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(resultados, func(i, j int) bool { return resultados[i].Score > resultados[j].Score })
+	if len(resultados) > limite {
+		resultados = resultados[:limite]
+	}
+	for i := range resultados {
+		destacar(&resultados[i], termos)
+	}
+	return resultados, nil
+}
+
+// destacar preenche o título e, para cada campo com algum termo, um trecho em
+// volta da primeira palavra encontrada, com o HTML escapado e as palavras
+// encontradas entre <em> e </em>
+func destacar(resultado *domain.ResultadoTexto, termos []string) {
+	campos := domain.CamposBuscaTexto[resultado.Tipo]
+	if len(campos) > 0 {
+		resultado.Titulo = resumir(resultado.Campos[campos[0]], tamanhoTitulo)
+	}
+
+	resultado.Destaques = []domain.Destaque{}
+	for _, campo := range campos {
+		texto := resultado.Campos[campo]
+		posicoes := domain.PalavrasComTermo(texto, termos)
+		if len(posicoes) == 0 {
+			continue
+		}
+
+		// O trecho começa e termina em espaços, para não cortar palavras
+		inicio, fim := 0, len(texto)
+		if posicoes[0][0] > margemDestaque {
+			inicio = posicoes[0][0]
+			if espaco := strings.IndexByte(texto[inicio-margemDestaque:inicio], ' '); espaco >= 0 {
+				inicio += espaco + 1 - margemDestaque
+			}
+		}
+		if posicoes[0][1]+margemDestaque < len(texto) {
+			fim = posicoes[0][1]
+			if espaco := strings.LastIndexByte(texto[fim:fim+margemDestaque], ' '); espaco >= 0 {
+				fim += espaco
+			}
+		}
+
+		var b strings.Builder
+		if inicio > 0 {
+			b.WriteString("…")
+		}
+		atual := inicio
+		for _, p := range posicoes {
+			if p[0] < inicio || p[1] > fim {
+				continue
+			}
+			b.WriteString(html.EscapeString(texto[atual:p[0]]))
+			b.WriteString("<em>" + html.EscapeString(texto[p[0]:p[1]]) + "</em>")
+			atual = p[1]
+		}
+		b.WriteString(html.EscapeString(texto[atual:fim]))
+		if fim < len(texto) {
+			b.WriteString("…")
+		}
+		resultado.Destaques = append(resultado.Destaques, domain.Destaque{Campo: campo, Trecho: b.String()})
+	}
+}
+
+// resumir corta o texto na primeira linha e em até n caracteres
+func resumir(texto string, n int) string {
+	texto, _, _ = strings.Cut(strings.TrimSpace(texto), "\n")
+	if runas := []rune(texto); len(runas) > n {
+		return strings.TrimSpace(string(runas[:n])) + "…"
+	}
+	return texto
+}
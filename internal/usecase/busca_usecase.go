@@ -0,0 +1,269 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"vend/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// loteEmbeddings é quantos textos vão em cada chamada de embeddings
+	loteEmbeddings = 64
+	// paginaSincronizacao é quantos prompts ou respostas a sincronização
+	// carrega de cada vez
+	paginaSincronizacao = 500
+	kPadrao             = 10
+	kMaximo             = 50
+)
+
+var (
+	ErrBuscaIndisponivel = errors.New("o provedor de LLM configurado não gera embeddings")
+	ErrConsultaVazia     = errors.New("informe o texto da busca")
+)
+
+// BuscaUseCase mantém o índice vetorial de prompts e respostas e responde
+// às buscas semânticas
+type BuscaUseCase struct {
+	repo       Repository
+	embeddings domain.ProvedorEmbeddings
+	consumo    *ConsumoUseCase
+	indice     domain.IndiceVetorial
+	intervalo  time.Duration
+
+	mu sync.Mutex
+	// indexados guarda o hash de cada documento presente no índice, por tipo
+	indexados map[string]map[string]string
+}
+
+// NewBuscaUseCase recebe embeddings nil quando o provedor não os oferece; a
+// busca então retorna ErrBuscaIndisponivel. Os embeddings de prompts e
+// respostas entram no consumo do contexto deles.
+func NewBuscaUseCase(repo Repository, embeddings domain.ProvedorEmbeddings, consumo *ConsumoUseCase, indice domain.IndiceVetorial, intervalo time.Duration) *BuscaUseCase {
+	return &BuscaUseCase{
+		repo:       repo,
+		embeddings: embeddings,
+		consumo:    consumo,
+		indice:     indice,
+		intervalo:  intervalo,
+		indexados: map[string]map[string]string{
+			domain.TipoBuscaPrompt:   {},
+			domain.TipoBuscaResposta: {},
+		},
+	}
+}
+
+// BuscarSemantica retorna os k documentos mais parecidos com q; tipo vazio
+// busca em prompts e respostas. A busca abrange todos os contextos, então o
+// embedding da consulta não entra no consumo de nenhum.
+func (u *BuscaUseCase) BuscarSemantica(ctx context.Context, q string, k int, tipo string) ([]domain.ResultadoBusca, error) {
+	if u.embeddings == nil {
+		return nil, ErrBuscaIndisponivel
+	}
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return nil, ErrConsultaVazia
+	}
+	if k <= 0 {
+		k = kPadrao
+	}
+	if k > kMaximo {
+		k = kMaximo
+	}
+
+	var tipos []string
+	switch tipo {
+	case "":
+	case domain.TipoBuscaPrompt, domain.TipoBuscaResposta:
+		tipos = []string{tipo}
+	default:
+		return nil, fmt.Errorf("%w: tipo %q (use %s ou %s)", ErrParametroInvalido, tipo, domain.TipoBuscaPrompt, domain.TipoBuscaResposta)
+	}
+
+	resultado, err := u.embeddings.Embeddings(ctx, []string{q})
+	if err != nil {
+		return nil, err
+	}
+	if len(resultado.Vetores) != 1 {
+		return nil, fmt.Errorf("%w: embedding da consulta ausente", domain.ErrLLMRespostaVazia)
+	}
+	return u.indice.Buscar(ctx, resultado.Vetores[0], k, tipos...)
+}
+
+// Iniciar sincroniza o índice ao subir e depois a cada intervalo, até ctx ser
+// cancelado. Documentos novos ou alterados ganham embeddings, gravados junto
+// ao documento para não serem recalculados após um reinício.
+func (u *BuscaUseCase) Iniciar(ctx context.Context) {
+	if u.embeddings == nil {
+		return
+	}
+	for {
+		if err := u.Sincronizar(ctx); err != nil {
+			log.Printf("Erro ao sincronizar o índice de busca: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(u.intervalo):
+		}
+	}
+}
+
+// Sincronizar indexa os prompts e respostas existentes e retira do índice os
+// documentos que não existem mais ou ficaram sem texto. Os registros são
+// lidos em páginas de paginaSincronizacao, pelo cursor das listagens.
+func (u *BuscaUseCase) Sincronizar(ctx context.Context) error {
+	if u.embeddings == nil {
+		return ErrBuscaIndisponivel
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	err := u.sincronizarTipo(ctx, domain.TipoBuscaPrompt, func(apos *domain.Marcador) ([]documentoBusca, error) {
+		prompts, _, err := u.repo.ListPrompts(domain.PromptFiltro{Paginacao: domain.Paginacao{Limite: paginaSincronizacao, Apos: apos}})
+		docs := make([]documentoBusca, len(prompts))
+		for i, p := range prompts {
+			docs[i] = documentoBusca{id: p.ID, criadoEm: p.CreatedAt, contextoID: p.ContextoID, texto: p.Conteudo, embedding: p.Embedding}
+		}
+		return docs, err
+	}, u.repo.UpdatePromptEmbedding)
+	if err != nil {
+		return err
+	}
+
+	return u.sincronizarTipo(ctx, domain.TipoBuscaResposta, func(apos *domain.Marcador) ([]documentoBusca, error) {
+		respostas, err := u.repo.ListRespostas(domain.RespostaFiltro{Paginacao: domain.Paginacao{Limite: paginaSincronizacao, Apos: apos}})
+		docs := make([]documentoBusca, len(respostas))
+		for i, r := range respostas {
+			docs[i] = documentoBusca{id: r.ID, criadoEm: r.CreatedAt, contextoID: r.ContextoID, texto: r.Conteudo, embedding: r.Embedding}
+		}
+		return docs, err
+	}, u.repo.UpdateRespostaEmbedding)
+}
+
+type documentoBusca struct {
+	id         primitive.ObjectID
+	criadoEm   time.Time
+	contextoID primitive.ObjectID
+	texto      string
+	embedding  *domain.Embedding
+}
+
+// sincronizarTipo percorre as páginas de listar, dos registros mais novos aos
+// mais antigos, e depois retira do índice os que não apareceram
+func (u *BuscaUseCase) sincronizarTipo(ctx context.Context, tipo string, listar func(apos *domain.Marcador) ([]documentoBusca, error), gravar func(string, *domain.Embedding) error) error {
+	indexados := u.indexados[tipo]
+	existentes := map[string]bool{}
+
+	var apos *domain.Marcador
+	for {
+		docs, err := listar(apos)
+		if err != nil {
+			return err
+		}
+		if err := u.indexar(ctx, tipo, docs, existentes, gravar); err != nil {
+			return err
+		}
+		if len(docs) < paginaSincronizacao {
+			break
+		}
+		ultimo := docs[len(docs)-1]
+		apos = &domain.Marcador{Valor: ultimo.criadoEm, ID: ultimo.id}
+	}
+
+	var removidos []string
+	for id := range indexados {
+		if !existentes[id] {
+			removidos = append(removidos, id)
+		}
+	}
+	if len(removidos) > 0 {
+		if err := u.indice.Remover(ctx, tipo, removidos...); err != nil {
+			return err
+		}
+		for _, id := range removidos {
+			delete(indexados, id)
+		}
+	}
+	return nil
+}
+
+// indexar põe no índice os documentos de uma página, calculando os embeddings
+// que faltam ou estão desatualizados, e marca em existentes os que têm texto
+func (u *BuscaUseCase) indexar(ctx context.Context, tipo string, docs []documentoBusca, existentes map[string]bool, gravar func(string, *domain.Embedding) error) error {
+	indexados := u.indexados[tipo]
+
+	var pendentes []documentoBusca
+	for _, doc := range docs {
+		if strings.TrimSpace(doc.texto) == "" {
+			continue
+		}
+		id := doc.id.Hex()
+		existentes[id] = true
+		if doc.embedding != nil && doc.embedding.Hash == hashTexto(doc.embedding.Modelo, doc.texto) {
+			if indexados[id] != doc.embedding.Hash {
+				if err := u.indice.Indexar(ctx, domain.DocumentoVetorial{Tipo: tipo, ID: id, Texto: doc.texto, Vetor: doc.embedding.Vetor}); err != nil {
+					return err
+				}
+				indexados[id] = doc.embedding.Hash
+			}
+			continue
+		}
+		pendentes = append(pendentes, doc)
+	}
+
+	// Cada lote é de um só contexto, para o uso ser atribuído a ele
+	sort.SliceStable(pendentes, func(i, j int) bool {
+		return pendentes[i].contextoID.Hex() < pendentes[j].contextoID.Hex()
+	})
+	for inicio := 0; inicio < len(pendentes); {
+		fim := inicio + 1
+		for fim < len(pendentes) && fim-inicio < loteEmbeddings && pendentes[fim].contextoID == pendentes[inicio].contextoID {
+			fim++
+		}
+		lote := pendentes[inicio:fim]
+		inicio = fim
+		textos := make([]string, len(lote))
+		for i, doc := range lote {
+			textos[i] = doc.texto
+		}
+
+		resultado, err := u.embeddings.Embeddings(ctx, textos)
+		if err != nil {
+			return err
+		}
+		if err := u.consumo.RegistrarEmbeddings(lote[0].contextoID, resultado); err != nil {
+			return err
+		}
+		if len(resultado.Vetores) != len(textos) {
+			return fmt.Errorf("%w: %d embeddings para %d textos", domain.ErrLLMRespostaVazia, len(resultado.Vetores), len(textos))
+		}
+		for i, doc := range lote {
+			id := doc.id.Hex()
+			embedding := &domain.Embedding{Modelo: resultado.Modelo, Hash: hashTexto(resultado.Modelo, doc.texto), Vetor: resultado.Vetores[i]}
+			if err := gravar(id, embedding); err != nil {
+				return err
+			}
+			if err := u.indice.Indexar(ctx, domain.DocumentoVetorial{Tipo: tipo, ID: id, Texto: doc.texto, Vetor: embedding.Vetor}); err != nil {
+				return err
+			}
+			indexados[id] = embedding.Hash
+		}
+	}
+	return nil
+}
+
+func hashTexto(modelo, texto string) string {
+	soma := sha256.Sum256([]byte(modelo + "\x00" + texto))
+	return hex.EncodeToString(soma[:])
+}
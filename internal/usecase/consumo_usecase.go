@@ -0,0 +1,169 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"vend/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrOrcamentoExcedido = errors.New("orçamento mensal de tokens do contexto excedido")
+
+// PrecoModelo é o preço em US$ por 1.000 tokens de entrada e de saída
+type PrecoModelo struct {
+	Prompt     float64
+	Completion float64
+}
+
+// TabelaPrecos é indexada pelo nome do modelo; nomes com sufixo de versão
+// (ex. gpt-4o-2024-08-06) usam o preço do prefixo mais longo cadastrado
+type TabelaPrecos map[string]PrecoModelo
+
+func PrecosPadrao() TabelaPrecos {
+	return TabelaPrecos{
+		"gpt-3.5-turbo": {Prompt: 0.0005, Completion: 0.0015},
+		"gpt-4":         {Prompt: 0.03, Completion: 0.06},
+		"gpt-4-turbo":   {Prompt: 0.01, Completion: 0.03},
+		"gpt-4o":        {Prompt: 0.005, Completion: 0.015},
+		"gpt-4o-mini":   {Prompt: 0.00015, Completion: 0.0006},
+		// Os embeddings só cobram a entrada
+		"text-embedding-3-small": {Prompt: 0.00002},
+		"text-embedding-3-large": {Prompt: 0.00013},
+		"text-embedding-ada-002": {Prompt: 0.0001},
+	}
+}
+
+// ParsePrecos lê preços no formato "modelo=prompt:completion;outro=prompt:completion"
+// e os sobrepõe aos preços padrão
+func ParsePrecos(texto string) (TabelaPrecos, error) {
+	precos := PrecosPadrao()
+	for _, item := range strings.Split(texto, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		modelo, valores, ok := strings.Cut(item, "=")
+		prompt, completion, ok2 := strings.Cut(valores, ":")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("preço inválido: %q", item)
+		}
+
+		p, err := strconv.ParseFloat(prompt, 64)
+		if err != nil {
+			return nil, fmt.Errorf("preço inválido: %q", item)
+		}
+		c, err := strconv.ParseFloat(completion, 64)
+		if err != nil {
+			return nil, fmt.Errorf("preço inválido: %q", item)
+		}
+		precos[strings.TrimSpace(modelo)] = PrecoModelo{Prompt: p, Completion: c}
+	}
+	return precos, nil
+}
+
+// Custo estima o custo do uso de tokens; modelos sem preço custam zero
+func (t TabelaPrecos) Custo(modelo string, uso domain.UsoTokens) float64 {
+	preco, encontrado := t[modelo]
+	if !encontrado {
+		maior := 0
+		for nome, p := range t {
+			if strings.HasPrefix(modelo, nome) && len(nome) > maior {
+				preco, maior = p, len(nome)
+			}
+		}
+	}
+	return float64(uso.PromptTokens)/1000*preco.Prompt + float64(uso.CompletionTokens)/1000*preco.Completion
+}
+
+type ConsumoUseCase struct {
+	repo   Repository
+	precos TabelaPrecos
+}
+
+func NewConsumoUseCase(repo Repository, precos TabelaPrecos) *ConsumoUseCase {
+	return &ConsumoUseCase{repo: repo, precos: precos}
+}
+
+// ConsumoContexto soma o consumo do contexto no mês de referência e o compara ao orçamento
+func (u *ConsumoUseCase) ConsumoContexto(contextoID string, referencia time.Time) (*domain.Consumo, error) {
+	contexto, err := u.repo.GetContexto(contextoID)
+	if err != nil {
+		return nil, naoEncontrado(ErrContextoNaoEncontrado, err)
+	}
+
+	return u.consumoContexto(contexto, referencia)
+}
+
+// ConsumoPessoa soma o consumo das gerações destinadas à pessoa no mês de referência
+func (u *ConsumoUseCase) ConsumoPessoa(pessoaID string, referencia time.Time) (*domain.Consumo, error) {
+	if _, err := u.repo.GetPessoa(pessoaID); err != nil {
+		return nil, naoEncontrado(ErrPessoaNaoEncontrada, err)
+	}
+
+	inicio, fim := mes(referencia)
+	return u.repo.SomarConsumo(domain.ConsumoFiltro{PessoaID: pessoaID, Inicio: inicio, Fim: fim})
+}
+
+// VerificarOrcamento retorna ErrOrcamentoExcedido se o contexto já usou todo
+// o orçamento de tokens do mês corrente
+func (u *ConsumoUseCase) VerificarOrcamento(contexto *domain.Contexto) error {
+	if contexto.OrcamentoTokensMensal <= 0 {
+		return nil
+	}
+
+	consumo, err := u.consumoContexto(contexto, time.Now())
+	if err != nil {
+		return err
+	}
+	if consumo.TotalTokens >= contexto.OrcamentoTokensMensal {
+		return fmt.Errorf("%w: %d de %d tokens usados", ErrOrcamentoExcedido, consumo.TotalTokens, contexto.OrcamentoTokensMensal)
+	}
+	return nil
+}
+
+// RegistrarEmbeddings soma ao consumo do contexto os tokens de uma chamada de
+// embeddings. Sem contexto (prompts avulsos e a busca semântica geral) o uso
+// não tem a quem ser atribuído e não é registrado.
+func (u *ConsumoUseCase) RegistrarEmbeddings(contextoID primitive.ObjectID, resultado *domain.ResultadoEmbeddings) error {
+	if u == nil || contextoID.IsZero() {
+		return nil
+	}
+	return u.repo.CreateUsoEmbeddings(&domain.UsoEmbeddings{
+		ContextoID:    contextoID,
+		Modelo:        resultado.Modelo,
+		Uso:           resultado.Uso,
+		CustoEstimado: u.Custo(resultado.Modelo, resultado.Uso),
+	})
+}
+
+// Custo estima o custo de uma geração pela tabela de preços
+func (u *ConsumoUseCase) Custo(modelo string, uso domain.UsoTokens) float64 {
+	return u.precos.Custo(modelo, uso)
+}
+
+func (u *ConsumoUseCase) consumoContexto(contexto *domain.Contexto, referencia time.Time) (*domain.Consumo, error) {
+	inicio, fim := mes(referencia)
+	consumo, err := u.repo.SomarConsumo(domain.ConsumoFiltro{ContextoID: contexto.ID.Hex(), Inicio: inicio, Fim: fim})
+	if err != nil {
+		return nil, err
+	}
+
+	if contexto.OrcamentoTokensMensal > 0 {
+		consumo.OrcamentoTokens = contexto.OrcamentoTokensMensal
+		restantes := max(contexto.OrcamentoTokensMensal-consumo.TotalTokens, 0)
+		consumo.TokensRestantes = &restantes
+	}
+	return consumo, nil
+}
+
+// mes retorna o início do mês (UTC) de referência e o início do mês seguinte
+func mes(referencia time.Time) (time.Time, time.Time) {
+	referencia = referencia.UTC()
+	inicio := time.Date(referencia.Year(), referencia.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return inicio, inicio.AddDate(0, 1, 0)
+}
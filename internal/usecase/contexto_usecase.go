@@ -1,33 +1,117 @@
 package usecase
 
 import (
+	"errors"
+	"fmt"
+	"slices"
 	"vend/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type ContextoUseCase struct {
-	repo Repository
+	repo    Repository
+	modelos ModelosPermitidos
+	regras  RegrasRemocao
+}
+
+func NewContextoUseCase(repo Repository, modelos ModelosPermitidos) *ContextoUseCase {
+	return &ContextoUseCase{repo: repo, modelos: modelos, regras: RegrasRemocaoPadrao()}
 }
 
-func NewContextoUseCase(repo Repository) *ContextoUseCase {
-	return &ContextoUseCase{repo: repo}
+// UsarRegrasRemocao troca a regra aplicada aos prompts ao remover um contexto
+func (u *ContextoUseCase) UsarRegrasRemocao(regras RegrasRemocao) {
+	u.regras = regras
 }
 
 func (u *ContextoUseCase) CreateContexto(contexto *domain.Contexto) error {
-	return u.repo.CreateContexto(contexto)
+	if err := u.modelos.Validar(contexto.Parametros); err != nil {
+		return err
+	}
+	if err := ValidarPrivacidade(contexto.Privacidade); err != nil {
+		return err
+	}
+
+	// As pessoas são referências: precisam existir e entram uma vez cada
+	pessoaIDs := make([]primitive.ObjectID, 0, len(contexto.PessoaIDs))
+	for _, id := range contexto.PessoaIDs {
+		if slices.Contains(pessoaIDs, id) {
+			continue
+		}
+		if _, err := u.repo.GetPessoa(id.Hex()); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrPessoaNaoEncontrada, id.Hex(), err)
+		}
+		pessoaIDs = append(pessoaIDs, id)
+	}
+	contexto.PessoaIDs = pessoaIDs
+	if err := u.repo.CreateContexto(contexto); err != nil {
+		return err
+	}
+	return u.recarregar(contexto)
 }
 
 func (u *ContextoUseCase) GetContexto(id string) (*domain.Contexto, error) {
 	return u.repo.GetContexto(id)
 }
 
-func (u *ContextoUseCase) ListContextos() ([]domain.Contexto, error) {
-	return u.repo.ListContextos()
+// ListContextos retorna uma página dos contextos aceitos pelo filtro
+func (u *ContextoUseCase) ListContextos(filtro domain.ContextoFiltro, pagina ParametrosPagina) (*domain.Pagina[domain.Contexto], error) {
+	return paginar(pagina, ordenacaoContextos, func(p domain.Paginacao) ([]domain.Contexto, int64, error) {
+		filtro.Paginacao = p
+		return u.repo.ListContextos(filtro)
+	})
 }
 
 func (u *ContextoUseCase) UpdateContexto(contexto *domain.Contexto) error {
-	return u.repo.UpdateContexto(contexto)
+	if err := u.modelos.Validar(contexto.Parametros); err != nil {
+		return err
+	}
+	if err := ValidarPrivacidade(contexto.Privacidade); err != nil {
+		return err
+	}
+	if err := u.repo.UpdateContexto(contexto); err != nil {
+		return err
+	}
+	return u.recarregar(contexto)
+}
+
+// AddPessoa põe a pessoa no contexto e retorna o contexto com as pessoas
+func (u *ContextoUseCase) AddPessoa(contextoID, pessoaID string) (*domain.Contexto, error) {
+	if _, err := u.repo.GetPessoa(pessoaID); err != nil {
+		return nil, naoEncontrado(ErrPessoaNaoEncontrada, err)
+	}
+	if err := u.repo.AddPessoaContexto(contextoID, pessoaID); err != nil {
+		return nil, naoEncontrado(ErrContextoNaoEncontrado, err)
+	}
+	return u.repo.GetContexto(contextoID)
+}
+
+// RemovePessoa tira a pessoa do contexto; a pessoa continua cadastrada
+func (u *ContextoUseCase) RemovePessoa(contextoID, pessoaID string) error {
+	if err := u.repo.RemovePessoaContexto(contextoID, pessoaID); err != nil {
+		if errors.Is(err, domain.ErrNaoEncontrado) {
+			return fmt.Errorf("%w: pessoa %s não está no contexto %s", err, pessoaID, contextoID)
+		}
+		return err
+	}
+	return nil
+}
+
+// recarregar substitui o contexto gravado pelo lido do repositório, com as
+// pessoas referenciadas e os campos que o repositório preenche
+func (u *ContextoUseCase) recarregar(contexto *domain.Contexto) error {
+	atual, err := u.repo.GetContexto(contexto.ID.Hex())
+	if err != nil {
+		return err
+	}
+	*contexto = *atual
+	return nil
 }
 
-func (u *ContextoUseCase) DeleteContexto(id string) error {
-	return u.repo.DeleteContexto(id)
+// DeleteContexto move o contexto para a lixeira, aplicando a regra de
+// remoção aos seus prompts; retorna ErrRemocaoRestrita se a regra restrict
+// impedir a remoção
+func (u *ContextoUseCase) DeleteContexto(id, usuario string) error {
+	relacoes := []domain.Relacao{domain.RelacaoContextoPrompts}
+	return removerComRegras(u.repo, u.regras, id, novaRemocao(usuario), relacoes, Repository.DeleteContexto)
 }
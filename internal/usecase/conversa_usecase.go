@@ -0,0 +1,141 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+	"vend/internal/domain"
+)
+
+// historicoMaximo é quantas mensagens da conversa, contando a nova, vão ao
+// modelo depois da mensagem de sistema; as mais antigas ficam de fora
+const historicoMaximo = 20
+
+var (
+	ErrPessoaNaoEncontrada   = errors.New("pessoa não encontrada")
+	ErrConversaNaoEncontrada = errors.New("conversa não encontrada")
+)
+
+type ConversaUseCase struct {
+	repo        Repository
+	provider    domain.LLMProvider
+	consumo     *ConsumoUseCase
+	modelos     ModelosPermitidos
+	ferramentas *Ferramentas
+	documentos  *DocumentoUseCase
+}
+
+func NewConversaUseCase(repo Repository, provider domain.LLMProvider, consumo *ConsumoUseCase, modelos ModelosPermitidos, ferramentas *Ferramentas, documentos *DocumentoUseCase) *ConversaUseCase {
+	return &ConversaUseCase{repo: repo, provider: provider, consumo: consumo, modelos: modelos, ferramentas: ferramentas, documentos: documentos}
+}
+
+func (u *ConversaUseCase) IniciarConversa(pessoaID, contextoID string) (*domain.Conversa, error) {
+	pessoa, err := u.repo.GetPessoa(pessoaID)
+	if err != nil {
+		return nil, naoEncontrado(ErrPessoaNaoEncontrada, err)
+	}
+
+	contexto, err := u.repo.GetContexto(contextoID)
+	if err != nil {
+		return nil, naoEncontrado(ErrContextoNaoEncontrado, err)
+	}
+
+	conversa := &domain.Conversa{PessoaID: pessoa.ID, ContextoID: contexto.ID}
+	if err := u.repo.CreateConversa(conversa); err != nil {
+		return nil, err
+	}
+
+	return conversa, nil
+}
+
+func (u *ConversaUseCase) GetConversa(id string) (*domain.Conversa, error) {
+	return u.repo.GetConversa(id)
+}
+
+func (u *ConversaUseCase) ListConversas(filtro domain.ConversaFiltro) ([]domain.Conversa, error) {
+	return u.repo.ListConversas(filtro)
+}
+
+// EnviarMensagem envia ao modelo as últimas mensagens da conversa seguidas da
+// nova mensagem do usuário e acrescenta a pergunta e a resposta à conversa
+func (u *ConversaUseCase) EnviarMensagem(ctx context.Context, conversaID, conteudo string) (*domain.MensagemConversa, error) {
+	conversa, err := u.repo.GetConversa(conversaID)
+	if err != nil {
+		return nil, naoEncontrado(ErrConversaNaoEncontrada, err)
+	}
+
+	contexto, err := u.repo.GetContexto(conversa.ContextoID.Hex())
+	if err != nil {
+		return nil, naoEncontrado(ErrContextoNaoEncontrado, err)
+	}
+
+	if err := u.consumo.VerificarOrcamento(contexto); err != nil {
+		return nil, err
+	}
+
+	pessoa, err := u.repo.GetPessoa(conversa.PessoaID.Hex())
+	if err != nil {
+		return nil, naoEncontrado(ErrPessoaNaoEncontrada, err)
+	}
+
+	pergunta := domain.MensagemConversa{Papel: domain.PapelUsuario, Conteudo: conteudo, CreatedAt: time.Now()}
+	parametros, err := u.modelos.parametrosEfetivos(contexto.Parametros)
+	if err != nil {
+		return nil, err
+	}
+	ctx = domain.ComPrivacidade(ctx, privacidadeDe(contexto, pessoa))
+	trechos, fontes, err := u.documentos.recuperar(ctx, contexto.ID, conteudo)
+	if err != nil {
+		return nil, err
+	}
+	req := comDocumentos(requisicaoConversa(contexto, pessoa, append(conversa.Mensagens, pergunta), parametros), trechos)
+	if req.Ferramentas, err = u.ferramentas.selecionar(parametros.Ferramentas); err != nil {
+		return nil, err
+	}
+
+	inicio := time.Now()
+	resultado, errGeracao := gerarComFerramentas(ctx, u.provider, u.ferramentas, req)
+	if resultado == nil {
+		return nil, errGeracao
+	}
+
+	resposta := novaResposta(req, resultado, inicio)
+	resposta.CustoEstimado = u.consumo.Custo(resultado.Modelo, resultado.Uso)
+	resposta.ContextoID = contexto.ID
+	resposta.PessoaID = pessoa.ID
+	resposta.ConversaID = conversa.ID
+	resposta.Fontes = fontes
+	if err := u.repo.CreateResposta(resposta); err != nil {
+		return nil, err
+	}
+	// Se o provedor falhou numa rodada de ferramentas, a resposta registra os
+	// tokens já consumidos, mas a conversa não recebe a troca
+	if errGeracao != nil {
+		return nil, errGeracao
+	}
+
+	assistente := domain.MensagemConversa{Papel: domain.PapelAssistente, Conteudo: resultado.Conteudo, Fontes: fontes, CreatedAt: time.Now()}
+	if err := u.repo.AppendMensagensConversa(conversaID, []domain.MensagemConversa{pergunta, assistente}); err != nil {
+		return nil, err
+	}
+
+	return &assistente, nil
+}
+
+// requisicaoConversa monta a mensagem de sistema seguida das últimas
+// historicoMaximo mensagens, começando por uma do usuário
+func requisicaoConversa(contexto *domain.Contexto, pessoa *domain.Pessoa, historico []domain.MensagemConversa, parametros domain.ParametrosGeracao) domain.RequisicaoGeracao {
+	if len(historico) > historicoMaximo {
+		historico = historico[len(historico)-historicoMaximo:]
+		for len(historico) > 1 && historico[0].Papel != domain.PapelUsuario {
+			historico = historico[1:]
+		}
+	}
+
+	mensagens := []domain.Mensagem{{Papel: domain.PapelSistema, Conteudo: mensagemSistema(contexto, pessoa)}}
+	for _, m := range historico {
+		mensagens = append(mensagens, domain.Mensagem{Papel: m.Papel, Conteudo: m.Conteudo})
+	}
+
+	return novaRequisicao(parametros, mensagens)
+}
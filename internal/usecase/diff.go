@@ -0,0 +1,51 @@
+package usecase
+
+import "strings"
+
+// DiffLinhas compara dois textos linha a linha (maior subsequência comum).
+// Cada linha do resultado começa com "  " (mantida), "- " (removida) ou "+ " (adicionada).
+func DiffLinhas(antes, depois string) string {
+	a := dividirLinhas(antes)
+	b := dividirLinhas(depois)
+
+	// lcs[i][j] é o tamanho da maior subsequência comum entre a[i:] e b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			diff.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			diff.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			diff.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+
+	return diff.String()
+}
+
+func dividirLinhas(texto string) []string {
+	if texto == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(texto, "\n"), "\n")
+}
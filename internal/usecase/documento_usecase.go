@@ -0,0 +1,241 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	"vend/internal/domain"
+	"vend/internal/extracao"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// tamanhoTrechoDocumento é o número máximo de caracteres de cada trecho
+	tamanhoTrechoDocumento = 1200
+	// trechosPorGeracao é quantos trechos entram na mensagem de sistema
+	trechosPorGeracao = 4
+)
+
+var ErrDocumentoNaoEncontrado = errors.New("documento não encontrado")
+
+// DocumentoUseCase recebe os documentos dos contextos e recupera os trechos
+// mais relevantes para cada geração
+type DocumentoUseCase struct {
+	repo       Repository
+	embeddings domain.ProvedorEmbeddings
+	consumo    *ConsumoUseCase
+}
+
+// NewDocumentoUseCase recebe embeddings nil quando o provedor não os oferece;
+// o envio de documentos então retorna ErrBuscaIndisponivel. Os tokens dos
+// embeddings entram no consumo do contexto.
+func NewDocumentoUseCase(repo Repository, embeddings domain.ProvedorEmbeddings, consumo *ConsumoUseCase) *DocumentoUseCase {
+	return &DocumentoUseCase{repo: repo, embeddings: embeddings, consumo: consumo}
+}
+
+// AdicionarDocumento extrai o texto do arquivo, o divide em trechos e grava
+// os trechos com os seus embeddings
+func (u *DocumentoUseCase) AdicionarDocumento(ctx context.Context, contextoID, nome string, dados []byte) (*domain.Documento, error) {
+	if u.embeddings == nil {
+		return nil, ErrBuscaIndisponivel
+	}
+
+	contexto, err := u.repo.GetContexto(contextoID)
+	if err != nil {
+		return nil, naoEncontrado(ErrContextoNaoEncontrado, err)
+	}
+
+	formato, err := extracao.Formato(nome)
+	if err != nil {
+		return nil, err
+	}
+	texto, err := extracao.Extrair(formato, dados)
+	if err != nil {
+		return nil, err
+	}
+
+	partes := DividirTexto(texto, tamanhoTrechoDocumento)
+	trechos := make([]domain.TrechoDocumento, 0, len(partes))
+	for inicio := 0; inicio < len(partes); inicio += loteEmbeddings {
+		lote := partes[inicio:min(inicio+loteEmbeddings, len(partes))]
+		resultado, err := u.embeddings.Embeddings(ctx, lote)
+		if err != nil {
+			return nil, err
+		}
+		if err := u.consumo.RegistrarEmbeddings(contexto.ID, resultado); err != nil {
+			return nil, err
+		}
+		if len(resultado.Vetores) != len(lote) {
+			return nil, fmt.Errorf("%w: %d embeddings para %d trechos", domain.ErrLLMRespostaVazia, len(resultado.Vetores), len(lote))
+		}
+		for i, parte := range lote {
+			trechos = append(trechos, domain.TrechoDocumento{
+				Documento: nome,
+				Indice:    inicio + i,
+				Conteudo:  parte,
+				Embedding: &domain.Embedding{Modelo: resultado.Modelo, Hash: hashTexto(resultado.Modelo, parte), Vetor: resultado.Vetores[i]},
+			})
+		}
+	}
+
+	documento := &domain.Documento{
+		ContextoID: contexto.ID,
+		Nome:       nome,
+		Formato:    formato,
+		Caracteres: utf8.RuneCountInString(texto),
+	}
+	if err := u.repo.CreateDocumento(documento, trechos); err != nil {
+		return nil, err
+	}
+	return documento, nil
+}
+
+func (u *DocumentoUseCase) ListDocumentos(contextoID string) ([]domain.Documento, error) {
+	if _, err := u.repo.GetContexto(contextoID); err != nil {
+		return nil, naoEncontrado(ErrContextoNaoEncontrado, err)
+	}
+	return u.repo.ListDocumentos(contextoID)
+}
+
+// DeleteDocumento remove o documento se ele pertencer ao contexto
+func (u *DocumentoUseCase) DeleteDocumento(contextoID, id string) error {
+	documento, err := u.repo.GetDocumento(id)
+	if err != nil || documento.ContextoID.Hex() != contextoID {
+		return ErrDocumentoNaoEncontrado
+	}
+	return u.repo.DeleteDocumento(id)
+}
+
+// recuperar retorna os trechos dos documentos do contexto mais parecidos com a
+// consulta, numerados na ordem em que são citados. Sem documentos ou sem
+// provedor de embeddings não há recuperação.
+func (u *DocumentoUseCase) recuperar(ctx context.Context, contextoID primitive.ObjectID, consulta string) ([]domain.TrechoDocumento, []domain.Fonte, error) {
+	if u == nil || u.embeddings == nil || strings.TrimSpace(consulta) == "" {
+		return nil, nil, nil
+	}
+
+	trechos, err := u.repo.ListTrechosDocumento(contextoID.Hex())
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(trechos) == 0 {
+		return nil, nil, nil
+	}
+
+	resultado, err := u.embeddings.Embeddings(ctx, []string{consulta})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := u.consumo.RegistrarEmbeddings(contextoID, resultado); err != nil {
+		return nil, nil, err
+	}
+	if len(resultado.Vetores) != 1 {
+		return nil, nil, fmt.Errorf("%w: embedding da consulta ausente", domain.ErrLLMRespostaVazia)
+	}
+
+	type candidato struct {
+		trecho domain.TrechoDocumento
+		score  float64
+	}
+	candidatos := make([]candidato, 0, len(trechos))
+	for _, trecho := range trechos {
+		if trecho.Embedding == nil {
+			continue
+		}
+		if score, ok := domain.Similaridade(resultado.Vetores[0], trecho.Embedding.Vetor); ok {
+			candidatos = append(candidatos, candidato{trecho: trecho, score: score})
+		}
+	}
+	sort.SliceStable(candidatos, func(i, j int) bool { return candidatos[i].score > candidatos[j].score })
+	if len(candidatos) > trechosPorGeracao {
+		candidatos = candidatos[:trechosPorGeracao]
+	}
+
+	selecionados := make([]domain.TrechoDocumento, len(candidatos))
+	fontes := make([]domain.Fonte, len(candidatos))
+	for i, c := range candidatos {
+		selecionados[i] = c.trecho
+		fontes[i] = domain.Fonte{
+			Numero:      i + 1,
+			DocumentoID: c.trecho.DocumentoID,
+			Documento:   c.trecho.Documento,
+			Trecho:      c.trecho.Indice,
+			Score:       c.score,
+		}
+	}
+	return selecionados, fontes, nil
+}
+
+// comDocumentos acrescenta os trechos recuperados à mensagem de sistema, com
+// a numeração que o modelo deve usar nas citações
+func comDocumentos(req domain.RequisicaoGeracao, trechos []domain.TrechoDocumento) domain.RequisicaoGeracao {
+	if len(trechos) == 0 || len(req.Mensagens) == 0 || req.Mensagens[0].Papel != domain.PapelSistema {
+		return req
+	}
+
+	var b strings.Builder
+	b.WriteString("\nDocumentos de referência do contexto. Use-os como fonte de informações sobre produtos e preços e cite os trechos usados pelo número, como [1]:\n")
+	for i, trecho := range trechos {
+		b.WriteString("[" + strconv.Itoa(i+1) + "] " + trecho.Documento + ":\n")
+		b.WriteString(strings.TrimSpace(trecho.Conteudo) + "\n")
+	}
+
+	mensagens := append([]domain.Mensagem(nil), req.Mensagens...)
+	mensagens[0].Conteudo += b.String()
+	req.Mensagens = mensagens
+	return req
+}
+
+// DividirTexto separa o texto em trechos de até tamanho caracteres, sem
+// quebrar parágrafos que caibam inteiros; parágrafos maiores são divididos
+// entre palavras
+func DividirTexto(texto string, tamanho int) []string {
+	var trechos []string
+	var atual strings.Builder
+	fechar := func() {
+		if t := strings.TrimSpace(atual.String()); t != "" {
+			trechos = append(trechos, t)
+		}
+		atual.Reset()
+	}
+	acrescentar := func(parte, separador string) {
+		if atual.Len() > 0 && utf8.RuneCountInString(atual.String())+utf8.RuneCountInString(separador+parte) > tamanho {
+			fechar()
+		}
+		if atual.Len() > 0 {
+			atual.WriteString(separador)
+		}
+		atual.WriteString(parte)
+	}
+
+	for _, paragrafo := range strings.Split(texto, "\n\n") {
+		paragrafo = strings.TrimSpace(paragrafo)
+		if paragrafo == "" {
+			continue
+		}
+		if utf8.RuneCountInString(paragrafo) <= tamanho {
+			acrescentar(paragrafo, "\n\n")
+			continue
+		}
+
+		fechar()
+		for _, palavra := range strings.Fields(paragrafo) {
+			// Palavras maiores que o trecho (tabelas sem espaços) são cortadas
+			for utf8.RuneCountInString(palavra) > tamanho {
+				runas := []rune(palavra)
+				fechar()
+				trechos = append(trechos, string(runas[:tamanho]))
+				palavra = string(runas[tamanho:])
+			}
+			acrescentar(palavra, " ")
+		}
+		fechar()
+	}
+	fechar()
+	return trechos
+}
@@ -0,0 +1,265 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"vend/internal/domain"
+)
+
+// maxRodadasFerramentas limita as idas e voltas entre o modelo e as
+// ferramentas; na última rodada as ferramentas saem da requisição para
+// obrigar o modelo a responder
+const maxRodadasFerramentas = 5
+
+const (
+	FerramentaBuscarPessoa    = "buscar_pessoa"
+	FerramentaListarTelefones = "listar_telefones"
+	FerramentaListarContextos = "listar_contextos"
+	FerramentaBuscarContexto  = "buscar_contexto"
+)
+
+// FerramentasDisponiveis são os nomes aceitos em ParametrosGeracao.Ferramentas
+var FerramentasDisponiveis = []string{
+	FerramentaBuscarPessoa,
+	FerramentaListarTelefones,
+	FerramentaListarContextos,
+	FerramentaBuscarContexto,
+}
+
+// resultadoLista é o resultado das ferramentas de listagem: uma página por
+// chamada, com o cursor para o modelo pedir a seguinte. O total só vem na
+// primeira página.
+type resultadoLista struct {
+	Itens         any    `json:"itens"`
+	Total         *int64 `json:"total,omitempty"`
+	Truncado      bool   `json:"truncado"`
+	ProximoCursor string `json:"proximo_cursor,omitempty"`
+}
+
+func listaPaginada[T any](pagina *domain.Pagina[T], itens any) resultadoLista {
+	return resultadoLista{
+		Itens:         itens,
+		Total:         pagina.Total,
+		Truncado:      pagina.ProximoCursor != "",
+		ProximoCursor: pagina.ProximoCursor,
+	}
+}
+
+type ferramenta struct {
+	domain.Ferramenta
+	executar func(args map[string]any) (any, error)
+}
+
+// Ferramentas executa as ferramentas que o modelo pode chamar, consultando
+// pessoas, telefones e contextos pelos casos de uso existentes
+type Ferramentas struct {
+	ferramentas map[string]ferramenta
+}
+
+func NewFerramentas(pessoas *PessoaUseCase, telefones *TelefoneUseCase, contextos *ContextoUseCase) *Ferramentas {
+	texto := func(descricao string) map[string]any {
+		return map[string]any{"type": "string", "description": descricao}
+	}
+	objeto := func(propriedades map[string]any) map[string]any {
+		return map[string]any{"type": "object", "properties": propriedades}
+	}
+	cursor := texto("proximo_cursor da chamada anterior, quando truncado for true")
+
+	lista := []ferramenta{
+		{
+			Ferramenta: domain.Ferramenta{
+				Nome:      FerramentaBuscarPessoa,
+				Descricao: "Busca pessoas (leads e clientes) pelo ID ou por parte do nome ou do email; a busca por nome ou email retorna uma página por chamada",
+				Parametros: objeto(map[string]any{
+					"id":     texto("ID da pessoa"),
+					"nome":   texto("Parte do nome, sem diferenciar maiúsculas"),
+					"email":  texto("Parte do email, sem diferenciar maiúsculas"),
+					"cursor": cursor,
+				}),
+			},
+			executar: func(args map[string]any) (any, error) {
+				if id := argumento(args, "id"); id != "" {
+					pessoa, err := pessoas.GetPessoa(id)
+					if err != nil {
+						return nil, fmt.Errorf("pessoa %s não encontrada", id)
+					}
+					return resultadoLista{Itens: []domain.Pessoa{*pessoa}}, nil
+				}
+
+				filtro := domain.PessoaFiltro{Nome: argumento(args, "nome"), Email: argumento(args, "email")}
+				pagina, err := pessoas.ListPessoas(filtro, paginaFerramenta(args))
+				if err != nil {
+					return nil, err
+				}
+				return listaPaginada(pagina, pagina.Itens), nil
+			},
+		},
+		{
+			Ferramenta: domain.Ferramenta{
+				Nome:      FerramentaListarTelefones,
+				Descricao: "Lista os telefones cadastrados (tipo e número), opcionalmente de uma pessoa, uma página por chamada",
+				Parametros: objeto(map[string]any{
+					"pessoa_id": texto("ID da pessoa; vazio lista todos"),
+					"cursor":    cursor,
+				}),
+			},
+			executar: func(args map[string]any) (any, error) {
+				filtro := domain.TelefoneFiltro{PessoaID: argumento(args, "pessoa_id")}
+				pagina, err := telefones.ListTelefones(filtro, paginaFerramenta(args))
+				if err != nil {
+					return nil, err
+				}
+				return listaPaginada(pagina, pagina.Itens), nil
+			},
+		},
+		{
+			Ferramenta: domain.Ferramenta{
+				Nome:       FerramentaListarContextos,
+				Descricao:  "Lista os contextos (campanhas) com nome, descrição e período, uma página por chamada",
+				Parametros: objeto(map[string]any{"cursor": cursor}),
+			},
+			executar: func(args map[string]any) (any, error) {
+				pagina, err := contextos.ListContextos(domain.ContextoFiltro{}, paginaFerramenta(args))
+				if err != nil {
+					return nil, err
+				}
+				resumos := make([]map[string]any, 0, len(pagina.Itens))
+				for _, c := range pagina.Itens {
+					resumos = append(resumos, map[string]any{
+						"id": c.ID.Hex(), "nome": c.Nome, "descricao": c.Descricao,
+						"data_inicio": c.DataInicio, "data_fim": c.DataFim,
+					})
+				}
+				return listaPaginada(pagina, resumos), nil
+			},
+		},
+		{
+			Ferramenta: domain.Ferramenta{
+				Nome:      FerramentaBuscarContexto,
+				Descricao: "Retorna um contexto (campanha) com as pessoas envolvidas",
+				Parametros: map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"id": texto("ID do contexto")},
+					"required":   []any{"id"},
+				},
+			},
+			executar: func(args map[string]any) (any, error) {
+				id := argumento(args, "id")
+				contexto, err := contextos.GetContexto(id)
+				if err != nil {
+					return nil, fmt.Errorf("contexto %s não encontrado", id)
+				}
+				return contexto, nil
+			},
+		},
+	}
+
+	f := &Ferramentas{ferramentas: make(map[string]ferramenta, len(lista))}
+	for _, item := range lista {
+		f.ferramentas[item.Nome] = item
+	}
+	return f
+}
+
+// selecionar retorna a descrição das ferramentas pedidas, para a requisição
+func (f *Ferramentas) selecionar(nomes []string) ([]domain.Ferramenta, error) {
+	selecionadas := make([]domain.Ferramenta, 0, len(nomes))
+	for _, nome := range nomes {
+		var item ferramenta
+		var ok bool
+		if f != nil {
+			item, ok = f.ferramentas[nome]
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: ferramenta %q indisponível", ErrParametroInvalido, nome)
+		}
+		selecionadas = append(selecionadas, item.Ferramenta)
+	}
+	return selecionadas, nil
+}
+
+// executar roda a chamada e devolve o resultado em JSON. Erros da ferramenta
+// também voltam como texto para o modelo, que pode corrigir os argumentos.
+func (f *Ferramentas) executar(chamada domain.ChamadaFerramenta) string {
+	item, ok := f.ferramentas[chamada.Nome]
+	if !ok {
+		return "erro: ferramenta " + chamada.Nome + " desconhecida"
+	}
+
+	args := map[string]any{}
+	if strings.TrimSpace(chamada.Argumentos) != "" {
+		if err := json.Unmarshal([]byte(chamada.Argumentos), &args); err != nil {
+			return "erro: argumentos inválidos: " + err.Error()
+		}
+	}
+
+	resultado, err := item.executar(args)
+	if err != nil {
+		return "erro: " + err.Error()
+	}
+	dados, err := json.Marshal(resultado)
+	if err != nil {
+		return "erro: " + err.Error()
+	}
+	return string(dados)
+}
+
+// gerarComFerramentas chama o provedor e executa as ferramentas pedidas até o
+// modelo responder com texto. O uso de tokens soma todas as rodadas; se o
+// provedor falhar depois da primeira rodada, ou as rodadas se esgotarem, o
+// resultado da última rodada volta junto com o erro, para ser registrado.
+func gerarComFerramentas(ctx context.Context, provider domain.LLMProvider, ferramentas *Ferramentas, req domain.RequisicaoGeracao) (*domain.ResultadoGeracao, error) {
+	if len(req.Ferramentas) == 0 {
+		return provider.Generate(ctx, req)
+	}
+
+	req.Mensagens = slices.Clone(req.Mensagens)
+	var uso domain.UsoTokens
+	var anterior *domain.ResultadoGeracao
+	for rodada := 1; rodada <= maxRodadasFerramentas; rodada++ {
+		if rodada == maxRodadasFerramentas {
+			req.Ferramentas = nil
+		}
+
+		resultado, err := provider.Generate(ctx, req)
+		if err != nil {
+			// As rodadas anteriores já consumiram tokens
+			return anterior, err
+		}
+		anterior = resultado
+		uso = somarUso(uso, resultado.Uso)
+		resultado.Uso = uso
+		if len(resultado.ChamadasFerramenta) == 0 {
+			return resultado, nil
+		}
+
+		req.Mensagens = append(req.Mensagens, domain.Mensagem{
+			Papel:              domain.PapelAssistente,
+			Conteudo:           resultado.Conteudo,
+			ChamadasFerramenta: resultado.ChamadasFerramenta,
+		})
+		for _, chamada := range resultado.ChamadasFerramenta {
+			req.Mensagens = append(req.Mensagens, domain.Mensagem{
+				Papel:     domain.PapelFerramenta,
+				Conteudo:  ferramentas.executar(chamada),
+				ChamadaID: chamada.ID,
+			})
+		}
+	}
+
+	return anterior, fmt.Errorf("%w: o modelo não respondeu após %d rodadas de ferramentas", domain.ErrLLMRespostaVazia, maxRodadasFerramentas)
+}
+
+// paginaFerramenta pede o total na primeira página e segue o cursor nas
+// seguintes
+func paginaFerramenta(args map[string]any) ParametrosPagina {
+	return ParametrosPagina{Cursor: argumento(args, "cursor"), Total: true}
+}
+
+func argumento(args map[string]any, nome string) string {
+	valor, _ := args[nome].(string)
+	return strings.TrimSpace(valor)
+}
@@ -0,0 +1,250 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"vend/internal/domain"
+)
+
+const temperaturaPadrao = 0.7
+
+var (
+	ErrContextoNaoEncontrado = errors.New("contexto não encontrado")
+	ErrPromptNaoEncontrado   = errors.New("prompt não encontrado")
+	ErrPromptForaDoContexto  = errors.New("prompt não pertence ao contexto")
+)
+
+// naoEncontrado troca a ausência do registro pelo erro do caso de uso, que a
+// API responde com 404; as demais falhas do repositório seguem como estão
+func naoEncontrado(alvo, err error) error {
+	if errors.Is(err, domain.ErrNaoEncontrado) {
+		return fmt.Errorf("%w: %v", alvo, err)
+	}
+	return err
+}
+
+// OpcoesExecucao são os dados opcionais de uma execução de prompt: a pessoa
+// a quem a mensagem se destina, os valores das variáveis do template e
+// parâmetros de geração que sobrepõem os do contexto e do prompt
+type OpcoesExecucao struct {
+	PessoaID   string
+	Variaveis  map[string]any
+	Parametros domain.ParametrosGeracao
+}
+
+type GeracaoUseCase struct {
+	repo        Repository
+	provider    domain.LLMProvider
+	consumo     *ConsumoUseCase
+	modelos     ModelosPermitidos
+	ferramentas *Ferramentas
+	documentos  *DocumentoUseCase
+}
+
+func NewGeracaoUseCase(repo Repository, provider domain.LLMProvider, consumo *ConsumoUseCase, modelos ModelosPermitidos, ferramentas *Ferramentas, documentos *DocumentoUseCase) *GeracaoUseCase {
+	return &GeracaoUseCase{repo: repo, provider: provider, consumo: consumo, modelos: modelos, ferramentas: ferramentas, documentos: documentos}
+}
+
+// ExecutarPrompt carrega o contexto e o prompt, gera a resposta contextual
+// e a registra no histórico de respostas. Prompts com schema de saída são
+// repetidos até a resposta seguir o schema; se nenhuma tentativa seguir, a
+// resposta é registrada (para contabilizar os tokens) e ErrSaidaInvalida é
+// retornado. Se o provedor falhar numa nova tentativa ou rodada de ferramentas,
+// a última resposta também é registrada antes de o erro do provedor ser
+// retornado.
+func (u *GeracaoUseCase) ExecutarPrompt(ctx context.Context, contextoID, promptID string, opcoes OpcoesExecucao) (*domain.Resposta, error) {
+	exec, err := u.preparar(ctx, contextoID, promptID, opcoes)
+	if err != nil {
+		return nil, err
+	}
+	ctx = domain.ComPrivacidade(ctx, exec.privacidade)
+
+	inicio := time.Now()
+	var resultado *domain.ResultadoGeracao
+	var saida any
+	var errGeracao error
+	if exec.req.SchemaSaida == nil {
+		resultado, errGeracao = gerarComFerramentas(ctx, u.provider, u.ferramentas, exec.req)
+	} else {
+		resultado, saida, errGeracao = u.gerarEstruturado(ctx, exec.req)
+	}
+	if resultado == nil {
+		return nil, errGeracao
+	}
+	resposta, err := u.registrar(exec, resultado, saida, inicio)
+	if err != nil {
+		return nil, err
+	}
+	return resposta, errGeracao
+}
+
+// ExecutarPromptStream funciona como ExecutarPrompt, mas repassa cada trecho
+// gerado para onDelta; a resposta só é registrada se o stream terminar. Com
+// ferramentas, as rodadas de chamadas não são transmitidas e a resposta final
+// chega em um único trecho.
+func (u *GeracaoUseCase) ExecutarPromptStream(ctx context.Context, contextoID, promptID string, opcoes OpcoesExecucao, onDelta func(string) error) (*domain.Resposta, error) {
+	exec, err := u.preparar(ctx, contextoID, promptID, opcoes)
+	if err != nil {
+		return nil, err
+	}
+	ctx = domain.ComPrivacidade(ctx, exec.privacidade)
+
+	inicio := time.Now()
+	var resultado *domain.ResultadoGeracao
+	if len(exec.req.Ferramentas) > 0 {
+		resultado, err = gerarComFerramentas(ctx, u.provider, u.ferramentas, exec.req)
+		if err != nil && resultado != nil {
+			// As rodadas anteriores já consumiram tokens
+			if _, errRegistro := u.registrar(exec, resultado, nil, inicio); errRegistro != nil {
+				return nil, errRegistro
+			}
+			return nil, err
+		}
+		if err == nil {
+			err = onDelta(resultado.Conteudo)
+		}
+	} else {
+		resultado, err = u.provider.GenerateStream(ctx, exec.req, onDelta)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// O texto já foi entregue, então não há nova tentativa: só a validação
+	if exec.req.SchemaSaida == nil {
+		return u.registrar(exec, resultado, nil, inicio)
+	}
+	saida, errSaida := LerSaida(exec.req.SchemaSaida, resultado.Conteudo)
+	resposta, err := u.registrar(exec, resultado, saida, inicio)
+	if err != nil {
+		return nil, err
+	}
+	if errSaida != nil {
+		return resposta, fmt.Errorf("%w: %v", ErrSaidaInvalida, errSaida)
+	}
+	return resposta, nil
+}
+
+// execucao reúne o que foi carregado e montado para uma chamada ao provedor
+type execucao struct {
+	contexto    *domain.Contexto
+	prompt      *domain.Prompt
+	pessoa      *domain.Pessoa
+	fontes      []domain.Fonte
+	privacidade domain.Privacidade
+	req         domain.RequisicaoGeracao
+}
+
+func (u *GeracaoUseCase) preparar(ctx context.Context, contextoID, promptID string, opcoes OpcoesExecucao) (*execucao, error) {
+	contexto, err := u.repo.GetContexto(contextoID)
+	if err != nil {
+		return nil, naoEncontrado(ErrContextoNaoEncontrado, err)
+	}
+
+	if err := u.consumo.VerificarOrcamento(contexto); err != nil {
+		return nil, err
+	}
+
+	prompt, err := u.repo.GetPrompt(promptID)
+	if err != nil {
+		return nil, naoEncontrado(ErrPromptNaoEncontrado, err)
+	}
+
+	if !prompt.ContextoID.IsZero() && prompt.ContextoID != contexto.ID {
+		return nil, ErrPromptForaDoContexto
+	}
+
+	var pessoa *domain.Pessoa
+	if opcoes.PessoaID != "" {
+		if pessoa, err = u.repo.GetPessoa(opcoes.PessoaID); err != nil {
+			return nil, naoEncontrado(ErrPessoaNaoEncontrada, err)
+		}
+	}
+
+	parametros, err := u.modelos.parametrosEfetivos(contexto.Parametros, prompt.Parametros, opcoes.Parametros)
+	if err != nil {
+		return nil, err
+	}
+	ferramentas, err := u.ferramentas.selecionar(parametros.Ferramentas)
+	if err != nil {
+		return nil, err
+	}
+
+	conteudo, err := RenderizarPrompt(prompt, pessoa, contexto, opcoes.Variaveis)
+	if err != nil {
+		return nil, err
+	}
+
+	privacidade := privacidadeDe(contexto, pessoa)
+	trechos, fontes, err := u.documentos.recuperar(domain.ComPrivacidade(ctx, privacidade), contexto.ID, conteudo)
+	if err != nil {
+		return nil, err
+	}
+
+	req := comDocumentos(requisicaoContextual(contexto, pessoa, conteudo, parametros, prompt.SchemaSaida), trechos)
+	req.Ferramentas = ferramentas
+
+	return &execucao{
+		contexto:    contexto,
+		prompt:      prompt,
+		pessoa:      pessoa,
+		fontes:      fontes,
+		privacidade: privacidade,
+		req:         req,
+	}, nil
+}
+
+func (u *GeracaoUseCase) registrar(exec *execucao, resultado *domain.ResultadoGeracao, saida any, inicio time.Time) (*domain.Resposta, error) {
+	resposta := novaResposta(exec.req, resultado, inicio)
+	resposta.Saida = saida
+	resposta.Fontes = exec.fontes
+	resposta.CustoEstimado = u.consumo.Custo(resultado.Modelo, resultado.Uso)
+	resposta.PromptID = exec.prompt.ID
+	resposta.PromptVersao = exec.prompt.Versao
+	resposta.ContextoID = exec.contexto.ID
+	if exec.pessoa != nil {
+		resposta.PessoaID = exec.pessoa.ID
+	}
+	if err := u.repo.CreateResposta(resposta); err != nil {
+		return nil, err
+	}
+
+	return resposta, nil
+}
+
+func novaResposta(req domain.RequisicaoGeracao, resultado *domain.ResultadoGeracao, inicio time.Time) *domain.Resposta {
+	return &domain.Resposta{
+		Modelo:      resultado.Modelo,
+		Temperatura: req.Temperatura,
+		Mensagens:   req.Mensagens,
+		Conteudo:    resultado.Conteudo,
+		Uso:         resultado.Uso,
+		DoCache:     resultado.DoCache,
+		LatenciaMs:  time.Since(inicio).Milliseconds(),
+	}
+}
+
+func requisicaoContextual(contexto *domain.Contexto, pessoa *domain.Pessoa, conteudo string, parametros domain.ParametrosGeracao, schemaSaida map[string]any) domain.RequisicaoGeracao {
+	req := novaRequisicao(parametros, []domain.Mensagem{
+		{Papel: domain.PapelSistema, Conteudo: mensagemSistema(contexto, pessoa)},
+		{Papel: domain.PapelUsuario, Conteudo: conteudo},
+	})
+	req.SchemaSaida = schemaSaida
+	return req
+}
+
+// mensagemSistema descreve o contexto e, se informada, a pessoa atendida
+func mensagemSistema(contexto *domain.Contexto, pessoa *domain.Pessoa) string {
+	systemMessage := "Contexto: " + contexto.Descricao + "\n"
+	systemMessage += "Período: " + contexto.DataInicio.Format("2006-01-02") + " até " + contexto.DataFim.Format("2006-01-02") + "\n"
+	systemMessage += "Pessoas envolvidas:\n"
+	for _, p := range contexto.Pessoas {
+		systemMessage += "- " + p.Nome + " (" + p.Email + ")\n"
+	}
+	if pessoa != nil {
+		systemMessage += "Pessoa atendida: " + pessoa.Nome + " (" + pessoa.Email + ")\n"
+	}
+	return systemMessage
+}
@@ -0,0 +1,95 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"vend/internal/domain"
+)
+
+var ErrRemocaoRestrita = errors.New("remoção impedida por registros relacionados")
+
+// descricoesRelacao nomeiam os registros de cada relação nas mensagens de erro
+var descricoesRelacao = map[domain.Relacao]string{
+	domain.RelacaoPessoaTelefones: "telefone(s) da pessoa",
+	domain.RelacaoPessoaContextos: "contexto(s) com a pessoa",
+	domain.RelacaoContextoPrompts: "prompt(s) do contexto",
+}
+
+// RegrasRemocao dá a regra de cada relação ao remover pessoas e contextos
+type RegrasRemocao map[domain.Relacao]string
+
+// RegrasRemocaoPadrao remove os telefones com a pessoa, tira a pessoa dos
+// contextos e impede a remoção de contextos com prompts
+func RegrasRemocaoPadrao() RegrasRemocao {
+	return RegrasRemocao{
+		domain.RelacaoPessoaTelefones: domain.RegraCascata,
+		domain.RelacaoPessoaContextos: domain.RegraAnular,
+		domain.RelacaoContextoPrompts: domain.RegraRestringir,
+	}
+}
+
+// ParseRegrasRemocao lê regras no formato "relacao=regra,outra=regra" e as
+// sobrepõe às regras padrão
+func ParseRegrasRemocao(texto string) (RegrasRemocao, error) {
+	regras := RegrasRemocaoPadrao()
+	for _, item := range strings.Split(texto, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		nome, regra, ok := strings.Cut(item, "=")
+		relacao := domain.Relacao(strings.TrimSpace(nome))
+		regra = strings.TrimSpace(regra)
+		if _, conhecida := descricoesRelacao[relacao]; !ok || !conhecida {
+			return nil, fmt.Errorf("relação inválida: %q (use %s)", item, strings.Join(nomesRelacoes(), ", "))
+		}
+		switch regra {
+		case domain.RegraCascata, domain.RegraRestringir, domain.RegraAnular:
+		default:
+			return nil, fmt.Errorf("regra inválida para %s: %q (use %s, %s ou %s)", relacao, regra,
+				domain.RegraCascata, domain.RegraRestringir, domain.RegraAnular)
+		}
+		regras[relacao] = regra
+	}
+	return regras, nil
+}
+
+func nomesRelacoes() []string {
+	nomes := make([]string, len(domain.Relacoes))
+	for i, relacao := range domain.Relacoes {
+		nomes[i] = string(relacao)
+	}
+	return nomes
+}
+
+// removerComRegras aplica a regra de cada relação e move o registro para a
+// lixeira, tudo em uma transação: se uma regra restrict encontrar registros
+// ou qualquer passo falhar, nada é removido. Os registros removidos em
+// cascata recebem a mesma remoção, para voltarem junto com o registro.
+func removerComRegras(repo Repository, regras RegrasRemocao, id string, remocao domain.Remocao, relacoes []domain.Relacao, remover func(repo Repository, id string, remocao domain.Remocao) error) error {
+	return repo.Transacao(func(tx Repository) error {
+		for _, relacao := range relacoes {
+			switch regras[relacao] {
+			case domain.RegraRestringir:
+				n, err := tx.ContarReferencias(relacao, id)
+				if err != nil {
+					return err
+				}
+				if n > 0 {
+					return fmt.Errorf("%w: %d %s (regra %s=%s)", ErrRemocaoRestrita, n, descricoesRelacao[relacao], relacao, domain.RegraRestringir)
+				}
+			case domain.RegraAnular:
+				if err := tx.AnularReferencias(relacao, id); err != nil {
+					return err
+				}
+			default:
+				if err := tx.RemoverReferencias(relacao, id, remocao); err != nil {
+					return err
+				}
+			}
+		}
+		return remover(tx, id, remocao)
+	})
+}
@@ -0,0 +1,217 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+	"vend/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrJobNaoEncontrado   = errors.New("job não encontrado")
+	ErrContextoSemPessoas = errors.New("o contexto não tem pessoas")
+)
+
+// OpcoesLote são aplicadas à execução do prompt para cada pessoa do lote
+type OpcoesLote struct {
+	Variaveis  map[string]any
+	Parametros domain.ParametrosGeracao
+}
+
+// ConfigLote controla o processamento dos jobs
+type ConfigLote struct {
+	// Workers é o número de gerações simultâneas de um job
+	Workers int
+	// Intervalo é o tempo mínimo entre o início de duas gerações, para
+	// respeitar o limite de requisições do provedor
+	Intervalo time.Duration
+	// Verificacao é de quanto em quanto tempo o banco é consultado em busca de
+	// jobs pendentes, além do aviso dado a cada novo job
+	Verificacao time.Duration
+}
+
+func ConfigLotePadrao() ConfigLote {
+	return ConfigLote{Workers: 4, Intervalo: 200 * time.Millisecond, Verificacao: 30 * time.Second}
+}
+
+type JobUseCase struct {
+	repo    Repository
+	geracao *GeracaoUseCase
+	config  ConfigLote
+	aviso   chan struct{}
+}
+
+func NewJobUseCase(repo Repository, geracao *GeracaoUseCase, config ConfigLote) *JobUseCase {
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	return &JobUseCase{repo: repo, geracao: geracao, config: config, aviso: make(chan struct{}, 1)}
+}
+
+// CriarLote registra um job com um item por pessoa do contexto; o
+// processamento acontece em segundo plano, em Iniciar
+func (u *JobUseCase) CriarLote(contextoID, promptID string, opcoes OpcoesLote) (*domain.Job, error) {
+	contexto, err := u.repo.GetContexto(contextoID)
+	if err != nil {
+		return nil, naoEncontrado(ErrContextoNaoEncontrado, err)
+	}
+
+	prompt, err := u.repo.GetPrompt(promptID)
+	if err != nil {
+		return nil, naoEncontrado(ErrPromptNaoEncontrado, err)
+	}
+	if !prompt.ContextoID.IsZero() && prompt.ContextoID != contexto.ID {
+		return nil, ErrPromptForaDoContexto
+	}
+
+	if err := u.geracao.modelos.Validar(opcoes.Parametros); err != nil {
+		return nil, err
+	}
+
+	vistas := make(map[primitive.ObjectID]bool, len(contexto.Pessoas))
+	itens := make([]domain.ItemJob, 0, len(contexto.Pessoas))
+	for _, pessoa := range contexto.Pessoas {
+		if pessoa.ID.IsZero() || vistas[pessoa.ID] {
+			continue
+		}
+		vistas[pessoa.ID] = true
+		itens = append(itens, domain.ItemJob{PessoaID: pessoa.ID, Status: domain.StatusItemPendente})
+	}
+	if len(itens) == 0 {
+		return nil, ErrContextoSemPessoas
+	}
+
+	job := &domain.Job{
+		ContextoID: contexto.ID,
+		PromptID:   prompt.ID,
+		Variaveis:  opcoes.Variaveis,
+		Parametros: opcoes.Parametros,
+		Status:     domain.StatusJobPendente,
+		Total:      len(itens),
+		Itens:      itens,
+	}
+	if err := u.repo.CreateJob(job); err != nil {
+		return nil, err
+	}
+
+	select {
+	case u.aviso <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+func (u *JobUseCase) GetJob(id string) (*domain.Job, error) {
+	job, err := u.repo.GetJob(id)
+	if err != nil {
+		return nil, naoEncontrado(ErrJobNaoEncontrado, err)
+	}
+	return job, nil
+}
+
+// Iniciar processa os jobs pendentes, um de cada vez, até ctx ser cancelado.
+// Jobs interrompidos por um reinício continuam em "executando" e são
+// retomados pelos itens ainda pendentes. Supõe uma única instância da API.
+func (u *JobUseCase) Iniciar(ctx context.Context) {
+	for {
+		jobs, err := u.repo.ListJobsPorStatus(domain.StatusJobExecutando, domain.StatusJobPendente)
+		if err != nil {
+			log.Printf("Erro ao buscar jobs pendentes: %v", err)
+		}
+		for i := range jobs {
+			if ctx.Err() != nil {
+				return
+			}
+			u.processar(ctx, &jobs[i])
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-u.aviso:
+		case <-time.After(u.config.Verificacao):
+		}
+	}
+}
+
+func (u *JobUseCase) processar(ctx context.Context, job *domain.Job) {
+	id := job.ID.Hex()
+	if err := u.repo.UpdateJobStatus(id, domain.StatusJobExecutando); err != nil {
+		log.Printf("Erro ao iniciar o job %s: %v", id, err)
+		return
+	}
+
+	var intervalo <-chan time.Time
+	if u.config.Intervalo > 0 {
+		ticker := time.NewTicker(u.config.Intervalo)
+		defer ticker.Stop()
+		intervalo = ticker.C
+	}
+
+	pendentes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < u.config.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range pendentes {
+				u.executarItem(ctx, job, i)
+			}
+		}()
+	}
+
+	for i, item := range job.Itens {
+		if item.Status != domain.StatusItemPendente {
+			continue
+		}
+		if intervalo != nil {
+			select {
+			case <-ctx.Done():
+			case <-intervalo:
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		pendentes <- i
+	}
+	close(pendentes)
+	wg.Wait()
+
+	// Interrompido: o job fica em "executando" para ser retomado
+	if ctx.Err() != nil {
+		return
+	}
+	if err := u.repo.UpdateJobStatus(id, domain.StatusJobConcluido); err != nil {
+		log.Printf("Erro ao concluir o job %s: %v", id, err)
+	}
+}
+
+func (u *JobUseCase) executarItem(ctx context.Context, job *domain.Job, i int) {
+	item := job.Itens[i]
+	opcoes := OpcoesExecucao{PessoaID: item.PessoaID.Hex(), Variaveis: job.Variaveis, Parametros: job.Parametros}
+
+	resposta, err := u.geracao.ExecutarPrompt(ctx, job.ContextoID.Hex(), job.PromptID.Hex(), opcoes)
+	if ctx.Err() != nil {
+		return
+	}
+
+	if resposta != nil {
+		item.RespostaID = resposta.ID
+		item.Conteudo = resposta.Conteudo
+	}
+	if err != nil {
+		item.Status = domain.StatusItemFalhou
+		item.Erro = err.Error()
+	} else {
+		item.Status = domain.StatusItemConcluido
+	}
+
+	if err := u.repo.UpdateJobItem(job.ID.Hex(), i, item); err != nil {
+		log.Printf("Erro ao gravar o item %d do job %s: %v", i, job.ID.Hex(), err)
+	}
+}
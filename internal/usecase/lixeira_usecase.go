@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+	"vend/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrEntidadeSemLixeira  = errors.New("entidade sem lixeira")
+	ErrRestauracaoImpedida = errors.New("restauração impedida por registro na lixeira")
+)
+
+// ConfigLixeira controla a purga da lixeira
+type ConfigLixeira struct {
+	// Retencao é por quanto tempo um registro fica na lixeira; 0 desliga a purga
+	Retencao time.Duration
+	// Intervalo é de quanto em quanto tempo a lixeira é purgada
+	Intervalo time.Duration
+}
+
+func ConfigLixeiraPadrao() ConfigLixeira {
+	return ConfigLixeira{Retencao: 30 * 24 * time.Hour, Intervalo: time.Hour}
+}
+
+var ordenacaoLixeira = ordenacao[domain.ItemLixeira]{
+	campos: map[string]func(domain.ItemLixeira) any{
+		"deleted_at": func(i domain.ItemLixeira) any { return i.DeletedAt },
+	},
+	id: func(i domain.ItemLixeira) primitive.ObjectID { return i.ID },
+}
+
+// relacoesRestauradas são as relações cujos registros removidos em cascata
+// voltam ao restaurar cada entidade
+var relacoesRestauradas = map[string]domain.Relacao{
+	domain.EntidadePessoas:   domain.RelacaoPessoaTelefones,
+	domain.EntidadeContextos: domain.RelacaoContextoPrompts,
+}
+
+// LixeiraUseCase lista e restaura os registros removidos e purga os que
+// passaram da retenção
+type LixeiraUseCase struct {
+	repo   Repository
+	config ConfigLixeira
+}
+
+func NewLixeiraUseCase(repo Repository, config ConfigLixeira) *LixeiraUseCase {
+	return &LixeiraUseCase{repo: repo, config: config}
+}
+
+// novaRemocao registra a remoção feita agora pelo usuário; o horário vai em
+// milissegundos, a precisão do MongoDB, para que os registros removidos em
+// cascata tenham exatamente o mesmo deleted_at nos três armazenamentos
+func novaRemocao(usuario string) domain.Remocao {
+	return domain.Remocao{Em: time.Now().UTC().Truncate(time.Millisecond), Por: usuario}
+}
+
+// ListLixeira retorna uma página dos registros removidos, dos mais recentes
+// aos mais antigos; entidade vazia lista todas
+func (u *LixeiraUseCase) ListLixeira(entidade string, pagina ParametrosPagina) (*domain.Pagina[domain.ItemLixeira], error) {
+	if entidade != "" {
+		if err := validarEntidade(entidade); err != nil {
+			return nil, err
+		}
+	}
+	if pagina.Ordem == "" && pagina.Cursor == "" {
+		pagina.Ordem = "-deleted_at"
+	}
+
+	resultado, err := paginar(pagina, ordenacaoLixeira, func(p domain.Paginacao) ([]domain.ItemLixeira, int64, error) {
+		return u.repo.ListLixeira(domain.LixeiraFiltro{Entidade: entidade, Paginacao: p})
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range resultado.Itens {
+		resultado.Itens[i].Titulo = resumir(resultado.Itens[i].Titulo, tamanhoTitulo)
+	}
+	return resultado, nil
+}
+
+// Restaurar tira o registro da lixeira junto com os registros removidos em
+// cascata com ele. Telefones e prompts só voltam se a pessoa ou o contexto a
+// que pertencem não estiverem na lixeira; as pessoas tiradas dos contextos
+// não voltam a eles.
+func (u *LixeiraUseCase) Restaurar(entidade, id string) error {
+	if err := validarEntidade(entidade); err != nil {
+		return err
+	}
+
+	return u.repo.Transacao(func(tx Repository) error {
+		// A referência é conferida antes de restaurar, para que a restauração
+		// impedida não altere nada
+		item, err := tx.GetLixeira(entidade, id)
+		if err != nil {
+			return err
+		}
+		switch entidade {
+		case domain.EntidadeTelefones:
+			err = exigirAtivo(item.ReferenciaID, "pessoa", tx.GetPessoa)
+		case domain.EntidadePrompts:
+			err = exigirAtivo(item.ReferenciaID, "contexto", tx.GetContexto)
+		}
+		if err != nil {
+			return err
+		}
+
+		remocao, err := tx.Restaurar(entidade, id)
+		if err != nil {
+			return err
+		}
+		if relacao, ok := relacoesRestauradas[entidade]; ok {
+			return tx.RestaurarReferencias(relacao, id, remocao.Em)
+		}
+		return nil
+	})
+}
+
+// exigirAtivo confere que o registro a que outro pertence existe fora da
+// lixeira; o ID zerado (referência anulada) não pertence a nenhum
+func exigirAtivo[T any](id primitive.ObjectID, nome string, buscar func(id string) (T, error)) error {
+	if id.IsZero() {
+		return nil
+	}
+	if _, err := buscar(id.Hex()); err != nil {
+		if errors.Is(err, domain.ErrNaoEncontrado) {
+			return fmt.Errorf("%w: %s %s", ErrRestauracaoImpedida, nome, id.Hex())
+		}
+		return err
+	}
+	return nil
+}
+
+// Purgar apaga de vez os registros que estão na lixeira há mais que a
+// retenção
+func (u *LixeiraUseCase) Purgar() (int64, error) {
+	return u.repo.PurgarLixeira(time.Now().Add(-u.config.Retencao))
+}
+
+// Iniciar purga a lixeira ao subir e depois a cada intervalo, até ctx ser
+// cancelado; sem retenção, não faz nada
+func (u *LixeiraUseCase) Iniciar(ctx context.Context) {
+	if u.config.Retencao <= 0 {
+		return
+	}
+	for {
+		if n, err := u.Purgar(); err != nil {
+			log.Printf("Erro ao purgar a lixeira: %v", err)
+		} else if n > 0 {
+			log.Printf("Lixeira: %d registro(s) apagado(s) após %s", n, u.config.Retencao)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(u.config.Intervalo):
+		}
+	}
+}
+
+func validarEntidade(entidade string) error {
+	if !slices.Contains(domain.EntidadesLixeira, entidade) {
+		return fmt.Errorf("%w: %q (use %s)", ErrEntidadeSemLixeira, entidade, strings.Join(domain.EntidadesLixeira, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package usecase
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"vend/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrPaginacaoInvalida = errors.New("paginação inválida")
+
+const (
+	LimitePadrao = 50
+	LimiteMaximo = 200
+)
+
+// ParametrosPagina são os parâmetros de paginação recebidos pela API. Ordem é
+// o campo ordenado, com "-" na frente para a ordem decrescente; Cursor é o
+// next_cursor da página anterior e continua na mesma ordem. Total pede a
+// contagem, que as páginas seguidas pelo cursor não repetem.
+type ParametrosPagina struct {
+	Limite int
+	Offset int
+	Cursor string
+	Ordem  string
+	Total  bool
+}
+
+// ordenacao descreve os campos pelos quais uma entidade pode ser ordenada:
+// cada campo retorna o seu valor no item, usado no cursor da próxima página
+type ordenacao[T any] struct {
+	campos map[string]func(T) any
+	id     func(T) primitive.ObjectID
+}
+
+var ordenacaoPessoas = ordenacao[domain.Pessoa]{
+	campos: map[string]func(domain.Pessoa) any{
+		"created_at": func(p domain.Pessoa) any { return p.CreatedAt },
+		"updated_at": func(p domain.Pessoa) any { return p.UpdatedAt },
+		"nome":       func(p domain.Pessoa) any { return p.Nome },
+		"email":      func(p domain.Pessoa) any { return p.Email },
+	},
+	id: func(p domain.Pessoa) primitive.ObjectID { return p.ID },
+}
+
+var ordenacaoTelefones = ordenacao[domain.Telefone]{
+	campos: map[string]func(domain.Telefone) any{
+		"created_at": func(t domain.Telefone) any { return t.CreatedAt },
+		"updated_at": func(t domain.Telefone) any { return t.UpdatedAt },
+		"numero":     func(t domain.Telefone) any { return t.Numero },
+		"tipo":       func(t domain.Telefone) any { return t.Tipo },
+	},
+	id: func(t domain.Telefone) primitive.ObjectID { return t.ID },
+}
+
+var ordenacaoContextos = ordenacao[domain.Contexto]{
+	campos: map[string]func(domain.Contexto) any{
+		"created_at":  func(c domain.Contexto) any { return c.CreatedAt },
+		"updated_at":  func(c domain.Contexto) any { return c.UpdatedAt },
+		"nome":        func(c domain.Contexto) any { return c.Nome },
+		"data_inicio": func(c domain.Contexto) any { return c.DataInicio },
+		"data_fim":    func(c domain.Contexto) any { return c.DataFim },
+	},
+	id: func(c domain.Contexto) primitive.ObjectID { return c.ID },
+}
+
+var ordenacaoPrompts = ordenacao[domain.Prompt]{
+	campos: map[string]func(domain.Prompt) any{
+		"created_at": func(p domain.Prompt) any { return p.CreatedAt },
+		"updated_at": func(p domain.Prompt) any { return p.UpdatedAt },
+		"versao":     func(p domain.Prompt) any { return p.Versao },
+	},
+	id: func(p domain.Prompt) primitive.ObjectID { return p.ID },
+}
+
+// cursor é o conteúdo do next_cursor, em JSON codificado em base64 (URL)
+type cursor struct {
+	Ordem string          `json:"o"`
+	Valor json.RawMessage `json:"v"`
+	ID    string          `json:"id"`
+}
+
+// paginar valida os parâmetros, busca uma página com um item a mais para
+// saber se há outra e monta o cursor a partir do último item devolvido
+func paginar[T any](params ParametrosPagina, o ordenacao[T], listar func(domain.Paginacao) ([]T, int64, error)) (*domain.Pagina[T], error) {
+	paginacao, err := o.paginacao(params)
+	if err != nil {
+		return nil, err
+	}
+
+	limite := paginacao.Limite
+	paginacao.Limite++
+	itens, total, err := listar(paginacao)
+	if err != nil {
+		return nil, err
+	}
+
+	pagina := &domain.Pagina[T]{Itens: itens}
+	if paginacao.ComTotal {
+		pagina.Total = &total
+	}
+	if len(itens) > limite {
+		pagina.Itens = itens[:limite]
+		ultimo := pagina.Itens[limite-1]
+		valor, err := json.Marshal(o.campos[paginacao.Ordem.Campo](ultimo))
+		if err != nil {
+			return nil, err
+		}
+		dados, err := json.Marshal(cursor{Ordem: textoOrdem(paginacao.Ordem), Valor: valor, ID: o.id(ultimo).Hex()})
+		if err != nil {
+			return nil, err
+		}
+		pagina.ProximoCursor = base64.RawURLEncoding.EncodeToString(dados)
+	}
+	return pagina, nil
+}
+
+func (o ordenacao[T]) paginacao(params ParametrosPagina) (domain.Paginacao, error) {
+	paginacao := domain.Paginacao{Limite: params.Limite, Offset: params.Offset, ComTotal: params.Total && params.Cursor == ""}
+	if paginacao.Limite == 0 {
+		paginacao.Limite = LimitePadrao
+	}
+	if paginacao.Limite < 0 || paginacao.Limite > LimiteMaximo {
+		return paginacao, fmt.Errorf("%w: limit deve estar entre 1 e %d", ErrPaginacaoInvalida, LimiteMaximo)
+	}
+	if paginacao.Offset < 0 {
+		return paginacao, fmt.Errorf("%w: offset negativo", ErrPaginacaoInvalida)
+	}
+
+	ordem := params.Ordem
+	var c cursor
+	if params.Cursor != "" {
+		if params.Offset > 0 {
+			return paginacao, fmt.Errorf("%w: use cursor ou offset, não os dois", ErrPaginacaoInvalida)
+		}
+		dados, err := base64.RawURLEncoding.DecodeString(params.Cursor)
+		if err != nil || json.Unmarshal(dados, &c) != nil {
+			return paginacao, fmt.Errorf("%w: cursor inválido", ErrPaginacaoInvalida)
+		}
+		if ordem == "" {
+			ordem = c.Ordem
+		} else if ordem != c.Ordem {
+			return paginacao, fmt.Errorf("%w: o cursor é da ordem %s", ErrPaginacaoInvalida, c.Ordem)
+		}
+	}
+
+	paginacao.Ordem = domain.OrdemRecentes
+	if ordem != "" {
+		paginacao.Ordem = domain.Ordem{Campo: strings.TrimPrefix(ordem, "-"), Decrescente: strings.HasPrefix(ordem, "-")}
+	}
+	valorCampo, ok := o.campos[paginacao.Ordem.Campo]
+	if !ok {
+		campos := make([]string, 0, len(o.campos))
+		for campo := range o.campos {
+			campos = append(campos, campo)
+		}
+		slices.Sort(campos)
+		return paginacao, fmt.Errorf("%w: ordenação por %s não suportada (use %s)", ErrPaginacaoInvalida, paginacao.Ordem.Campo, strings.Join(campos, ", "))
+	}
+
+	if params.Cursor != "" {
+		id, err := primitive.ObjectIDFromHex(c.ID)
+		if err != nil {
+			return paginacao, fmt.Errorf("%w: cursor inválido", ErrPaginacaoInvalida)
+		}
+		// O valor é decodificado no tipo do campo, que o JSON não preserva
+		var zero T
+		valor, err := decodificarValor(c.Valor, valorCampo(zero))
+		if err != nil {
+			return paginacao, fmt.Errorf("%w: cursor inválido", ErrPaginacaoInvalida)
+		}
+		paginacao.Apos = &domain.Marcador{Valor: valor, ID: id}
+	}
+	return paginacao, nil
+}
+
+func decodificarValor(dados json.RawMessage, tipo any) (any, error) {
+	switch tipo.(type) {
+	case time.Time:
+		var valor time.Time
+		err := json.Unmarshal(dados, &valor)
+		return valor, err
+	case int:
+		var valor int
+		err := json.Unmarshal(dados, &valor)
+		return valor, err
+	default:
+		var valor string
+		err := json.Unmarshal(dados, &valor)
+		return valor, err
+	}
+}
+
+func textoOrdem(ordem domain.Ordem) string {
+	if ordem.Decrescente {
+		return "-" + ordem.Campo
+	}
+	return ordem.Campo
+}
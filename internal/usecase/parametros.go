@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"vend/internal/domain"
+)
+
+const maxStop = 4
+
+var ErrParametroInvalido = errors.New("parâmetro de geração inválido")
+
+// ModelosPermitidos é a lista de modelos aceitos nos parâmetros de geração;
+// uma lista vazia aceita qualquer modelo
+type ModelosPermitidos []string
+
+// ParseModelos lê uma lista de modelos separados por vírgula
+func ParseModelos(texto string) ModelosPermitidos {
+	var modelos ModelosPermitidos
+	for _, modelo := range strings.Split(texto, ",") {
+		if modelo = strings.TrimSpace(modelo); modelo != "" {
+			modelos = append(modelos, modelo)
+		}
+	}
+	return modelos
+}
+
+// Validar confere o modelo contra a lista e os limites de cada parâmetro
+func (m ModelosPermitidos) Validar(p domain.ParametrosGeracao) error {
+	if p.Modelo != "" && len(m) > 0 && !slices.Contains(m, p.Modelo) {
+		return fmt.Errorf("%w: modelo %q não permitido (permitidos: %s)", ErrParametroInvalido, p.Modelo, strings.Join(m, ", "))
+	}
+	if p.Temperatura != nil && (*p.Temperatura < 0 || *p.Temperatura > 2) {
+		return fmt.Errorf("%w: temperatura deve estar entre 0 e 2", ErrParametroInvalido)
+	}
+	if p.TopP != nil && (*p.TopP < 0 || *p.TopP > 1) {
+		return fmt.Errorf("%w: top_p deve estar entre 0 e 1", ErrParametroInvalido)
+	}
+	if p.MaxTokens != nil && *p.MaxTokens < 1 {
+		return fmt.Errorf("%w: max_tokens deve ser positivo", ErrParametroInvalido)
+	}
+	if len(p.Stop) > maxStop {
+		return fmt.Errorf("%w: no máximo %d sequências de parada", ErrParametroInvalido, maxStop)
+	}
+	for _, nome := range p.Ferramentas {
+		if !slices.Contains(FerramentasDisponiveis, nome) {
+			return fmt.Errorf("%w: ferramenta %q desconhecida (disponíveis: %s)", ErrParametroInvalido, nome, strings.Join(FerramentasDisponiveis, ", "))
+		}
+	}
+	return nil
+}
+
+// parametrosEfetivos aplica, sobre o padrão do sistema, os parâmetros de cada
+// nível na ordem recebida (contexto, prompt, requisição) e valida o resultado
+func (m ModelosPermitidos) parametrosEfetivos(niveis ...domain.ParametrosGeracao) (domain.ParametrosGeracao, error) {
+	temperatura := float32(temperaturaPadrao)
+	parametros := domain.ParametrosGeracao{Temperatura: &temperatura}
+	for _, nivel := range niveis {
+		parametros = parametros.Sobrepor(nivel)
+	}
+	return parametros, m.Validar(parametros)
+}
+
+// novaRequisicao monta a requisição com os parâmetros; o prefixo de sistema
+// entra no início da primeira mensagem, que é sempre a de sistema
+func novaRequisicao(parametros domain.ParametrosGeracao, mensagens []domain.Mensagem) domain.RequisicaoGeracao {
+	if parametros.PrefixoSistema != "" && len(mensagens) > 0 && mensagens[0].Papel == domain.PapelSistema {
+		mensagens[0].Conteudo = parametros.PrefixoSistema + "\n" + mensagens[0].Conteudo
+	}
+
+	req := domain.RequisicaoGeracao{
+		Modelo:    parametros.Modelo,
+		Stop:      parametros.Stop,
+		Mensagens: mensagens,
+	}
+	if parametros.Temperatura != nil {
+		req.Temperatura = *parametros.Temperatura
+	}
+	if parametros.MaxTokens != nil {
+		req.MaxTokens = *parametros.MaxTokens
+	}
+	if parametros.TopP != nil {
+		req.TopP = *parametros.TopP
+	}
+	return req
+}
@@ -1,45 +1,132 @@
 package usecase
 
 import (
+	"time"
 	"vend/internal/domain"
 )
 
+// Repository é o armazenamento das entidades. As listagens de pessoas,
+// telefones, contextos e prompts retornam a página pedida no filtro e o total
+// de registros aceitos por ele. Remover uma dessas entidades a move para a
+// lixeira, e as leituras ignoram os registros na lixeira.
 type Repository interface {
 	// Métodos de Pessoa
 	CreatePessoa(pessoa *domain.Pessoa) error
 	GetPessoa(id string) (*domain.Pessoa, error)
-	ListPessoas() ([]domain.Pessoa, error)
+	ListPessoas(filtro domain.PessoaFiltro) ([]domain.Pessoa, int64, error)
 	UpdatePessoa(pessoa *domain.Pessoa) error
-	DeletePessoa(id string) error
+	DeletePessoa(id string, remocao domain.Remocao) error
 
 	// Métodos de Telefone
 	CreateTelefone(telefone *domain.Telefone) error
 	GetTelefone(id string) (*domain.Telefone, error)
-	ListTelefones() ([]domain.Telefone, error)
+	ListTelefones(filtro domain.TelefoneFiltro) ([]domain.Telefone, int64, error)
 	UpdateTelefone(telefone *domain.Telefone) error
-	DeleteTelefone(id string) error
+	DeleteTelefone(id string, remocao domain.Remocao) error
 
 	// Métodos de Contexto
 	CreateContexto(contexto *domain.Contexto) error
 	GetContexto(id string) (*domain.Contexto, error)
-	ListContextos() ([]domain.Contexto, error)
+	ListContextos(filtro domain.ContextoFiltro) ([]domain.Contexto, int64, error)
+	// UpdateContexto não altera as pessoas do contexto
 	UpdateContexto(contexto *domain.Contexto) error
-	DeleteContexto(id string) error
+	DeleteContexto(id string, remocao domain.Remocao) error
+	// AddPessoaContexto põe a pessoa no fim do contexto; se ela já estiver nele,
+	// nada muda
+	AddPessoaContexto(contextoID, pessoaID string) error
+	// RemovePessoaContexto retorna domain.ErrNaoEncontrado também quando a
+	// pessoa não está no contexto
+	RemovePessoaContexto(contextoID, pessoaID string) error
 
 	// Métodos de Prompt
 	CreatePrompt(prompt *domain.Prompt) error
 	GetPrompt(id string) (*domain.Prompt, error)
-	ListPrompts() ([]domain.Prompt, error)
+	ListPrompts(filtro domain.PromptFiltro) ([]domain.Prompt, int64, error)
 	UpdatePrompt(prompt *domain.Prompt) error
-	DeletePrompt(id string) error
+	DeletePrompt(id string, remocao domain.Remocao) error
+	CreatePromptVersao(versao *domain.PromptVersao) error
+	GetPromptVersao(promptID string, numero int) (*domain.PromptVersao, error)
+	ListPromptVersoes(promptID string) ([]domain.PromptVersao, error)
+	UpdatePromptEmbedding(id string, embedding *domain.Embedding) error
+
+	// Métodos de Resposta
+	CreateResposta(resposta *domain.Resposta) error
+	GetResposta(id string) (*domain.Resposta, error)
+	ListRespostas(filtro domain.RespostaFiltro) ([]domain.Resposta, error)
+	SomarConsumo(filtro domain.ConsumoFiltro) (*domain.Consumo, error)
+	CreateUsoEmbeddings(uso *domain.UsoEmbeddings) error
+	UpdateRespostaEmbedding(id string, embedding *domain.Embedding) error
+
+	// Métodos de Conversa
+	CreateConversa(conversa *domain.Conversa) error
+	GetConversa(id string) (*domain.Conversa, error)
+	ListConversas(filtro domain.ConversaFiltro) ([]domain.Conversa, error)
+	AppendMensagensConversa(id string, mensagens []domain.MensagemConversa) error
+
+	// Métodos de Documento
+	// CreateDocumento grava o documento e os seus trechos
+	CreateDocumento(documento *domain.Documento, trechos []domain.TrechoDocumento) error
+	GetDocumento(id string) (*domain.Documento, error)
+	ListDocumentos(contextoID string) ([]domain.Documento, error)
+	ListTrechosDocumento(contextoID string) ([]domain.TrechoDocumento, error)
+	// DeleteDocumento remove o documento e os seus trechos
+	DeleteDocumento(id string) error
+
+	// Métodos de Job
+	CreateJob(job *domain.Job) error
+	GetJob(id string) (*domain.Job, error)
+	ListJobsPorStatus(status ...string) ([]domain.Job, error)
+	UpdateJobStatus(id string, status string) error
+	// UpdateJobItem grava o item de índice i e soma o resultado aos contadores do job
+	UpdateJobItem(id string, i int, item domain.ItemJob) error
+
+	// Métodos de Busca
+	// BuscarTexto retorna os registros com algum dos termos, com o score e os
+	// campos de domain.CamposBuscaTexto preenchidos
+	BuscarTexto(filtro domain.BuscaTextoFiltro) ([]domain.ResultadoTexto, error)
+
+	// Métodos de integridade
+	// Transacao executa fn com um repositório cujas operações são gravadas
+	// juntas: se fn retornar erro, nenhuma é gravada
+	Transacao(fn func(repo Repository) error) error
+	// ContarReferencias conta os registros que apontam para id pela relação
+	ContarReferencias(relacao domain.Relacao, id string) (int64, error)
+	// RemoverReferencias move para a lixeira os registros que apontam para
+	// id; nos contextos de uma pessoa, remove só a pessoa do contexto
+	RemoverReferencias(relacao domain.Relacao, id string, remocao domain.Remocao) error
+	// AnularReferencias mantém os registros que apontam para id, sem a
+	// referência
+	AnularReferencias(relacao domain.Relacao, id string) error
+
+	// Métodos de Lixeira
+	ListLixeira(filtro domain.LixeiraFiltro) ([]domain.ItemLixeira, int64, error)
+	// GetLixeira busca o registro na lixeira, com a referência ao registro a
+	// que ele pertence
+	GetLixeira(entidade, id string) (*domain.ItemLixeira, error)
+	// Restaurar tira o registro da lixeira e retorna a remoção desfeita;
+	// retorna domain.ErrNaoEncontrado se ele não estiver na lixeira
+	Restaurar(entidade, id string) (*domain.Remocao, error)
+	// RestaurarReferencias tira da lixeira os registros que apontam para id e
+	// foram removidos em em, junto com ele
+	RestaurarReferencias(relacao domain.Relacao, id string, em time.Time) error
+	// PurgarLixeira apaga de vez os registros removidos até ate e retorna
+	// quantos foram apagados
+	PurgarLixeira(ate time.Time) (int64, error)
 }
 
 type PessoaUseCase struct {
-	repo Repository
+	repo   Repository
+	regras RegrasRemocao
 }
 
 func NewPessoaUseCase(repo Repository) *PessoaUseCase {
-	return &PessoaUseCase{repo: repo}
+	return &PessoaUseCase{repo: repo, regras: RegrasRemocaoPadrao()}
+}
+
+// UsarRegrasRemocao troca as regras aplicadas aos telefones e aos contextos
+// ao remover uma pessoa
+func (u *PessoaUseCase) UsarRegrasRemocao(regras RegrasRemocao) {
+	u.regras = regras
 }
 
 func (u *PessoaUseCase) CreatePessoa(pessoa *domain.Pessoa) error {
@@ -50,14 +137,35 @@ func (u *PessoaUseCase) GetPessoa(id string) (*domain.Pessoa, error) {
 	return u.repo.GetPessoa(id)
 }
 
-func (u *PessoaUseCase) ListPessoas() ([]domain.Pessoa, error) {
-	return u.repo.ListPessoas()
+// ListPessoas retorna uma página das pessoas aceitas pelo filtro
+func (u *PessoaUseCase) ListPessoas(filtro domain.PessoaFiltro, pagina ParametrosPagina) (*domain.Pagina[domain.Pessoa], error) {
+	return paginar(pagina, ordenacaoPessoas, func(p domain.Paginacao) ([]domain.Pessoa, int64, error) {
+		filtro.Paginacao = p
+		return u.repo.ListPessoas(filtro)
+	})
 }
 
+// UpdatePessoa ignora os telefones e os contextos recebidos, que são
+// alterados pelos seus próprios endpoints
 func (u *PessoaUseCase) UpdatePessoa(pessoa *domain.Pessoa) error {
+	pessoa.Telefones, pessoa.Contextos = nil, nil
 	return u.repo.UpdatePessoa(pessoa)
 }
 
-func (u *PessoaUseCase) DeletePessoa(id string) error {
-	return u.repo.DeletePessoa(id)
+// ListTelefones retorna uma página dos telefones da pessoa
+func (u *PessoaUseCase) ListTelefones(id string, pagina ParametrosPagina) (*domain.Pagina[domain.Telefone], error) {
+	if _, err := u.repo.GetPessoa(id); err != nil {
+		return nil, naoEncontrado(ErrPessoaNaoEncontrada, err)
+	}
+	return paginar(pagina, ordenacaoTelefones, func(p domain.Paginacao) ([]domain.Telefone, int64, error) {
+		return u.repo.ListTelefones(domain.TelefoneFiltro{PessoaID: id, Paginacao: p})
+	})
+}
+
+// DeletePessoa move a pessoa para a lixeira, aplicando as regras de remoção
+// aos seus telefones e contextos; retorna ErrRemocaoRestrita se uma regra
+// restrict impedir a remoção
+func (u *PessoaUseCase) DeletePessoa(id, usuario string) error {
+	relacoes := []domain.Relacao{domain.RelacaoPessoaTelefones, domain.RelacaoPessoaContextos}
+	return removerComRegras(u.repo, u.regras, id, novaRemocao(usuario), relacoes, Repository.DeletePessoa)
 }
@@ -0,0 +1,40 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"vend/internal/domain"
+)
+
+var ErrPrivacidadeInvalida = errors.New("política de privacidade inválida")
+
+// ValidarPrivacidade confere o modo e os tipos de dado da política
+func ValidarPrivacidade(p domain.PoliticaPrivacidade) error {
+	switch p.Modo {
+	case "", domain.PrivacidadeNenhuma, domain.PrivacidadeMascarar, domain.PrivacidadePseudonimizar:
+	default:
+		return fmt.Errorf("%w: modo %q (use %s, %s ou %s)", ErrPrivacidadeInvalida, p.Modo,
+			domain.PrivacidadeNenhuma, domain.PrivacidadeMascarar, domain.PrivacidadePseudonimizar)
+	}
+	for _, dado := range p.Dados {
+		if !slices.Contains(domain.DadosPessoais, dado) {
+			return fmt.Errorf("%w: dado %q desconhecido (disponíveis: %s)", ErrPrivacidadeInvalida, dado, strings.Join(domain.DadosPessoais, ", "))
+		}
+	}
+	return nil
+}
+
+// privacidadeDe reúne a política do contexto e os nomes das pessoas
+// envolvidas, que o provedor protege antes de enviar as mensagens
+func privacidadeDe(contexto *domain.Contexto, pessoa *domain.Pessoa) domain.Privacidade {
+	nomes := make([]string, 0, len(contexto.Pessoas)+1)
+	for _, p := range contexto.Pessoas {
+		nomes = append(nomes, p.Nome)
+	}
+	if pessoa != nil {
+		nomes = append(nomes, pessoa.Nome)
+	}
+	return domain.Privacidade{Politica: contexto.Privacidade, Nomes: nomes}
+}
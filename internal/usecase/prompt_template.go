@@ -0,0 +1,206 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"vend/internal/domain"
+)
+
+var (
+	ErrTemplateInvalido = errors.New("template inválido")
+	ErrVariavelInvalida = errors.New("variável inválida")
+)
+
+var nomeVariavel = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// DadosTemplate são os dados disponíveis no template do prompt, por exemplo
+// {{.Pessoa.Nome}}, {{.Contexto.DataFim.Format "02/01/2006"}} ou {{.Vars.desconto}}
+type DadosTemplate struct {
+	Pessoa   domain.Pessoa
+	Contexto domain.Contexto
+	Vars     map[string]any
+}
+
+// ValidarPrompt confere o schema de saída, o esquema de variáveis e o
+// template do prompt. O template é executado com dados vazios, o que rejeita
+// campos inexistentes e variáveis não declaradas.
+func ValidarPrompt(prompt *domain.Prompt) error {
+	if err := validarSchemaSaida(prompt.SchemaSaida); err != nil {
+		return err
+	}
+
+	declaradas := make(map[string]bool, len(prompt.Variaveis))
+	for i := range prompt.Variaveis {
+		v := &prompt.Variaveis[i]
+		if !nomeVariavel.MatchString(v.Nome) {
+			return fmt.Errorf("%w: nome %q deve ser um identificador", ErrVariavelInvalida, v.Nome)
+		}
+		if declaradas[v.Nome] {
+			return fmt.Errorf("%w: %s declarada mais de uma vez", ErrVariavelInvalida, v.Nome)
+		}
+		declaradas[v.Nome] = true
+
+		if v.Tipo == "" {
+			v.Tipo = domain.TipoVariavelTexto
+		}
+		if _, ok := valorZero(v.Tipo); !ok {
+			return fmt.Errorf("%w: tipo %q desconhecido para %s", ErrVariavelInvalida, v.Tipo, v.Nome)
+		}
+		if v.Padrao != "" {
+			if _, err := converterVariavel(*v, v.Padrao); err != nil {
+				return err
+			}
+		}
+	}
+
+	tmpl, err := parseTemplate(prompt.Conteudo)
+	if err != nil {
+		return err
+	}
+
+	vars := make(map[string]any, len(prompt.Variaveis))
+	for _, v := range prompt.Variaveis {
+		vars[v.Nome], _ = valorZero(v.Tipo)
+	}
+	if err := tmpl.Execute(io.Discard, DadosTemplate{Vars: vars}); err != nil {
+		return fmt.Errorf("%w: %v", ErrTemplateInvalido, err)
+	}
+
+	return nil
+}
+
+// RenderizarPrompt aplica o template do prompt à pessoa, ao contexto e aos
+// valores informados para as variáveis declaradas; pessoa e contexto podem ser nil
+func RenderizarPrompt(prompt *domain.Prompt, pessoa *domain.Pessoa, contexto *domain.Contexto, valores map[string]any) (string, error) {
+	vars, err := resolverVariaveis(prompt.Variaveis, valores)
+	if err != nil {
+		return "", err
+	}
+
+	tmpl, err := parseTemplate(prompt.Conteudo)
+	if err != nil {
+		return "", err
+	}
+
+	dados := DadosTemplate{Vars: vars}
+	if pessoa != nil {
+		dados.Pessoa = *pessoa
+	}
+	if contexto != nil {
+		dados.Contexto = *contexto
+	}
+
+	var conteudo strings.Builder
+	if err := tmpl.Execute(&conteudo, dados); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrTemplateInvalido, err)
+	}
+
+	return conteudo.String(), nil
+}
+
+func parseTemplate(conteudo string) (*template.Template, error) {
+	tmpl, err := template.New("prompt").Option("missingkey=error").Parse(conteudo)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTemplateInvalido, err)
+	}
+	return tmpl, nil
+}
+
+func resolverVariaveis(variaveis []domain.VariavelPrompt, valores map[string]any) (map[string]any, error) {
+	declaradas := make(map[string]bool, len(variaveis))
+	vars := make(map[string]any, len(variaveis))
+	for _, v := range variaveis {
+		declaradas[v.Nome] = true
+
+		valor, informado := valores[v.Nome]
+		switch {
+		case informado:
+		case v.Padrao != "":
+			valor = v.Padrao
+		case v.Obrigatoria:
+			return nil, fmt.Errorf("%w: %s é obrigatória", ErrVariavelInvalida, v.Nome)
+		default:
+			vars[v.Nome], _ = valorZero(v.Tipo)
+			continue
+		}
+
+		convertido, err := converterVariavel(v, valor)
+		if err != nil {
+			return nil, err
+		}
+		vars[v.Nome] = convertido
+	}
+
+	for nome := range valores {
+		if !declaradas[nome] {
+			return nil, fmt.Errorf("%w: %s não está declarada no prompt", ErrVariavelInvalida, nome)
+		}
+	}
+
+	return vars, nil
+}
+
+// converterVariavel converte o valor recebido (JSON ou texto) para o tipo declarado
+func converterVariavel(v domain.VariavelPrompt, valor any) (any, error) {
+	invalido := fmt.Errorf("%w: valor %v não é do tipo %s para %s", ErrVariavelInvalida, valor, v.Tipo, v.Nome)
+
+	switch v.Tipo {
+	case domain.TipoVariavelTexto, "":
+		return fmt.Sprint(valor), nil
+	case domain.TipoVariavelNumero:
+		switch n := valor.(type) {
+		case float64:
+			return n, nil
+		case int:
+			return float64(n), nil
+		case string:
+			f, err := strconv.ParseFloat(n, 64)
+			if err != nil {
+				return nil, invalido
+			}
+			return f, nil
+		}
+	case domain.TipoVariavelBooleano:
+		switch b := valor.(type) {
+		case bool:
+			return b, nil
+		case string:
+			parsed, err := strconv.ParseBool(b)
+			if err != nil {
+				return nil, invalido
+			}
+			return parsed, nil
+		}
+	case domain.TipoVariavelData:
+		if texto, ok := valor.(string); ok {
+			for _, layout := range []string{"2006-01-02", time.RFC3339} {
+				if data, err := time.Parse(layout, texto); err == nil {
+					return data, nil
+				}
+			}
+		}
+	}
+
+	return nil, invalido
+}
+
+func valorZero(tipo string) (any, bool) {
+	switch tipo {
+	case domain.TipoVariavelTexto:
+		return "", true
+	case domain.TipoVariavelNumero:
+		return float64(0), true
+	case domain.TipoVariavelBooleano:
+		return false, true
+	case domain.TipoVariavelData:
+		return time.Time{}, true
+	default:
+		return nil, false
+	}
+}
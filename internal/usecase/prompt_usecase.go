@@ -1,33 +1,185 @@
 package usecase
 
 import (
+	"errors"
+	"reflect"
 	"vend/internal/domain"
 )
 
+var ErrVersaoNaoEncontrada = errors.New("versão do prompt não encontrada")
+
 type PromptUseCase struct {
-	repo Repository
+	repo    Repository
+	modelos ModelosPermitidos
 }
 
-func NewPromptUseCase(repo Repository) *PromptUseCase {
-	return &PromptUseCase{repo: repo}
+func NewPromptUseCase(repo Repository, modelos ModelosPermitidos) *PromptUseCase {
+	return &PromptUseCase{repo: repo, modelos: modelos}
 }
 
-func (u *PromptUseCase) CreatePrompt(prompt *domain.Prompt) error {
-	return u.repo.CreatePrompt(prompt)
+// CreatePrompt cria o prompt e registra a sua versão 1, na mesma transação
+func (u *PromptUseCase) CreatePrompt(prompt *domain.Prompt, autor string) error {
+	if err := ValidarPrompt(prompt); err != nil {
+		return err
+	}
+	if err := u.modelos.Validar(prompt.Parametros); err != nil {
+		return err
+	}
+
+	prompt.Versao = 1
+	return u.repo.Transacao(func(tx Repository) error {
+		if err := tx.CreatePrompt(prompt); err != nil {
+			return err
+		}
+		return tx.CreatePromptVersao(novaVersao(prompt, autor, ""))
+	})
 }
 
 func (u *PromptUseCase) GetPrompt(id string) (*domain.Prompt, error) {
 	return u.repo.GetPrompt(id)
 }
 
-func (u *PromptUseCase) ListPrompts() ([]domain.Prompt, error) {
-	return u.repo.ListPrompts()
+// ListPrompts retorna uma página dos prompts aceitos pelo filtro
+func (u *PromptUseCase) ListPrompts(filtro domain.PromptFiltro, pagina ParametrosPagina) (*domain.Pagina[domain.Prompt], error) {
+	return paginar(pagina, ordenacaoPrompts, func(p domain.Paginacao) ([]domain.Prompt, int64, error) {
+		filtro.Paginacao = p
+		return u.repo.ListPrompts(filtro)
+	})
+}
+
+// UpdatePrompt grava o prompt e, se o conteúdo ou as variáveis mudaram,
+// registra uma nova versão com o diff em relação à anterior. A gravação e a
+// versão ficam na mesma transação: se duas atualizações concorrentes tentarem
+// o mesmo número de versão, o índice único de (prompt_id, numero) rejeita a
+// segunda e o prompt dela não é alterado.
+func (u *PromptUseCase) UpdatePrompt(prompt *domain.Prompt, autor string) error {
+	if err := ValidarPrompt(prompt); err != nil {
+		return err
+	}
+	if err := u.modelos.Validar(prompt.Parametros); err != nil {
+		return err
+	}
+
+	return u.repo.Transacao(func(tx Repository) error {
+		atual, err := tx.GetPrompt(prompt.ID.Hex())
+		if err != nil {
+			return naoEncontrado(ErrPromptNaoEncontrado, err)
+		}
+
+		prompt.CreatedAt = atual.CreatedAt
+		prompt.Versao = atual.Versao
+		mesmasVariaveis := len(prompt.Variaveis) == 0 && len(atual.Variaveis) == 0 ||
+			reflect.DeepEqual(prompt.Variaveis, atual.Variaveis)
+		if prompt.Conteudo == atual.Conteudo && mesmasVariaveis {
+			return tx.UpdatePrompt(prompt)
+		}
+
+		// Prompts criados antes do versionamento ganham a versão 1 com o texto atual
+		if atual.Versao == 0 {
+			atual.Versao = 1
+			if err := tx.CreatePromptVersao(novaVersao(atual, "", "")); err != nil {
+				return err
+			}
+		}
+
+		prompt.Versao = atual.Versao + 1
+		if err := tx.UpdatePrompt(prompt); err != nil {
+			return err
+		}
+
+		return tx.CreatePromptVersao(novaVersao(prompt, autor, atual.Conteudo))
+	})
+}
+
+// DeletePrompt move o prompt para a lixeira; as versões ficam com ele
+func (u *PromptUseCase) DeletePrompt(id, usuario string) error {
+	return u.repo.DeletePrompt(id, novaRemocao(usuario))
 }
 
-func (u *PromptUseCase) UpdatePrompt(prompt *domain.Prompt) error {
-	return u.repo.UpdatePrompt(prompt)
+// RenderPrompt pré-visualiza o prompt com os dados da pessoa e do contexto.
+// Sem contextoID, usa o contexto ao qual o prompt pertence, se houver.
+func (u *PromptUseCase) RenderPrompt(id, pessoaID, contextoID string, valores map[string]any) (string, error) {
+	prompt, err := u.repo.GetPrompt(id)
+	if err != nil {
+		return "", naoEncontrado(ErrPromptNaoEncontrado, err)
+	}
+
+	var pessoa *domain.Pessoa
+	if pessoaID != "" {
+		if pessoa, err = u.repo.GetPessoa(pessoaID); err != nil {
+			return "", naoEncontrado(ErrPessoaNaoEncontrada, err)
+		}
+	}
+
+	if contextoID == "" && !prompt.ContextoID.IsZero() {
+		contextoID = prompt.ContextoID.Hex()
+	}
+	var contexto *domain.Contexto
+	if contextoID != "" {
+		if contexto, err = u.repo.GetContexto(contextoID); err != nil {
+			return "", naoEncontrado(ErrContextoNaoEncontrado, err)
+		}
+	}
+
+	return RenderizarPrompt(prompt, pessoa, contexto, valores)
+}
+
+func (u *PromptUseCase) ListVersoes(promptID string) ([]domain.PromptVersao, error) {
+	return u.repo.ListPromptVersoes(promptID)
+}
+
+func (u *PromptUseCase) GetVersao(promptID string, numero int) (*domain.PromptVersao, error) {
+	versao, err := u.repo.GetPromptVersao(promptID, numero)
+	if err != nil {
+		return nil, naoEncontrado(ErrVersaoNaoEncontrada, err)
+	}
+	return versao, nil
+}
+
+// DiffVersoes compara o conteúdo de duas versões do prompt
+func (u *PromptUseCase) DiffVersoes(promptID string, de, para int) (string, error) {
+	origem, err := u.GetVersao(promptID, de)
+	if err != nil {
+		return "", err
+	}
+
+	destino, err := u.GetVersao(promptID, para)
+	if err != nil {
+		return "", err
+	}
+
+	return DiffLinhas(origem.Conteudo, destino.Conteudo), nil
+}
+
+// RestaurarVersao volta o prompt ao texto de uma versão anterior. O histórico
+// não é reescrito: a restauração vira uma nova versão.
+func (u *PromptUseCase) RestaurarVersao(promptID string, numero int, autor string) (*domain.Prompt, error) {
+	versao, err := u.GetVersao(promptID, numero)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt, err := u.repo.GetPrompt(promptID)
+	if err != nil {
+		return nil, naoEncontrado(ErrPromptNaoEncontrado, err)
+	}
+
+	prompt.Conteudo = versao.Conteudo
+	prompt.Variaveis = versao.Variaveis
+	if err := u.UpdatePrompt(prompt, autor); err != nil {
+		return nil, err
+	}
+
+	return prompt, nil
 }
 
-func (u *PromptUseCase) DeletePrompt(id string) error {
-	return u.repo.DeletePrompt(id)
+func novaVersao(prompt *domain.Prompt, autor, conteudoAnterior string) *domain.PromptVersao {
+	return &domain.PromptVersao{
+		PromptID:  prompt.ID,
+		Numero:    prompt.Versao,
+		Conteudo:  prompt.Conteudo,
+		Variaveis: prompt.Variaveis,
+		Autor:     autor,
+		Diff:      DiffLinhas(conteudoAnterior, prompt.Conteudo),
+	}
 }
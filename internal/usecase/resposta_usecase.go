@@ -0,0 +1,21 @@
+package usecase
+
+import (
+	"vend/internal/domain"
+)
+
+type RespostaUseCase struct {
+	repo Repository
+}
+
+func NewRespostaUseCase(repo Repository) *RespostaUseCase {
+	return &RespostaUseCase{repo: repo}
+}
+
+func (u *RespostaUseCase) GetResposta(id string) (*domain.Resposta, error) {
+	return u.repo.GetResposta(id)
+}
+
+func (u *RespostaUseCase) ListRespostas(filtro domain.RespostaFiltro) ([]domain.Resposta, error) {
+	return u.repo.ListRespostas(filtro)
+}
@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"vend/internal/domain"
+	"vend/internal/jsonschema"
+)
+
+// tentativasSaida é quantas vezes o modelo é chamado até produzir uma saída
+// que siga o schema do prompt
+const tentativasSaida = 3
+
+var ErrSaidaInvalida = errors.New("o modelo não produziu uma saída válida para o schema do prompt")
+
+// validarSchemaSaida exige um objeto na raiz, como pedem os provedores
+func validarSchemaSaida(schema map[string]any) error {
+	if schema == nil {
+		return nil
+	}
+	if schema["type"] != "object" {
+		return fmt.Errorf("%w: a raiz do schema de saída deve ter \"type\": \"object\"", jsonschema.ErrSchemaInvalido)
+	}
+	return jsonschema.ValidarSchema(schema)
+}
+
+// LerSaida decodifica o conteúdo gerado e o valida contra o schema; blocos
+// de código markdown em volta do JSON são ignorados
+func LerSaida(schema map[string]any, conteudo string) (any, error) {
+	conteudo = strings.TrimSpace(conteudo)
+	if strings.HasPrefix(conteudo, "```") {
+		conteudo = strings.TrimPrefix(conteudo, "```json")
+		conteudo = strings.TrimPrefix(conteudo, "```")
+		conteudo = strings.TrimSuffix(strings.TrimSpace(conteudo), "```")
+	}
+
+	var saida any
+	if err := json.Unmarshal([]byte(conteudo), &saida); err != nil {
+		return nil, fmt.Errorf("JSON inválido: %v", err)
+	}
+	if err := jsonschema.Validar(schema, saida); err != nil {
+		return nil, err
+	}
+	return saida, nil
+}
+
+// gerarEstruturado chama o provedor até a saída seguir o schema, mostrando ao
+// modelo o erro da tentativa anterior. O uso de tokens soma todas as tentativas;
+// após a última falha, ou se o provedor falhar depois de consumir tokens, o
+// último resultado volta junto com o erro, para ser registrado.
+func (u *GeracaoUseCase) gerarEstruturado(ctx context.Context, req domain.RequisicaoGeracao) (*domain.ResultadoGeracao, any, error) {
+	req.Mensagens = slices.Clone(req.Mensagens)
+
+	var uso domain.UsoTokens
+	var resultado *domain.ResultadoGeracao
+	var errSaida error
+	for tentativa := 0; tentativa < tentativasSaida; tentativa++ {
+		atual, err := gerarComFerramentas(ctx, u.provider, u.ferramentas, req)
+		if atual != nil {
+			resultado = atual
+			uso = somarUso(uso, resultado.Uso)
+			resultado.Uso = uso
+		}
+		if err != nil {
+			// As tentativas e rodadas anteriores já consumiram tokens
+			return resultado, nil, err
+		}
+
+		var saida any
+		saida, errSaida = LerSaida(req.SchemaSaida, resultado.Conteudo)
+		if errSaida == nil {
+			return resultado, saida, nil
+		}
+
+		req.Mensagens = append(req.Mensagens,
+			domain.Mensagem{Papel: domain.PapelAssistente, Conteudo: resultado.Conteudo},
+			domain.Mensagem{Papel: domain.PapelUsuario, Conteudo: "A resposta não segue o schema (" + errSaida.Error() + "). Responda novamente apenas com o JSON corrigido."},
+		)
+	}
+
+	return resultado, nil, fmt.Errorf("%w: %v", ErrSaidaInvalida, errSaida)
+}
+
+func somarUso(a, b domain.UsoTokens) domain.UsoTokens {
+	return domain.UsoTokens{
+		PromptTokens:     a.PromptTokens + b.PromptTokens,
+		CompletionTokens: a.CompletionTokens + b.CompletionTokens,
+		TotalTokens:      a.TotalTokens + b.TotalTokens,
+	}
+}
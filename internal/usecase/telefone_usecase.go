@@ -20,14 +20,19 @@ func (u *TelefoneUseCase) GetTelefone(id string) (*domain.Telefone, error) {
 	return u.repo.GetTelefone(id)
 }
 
-func (u *TelefoneUseCase) ListTelefones() ([]domain.Telefone, error) {
-	return u.repo.ListTelefones()
+// ListTelefones retorna uma página dos telefones aceitos pelo filtro
+func (u *TelefoneUseCase) ListTelefones(filtro domain.TelefoneFiltro, pagina ParametrosPagina) (*domain.Pagina[domain.Telefone], error) {
+	return paginar(pagina, ordenacaoTelefones, func(p domain.Paginacao) ([]domain.Telefone, int64, error) {
+		filtro.Paginacao = p
+		return u.repo.ListTelefones(filtro)
+	})
 }
 
 func (u *TelefoneUseCase) UpdateTelefone(telefone *domain.Telefone) error {
 	return u.repo.UpdateTelefone(telefone)
 }
 
-func (u *TelefoneUseCase) DeleteTelefone(id string) error {
-	return u.repo.DeleteTelefone(id)
+// DeleteTelefone move o telefone para a lixeira
+func (u *TelefoneUseCase) DeleteTelefone(id, usuario string) error {
+	return u.repo.DeleteTelefone(id, novaRemocao(usuario))
 }
@@ -0,0 +1,107 @@
+package integration
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+	"vend/internal/domain"
+	"vend/internal/infrastructure/memoria"
+	mongoRepo "vend/internal/infrastructure/mongodb"
+	"vend/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func setupTestMongo(t *testing.T) *mongoRepo.Repository {
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetServerSelectionTimeout(2*time.Second))
+	if err == nil {
+		err = client.Ping(ctx, nil)
+	}
+	if err != nil {
+		t.Skipf("MongoDB indisponível: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+
+	// Limpar o banco e recriar os índices
+	db := client.Database("vend_test")
+	if err := db.Drop(ctx); err != nil {
+		t.Fatalf("Erro ao limpar o banco de dados: %v", err)
+	}
+	repo := mongoRepo.NewRepository(db)
+	if err := repo.CriarIndices(); err != nil {
+		t.Fatalf("Erro ao criar os índices: %v", err)
+	}
+
+	return repo
+}
+
+// TestAtualizacaoIntegration confere que atualizar substitui o registro: os
+// campos opcionais deixados vazios voltam vazios em todos os armazenamentos
+func TestAtualizacaoIntegration(t *testing.T) {
+	t.Run("memoria", func(t *testing.T) {
+		testarAtualizacao(t, memoria.NewRepository())
+	})
+	t.Run("postgres", func(t *testing.T) {
+		testarAtualizacao(t, setupTestDB(t))
+	})
+	t.Run("mongodb", func(t *testing.T) {
+		testarAtualizacao(t, setupTestMongo(t))
+	})
+}
+
+func testarAtualizacao(t *testing.T, repo usecase.Repository) {
+	t.Run("Limpar orçamento do contexto", func(t *testing.T) {
+		contextos := usecase.NewContextoUseCase(repo, nil)
+		contexto := &domain.Contexto{Nome: "Campanha", OrcamentoTokensMensal: 1000}
+		assert.NoError(t, contextos.CreateContexto(contexto))
+
+		contexto.OrcamentoTokensMensal = 0
+		assert.NoError(t, contextos.UpdateContexto(contexto))
+
+		recuperado, err := contextos.GetContexto(contexto.ID.Hex())
+		assert.NoError(t, err)
+		assert.Zero(t, recuperado.OrcamentoTokensMensal)
+	})
+
+	t.Run("Limpar variáveis e schema do prompt", func(t *testing.T) {
+		prompts := usecase.NewPromptUseCase(repo, nil)
+		prompt := &domain.Prompt{
+			Conteudo:    "Olá, {{.Vars.nome}}",
+			Variaveis:   []domain.VariavelPrompt{{Nome: "nome"}},
+			SchemaSaida: map[string]any{"type": "object"},
+		}
+		assert.NoError(t, prompts.CreatePrompt(prompt, "teste"))
+
+		prompt.Conteudo = "Olá"
+		prompt.Variaveis = nil
+		prompt.SchemaSaida = nil
+		assert.NoError(t, prompts.UpdatePrompt(prompt, "teste"))
+
+		recuperado, err := prompts.GetPrompt(prompt.ID.Hex())
+		assert.NoError(t, err)
+		assert.Empty(t, recuperado.Variaveis)
+		assert.Empty(t, recuperado.SchemaSaida)
+
+		// Restaurar uma versão sem variáveis também as remove
+		_, err = prompts.RestaurarVersao(prompt.ID.Hex(), 1, "teste")
+		assert.NoError(t, err)
+		restaurado, err := prompts.RestaurarVersao(prompt.ID.Hex(), 2, "teste")
+		assert.NoError(t, err)
+		assert.Empty(t, restaurado.Variaveis)
+
+		recuperado, err = prompts.GetPrompt(prompt.ID.Hex())
+		assert.NoError(t, err)
+		assert.Empty(t, recuperado.Variaveis)
+		assert.Equal(t, 4, recuperado.Versao)
+	})
+}
@@ -0,0 +1,57 @@
+package integration
+
+import (
+	"testing"
+	"vend/internal/domain"
+	"vend/internal/infrastructure/memoria"
+	"vend/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestBuscaTextoIntegration confere que os armazenamentos encontram as
+// palavras que começam pelos termos, sem diferenciar maiúsculas nem acentos
+func TestBuscaTextoIntegration(t *testing.T) {
+	t.Run("memoria", func(t *testing.T) {
+		testarBuscaTexto(t, memoria.NewRepository())
+	})
+	t.Run("postgres", func(t *testing.T) {
+		testarBuscaTexto(t, setupTestDB(t))
+	})
+	t.Run("mongodb", func(t *testing.T) {
+		testarBuscaTexto(t, setupTestMongo(t))
+	})
+}
+
+func testarBuscaTexto(t *testing.T, repo usecase.Repository) {
+	pessoa := &domain.Pessoa{Nome: "Ana Silva", Email: "ana@teste.com"}
+	assert.NoError(t, repo.CreatePessoa(pessoa))
+	contexto := &domain.Contexto{Nome: "Promoção de Verão", Descricao: "Descontos para clientes antigos"}
+	assert.NoError(t, repo.CreateContexto(contexto))
+
+	casos := []struct {
+		termo string
+		tipo  string
+		id    string
+	}{
+		{"silv", domain.TipoBuscaPessoa, pessoa.ID.Hex()},
+		{"silva", domain.TipoBuscaPessoa, pessoa.ID.Hex()},
+		{"promoc", domain.TipoBuscaContexto, contexto.ID.Hex()},
+		{"vera", domain.TipoBuscaContexto, contexto.ID.Hex()},
+		{"desconto", domain.TipoBuscaContexto, contexto.ID.Hex()},
+	}
+	for _, caso := range casos {
+		t.Run(caso.termo, func(t *testing.T) {
+			resultados, err := repo.BuscarTexto(domain.BuscaTextoFiltro{Termos: domain.TermosBusca(caso.termo), Tipos: []string{caso.tipo}, Limite: 10})
+			assert.NoError(t, err)
+			if assert.Len(t, resultados, 1) {
+				assert.Equal(t, caso.id, resultados[0].ID)
+				assert.Positive(t, resultados[0].Score)
+			}
+		})
+	}
+
+	resultados, err := repo.BuscarTexto(domain.BuscaTextoFiltro{Termos: []string{"silvestre"}, Tipos: []string{domain.TipoBuscaPessoa}, Limite: 10})
+	assert.NoError(t, err)
+	assert.Empty(t, resultados)
+}
@@ -1,33 +1,56 @@
 package integration
 
 import (
+	"os"
 	"testing"
+	"time"
 	"vend/internal/domain"
+	"vend/internal/infrastructure/memoria"
 	postgresRepo "vend/internal/infrastructure/postgres"
 	"vend/internal/usecase"
 
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-func setupTestDB(t *testing.T) *gorm.DB {
-	dsn := "host=localhost user=postgres password=postgres dbname=vend_test port=5432 sslmode=disable"
+func setupTestDB(t *testing.T) *postgresRepo.Repository {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		dsn = "host=localhost user=postgres password=postgres dbname=vend_test port=5432 sslmode=disable"
+	}
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
-		t.Fatalf("Erro ao conectar ao banco de dados: %v", err)
+		t.Skipf("PostgreSQL indisponível: %v", err)
 	}
 
 	// Limpar e migrar tabelas
-	db.Migrator().DropTable(&domain.Pessoa{}, &domain.Telefone{}, &domain.Contexto{}, &domain.Prompt{})
-	db.AutoMigrate(&domain.Pessoa{}, &domain.Telefone{}, &domain.Contexto{}, &domain.Prompt{})
+	err = db.Exec(`DROP TABLE IF EXISTS itens_job, jobs, trechos_documento, documentos, mensagens_conversa,
+		conversas, respostas, usos_embeddings, prompt_versoes, prompts, contexto_pessoas, contextos, telefones, pessoas CASCADE`).Error
+	if err != nil {
+		t.Fatalf("Erro ao limpar o banco de dados: %v", err)
+	}
+	repo := postgresRepo.NewRepository(db)
+	if err := repo.Migrar(); err != nil {
+		t.Fatalf("Erro ao migrar o banco de dados: %v", err)
+	}
 
-	return db
+	return repo
 }
 
+// TestPessoaIntegration roda os mesmos casos no repositório em memória e no
+// PostgreSQL, que devem se comportar igual
 func TestPessoaIntegration(t *testing.T) {
-	db := setupTestDB(t)
-	repo := postgresRepo.NewRepository(db)
+	t.Run("memoria", func(t *testing.T) {
+		testarPessoas(t, memoria.NewRepository())
+	})
+	t.Run("postgres", func(t *testing.T) {
+		testarPessoas(t, setupTestDB(t))
+	})
+}
+
+func testarPessoas(t *testing.T, repo usecase.Repository) {
 	useCase := usecase.NewPessoaUseCase(repo)
 
 	t.Run("Criar e recuperar pessoa", func(t *testing.T) {
@@ -38,9 +61,9 @@ func TestPessoaIntegration(t *testing.T) {
 
 		err := useCase.CreatePessoa(pessoa)
 		assert.NoError(t, err)
-		assert.NotZero(t, pessoa.ID)
+		assert.False(t, pessoa.ID.IsZero())
 
-		recuperada, err := useCase.GetPessoa(pessoa.ID)
+		recuperada, err := useCase.GetPessoa(pessoa.ID.Hex())
 		assert.NoError(t, err)
 		assert.Equal(t, pessoa.Nome, recuperada.Nome)
 		assert.Equal(t, pessoa.Email, recuperada.Email)
@@ -63,9 +86,9 @@ func TestPessoaIntegration(t *testing.T) {
 			assert.NoError(t, err)
 		}
 
-		lista, err := useCase.ListPessoas()
+		lista, err := useCase.ListPessoas(domain.PessoaFiltro{}, usecase.ParametrosPagina{})
 		assert.NoError(t, err)
-		assert.GreaterOrEqual(t, len(lista), 2)
+		assert.GreaterOrEqual(t, len(lista.Itens), 2)
 	})
 
 	t.Run("Atualizar pessoa", func(t *testing.T) {
@@ -78,12 +101,15 @@ func TestPessoaIntegration(t *testing.T) {
 		assert.NoError(t, err)
 
 		pessoa.Nome = "Teste Atualizado"
+		pessoa.Email = ""
 		err = useCase.UpdatePessoa(pessoa)
 		assert.NoError(t, err)
 
-		atualizada, err := useCase.GetPessoa(pessoa.ID)
+		atualizada, err := useCase.GetPessoa(pessoa.ID.Hex())
 		assert.NoError(t, err)
 		assert.Equal(t, "Teste Atualizado", atualizada.Nome)
+		assert.Empty(t, atualizada.Email)
+		assert.Equal(t, pessoa.CreatedAt.Unix(), atualizada.CreatedAt.Unix())
 	})
 
 	t.Run("Deletar pessoa", func(t *testing.T) {
@@ -95,10 +121,51 @@ func TestPessoaIntegration(t *testing.T) {
 		err := useCase.CreatePessoa(pessoa)
 		assert.NoError(t, err)
 
-		err = useCase.DeletePessoa(pessoa.ID)
+		err = useCase.DeletePessoa(pessoa.ID.Hex(), "teste")
 		assert.NoError(t, err)
 
-		_, err = useCase.GetPessoa(pessoa.ID)
-		assert.Error(t, err)
+		_, err = useCase.GetPessoa(pessoa.ID.Hex())
+		assert.ErrorIs(t, err, domain.ErrNaoEncontrado)
 	})
 }
+
+func TestContextoPessoasIntegration(t *testing.T) {
+	repo := setupTestDB(t)
+
+	pessoa := &domain.Pessoa{Nome: "Lead", Email: "lead@teste.com"}
+	assert.NoError(t, repo.CreatePessoa(pessoa))
+	telefone := &domain.Telefone{Numero: "11987654321", Tipo: "celular", PessoaID: pessoa.ID}
+	assert.NoError(t, repo.CreateTelefone(telefone))
+
+	outra := &domain.Pessoa{Nome: "Cliente", Email: "cliente@teste.com"}
+	assert.NoError(t, repo.CreatePessoa(outra))
+
+	contexto := &domain.Contexto{Nome: "Campanha", PessoaIDs: []primitive.ObjectID{pessoa.ID}}
+	assert.NoError(t, repo.CreateContexto(contexto))
+	assert.NoError(t, repo.AddPessoaContexto(contexto.ID.Hex(), outra.ID.Hex()))
+	assert.NoError(t, repo.AddPessoaContexto(contexto.ID.Hex(), outra.ID.Hex()))
+
+	// As pessoas do contexto são lidas da pessoa, não de uma cópia
+	pessoa.Nome = "Lead Atualizado"
+	assert.NoError(t, repo.UpdatePessoa(pessoa))
+
+	recuperado, err := repo.GetContexto(contexto.ID.Hex())
+	assert.NoError(t, err)
+	assert.Equal(t, []primitive.ObjectID{pessoa.ID, outra.ID}, recuperado.PessoaIDs)
+	assert.Len(t, recuperado.Pessoas, 2)
+	assert.Equal(t, "Lead Atualizado", recuperado.Pessoas[0].Nome)
+
+	comRelacoes, err := repo.GetPessoa(pessoa.ID.Hex())
+	assert.NoError(t, err)
+	assert.Len(t, comRelacoes.Telefones, 1)
+	assert.Len(t, comRelacoes.Contextos, 1)
+
+	assert.NoError(t, repo.RemovePessoaContexto(contexto.ID.Hex(), outra.ID.Hex()))
+	assert.ErrorIs(t, repo.RemovePessoaContexto(contexto.ID.Hex(), outra.ID.Hex()), domain.ErrNaoEncontrado)
+
+	// Remover a pessoa desfaz a ligação com o contexto
+	assert.NoError(t, repo.DeletePessoa(pessoa.ID.Hex(), domain.Remocao{Em: time.Now()}))
+	recuperado, err = repo.GetContexto(contexto.ID.Hex())
+	assert.NoError(t, err)
+	assert.Empty(t, recuperado.Pessoas)
+}
@@ -0,0 +1,199 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"vend/internal/domain"
+	"vend/internal/infrastructure/memoria"
+	"vend/internal/infrastructure/vetorial"
+	"vend/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFakeEmbeddingsDeterministicos(t *testing.T) {
+	provider := newFakeProvider(t, "")
+
+	a, err := provider.Embeddings(context.Background(), []string{"Desconto de Black Friday", "desconto de black friday!"})
+	assert.NoError(t, err)
+	b, err := provider.Embeddings(context.Background(), []string{"Desconto de Black Friday"})
+	assert.NoError(t, err)
+
+	assert.Len(t, a.Vetores, 2)
+	assert.Equal(t, a.Vetores[0], b.Vetores[0])
+	assert.Equal(t, a.Vetores[0], a.Vetores[1])
+}
+
+func TestIndiceMemoriaOrdenaPorSimilaridade(t *testing.T) {
+	indice := vetorial.NewMemoria()
+	ctx := context.Background()
+
+	assert.NoError(t, indice.Indexar(ctx,
+		domain.DocumentoVetorial{Tipo: domain.TipoBuscaPrompt, ID: "a", Texto: "a", Vetor: []float32{1, 0}},
+		domain.DocumentoVetorial{Tipo: domain.TipoBuscaPrompt, ID: "b", Texto: "b", Vetor: []float32{1, 1}},
+		domain.DocumentoVetorial{Tipo: domain.TipoBuscaResposta, ID: "c", Texto: "c", Vetor: []float32{0, 1}},
+		domain.DocumentoVetorial{Tipo: domain.TipoBuscaResposta, ID: "d", Texto: "d", Vetor: []float32{1, 0, 0}},
+	))
+
+	resultados, err := indice.Buscar(ctx, []float32{0, 1}, 2)
+	assert.NoError(t, err)
+	assert.Len(t, resultados, 2)
+	assert.Equal(t, "c", resultados[0].ID)
+	assert.InDelta(t, 1.0, resultados[0].Score, 1e-9)
+	assert.Equal(t, "b", resultados[1].ID)
+
+	resultados, err = indice.Buscar(ctx, []float32{0, 1}, 10, domain.TipoBuscaPrompt)
+	assert.NoError(t, err)
+	assert.Len(t, resultados, 2)
+
+	assert.NoError(t, indice.Remover(ctx, domain.TipoBuscaResposta, "c"))
+	resultados, err = indice.Buscar(ctx, []float32{0, 1}, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, "b", resultados[0].ID)
+}
+
+func TestBuscaSemanticaEncontraPromptParecido(t *testing.T) {
+	mockRepo := new(MockRepository)
+	useCase := usecase.NewBuscaUseCase(mockRepo, newFakeProvider(t, ""), nil, vetorial.NewMemoria(), 0)
+
+	desconto := domain.Prompt{ID: primitive.NewObjectID(), Conteudo: "Ofereça desconto de Black Friday no plano anual"}
+	suporte := domain.Prompt{ID: primitive.NewObjectID(), Conteudo: "Responda dúvidas de suporte técnico"}
+	resposta := domain.Resposta{ID: primitive.NewObjectID(), Conteudo: "Aproveite o desconto no plano anual"}
+
+	mockRepo.On("ListPrompts", mock.Anything).Return([]domain.Prompt{desconto, suporte}, int64(2), nil)
+	mockRepo.On("ListRespostas", mock.Anything).Return([]domain.Resposta{resposta}, nil)
+	mockRepo.On("UpdatePromptEmbedding", mock.Anything, mock.AnythingOfType("*domain.Embedding")).Return(nil)
+	mockRepo.On("UpdateRespostaEmbedding", resposta.ID.Hex(), mock.AnythingOfType("*domain.Embedding")).Return(nil)
+
+	assert.NoError(t, useCase.Sincronizar(context.Background()))
+
+	resultados, err := useCase.BuscarSemantica(context.Background(), "desconto black friday", 2, domain.TipoBuscaPrompt)
+	assert.NoError(t, err)
+	assert.Len(t, resultados, 2)
+	assert.Equal(t, desconto.ID.Hex(), resultados[0].ID)
+	assert.Greater(t, resultados[0].Score, resultados[1].Score)
+
+	resultados, err = useCase.BuscarSemantica(context.Background(), "plano anual", 0, "")
+	assert.NoError(t, err)
+	assert.Len(t, resultados, 3)
+	mockRepo.AssertNumberOfCalls(t, "UpdatePromptEmbedding", 2)
+}
+
+func TestBuscaSemanticaReaproveitaEmbeddingGravado(t *testing.T) {
+	mockRepo := new(MockRepository)
+	provider := newFakeProvider(t, "")
+	useCase := usecase.NewBuscaUseCase(mockRepo, provider, nil, vetorial.NewMemoria(), 0)
+
+	prompt := domain.Prompt{ID: primitive.NewObjectID(), Conteudo: "Ofereça desconto"}
+	mockRepo.On("ListPrompts", mock.Anything).Return([]domain.Prompt{prompt}, int64(1), nil).Once()
+	mockRepo.On("ListRespostas", mock.Anything).Return([]domain.Resposta{}, nil)
+	var gravado *domain.Embedding
+	mockRepo.On("UpdatePromptEmbedding", prompt.ID.Hex(), mock.AnythingOfType("*domain.Embedding")).Return(nil).Run(func(args mock.Arguments) {
+		gravado = args.Get(1).(*domain.Embedding)
+	}).Once()
+
+	assert.NoError(t, useCase.Sincronizar(context.Background()))
+
+	// Após um reinício o embedding volta do banco e não é recalculado
+	prompt.Embedding = gravado
+	mockRepo.On("ListPrompts", mock.Anything).Return([]domain.Prompt{prompt}, int64(1), nil)
+	outro := usecase.NewBuscaUseCase(mockRepo, provider, nil, vetorial.NewMemoria(), 0)
+	assert.NoError(t, outro.Sincronizar(context.Background()))
+
+	resultados, err := outro.BuscarSemantica(context.Background(), "desconto", 5, "")
+	assert.NoError(t, err)
+	assert.Len(t, resultados, 1)
+	mockRepo.AssertNumberOfCalls(t, "UpdatePromptEmbedding", 1)
+}
+
+// A sincronização lê os registros em páginas e chega aos mais antigos
+func TestBuscaSincronizaEmPaginas(t *testing.T) {
+	repo := memoria.NewRepository()
+	useCase := usecase.NewBuscaUseCase(repo, newFakeProvider(t, ""), nil, vetorial.NewMemoria(), 0)
+
+	antigo := &domain.Prompt{Conteudo: "Ofereça desconto de Black Friday no plano anual"}
+	assert.NoError(t, repo.CreatePrompt(antigo))
+	for i := 0; i < 1200; i++ {
+		assert.NoError(t, repo.CreatePrompt(&domain.Prompt{Conteudo: fmt.Sprintf("Lembrete %d de renovação", i)}))
+	}
+
+	assert.NoError(t, useCase.Sincronizar(context.Background()))
+
+	resultados, err := useCase.BuscarSemantica(context.Background(), "desconto black friday", 1, domain.TipoBuscaPrompt)
+	assert.NoError(t, err)
+	if assert.Len(t, resultados, 1) {
+		assert.Equal(t, antigo.ID.Hex(), resultados[0].ID)
+	}
+	prompts, _, err := repo.ListPrompts(domain.PromptFiltro{})
+	assert.NoError(t, err)
+	for _, prompt := range prompts {
+		assert.NotNil(t, prompt.Embedding)
+	}
+}
+
+func TestBuscaSemanticaSemEmbeddings(t *testing.T) {
+	useCase := usecase.NewBuscaUseCase(new(MockRepository), nil, nil, vetorial.NewMemoria(), 0)
+
+	_, err := useCase.BuscarSemantica(context.Background(), "desconto", 5, "")
+
+	assert.ErrorIs(t, err, usecase.ErrBuscaIndisponivel)
+}
+
+func TestBuscaTextoOrdenaEDestaca(t *testing.T) {
+	repo := memoria.NewRepository()
+	useCase := usecase.NewBuscaUseCase(repo, nil, nil, vetorial.NewMemoria(), 0)
+
+	assert.NoError(t, repo.CreatePessoa(&domain.Pessoa{Nome: "João Desconto", Email: "joao@exemplo.com"}))
+	prompt := &domain.Prompt{Conteudo: "Ofereça <b>desconto</b> de Black Friday, com descontos progressivos"}
+	assert.NoError(t, repo.CreatePrompt(prompt))
+	assert.NoError(t, repo.CreateContexto(&domain.Contexto{Nome: "Campanha", Descricao: "Sem promoções"}))
+
+	resultados, err := useCase.BuscarTexto("Descontos", "", 0)
+	assert.NoError(t, err)
+	assert.Len(t, resultados, 2)
+	assert.Equal(t, domain.TipoBuscaPrompt, resultados[0].Tipo)
+	assert.Equal(t, prompt.ID.Hex(), resultados[0].ID)
+	assert.Equal(t, []domain.Destaque{{
+		Campo:  "conteudo",
+		Trecho: "Ofereça &lt;b&gt;<em>desconto</em>&lt;/b&gt; de Black Friday, com <em>descontos</em> progressivos",
+	}}, resultados[0].Destaques)
+	assert.Equal(t, domain.TipoBuscaPessoa, resultados[1].Tipo)
+	assert.Equal(t, "João Desconto", resultados[1].Titulo)
+
+	resultados, err = useCase.BuscarTexto("joao", domain.TipoBuscaPessoa, 0)
+	assert.NoError(t, err)
+	assert.Len(t, resultados, 1)
+	assert.Equal(t, []domain.Destaque{
+		{Campo: "nome", Trecho: "<em>João</em> Desconto"},
+		{Campo: "email", Trecho: "<em>joao</em>@exemplo.com"},
+	}, resultados[0].Destaques)
+
+	_, err = useCase.BuscarTexto("  ", "", 0)
+	assert.ErrorIs(t, err, usecase.ErrConsultaVazia)
+	_, err = useCase.BuscarTexto("desconto", domain.TipoBuscaResposta, 0)
+	assert.ErrorIs(t, err, usecase.ErrParametroInvalido)
+}
+
+func TestBuscaTextoRecortaTrechoLongo(t *testing.T) {
+	mockRepo := new(MockRepository)
+	useCase := usecase.NewBuscaUseCase(mockRepo, nil, nil, vetorial.NewMemoria(), 0)
+
+	conteudo := strings.Repeat("texto de preenchimento ", 10) + "com desconto " + strings.Repeat("mais texto ", 20)
+	mockRepo.On("BuscarTexto", domain.BuscaTextoFiltro{Termos: []string{"desconto"}, Tipos: []string{domain.TipoBuscaPrompt}, Limite: 20}).
+		Return([]domain.ResultadoTexto{{Tipo: domain.TipoBuscaPrompt, ID: "1", Score: 1, Campos: map[string]string{"conteudo": conteudo}}}, nil)
+
+	resultados, err := useCase.BuscarTexto("desconto", domain.TipoBuscaPrompt, 0)
+	assert.NoError(t, err)
+	trecho := resultados[0].Destaques[0].Trecho
+	assert.True(t, strings.HasPrefix(trecho, "…"))
+	assert.True(t, strings.HasSuffix(trecho, "…"))
+	assert.Contains(t, trecho, "com <em>desconto</em> mais")
+	assert.Less(t, len(trecho), len(conteudo))
+	assert.Equal(t, "texto de preenchimento", resultados[0].Titulo[:22])
+	assert.True(t, strings.HasSuffix(resultados[0].Titulo, "…"))
+	mockRepo.AssertExpectations(t)
+}
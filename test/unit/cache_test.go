@@ -0,0 +1,71 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+	"vend/internal/domain"
+	"vend/internal/infrastructure/cache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUDescartaMenosUsado(t *testing.T) {
+	ctx := context.Background()
+	lru := cache.NewLRU(2)
+
+	lru.Set(ctx, "a", &domain.ResultadoGeracao{Conteudo: "a"}, 0)
+	lru.Set(ctx, "b", &domain.ResultadoGeracao{Conteudo: "b"}, 0)
+	_, _, _ = lru.Get(ctx, "a")
+	lru.Set(ctx, "c", &domain.ResultadoGeracao{Conteudo: "c"}, 0)
+
+	_, ok, _ := lru.Get(ctx, "b")
+	assert.False(t, ok)
+	resultado, ok, _ := lru.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, "a", resultado.Conteudo)
+}
+
+func TestLRUExpiraItens(t *testing.T) {
+	ctx := context.Background()
+	lru := cache.NewLRU(10)
+
+	lru.Set(ctx, "a", &domain.ResultadoGeracao{Conteudo: "a"}, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	_, ok, _ := lru.Get(ctx, "a")
+	assert.False(t, ok)
+}
+
+func TestCacheEvitaNovaChamada(t *testing.T) {
+	roteiro := &providerRoteiro{}
+	provider := cache.NewProvider(roteiro, cache.NewLRU(10), time.Hour)
+	req := domain.RequisicaoGeracao{Modelo: "gpt-4o", Mensagens: []domain.Mensagem{{Papel: domain.PapelUsuario, Conteudo: "resuma"}}}
+
+	_, err := provider.Generate(context.Background(), req)
+	assert.NoError(t, err)
+
+	resultado, err := provider.Generate(context.Background(), req)
+	assert.NoError(t, err)
+	assert.True(t, resultado.DoCache)
+	assert.Zero(t, resultado.Uso.TotalTokens)
+	assert.Equal(t, 1, roteiro.chamadas)
+
+	outra := req
+	outra.Temperatura = 0.2
+	_, _ = provider.Generate(context.Background(), outra)
+	assert.Equal(t, 2, roteiro.chamadas)
+}
+
+func TestCacheIgnoradoComNoCache(t *testing.T) {
+	roteiro := &providerRoteiro{}
+	provider := cache.NewProvider(roteiro, cache.NewLRU(10), time.Hour)
+	req := domain.RequisicaoGeracao{Mensagens: []domain.Mensagem{{Papel: domain.PapelUsuario, Conteudo: "resuma"}}}
+
+	_, _ = provider.Generate(context.Background(), req)
+	resultado, err := provider.Generate(domain.IgnorarCache(context.Background()), req)
+
+	assert.NoError(t, err)
+	assert.False(t, resultado.DoCache)
+	assert.Equal(t, 2, roteiro.chamadas)
+}
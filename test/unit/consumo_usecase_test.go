@@ -0,0 +1,96 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+	"vend/internal/domain"
+	"vend/internal/infrastructure/memoria"
+	"vend/internal/infrastructure/vetorial"
+	"vend/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCustoUsaPrefixoMaisLongo(t *testing.T) {
+	precos, err := usecase.ParsePrecos("gpt-4o=0.0025:0.01; local=0:0")
+	assert.NoError(t, err)
+
+	uso := domain.UsoTokens{PromptTokens: 1000, CompletionTokens: 2000, TotalTokens: 3000}
+
+	assert.InDelta(t, 0.0225, precos.Custo("gpt-4o-2024-08-06", uso), 1e-9)
+	assert.InDelta(t, 0.00135, precos.Custo("gpt-4o-mini", uso), 1e-9)
+	assert.Zero(t, precos.Custo("modelo-desconhecido", uso))
+
+	_, err = usecase.ParsePrecos("gpt-4o=barato")
+	assert.Error(t, err)
+}
+
+func TestConsumoContextoCalculaOrcamentoRestante(t *testing.T) {
+	mockRepo := new(MockRepository)
+	useCase := usecase.NewConsumoUseCase(mockRepo, usecase.PrecosPadrao())
+
+	contexto := &domain.Contexto{ID: primitive.NewObjectID(), OrcamentoTokensMensal: 1000}
+	filtro := domain.ConsumoFiltro{
+		ContextoID: contexto.ID.Hex(),
+		Inicio:     time.Date(2024, time.November, 1, 0, 0, 0, 0, time.UTC),
+		Fim:        time.Date(2024, time.December, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	mockRepo.On("GetContexto", contexto.ID.Hex()).Return(contexto, nil)
+	mockRepo.On("SomarConsumo", filtro).Return(&domain.Consumo{Geracoes: 3, TotalTokens: 1200}, nil)
+
+	consumo, err := useCase.ConsumoContexto(contexto.ID.Hex(), time.Date(2024, time.November, 20, 15, 0, 0, 0, time.UTC))
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), consumo.OrcamentoTokens)
+	assert.Equal(t, int64(0), *consumo.TokensRestantes)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestExecutarPromptComOrcamentoEsgotado(t *testing.T) {
+	mockRepo := new(MockRepository)
+	useCase := newGeracaoUseCase(t, mockRepo, "")
+
+	contexto := &domain.Contexto{ID: primitive.NewObjectID(), OrcamentoTokensMensal: 500}
+	prompt := &domain.Prompt{ID: primitive.NewObjectID(), ContextoID: contexto.ID}
+
+	mockRepo.On("GetContexto", contexto.ID.Hex()).Return(contexto, nil)
+	mockRepo.On("SomarConsumo", mock.AnythingOfType("domain.ConsumoFiltro")).Return(&domain.Consumo{TotalTokens: 500}, nil)
+
+	_, err := useCase.ExecutarPrompt(context.Background(), contexto.ID.Hex(), prompt.ID.Hex(), usecase.OpcoesExecucao{})
+
+	assert.ErrorIs(t, err, usecase.ErrOrcamentoExcedido)
+	mockRepo.AssertNotCalled(t, "GetPrompt", mock.Anything)
+	mockRepo.AssertNotCalled(t, "CreateResposta", mock.Anything)
+}
+
+func TestEmbeddingsEntramNoConsumoDoContexto(t *testing.T) {
+	repo := memoria.NewRepository()
+	provider := newFakeProvider(t, "")
+	consumo := usecase.NewConsumoUseCase(repo, usecase.PrecosPadrao())
+	documentos := usecase.NewDocumentoUseCase(repo, provider, consumo)
+	busca := usecase.NewBuscaUseCase(repo, provider, consumo, vetorial.NewMemoria(), 0)
+
+	contexto := &domain.Contexto{Nome: "Campanha"}
+	assert.NoError(t, repo.CreateContexto(contexto))
+	_, err := documentos.AdicionarDocumento(context.Background(), contexto.ID.Hex(), "precos.md", []byte("Plano anual: R$ 99"))
+	assert.NoError(t, err)
+
+	depoisDoDocumento, err := consumo.ConsumoContexto(contexto.ID.Hex(), time.Now())
+	assert.NoError(t, err)
+	assert.Positive(t, depoisDoDocumento.EmbeddingTokens)
+	assert.Equal(t, depoisDoDocumento.EmbeddingTokens, depoisDoDocumento.TotalTokens)
+	assert.Zero(t, depoisDoDocumento.Geracoes)
+
+	// O prompt do contexto é cobrado dele; o prompt avulso não tem a quem cobrar
+	assert.NoError(t, repo.CreatePrompt(&domain.Prompt{Conteudo: "Ofereça o plano anual", ContextoID: contexto.ID}))
+	assert.NoError(t, repo.CreatePrompt(&domain.Prompt{Conteudo: "Prompt sem contexto"}))
+	assert.NoError(t, busca.Sincronizar(context.Background()))
+
+	depoisDaBusca, err := consumo.ConsumoContexto(contexto.ID.Hex(), time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, depoisDoDocumento.EmbeddingTokens+4, depoisDaBusca.EmbeddingTokens)
+}
@@ -0,0 +1,105 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+	"vend/internal/domain"
+	"vend/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestEnviarMensagemEnviaHistoricoCompleto(t *testing.T) {
+	mockRepo := new(MockRepository)
+	useCase := usecase.NewConversaUseCase(mockRepo, newFakeProvider(t, "{{len .Mensagens}} mensagens"), usecase.NewConsumoUseCase(mockRepo, usecase.PrecosPadrao()), nil, nil, nil)
+
+	pessoa := &domain.Pessoa{ID: primitive.NewObjectID(), Nome: "Maria", Email: "maria@teste.com"}
+	contexto := &domain.Contexto{ID: primitive.NewObjectID(), Descricao: "Renovação"}
+	conversa := &domain.Conversa{
+		ID:         primitive.NewObjectID(),
+		PessoaID:   pessoa.ID,
+		ContextoID: contexto.ID,
+		Mensagens: []domain.MensagemConversa{
+			{Papel: domain.PapelUsuario, Conteudo: "Oi", CreatedAt: time.Now()},
+			{Papel: domain.PapelAssistente, Conteudo: "Olá, Maria!", CreatedAt: time.Now()},
+		},
+	}
+
+	mockRepo.On("GetConversa", conversa.ID.Hex()).Return(conversa, nil)
+	mockRepo.On("GetContexto", contexto.ID.Hex()).Return(contexto, nil)
+	mockRepo.On("GetPessoa", pessoa.ID.Hex()).Return(pessoa, nil)
+	mockRepo.On("CreateResposta", mock.MatchedBy(func(r *domain.Resposta) bool {
+		return r.ConversaID == conversa.ID && r.PessoaID == pessoa.ID
+	})).Return(nil)
+	mockRepo.On("AppendMensagensConversa", conversa.ID.Hex(), mock.MatchedBy(func(m []domain.MensagemConversa) bool {
+		return len(m) == 2 && m[0].Papel == domain.PapelUsuario && m[1].Papel == domain.PapelAssistente
+	})).Return(nil)
+
+	mensagem, err := useCase.EnviarMensagem(context.Background(), conversa.ID.Hex(), "Quais planos vocês têm?")
+
+	// sistema + 2 mensagens do histórico + nova pergunta
+	assert.NoError(t, err)
+	assert.Equal(t, domain.PapelAssistente, mensagem.Papel)
+	assert.Equal(t, "4 mensagens", mensagem.Conteudo)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestEnviarMensagemLimitaHistorico(t *testing.T) {
+	mockRepo := new(MockRepository)
+	useCase := usecase.NewConversaUseCase(mockRepo, newFakeProvider(t, "{{len .Mensagens}} mensagens, a primeira depois do sistema: {{(index .Mensagens 1).Conteudo}}"), usecase.NewConsumoUseCase(mockRepo, usecase.PrecosPadrao()), nil, nil, nil)
+
+	pessoa := &domain.Pessoa{ID: primitive.NewObjectID(), Nome: "Maria", Email: "maria@teste.com"}
+	contexto := &domain.Contexto{ID: primitive.NewObjectID()}
+	conversa := &domain.Conversa{ID: primitive.NewObjectID(), PessoaID: pessoa.ID, ContextoID: contexto.ID}
+	for i := 0; i < 30; i++ {
+		papel := domain.PapelUsuario
+		if i%2 == 1 {
+			papel = domain.PapelAssistente
+		}
+		conversa.Mensagens = append(conversa.Mensagens, domain.MensagemConversa{Papel: papel, Conteudo: fmt.Sprintf("mensagem %d", i), CreatedAt: time.Now()})
+	}
+
+	mockRepo.On("GetConversa", conversa.ID.Hex()).Return(conversa, nil)
+	mockRepo.On("GetContexto", contexto.ID.Hex()).Return(contexto, nil)
+	mockRepo.On("GetPessoa", pessoa.ID.Hex()).Return(pessoa, nil)
+	mockRepo.On("CreateResposta", mock.AnythingOfType("*domain.Resposta")).Return(nil)
+	mockRepo.On("AppendMensagensConversa", conversa.ID.Hex(), mock.Anything).Return(nil)
+
+	mensagem, err := useCase.EnviarMensagem(context.Background(), conversa.ID.Hex(), "Ainda tem desconto?")
+
+	// sistema + as mensagens 12 a 29 + nova pergunta: das 20 últimas, a
+	// primeira é do assistente e fica de fora
+	assert.NoError(t, err)
+	assert.Equal(t, "20 mensagens, a primeira depois do sistema: mensagem 12", mensagem.Conteudo)
+}
+
+func TestEnviarMensagemRegistraRodadasAntesDaFalha(t *testing.T) {
+	mockRepo := new(MockRepository)
+	ferramentas := usecase.NewFerramentas(usecase.NewPessoaUseCase(mockRepo), usecase.NewTelefoneUseCase(mockRepo), usecase.NewContextoUseCase(mockRepo, nil))
+	useCase := usecase.NewConversaUseCase(mockRepo, &providerFerramentasInstavel{}, usecase.NewConsumoUseCase(mockRepo, usecase.PrecosPadrao()), nil, ferramentas, nil)
+
+	pessoa := &domain.Pessoa{ID: primitive.NewObjectID(), Nome: "Maria", Email: "maria@teste.com"}
+	contexto := &domain.Contexto{ID: primitive.NewObjectID(), Parametros: domain.ParametrosGeracao{
+		Ferramentas: []string{usecase.FerramentaListarTelefones},
+	}}
+	conversa := &domain.Conversa{ID: primitive.NewObjectID(), PessoaID: pessoa.ID, ContextoID: contexto.ID}
+
+	mockRepo.On("GetConversa", conversa.ID.Hex()).Return(conversa, nil)
+	mockRepo.On("GetContexto", contexto.ID.Hex()).Return(contexto, nil)
+	mockRepo.On("GetPessoa", pessoa.ID.Hex()).Return(pessoa, nil)
+	mockRepo.On("ListTelefones", mock.Anything).Return([]domain.Telefone{}, int64(0), nil)
+	mockRepo.On("CreateResposta", mock.MatchedBy(func(r *domain.Resposta) bool {
+		return r.ConversaID == conversa.ID && r.Uso.TotalTokens == 5
+	})).Return(nil)
+
+	_, err := useCase.EnviarMensagem(context.Background(), conversa.ID.Hex(), "Quais telefones eu tenho?")
+
+	// Os tokens da primeira rodada contam, mas a conversa não muda
+	assert.ErrorIs(t, err, domain.ErrLLMIndisponivel)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "AppendMensagensConversa", mock.Anything, mock.Anything)
+}
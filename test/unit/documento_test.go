@@ -0,0 +1,133 @@
+package unit
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+	"vend/internal/domain"
+	"vend/internal/extracao"
+	"vend/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// pdfComTexto monta um PDF mínimo com um stream de conteúdo compactado
+func pdfComTexto(conteudo string) []byte {
+	var compactado bytes.Buffer
+	w := zlib.NewWriter(&compactado)
+	w.Write([]byte(conteudo))
+	w.Close()
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", compactado.Len())
+	pdf.Write(compactado.Bytes())
+	pdf.WriteString("\nendstream\nendobj\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+func TestExtrairTextoPDF(t *testing.T) {
+	pdf := pdfComTexto("BT /F1 12 Tf 72 712 Td (Plano Anual: R$ 99 \\(mensal\\)) Tj 0 -14 Td [(Desc) 30 (onto) -300 (de 10%)] TJ ET")
+
+	texto, err := extracao.Extrair(domain.FormatoPDF, pdf)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Plano Anual: R$ 99 (mensal)\nDesconto de 10%\n", texto)
+}
+
+func TestExtrairPDFSemTexto(t *testing.T) {
+	_, err := extracao.Extrair(domain.FormatoPDF, pdfComTexto("q 100 0 0 100 0 0 cm /Im1 Do Q"))
+
+	assert.ErrorIs(t, err, extracao.ErrSemTexto)
+}
+
+// Um stream que se expande além do limite é lido só até ele: o texto depois
+// de 64 MB de espaços fica de fora
+func TestExtrairPDFLimitaConteudoDescompactado(t *testing.T) {
+	pdf := pdfComTexto(strings.Repeat(" ", 64<<20) + "BT (fim) Tj ET")
+	assert.Less(t, len(pdf), 1<<20)
+
+	_, err := extracao.Extrair(domain.FormatoPDF, pdf)
+
+	assert.ErrorIs(t, err, extracao.ErrSemTexto)
+}
+
+func TestFormatoDocumento(t *testing.T) {
+	formato, err := extracao.Formato("Tabela de Preços.MD")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.FormatoMarkdown, formato)
+
+	_, err = extracao.Formato("planilha.xlsx")
+	assert.ErrorIs(t, err, extracao.ErrFormatoNaoSuportado)
+}
+
+func TestDividirTexto(t *testing.T) {
+	texto := "Primeiro parágrafo.\n\nSegundo parágrafo.\n\n" + strings.Repeat("palavra ", 40)
+
+	trechos := usecase.DividirTexto(texto, 60)
+
+	assert.Equal(t, "Primeiro parágrafo.\n\nSegundo parágrafo.", trechos[0])
+	assert.Greater(t, len(trechos), 2)
+	for _, trecho := range trechos {
+		assert.LessOrEqual(t, utf8.RuneCountInString(trecho), 60)
+	}
+	assert.Equal(t, 40, strings.Count(strings.Join(trechos[1:], " "), "palavra"))
+}
+
+func TestAdicionarDocumentoGravaTrechos(t *testing.T) {
+	mockRepo := new(MockRepository)
+	useCase := usecase.NewDocumentoUseCase(mockRepo, newFakeProvider(t, ""), nil)
+
+	contexto := &domain.Contexto{ID: primitive.NewObjectID()}
+	mockRepo.On("GetContexto", contexto.ID.Hex()).Return(contexto, nil)
+	mockRepo.On("CreateDocumento", mock.AnythingOfType("*domain.Documento"), mock.MatchedBy(func(trechos []domain.TrechoDocumento) bool {
+		return len(trechos) == 1 && trechos[0].Embedding != nil && trechos[0].Documento == "precos.md"
+	})).Return(nil)
+
+	documento, err := useCase.AdicionarDocumento(context.Background(), contexto.ID.Hex(), "precos.md", []byte("# Preços\n\nPlano anual: R$ 99"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.FormatoMarkdown, documento.Formato)
+	assert.Equal(t, contexto.ID, documento.ContextoID)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestExecutarPromptComDocumentos(t *testing.T) {
+	mockRepo := new(MockRepository)
+	provider := newFakeProvider(t, "{{.Sistema}}")
+	documentos := usecase.NewDocumentoUseCase(mockRepo, provider, nil)
+	useCase := usecase.NewGeracaoUseCase(mockRepo, provider, usecase.NewConsumoUseCase(mockRepo, usecase.PrecosPadrao()), nil, nil, documentos)
+
+	contexto := &domain.Contexto{ID: primitive.NewObjectID(), Descricao: "Campanha"}
+	prompt := &domain.Prompt{ID: primitive.NewObjectID(), Conteudo: "Qual o preço do plano anual?"}
+
+	textos := []string{"O plano anual custa R$ 99 por mês", "O suporte atende em horário comercial"}
+	vetores, err := provider.Embeddings(context.Background(), textos)
+	assert.NoError(t, err)
+	documentoID := primitive.NewObjectID()
+	trechos := []domain.TrechoDocumento{
+		{DocumentoID: documentoID, Documento: "suporte.txt", Indice: 0, Conteudo: textos[1], Embedding: &domain.Embedding{Vetor: vetores.Vetores[1]}},
+		{DocumentoID: documentoID, Documento: "precos.md", Indice: 3, Conteudo: textos[0], Embedding: &domain.Embedding{Vetor: vetores.Vetores[0]}},
+	}
+
+	mockRepo.On("GetContexto", contexto.ID.Hex()).Return(contexto, nil)
+	mockRepo.On("GetPrompt", prompt.ID.Hex()).Return(prompt, nil)
+	mockRepo.On("ListTrechosDocumento", contexto.ID.Hex()).Return(trechos, nil)
+	mockRepo.On("CreateResposta", mock.AnythingOfType("*domain.Resposta")).Return(nil)
+
+	resposta, err := useCase.ExecutarPrompt(context.Background(), contexto.ID.Hex(), prompt.ID.Hex(), usecase.OpcoesExecucao{})
+
+	assert.NoError(t, err)
+	assert.Contains(t, resposta.Conteudo, "[1] precos.md:\nO plano anual custa R$ 99 por mês")
+	assert.Len(t, resposta.Fontes, 2)
+	assert.Equal(t, 1, resposta.Fontes[0].Numero)
+	assert.Equal(t, "precos.md", resposta.Fontes[0].Documento)
+	assert.Equal(t, 3, resposta.Fontes[0].Trecho)
+	assert.Greater(t, resposta.Fontes[0].Score, resposta.Fontes[1].Score)
+}
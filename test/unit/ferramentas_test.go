@@ -0,0 +1,179 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"vend/internal/domain"
+	"vend/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// providerFerramentas pede listar_telefones na primeira chamada e responde
+// com o resultado da ferramenta na segunda
+type providerFerramentas struct {
+	requisicoes []domain.RequisicaoGeracao
+}
+
+func (p *providerFerramentas) Generate(ctx context.Context, req domain.RequisicaoGeracao) (*domain.ResultadoGeracao, error) {
+	p.requisicoes = append(p.requisicoes, req)
+	ultima := req.Mensagens[len(req.Mensagens)-1]
+	if ultima.Papel == domain.PapelFerramenta {
+		return &domain.ResultadoGeracao{Conteudo: "Telefones: " + ultima.Conteudo, Uso: domain.UsoTokens{TotalTokens: 10}}, nil
+	}
+	return &domain.ResultadoGeracao{
+		ChamadasFerramenta: []domain.ChamadaFerramenta{{ID: "call_1", Nome: usecase.FerramentaListarTelefones, Argumentos: `{}`}},
+		Uso:                domain.UsoTokens{TotalTokens: 5},
+	}, nil
+}
+
+func (p *providerFerramentas) GenerateStream(ctx context.Context, req domain.RequisicaoGeracao, onDelta func(string) error) (*domain.ResultadoGeracao, error) {
+	return p.Generate(ctx, req)
+}
+
+func TestExecutarPromptComFerramentas(t *testing.T) {
+	mockRepo := new(MockRepository)
+	provider := &providerFerramentas{}
+	ferramentas := usecase.NewFerramentas(usecase.NewPessoaUseCase(mockRepo), usecase.NewTelefoneUseCase(mockRepo), usecase.NewContextoUseCase(mockRepo, nil))
+	useCase := usecase.NewGeracaoUseCase(mockRepo, provider, usecase.NewConsumoUseCase(mockRepo, usecase.PrecosPadrao()), nil, ferramentas, nil)
+
+	contexto := &domain.Contexto{ID: primitive.NewObjectID(), Parametros: domain.ParametrosGeracao{
+		Ferramentas: []string{usecase.FerramentaListarTelefones},
+	}}
+	prompt := &domain.Prompt{ID: primitive.NewObjectID(), Conteudo: "Quais leads não têm celular?"}
+
+	mockRepo.On("GetContexto", contexto.ID.Hex()).Return(contexto, nil)
+	mockRepo.On("GetPrompt", prompt.ID.Hex()).Return(prompt, nil)
+	mockRepo.On("ListTelefones", mock.Anything).Return([]domain.Telefone{{Numero: "1199999-0000", Tipo: "fixo"}}, int64(1), nil)
+	mockRepo.On("CreateResposta", mock.AnythingOfType("*domain.Resposta")).Return(nil)
+
+	resposta, err := useCase.ExecutarPrompt(context.Background(), contexto.ID.Hex(), prompt.ID.Hex(), usecase.OpcoesExecucao{})
+
+	assert.NoError(t, err)
+	assert.Contains(t, resposta.Conteudo, "1199999-0000")
+	assert.Equal(t, 15, resposta.Uso.TotalTokens)
+	assert.Len(t, provider.requisicoes, 2)
+	assert.Equal(t, usecase.FerramentaListarTelefones, provider.requisicoes[0].Ferramentas[0].Nome)
+	assert.Equal(t, "call_1", provider.requisicoes[1].Mensagens[3].ChamadaID)
+}
+
+func TestExecutarPromptComFerramentaDesconhecida(t *testing.T) {
+	mockRepo := new(MockRepository)
+	useCase := newGeracaoUseCase(t, mockRepo, "")
+
+	contexto := &domain.Contexto{ID: primitive.NewObjectID()}
+	prompt := &domain.Prompt{ID: primitive.NewObjectID(), Conteudo: "Resuma"}
+
+	mockRepo.On("GetContexto", contexto.ID.Hex()).Return(contexto, nil)
+	mockRepo.On("GetPrompt", prompt.ID.Hex()).Return(prompt, nil)
+
+	opcoes := usecase.OpcoesExecucao{Parametros: domain.ParametrosGeracao{Ferramentas: []string{"apagar_tudo"}}}
+	_, err := useCase.ExecutarPrompt(context.Background(), contexto.ID.Hex(), prompt.ID.Hex(), opcoes)
+
+	assert.ErrorIs(t, err, usecase.ErrParametroInvalido)
+}
+
+func TestFerramentaListarTelefonesTruncada(t *testing.T) {
+	mockRepo := new(MockRepository)
+	provider := &providerFerramentas{}
+	ferramentas := usecase.NewFerramentas(usecase.NewPessoaUseCase(mockRepo), usecase.NewTelefoneUseCase(mockRepo), usecase.NewContextoUseCase(mockRepo, nil))
+	useCase := usecase.NewGeracaoUseCase(mockRepo, provider, usecase.NewConsumoUseCase(mockRepo, usecase.PrecosPadrao()), nil, ferramentas, nil)
+
+	contexto := &domain.Contexto{ID: primitive.NewObjectID(), Parametros: domain.ParametrosGeracao{
+		Ferramentas: []string{usecase.FerramentaListarTelefones},
+	}}
+	prompt := &domain.Prompt{ID: primitive.NewObjectID(), Conteudo: "Liste os telefones"}
+
+	// O repositório devolve um item além do limite: há outra página
+	telefones := make([]domain.Telefone, usecase.LimitePadrao+1)
+	for i := range telefones {
+		telefones[i] = domain.Telefone{ID: primitive.NewObjectID(), Numero: "1199999-0000"}
+	}
+	mockRepo.On("GetContexto", contexto.ID.Hex()).Return(contexto, nil)
+	mockRepo.On("GetPrompt", prompt.ID.Hex()).Return(prompt, nil)
+	mockRepo.On("ListTelefones", mock.Anything).Return(telefones, int64(120), nil)
+	mockRepo.On("CreateResposta", mock.AnythingOfType("*domain.Resposta")).Return(nil)
+
+	_, err := useCase.ExecutarPrompt(context.Background(), contexto.ID.Hex(), prompt.ID.Hex(), usecase.OpcoesExecucao{})
+	assert.NoError(t, err)
+
+	var resultado struct {
+		Itens         []domain.Telefone `json:"itens"`
+		Total         *int64            `json:"total"`
+		Truncado      bool              `json:"truncado"`
+		ProximoCursor string            `json:"proximo_cursor"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(provider.requisicoes[1].Mensagens[3].Conteudo), &resultado))
+	assert.Len(t, resultado.Itens, usecase.LimitePadrao)
+	assert.Equal(t, int64(120), *resultado.Total)
+	assert.True(t, resultado.Truncado)
+	assert.NotEmpty(t, resultado.ProximoCursor)
+}
+
+// providerFerramentasInstavel pede listar_telefones na primeira chamada e
+// falha na rodada seguinte
+type providerFerramentasInstavel struct {
+	providerFerramentas
+}
+
+func (p *providerFerramentasInstavel) Generate(ctx context.Context, req domain.RequisicaoGeracao) (*domain.ResultadoGeracao, error) {
+	if len(p.requisicoes) > 0 {
+		return nil, domain.ErrLLMIndisponivel
+	}
+	return p.providerFerramentas.Generate(ctx, req)
+}
+
+func (p *providerFerramentasInstavel) GenerateStream(ctx context.Context, req domain.RequisicaoGeracao, onDelta func(string) error) (*domain.ResultadoGeracao, error) {
+	return p.Generate(ctx, req)
+}
+
+func TestExecutarPromptRegistraRodadasAntesDaFalha(t *testing.T) {
+	mockRepo := new(MockRepository)
+	ferramentas := usecase.NewFerramentas(usecase.NewPessoaUseCase(mockRepo), usecase.NewTelefoneUseCase(mockRepo), usecase.NewContextoUseCase(mockRepo, nil))
+	consumo := usecase.NewConsumoUseCase(mockRepo, usecase.PrecosPadrao())
+
+	contexto := &domain.Contexto{ID: primitive.NewObjectID(), Parametros: domain.ParametrosGeracao{
+		Ferramentas: []string{usecase.FerramentaListarTelefones},
+	}}
+	prompt := &domain.Prompt{ID: primitive.NewObjectID(), Conteudo: "Quais leads não têm celular?"}
+
+	mockRepo.On("GetContexto", contexto.ID.Hex()).Return(contexto, nil)
+	mockRepo.On("GetPrompt", prompt.ID.Hex()).Return(prompt, nil)
+	mockRepo.On("ListTelefones", mock.Anything).Return([]domain.Telefone{}, int64(0), nil)
+	mockRepo.On("CreateResposta", mock.AnythingOfType("*domain.Resposta")).Return(nil)
+
+	t.Run("ExecutarPrompt", func(t *testing.T) {
+		useCase := usecase.NewGeracaoUseCase(mockRepo, &providerFerramentasInstavel{}, consumo, nil, ferramentas, nil)
+		resposta, err := useCase.ExecutarPrompt(context.Background(), contexto.ID.Hex(), prompt.ID.Hex(), usecase.OpcoesExecucao{})
+
+		// O erro é o do provedor, mas os tokens da primeira rodada contam
+		assert.ErrorIs(t, err, domain.ErrLLMIndisponivel)
+		assert.Equal(t, 5, resposta.Uso.TotalTokens)
+	})
+
+	t.Run("ExecutarPromptStream", func(t *testing.T) {
+		useCase := usecase.NewGeracaoUseCase(mockRepo, &providerFerramentasInstavel{}, consumo, nil, ferramentas, nil)
+		_, err := useCase.ExecutarPromptStream(context.Background(), contexto.ID.Hex(), prompt.ID.Hex(), usecase.OpcoesExecucao{}, func(string) error {
+			t.Fatal("nada deve ser transmitido")
+			return nil
+		})
+
+		assert.ErrorIs(t, err, domain.ErrLLMIndisponivel)
+	})
+
+	t.Run("com schema de saída", func(t *testing.T) {
+		estruturado := &domain.Prompt{ID: primitive.NewObjectID(), Conteudo: "Classifique o lead", SchemaSaida: schemaLead}
+		mockRepo.On("GetPrompt", estruturado.ID.Hex()).Return(estruturado, nil)
+
+		useCase := usecase.NewGeracaoUseCase(mockRepo, &providerFerramentasInstavel{}, consumo, nil, ferramentas, nil)
+		resposta, err := useCase.ExecutarPrompt(context.Background(), contexto.ID.Hex(), estruturado.ID.Hex(), usecase.OpcoesExecucao{})
+
+		assert.ErrorIs(t, err, domain.ErrLLMIndisponivel)
+		assert.Equal(t, 5, resposta.Uso.TotalTokens)
+	})
+
+	mockRepo.AssertNumberOfCalls(t, "CreateResposta", 3)
+}
@@ -0,0 +1,107 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"vend/internal/domain"
+	"vend/internal/infrastructure/fakellm"
+	"vend/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newFakeProvider(t *testing.T, tmpl string) *fakellm.Provider {
+	provider, err := fakellm.NewProvider(tmpl)
+	if err != nil {
+		t.Fatalf("Erro ao criar o provedor fake: %v", err)
+	}
+	return provider
+}
+
+func newGeracaoUseCase(t *testing.T, repo *MockRepository, tmpl string) *usecase.GeracaoUseCase {
+	return usecase.NewGeracaoUseCase(repo, newFakeProvider(t, tmpl), usecase.NewConsumoUseCase(repo, usecase.PrecosPadrao()), nil, nil, nil)
+}
+
+func TestExecutarPromptRegistraResposta(t *testing.T) {
+	mockRepo := new(MockRepository)
+	useCase := newGeracaoUseCase(t, mockRepo, "")
+
+	contexto := &domain.Contexto{ID: primitive.NewObjectID(), Nome: "Campanha", Descricao: "Black Friday"}
+	prompt := &domain.Prompt{ID: primitive.NewObjectID(), Conteudo: "Escreva um pitch", ContextoID: contexto.ID}
+
+	mockRepo.On("GetContexto", contexto.ID.Hex()).Return(contexto, nil)
+	mockRepo.On("GetPrompt", prompt.ID.Hex()).Return(prompt, nil)
+	mockRepo.On("CreateResposta", mock.AnythingOfType("*domain.Resposta")).Return(nil)
+
+	resposta, err := useCase.ExecutarPrompt(context.Background(), contexto.ID.Hex(), prompt.ID.Hex(), usecase.OpcoesExecucao{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Resposta simulada para: Escreva um pitch", resposta.Conteudo)
+	assert.Equal(t, fakellm.ModeloPadrao, resposta.Modelo)
+	assert.Equal(t, prompt.ID, resposta.PromptID)
+	assert.Equal(t, contexto.ID, resposta.ContextoID)
+	assert.Len(t, resposta.Mensagens, 2)
+	assert.Contains(t, resposta.Mensagens[0].Conteudo, "Black Friday")
+	assert.Equal(t, resposta.Uso.PromptTokens+resposta.Uso.CompletionTokens, resposta.Uso.TotalTokens)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestExecutarPromptStreamEntregaTrechos(t *testing.T) {
+	mockRepo := new(MockRepository)
+	useCase := newGeracaoUseCase(t, mockRepo, "{{.Modelo}} diz: {{.Ultima}}")
+
+	contexto := &domain.Contexto{ID: primitive.NewObjectID()}
+	prompt := &domain.Prompt{ID: primitive.NewObjectID(), Conteudo: "olá mundo"}
+
+	mockRepo.On("GetContexto", contexto.ID.Hex()).Return(contexto, nil)
+	mockRepo.On("GetPrompt", prompt.ID.Hex()).Return(prompt, nil)
+	mockRepo.On("CreateResposta", mock.AnythingOfType("*domain.Resposta")).Return(nil)
+
+	var trechos []string
+	resposta, err := useCase.ExecutarPromptStream(context.Background(), contexto.ID.Hex(), prompt.ID.Hex(), usecase.OpcoesExecucao{}, func(delta string) error {
+		trechos = append(trechos, delta)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"fake ", "diz: ", "olá ", "mundo"}, trechos)
+	assert.Equal(t, "fake diz: olá mundo", resposta.Conteudo)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestExecutarPromptDeOutroContexto(t *testing.T) {
+	mockRepo := new(MockRepository)
+	useCase := newGeracaoUseCase(t, mockRepo, "")
+
+	contexto := &domain.Contexto{ID: primitive.NewObjectID()}
+	prompt := &domain.Prompt{ID: primitive.NewObjectID(), ContextoID: primitive.NewObjectID()}
+
+	mockRepo.On("GetContexto", contexto.ID.Hex()).Return(contexto, nil)
+	mockRepo.On("GetPrompt", prompt.ID.Hex()).Return(prompt, nil)
+
+	_, err := useCase.ExecutarPrompt(context.Background(), contexto.ID.Hex(), prompt.ID.Hex(), usecase.OpcoesExecucao{})
+
+	assert.ErrorIs(t, err, usecase.ErrPromptForaDoContexto)
+	mockRepo.AssertNotCalled(t, "CreateResposta", mock.Anything)
+}
+
+func TestExecutarPromptFalhaDoRepositorio(t *testing.T) {
+	mockRepo := new(MockRepository)
+	useCase := newGeracaoUseCase(t, mockRepo, "")
+
+	contexto := &domain.Contexto{ID: primitive.NewObjectID()}
+	promptID := primitive.NewObjectID().Hex()
+	falha := errors.New("conexão recusada")
+
+	mockRepo.On("GetContexto", contexto.ID.Hex()).Return(contexto, nil)
+	mockRepo.On("GetPrompt", promptID).Return(nil, falha)
+
+	// Só a ausência do registro vira "não encontrado"; a falha do banco segue
+	_, err := useCase.ExecutarPrompt(context.Background(), contexto.ID.Hex(), promptID, usecase.OpcoesExecucao{})
+
+	assert.ErrorIs(t, err, falha)
+	assert.NotErrorIs(t, err, usecase.ErrPromptNaoEncontrado)
+}
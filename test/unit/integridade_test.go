@@ -0,0 +1,84 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	handlers "vend/internal/delivery/http"
+	"vend/internal/domain"
+	"vend/internal/infrastructure/memoria"
+	"vend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRemocaoRegrasPadrao(t *testing.T) {
+	repo := memoria.NewRepository()
+	pessoa := &domain.Pessoa{Nome: "Ana"}
+	assert.NoError(t, repo.CreatePessoa(pessoa))
+	assert.NoError(t, repo.CreateTelefone(&domain.Telefone{PessoaID: pessoa.ID, Numero: "11999990000"}))
+	contexto := &domain.Contexto{Nome: "Campanha", PessoaIDs: []primitive.ObjectID{pessoa.ID}}
+	assert.NoError(t, repo.CreateContexto(contexto))
+	prompt := &domain.Prompt{Conteudo: "Olá", ContextoID: contexto.ID}
+	assert.NoError(t, repo.CreatePrompt(prompt))
+
+	// Os telefones vão junto com a pessoa e ela sai do contexto
+	assert.NoError(t, usecase.NewPessoaUseCase(repo).DeletePessoa(pessoa.ID.Hex(), ""))
+	_, total, err := repo.ListTelefones(domain.TelefoneFiltro{})
+	assert.NoError(t, err)
+	assert.Zero(t, total)
+	recuperado, err := repo.GetContexto(contexto.ID.Hex())
+	assert.NoError(t, err)
+	assert.Empty(t, recuperado.PessoaIDs)
+
+	// O contexto com prompts não pode ser removido
+	contextoUseCase := usecase.NewContextoUseCase(repo, nil)
+	assert.ErrorIs(t, contextoUseCase.DeleteContexto(contexto.ID.Hex(), ""), usecase.ErrRemocaoRestrita)
+	_, err = repo.GetContexto(contexto.ID.Hex())
+	assert.NoError(t, err)
+
+	regras, err := usecase.ParseRegrasRemocao("contexto_prompts=nullify")
+	assert.NoError(t, err)
+	contextoUseCase.UsarRegrasRemocao(regras)
+	assert.NoError(t, contextoUseCase.DeleteContexto(contexto.ID.Hex(), ""))
+	mantido, err := repo.GetPrompt(prompt.ID.Hex())
+	assert.NoError(t, err)
+	assert.True(t, mantido.ContextoID.IsZero())
+}
+
+func TestRemocaoRestritaDesfazCascata(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := memoria.NewRepository()
+	pessoa := &domain.Pessoa{Nome: "Ana"}
+	assert.NoError(t, repo.CreatePessoa(pessoa))
+	assert.NoError(t, repo.CreateTelefone(&domain.Telefone{PessoaID: pessoa.ID, Numero: "11999990000"}))
+	assert.NoError(t, repo.CreateContexto(&domain.Contexto{Nome: "Campanha", PessoaIDs: []primitive.ObjectID{pessoa.ID}}))
+
+	pessoaUseCase := usecase.NewPessoaUseCase(repo)
+	regras, err := usecase.ParseRegrasRemocao("pessoa_telefones=cascade, pessoa_contextos=restrict")
+	assert.NoError(t, err)
+	pessoaUseCase.UsarRegrasRemocao(regras)
+	h := handlers.NewHandler(pessoaUseCase, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	r := gin.New()
+	r.DELETE("/pessoas/:id", h.DeletePessoa)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/pessoas/"+pessoa.ID.Hex(), nil))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "pessoa_contextos=restrict")
+
+	// Os telefones removidos antes da regra restrict voltam com a transação
+	recuperada, err := repo.GetPessoa(pessoa.ID.Hex())
+	assert.NoError(t, err)
+	assert.Len(t, recuperada.Telefones, 1)
+	assert.Len(t, recuperada.Contextos, 1)
+}
+
+func TestParseRegrasRemocaoInvalidas(t *testing.T) {
+	for _, texto := range []string{"pessoa_telefones", "telefones=cascade", "contexto_prompts=apagar"} {
+		_, err := usecase.ParseRegrasRemocao(texto)
+		assert.Error(t, err, texto)
+	}
+}
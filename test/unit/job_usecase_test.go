@@ -0,0 +1,114 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"vend/internal/domain"
+	"vend/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCriarLoteUmItemPorPessoa(t *testing.T) {
+	mockRepo := new(MockRepository)
+	useCase := usecase.NewJobUseCase(mockRepo, newGeracaoUseCase(t, mockRepo, ""), usecase.ConfigLotePadrao())
+
+	ana := domain.Pessoa{ID: primitive.NewObjectID(), Nome: "Ana"}
+	bruno := domain.Pessoa{ID: primitive.NewObjectID(), Nome: "Bruno"}
+	contexto := &domain.Contexto{ID: primitive.NewObjectID(), Pessoas: []domain.Pessoa{ana, bruno, ana}}
+	prompt := &domain.Prompt{ID: primitive.NewObjectID(), ContextoID: contexto.ID}
+
+	mockRepo.On("GetContexto", contexto.ID.Hex()).Return(contexto, nil)
+	mockRepo.On("GetPrompt", prompt.ID.Hex()).Return(prompt, nil)
+	mockRepo.On("CreateJob", mock.AnythingOfType("*domain.Job")).Return(nil)
+
+	job, err := useCase.CriarLote(contexto.ID.Hex(), prompt.ID.Hex(), usecase.OpcoesLote{Variaveis: map[string]any{"produto": "Plano"}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.StatusJobPendente, job.Status)
+	assert.Equal(t, 2, job.Total)
+	assert.Len(t, job.Itens, 2)
+	assert.Equal(t, ana.ID, job.Itens[0].PessoaID)
+	assert.Equal(t, bruno.ID, job.Itens[1].PessoaID)
+	assert.Equal(t, domain.StatusItemPendente, job.Itens[1].Status)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCriarLoteContextoSemPessoas(t *testing.T) {
+	mockRepo := new(MockRepository)
+	useCase := usecase.NewJobUseCase(mockRepo, newGeracaoUseCase(t, mockRepo, ""), usecase.ConfigLotePadrao())
+
+	contexto := &domain.Contexto{ID: primitive.NewObjectID()}
+	prompt := &domain.Prompt{ID: primitive.NewObjectID()}
+
+	mockRepo.On("GetContexto", contexto.ID.Hex()).Return(contexto, nil)
+	mockRepo.On("GetPrompt", prompt.ID.Hex()).Return(prompt, nil)
+
+	_, err := useCase.CriarLote(contexto.ID.Hex(), prompt.ID.Hex(), usecase.OpcoesLote{})
+
+	assert.ErrorIs(t, err, usecase.ErrContextoSemPessoas)
+	mockRepo.AssertNotCalled(t, "CreateJob", mock.Anything)
+}
+
+func TestIniciarProcessaItensPendentes(t *testing.T) {
+	mockRepo := new(MockRepository)
+	config := usecase.ConfigLote{Workers: 2, Intervalo: time.Millisecond, Verificacao: time.Hour}
+	useCase := usecase.NewJobUseCase(mockRepo, newGeracaoUseCase(t, mockRepo, ""), config)
+
+	ana := &domain.Pessoa{ID: primitive.NewObjectID(), Nome: "Ana"}
+	ausente := primitive.NewObjectID()
+	contexto := &domain.Contexto{ID: primitive.NewObjectID()}
+	prompt := &domain.Prompt{ID: primitive.NewObjectID(), Conteudo: "Olá"}
+
+	// O primeiro item já foi concluído antes de um reinício e não é refeito
+	job := domain.Job{
+		ID:         primitive.NewObjectID(),
+		ContextoID: contexto.ID,
+		PromptID:   prompt.ID,
+		Status:     domain.StatusJobExecutando,
+		Total:      3,
+		Itens: []domain.ItemJob{
+			{PessoaID: primitive.NewObjectID(), Status: domain.StatusItemConcluido},
+			{PessoaID: ana.ID, Status: domain.StatusItemPendente},
+			{PessoaID: ausente, Status: domain.StatusItemPendente},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mockRepo.On("ListJobsPorStatus", mock.Anything).Return([]domain.Job{job}, nil).Once()
+	mockRepo.On("ListJobsPorStatus", mock.Anything).Return([]domain.Job{}, nil)
+	mockRepo.On("UpdateJobStatus", job.ID.Hex(), domain.StatusJobExecutando).Return(nil)
+	mockRepo.On("UpdateJobStatus", job.ID.Hex(), domain.StatusJobConcluido).Return(nil).Run(func(mock.Arguments) { cancel() })
+	mockRepo.On("GetContexto", contexto.ID.Hex()).Return(contexto, nil)
+	mockRepo.On("GetPrompt", prompt.ID.Hex()).Return(prompt, nil)
+	mockRepo.On("GetPessoa", ana.ID.Hex()).Return(ana, nil)
+	mockRepo.On("GetPessoa", ausente.Hex()).Return(nil, errors.New("não encontrado"))
+	mockRepo.On("CreateResposta", mock.AnythingOfType("*domain.Resposta")).Return(nil)
+	mockRepo.On("UpdateJobItem", job.ID.Hex(), mock.Anything, mock.Anything).Return(nil)
+
+	concluido := make(chan struct{})
+	go func() {
+		useCase.Iniciar(ctx)
+		close(concluido)
+	}()
+
+	select {
+	case <-concluido:
+	case <-time.After(5 * time.Second):
+		t.Fatal("o job não foi concluído")
+	}
+
+	mockRepo.AssertNumberOfCalls(t, "UpdateJobItem", 2)
+	mockRepo.AssertCalled(t, "UpdateJobItem", job.ID.Hex(), 1, mock.MatchedBy(func(item domain.ItemJob) bool {
+		return item.Status == domain.StatusItemConcluido && item.Conteudo == "Resposta simulada para: Olá"
+	}))
+	mockRepo.AssertCalled(t, "UpdateJobItem", job.ID.Hex(), 2, mock.MatchedBy(func(item domain.ItemJob) bool {
+		return item.Status == domain.StatusItemFalhou && item.Erro != ""
+	}))
+}
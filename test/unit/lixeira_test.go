@@ -0,0 +1,132 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	handlers "vend/internal/delivery/http"
+	"vend/internal/domain"
+	"vend/internal/infrastructure/memoria"
+	"vend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestLixeiraRemoverListarRestaurar(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := memoria.NewRepository()
+	pessoa := &domain.Pessoa{Nome: "Ana", Email: "ana@exemplo.com"}
+	assert.NoError(t, repo.CreatePessoa(pessoa))
+	telefone := &domain.Telefone{PessoaID: pessoa.ID, Numero: "11999990000"}
+	assert.NoError(t, repo.CreateTelefone(telefone))
+
+	h := handlers.NewHandler(usecase.NewPessoaUseCase(repo), usecase.NewTelefoneUseCase(repo), nil, nil, nil, nil, nil, nil, nil, nil, nil,
+		usecase.NewLixeiraUseCase(repo, usecase.ConfigLixeiraPadrao()))
+	r := gin.New()
+	r.DELETE("/pessoas/:id", h.DeletePessoa)
+	r.POST("/pessoas/:id/restaurar", h.Restaurar(domain.EntidadePessoas))
+	r.POST("/telefones/:id/restaurar", h.Restaurar(domain.EntidadeTelefones))
+	r.GET("/lixeira", h.ListLixeira)
+
+	requisicao := httptest.NewRequest(http.MethodDelete, "/pessoas/"+pessoa.ID.Hex(), nil)
+	requisicao.Header.Set("X-Usuario", "maria")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, requisicao)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// A pessoa e o telefone removido em cascata somem das leituras
+	_, err := repo.GetPessoa(pessoa.ID.Hex())
+	assert.ErrorIs(t, err, domain.ErrNaoEncontrado)
+	_, total, err := repo.ListTelefones(domain.TelefoneFiltro{})
+	assert.NoError(t, err)
+	assert.Zero(t, total)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/lixeira?total=true", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var pagina domain.Pagina[domain.ItemLixeira]
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &pagina))
+	assert.Equal(t, int64(2), *pagina.Total)
+	for _, item := range pagina.Itens {
+		assert.Equal(t, "maria", item.DeletedBy)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/lixeira?entidade=pessoas", nil))
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &pagina))
+	assert.Len(t, pagina.Itens, 1)
+	assert.Equal(t, "Ana", pagina.Itens[0].Titulo)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/lixeira?entidade=jobs", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// O telefone não volta enquanto a pessoa estiver na lixeira
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/telefones/"+telefone.ID.Hex()+"/restaurar", nil))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Restaurar a pessoa traz de volta o telefone removido com ela
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/pessoas/"+pessoa.ID.Hex()+"/restaurar", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	recuperada, err := repo.GetPessoa(pessoa.ID.Hex())
+	assert.NoError(t, err)
+	assert.Len(t, recuperada.Telefones, 1)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/pessoas/"+pessoa.ID.Hex()+"/restaurar", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestLixeiraPurgar(t *testing.T) {
+	repo := memoria.NewRepository()
+	contexto := &domain.Contexto{Nome: "Campanha"}
+	assert.NoError(t, repo.CreateContexto(contexto))
+	antigo := &domain.Prompt{Conteudo: "antigo", ContextoID: contexto.ID}
+	assert.NoError(t, repo.CreatePrompt(antigo))
+	assert.NoError(t, repo.CreatePromptVersao(&domain.PromptVersao{PromptID: antigo.ID, Numero: 1, Conteudo: "antigo"}))
+	recente := &domain.Prompt{Conteudo: "recente", ContextoID: contexto.ID}
+	assert.NoError(t, repo.CreatePrompt(recente))
+
+	assert.NoError(t, repo.DeletePrompt(antigo.ID.Hex(), domain.Remocao{Em: time.Now().Add(-48 * time.Hour)}))
+	assert.NoError(t, repo.DeletePrompt(recente.ID.Hex(), domain.Remocao{Em: time.Now()}))
+
+	lixeira := usecase.NewLixeiraUseCase(repo, usecase.ConfigLixeira{Retencao: 24 * time.Hour, Intervalo: time.Hour})
+	n, err := lixeira.Purgar()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	// O prompt purgado não pode mais ser restaurado e as versões vão com ele
+	assert.ErrorIs(t, lixeira.Restaurar(domain.EntidadePrompts, antigo.ID.Hex()), domain.ErrNaoEncontrado)
+	versoes, err := repo.ListPromptVersoes(antigo.ID.Hex())
+	assert.NoError(t, err)
+	assert.Empty(t, versoes)
+
+	assert.NoError(t, lixeira.Restaurar(domain.EntidadePrompts, recente.ID.Hex()))
+	_, err = repo.GetPrompt(recente.ID.Hex())
+	assert.NoError(t, err)
+}
+
+func TestLixeiraRestauracaoImpedidaNaoAltera(t *testing.T) {
+	mockRepo := new(MockRepository)
+	lixeira := usecase.NewLixeiraUseCase(mockRepo, usecase.ConfigLixeiraPadrao())
+
+	id := primitive.NewObjectID().Hex()
+	pessoaID := primitive.NewObjectID()
+	mockRepo.On("GetLixeira", domain.EntidadeTelefones, id).Return(&domain.ItemLixeira{ReferenciaID: pessoaID}, nil)
+	mockRepo.On("GetPessoa", pessoaID.Hex()).Return(nil, domain.ErrNaoEncontrado)
+
+	// O mock não desfaz nada em Transacao: a pessoa na lixeira precisa ser
+	// vista antes de o telefone ser restaurado
+	err := lixeira.Restaurar(domain.EntidadeTelefones, id)
+
+	assert.ErrorIs(t, err, usecase.ErrRestauracaoImpedida)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Restaurar", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,190 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	handlers "vend/internal/delivery/http"
+	"vend/internal/domain"
+	"vend/internal/infrastructure/memoria"
+	"vend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoriaCrudPessoa(t *testing.T) {
+	repo := memoria.NewRepository()
+
+	pessoa := &domain.Pessoa{Nome: "Ana", Email: "ana@exemplo.com"}
+	assert.NoError(t, repo.CreatePessoa(pessoa))
+	assert.False(t, pessoa.ID.IsZero())
+
+	// O registro guardado não muda quando o chamador altera a sua cópia
+	pessoa.Nome = "Alterada sem gravar"
+	recuperada, err := repo.GetPessoa(pessoa.ID.Hex())
+	assert.NoError(t, err)
+	assert.Equal(t, "Ana", recuperada.Nome)
+
+	criadaEm := recuperada.CreatedAt
+	atualizacao := &domain.Pessoa{ID: pessoa.ID, Nome: "Ana Souza", Email: "ana@exemplo.com"}
+	assert.NoError(t, repo.UpdatePessoa(atualizacao))
+	assert.Equal(t, criadaEm, atualizacao.CreatedAt)
+
+	remocao := domain.Remocao{Em: time.Now(), Por: "teste"}
+	assert.NoError(t, repo.DeletePessoa(pessoa.ID.Hex(), remocao))
+	_, err = repo.GetPessoa(pessoa.ID.Hex())
+	assert.ErrorIs(t, err, domain.ErrNaoEncontrado)
+	assert.ErrorIs(t, repo.DeletePessoa(pessoa.ID.Hex(), remocao), domain.ErrNaoEncontrado)
+	assert.ErrorIs(t, repo.UpdatePessoa(atualizacao), domain.ErrNaoEncontrado)
+	_, err = repo.GetPessoa("invalido")
+	assert.ErrorIs(t, err, domain.ErrNaoEncontrado)
+}
+
+// Atualizar substitui o registro: os campos opcionais vazios voltam vazios,
+// mas as pessoas do contexto e o embedding do prompt continuam
+func TestMemoriaAtualizarLimpaCampos(t *testing.T) {
+	repo := memoria.NewRepository()
+
+	pessoa := &domain.Pessoa{Nome: "Ana", Email: "ana@exemplo.com"}
+	assert.NoError(t, repo.CreatePessoa(pessoa))
+	contexto := &domain.Contexto{Nome: "Campanha", OrcamentoTokensMensal: 1000, PessoaIDs: []primitive.ObjectID{pessoa.ID}}
+	assert.NoError(t, repo.CreateContexto(contexto))
+
+	assert.NoError(t, repo.UpdateContexto(&domain.Contexto{ID: contexto.ID, Nome: "Campanha"}))
+	recuperado, err := repo.GetContexto(contexto.ID.Hex())
+	assert.NoError(t, err)
+	assert.Zero(t, recuperado.OrcamentoTokensMensal)
+	assert.Equal(t, []primitive.ObjectID{pessoa.ID}, recuperado.PessoaIDs)
+	assert.Equal(t, contexto.CreatedAt.Unix(), recuperado.CreatedAt.Unix())
+
+	prompt := &domain.Prompt{
+		Conteudo:    "Olá, {{.Vars.nome}}",
+		Variaveis:   []domain.VariavelPrompt{{Nome: "nome", Tipo: domain.TipoVariavelTexto}},
+		SchemaSaida: map[string]any{"type": "object"},
+	}
+	assert.NoError(t, repo.CreatePrompt(prompt))
+	assert.NoError(t, repo.UpdatePromptEmbedding(prompt.ID.Hex(), &domain.Embedding{Modelo: "teste", Vetor: []float32{1}}))
+
+	assert.NoError(t, repo.UpdatePrompt(&domain.Prompt{ID: prompt.ID, Conteudo: "Olá"}))
+	atualizado, err := repo.GetPrompt(prompt.ID.Hex())
+	assert.NoError(t, err)
+	assert.Empty(t, atualizado.Variaveis)
+	assert.Empty(t, atualizado.SchemaSaida)
+	assert.NotNil(t, atualizado.Embedding)
+}
+
+func TestMemoriaListagensEFiltros(t *testing.T) {
+	repo := memoria.NewRepository()
+
+	primeiro := &domain.Prompt{Conteudo: "primeiro"}
+	segundo := &domain.Prompt{Conteudo: "segundo"}
+	assert.NoError(t, repo.CreatePrompt(primeiro))
+	assert.NoError(t, repo.CreatePrompt(segundo))
+
+	prompts, _, err := repo.ListPrompts(domain.PromptFiltro{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"segundo", "primeiro"}, []string{prompts[0].Conteudo, prompts[1].Conteudo})
+
+	contextoID := segundo.ID
+	agora := time.Now()
+	assert.NoError(t, repo.CreateResposta(&domain.Resposta{PromptID: primeiro.ID, Uso: domain.UsoTokens{TotalTokens: 10}}))
+	assert.NoError(t, repo.CreateResposta(&domain.Resposta{PromptID: segundo.ID, ContextoID: contextoID, Uso: domain.UsoTokens{TotalTokens: 5}, CustoEstimado: 0.5}))
+
+	respostas, err := repo.ListRespostas(domain.RespostaFiltro{PromptID: primeiro.ID.Hex()})
+	assert.NoError(t, err)
+	assert.Len(t, respostas, 1)
+
+	consumo, err := repo.SomarConsumo(domain.ConsumoFiltro{ContextoID: contextoID.Hex(), Inicio: agora.Add(-time.Minute), Fim: agora.Add(time.Minute)})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), consumo.Geracoes)
+	assert.Equal(t, int64(5), consumo.TotalTokens)
+	assert.Equal(t, 0.5, consumo.CustoEstimado)
+
+	vazia, err := repo.ListConversas(domain.ConversaFiltro{})
+	assert.NoError(t, err)
+	assert.NotNil(t, vazia)
+}
+
+func TestMemoriaJobEConversa(t *testing.T) {
+	repo := memoria.NewRepository()
+
+	job := &domain.Job{Status: domain.StatusJobPendente, Total: 2, Itens: make([]domain.ItemJob, 2)}
+	assert.NoError(t, repo.CreateJob(job))
+	assert.NoError(t, repo.UpdateJobItem(job.ID.Hex(), 1, domain.ItemJob{Status: domain.StatusItemFalhou, Erro: "falha"}))
+	assert.NoError(t, repo.UpdateJobStatus(job.ID.Hex(), domain.StatusJobExecutando))
+
+	pendentes, err := repo.ListJobsPorStatus(domain.StatusJobExecutando)
+	assert.NoError(t, err)
+	assert.Len(t, pendentes, 1)
+	assert.Equal(t, 1, pendentes[0].Falhas)
+	assert.Equal(t, "falha", pendentes[0].Itens[1].Erro)
+
+	conversa := &domain.Conversa{}
+	assert.NoError(t, repo.CreateConversa(conversa))
+	assert.NoError(t, repo.AppendMensagensConversa(conversa.ID.Hex(), []domain.MensagemConversa{{Papel: domain.PapelUsuario, Conteudo: "oi"}}))
+	recuperada, err := repo.GetConversa(conversa.ID.Hex())
+	assert.NoError(t, err)
+	assert.Len(t, recuperada.Mensagens, 1)
+	assert.ErrorIs(t, repo.AppendMensagensConversa(job.ID.Hex(), nil), domain.ErrNaoEncontrado)
+}
+
+func TestMemoriaPersisteNoArquivo(t *testing.T) {
+	arquivo := filepath.Join(t.TempDir(), "vend.json")
+	repo, err := memoria.NewRepositoryArquivo(arquivo)
+	assert.NoError(t, err)
+
+	contexto := &domain.Contexto{Nome: "Campanha"}
+	assert.NoError(t, repo.CreateContexto(contexto))
+	documento := &domain.Documento{ContextoID: contexto.ID, Nome: "ficha.txt"}
+	trechos := []domain.TrechoDocumento{{Indice: 0, Conteudo: "a"}, {Indice: 1, Conteudo: "b"}}
+	assert.NoError(t, repo.CreateDocumento(documento, trechos))
+
+	reaberto, err := memoria.NewRepositoryArquivo(arquivo)
+	assert.NoError(t, err)
+	recuperado, err := reaberto.GetContexto(contexto.ID.Hex())
+	assert.NoError(t, err)
+	assert.Equal(t, "Campanha", recuperado.Nome)
+	assert.WithinDuration(t, contexto.CreatedAt, recuperado.CreatedAt, time.Millisecond)
+	recuperados, err := reaberto.ListTrechosDocumento(contexto.ID.Hex())
+	assert.NoError(t, err)
+	assert.Len(t, recuperados, 2)
+
+	assert.NoError(t, reaberto.DeleteDocumento(documento.ID.Hex()))
+	recuperados, err = reaberto.ListTrechosDocumento(contexto.ID.Hex())
+	assert.NoError(t, err)
+	assert.Empty(t, recuperados)
+}
+
+func TestMemoriaHandlersPessoa(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := memoria.NewRepository()
+	h := handlers.NewHandler(usecase.NewPessoaUseCase(repo), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	r := gin.New()
+	r.POST("/pessoas", h.CreatePessoa)
+	r.GET("/pessoas/:id", h.GetPessoa)
+	r.DELETE("/pessoas/:id", h.DeletePessoa)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/pessoas", strings.NewReader(`{"nome":"Ana","email":"ana@exemplo.com"}`)))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var criada domain.Pessoa
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &criada))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pessoas/"+criada.ID.Hex(), nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/pessoas/"+criada.ID.Hex(), nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pessoas/"+criada.ID.Hex(), nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
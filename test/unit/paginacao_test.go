@@ -0,0 +1,100 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	handlers "vend/internal/delivery/http"
+	"vend/internal/domain"
+	"vend/internal/infrastructure/memoria"
+	"vend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaginacaoPorCursor(t *testing.T) {
+	repo := memoria.NewRepository()
+	useCase := usecase.NewPessoaUseCase(repo)
+	for _, nome := range []string{"Carla", "Ana", "Bruno", "Ana Paula", "Daniel"} {
+		assert.NoError(t, repo.CreatePessoa(&domain.Pessoa{Nome: nome}))
+	}
+
+	var nomes []string
+	params := usecase.ParametrosPagina{Limite: 2, Ordem: "nome", Total: true}
+	for paginas := 0; paginas < 5; paginas++ {
+		pagina, err := useCase.ListPessoas(domain.PessoaFiltro{}, params)
+		assert.NoError(t, err)
+		// O total vem só na primeira página; as seguintes não contam de novo
+		if params.Cursor == "" {
+			assert.Equal(t, int64(5), *pagina.Total)
+		} else {
+			assert.Nil(t, pagina.Total)
+		}
+		for _, p := range pagina.Itens {
+			nomes = append(nomes, p.Nome)
+		}
+		if pagina.ProximoCursor == "" {
+			break
+		}
+		// O cursor continua na ordem em que foi gerado
+		params = usecase.ParametrosPagina{Limite: 2, Cursor: pagina.ProximoCursor, Total: true}
+	}
+	assert.Equal(t, []string{"Ana", "Ana Paula", "Bruno", "Carla", "Daniel"}, nomes)
+
+	pagina, err := useCase.ListPessoas(domain.PessoaFiltro{Nome: "ana"}, usecase.ParametrosPagina{Ordem: "-nome", Offset: 1, Total: true})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), *pagina.Total)
+	assert.Len(t, pagina.Itens, 1)
+	assert.Equal(t, "Ana", pagina.Itens[0].Nome)
+}
+
+func TestPaginacaoParametrosInvalidos(t *testing.T) {
+	useCase := usecase.NewPessoaUseCase(memoria.NewRepository())
+
+	invalidos := map[string]usecase.ParametrosPagina{
+		"limite acima do máximo": {Limite: usecase.LimiteMaximo + 1},
+		"offset negativo":        {Offset: -1},
+		"campo desconhecido":     {Ordem: "telefone"},
+		"cursor inválido":        {Cursor: "xyz"},
+	}
+	for nome, params := range invalidos {
+		_, err := useCase.ListPessoas(domain.PessoaFiltro{}, params)
+		assert.ErrorIs(t, err, usecase.ErrPaginacaoInvalida, nome)
+	}
+}
+
+func TestPaginacaoHandlerContextos(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := memoria.NewRepository()
+	inicio := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	for i, nome := range []string{"Março", "Abril", "Maio"} {
+		assert.NoError(t, repo.CreateContexto(&domain.Contexto{Nome: nome, DataInicio: inicio.AddDate(0, i, 0)}))
+	}
+	h := handlers.NewHandler(nil, nil, usecase.NewContextoUseCase(repo, nil), nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	r := gin.New()
+	r.GET("/contextos", h.ListContextos)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/contextos?data_inicio_de=2024-04-01&data_inicio_ate=2024-05-10&sort=data_inicio&total=true", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var pagina domain.Pagina[domain.Contexto]
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &pagina))
+	assert.Equal(t, int64(2), *pagina.Total)
+	assert.Equal(t, "Abril", pagina.Itens[0].Nome)
+	assert.Equal(t, "Maio", pagina.Itens[1].Nome)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/contextos", nil))
+	assert.NotContains(t, w.Body.String(), `"total"`)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/contextos?limit=1&offset=1&cursor=abc", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/contextos?total=talvez", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
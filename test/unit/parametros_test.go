@@ -0,0 +1,70 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"vend/internal/domain"
+	"vend/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func float32Ptr(v float32) *float32 { return &v }
+
+func intPtr(v int) *int { return &v }
+
+func TestExecutarPromptSobrepoeParametros(t *testing.T) {
+	mockRepo := new(MockRepository)
+	modelos := usecase.ParseModelos("gpt-4o, gpt-4o-mini")
+	useCase := usecase.NewGeracaoUseCase(mockRepo, newFakeProvider(t, ""), usecase.NewConsumoUseCase(mockRepo, usecase.PrecosPadrao()), modelos, nil, nil)
+
+	contexto := &domain.Contexto{ID: primitive.NewObjectID(), Parametros: domain.ParametrosGeracao{
+		Modelo:         "gpt-4o",
+		Temperatura:    float32Ptr(0.2),
+		PrefixoSistema: "Responda sempre em português.",
+	}}
+	prompt := &domain.Prompt{ID: primitive.NewObjectID(), Conteudo: "Resuma", ContextoID: contexto.ID, Parametros: domain.ParametrosGeracao{
+		Temperatura: float32Ptr(1.1),
+	}}
+
+	mockRepo.On("GetContexto", contexto.ID.Hex()).Return(contexto, nil)
+	mockRepo.On("GetPrompt", prompt.ID.Hex()).Return(prompt, nil)
+	mockRepo.On("CreateResposta", mock.AnythingOfType("*domain.Resposta")).Return(nil)
+
+	opcoes := usecase.OpcoesExecucao{Parametros: domain.ParametrosGeracao{Modelo: "gpt-4o-mini", MaxTokens: intPtr(200)}}
+	resposta, err := useCase.ExecutarPrompt(context.Background(), contexto.ID.Hex(), prompt.ID.Hex(), opcoes)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "gpt-4o-mini", resposta.Modelo)
+	assert.Equal(t, float32(1.1), resposta.Temperatura)
+	assert.Contains(t, resposta.Mensagens[0].Conteudo, "Responda sempre em português.\nContexto:")
+}
+
+func TestExecutarPromptComModeloNaoPermitido(t *testing.T) {
+	mockRepo := new(MockRepository)
+	useCase := usecase.NewGeracaoUseCase(mockRepo, newFakeProvider(t, ""), usecase.NewConsumoUseCase(mockRepo, usecase.PrecosPadrao()), usecase.ModelosPermitidos{"gpt-4o"}, nil, nil)
+
+	contexto := &domain.Contexto{ID: primitive.NewObjectID()}
+	prompt := &domain.Prompt{ID: primitive.NewObjectID(), Conteudo: "Resuma"}
+
+	mockRepo.On("GetContexto", contexto.ID.Hex()).Return(contexto, nil)
+	mockRepo.On("GetPrompt", prompt.ID.Hex()).Return(prompt, nil)
+
+	opcoes := usecase.OpcoesExecucao{Parametros: domain.ParametrosGeracao{Modelo: "gpt-4"}}
+	_, err := useCase.ExecutarPrompt(context.Background(), contexto.ID.Hex(), prompt.ID.Hex(), opcoes)
+
+	assert.ErrorIs(t, err, usecase.ErrParametroInvalido)
+	mockRepo.AssertNotCalled(t, "CreateResposta", mock.Anything)
+}
+
+func TestValidarParametrosLimites(t *testing.T) {
+	var modelos usecase.ModelosPermitidos
+
+	assert.NoError(t, modelos.Validar(domain.ParametrosGeracao{Modelo: "qualquer", Temperatura: float32Ptr(0)}))
+	assert.ErrorIs(t, modelos.Validar(domain.ParametrosGeracao{Temperatura: float32Ptr(2.5)}), usecase.ErrParametroInvalido)
+	assert.ErrorIs(t, modelos.Validar(domain.ParametrosGeracao{TopP: float32Ptr(1.5)}), usecase.ErrParametroInvalido)
+	assert.ErrorIs(t, modelos.Validar(domain.ParametrosGeracao{MaxTokens: intPtr(0)}), usecase.ErrParametroInvalido)
+	assert.ErrorIs(t, modelos.Validar(domain.ParametrosGeracao{Stop: []string{"a", "b", "c", "d", "e"}}), usecase.ErrParametroInvalido)
+}
@@ -2,11 +2,13 @@ package unit
 
 import (
 	"testing"
+	"time"
 	"vend/internal/domain"
 	"vend/internal/usecase"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type MockRepository struct {
@@ -18,7 +20,7 @@ func (m *MockRepository) CreatePessoa(pessoa *domain.Pessoa) error {
 	return args.Error(0)
 }
 
-func (m *MockRepository) GetPessoa(id uint) (*domain.Pessoa, error) {
+func (m *MockRepository) GetPessoa(id string) (*domain.Pessoa, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -26,12 +28,12 @@ func (m *MockRepository) GetPessoa(id uint) (*domain.Pessoa, error) {
 	return args.Get(0).(*domain.Pessoa), args.Error(1)
 }
 
-func (m *MockRepository) ListPessoas() ([]domain.Pessoa, error) {
-	args := m.Called()
+func (m *MockRepository) ListPessoas(filtro domain.PessoaFiltro) ([]domain.Pessoa, int64, error) {
+	args := m.Called(filtro)
 	if args.Get(0) == nil {
-		return nil, args.Error(1)
+		return nil, 0, args.Error(2)
 	}
-	return args.Get(0).([]domain.Pessoa), args.Error(1)
+	return args.Get(0).([]domain.Pessoa), args.Get(1).(int64), args.Error(2)
 }
 
 func (m *MockRepository) UpdatePessoa(pessoa *domain.Pessoa) error {
@@ -39,8 +41,8 @@ func (m *MockRepository) UpdatePessoa(pessoa *domain.Pessoa) error {
 	return args.Error(0)
 }
 
-func (m *MockRepository) DeletePessoa(id uint) error {
-	args := m.Called(id)
+func (m *MockRepository) DeletePessoa(id string, remocao domain.Remocao) error {
+	args := m.Called(id, remocao)
 	return args.Error(0)
 }
 
@@ -49,7 +51,7 @@ func (m *MockRepository) CreateTelefone(telefone *domain.Telefone) error {
 	return args.Error(0)
 }
 
-func (m *MockRepository) GetTelefone(id uint) (*domain.Telefone, error) {
+func (m *MockRepository) GetTelefone(id string) (*domain.Telefone, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -57,12 +59,12 @@ func (m *MockRepository) GetTelefone(id uint) (*domain.Telefone, error) {
 	return args.Get(0).(*domain.Telefone), args.Error(1)
 }
 
-func (m *MockRepository) ListTelefones() ([]domain.Telefone, error) {
-	args := m.Called()
+func (m *MockRepository) ListTelefones(filtro domain.TelefoneFiltro) ([]domain.Telefone, int64, error) {
+	args := m.Called(filtro)
 	if args.Get(0) == nil {
-		return nil, args.Error(1)
+		return nil, 0, args.Error(2)
 	}
-	return args.Get(0).([]domain.Telefone), args.Error(1)
+	return args.Get(0).([]domain.Telefone), args.Get(1).(int64), args.Error(2)
 }
 
 func (m *MockRepository) UpdateTelefone(telefone *domain.Telefone) error {
@@ -70,8 +72,8 @@ func (m *MockRepository) UpdateTelefone(telefone *domain.Telefone) error {
 	return args.Error(0)
 }
 
-func (m *MockRepository) DeleteTelefone(id uint) error {
-	args := m.Called(id)
+func (m *MockRepository) DeleteTelefone(id string, remocao domain.Remocao) error {
+	args := m.Called(id, remocao)
 	return args.Error(0)
 }
 
@@ -80,7 +82,7 @@ func (m *MockRepository) CreateContexto(contexto *domain.Contexto) error {
 	return args.Error(0)
 }
 
-func (m *MockRepository) GetContexto(id uint) (*domain.Contexto, error) {
+func (m *MockRepository) GetContexto(id string) (*domain.Contexto, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -88,12 +90,12 @@ func (m *MockRepository) GetContexto(id uint) (*domain.Contexto, error) {
 	return args.Get(0).(*domain.Contexto), args.Error(1)
 }
 
-func (m *MockRepository) ListContextos() ([]domain.Contexto, error) {
-	args := m.Called()
+func (m *MockRepository) ListContextos(filtro domain.ContextoFiltro) ([]domain.Contexto, int64, error) {
+	args := m.Called(filtro)
 	if args.Get(0) == nil {
-		return nil, args.Error(1)
+		return nil, 0, args.Error(2)
 	}
-	return args.Get(0).([]domain.Contexto), args.Error(1)
+	return args.Get(0).([]domain.Contexto), args.Get(1).(int64), args.Error(2)
 }
 
 func (m *MockRepository) UpdateContexto(contexto *domain.Contexto) error {
@@ -101,8 +103,8 @@ func (m *MockRepository) UpdateContexto(contexto *domain.Contexto) error {
 	return args.Error(0)
 }
 
-func (m *MockRepository) DeleteContexto(id uint) error {
-	args := m.Called(id)
+func (m *MockRepository) DeleteContexto(id string, remocao domain.Remocao) error {
+	args := m.Called(id, remocao)
 	return args.Error(0)
 }
 
@@ -111,7 +113,7 @@ func (m *MockRepository) CreatePrompt(prompt *domain.Prompt) error {
 	return args.Error(0)
 }
 
-func (m *MockRepository) GetPrompt(id uint) (*domain.Prompt, error) {
+func (m *MockRepository) GetPrompt(id string) (*domain.Prompt, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -119,12 +121,12 @@ func (m *MockRepository) GetPrompt(id uint) (*domain.Prompt, error) {
 	return args.Get(0).(*domain.Prompt), args.Error(1)
 }
 
-func (m *MockRepository) ListPrompts() ([]domain.Prompt, error) {
-	args := m.Called()
+func (m *MockRepository) ListPrompts(filtro domain.PromptFiltro) ([]domain.Prompt, int64, error) {
+	args := m.Called(filtro)
 	if args.Get(0) == nil {
-		return nil, args.Error(1)
+		return nil, 0, args.Error(2)
 	}
-	return args.Get(0).([]domain.Prompt), args.Error(1)
+	return args.Get(0).([]domain.Prompt), args.Get(1).(int64), args.Error(2)
 }
 
 func (m *MockRepository) UpdatePrompt(prompt *domain.Prompt) error {
@@ -132,11 +134,239 @@ func (m *MockRepository) UpdatePrompt(prompt *domain.Prompt) error {
 	return args.Error(0)
 }
 
-func (m *MockRepository) DeletePrompt(id uint) error {
+func (m *MockRepository) DeletePrompt(id string, remocao domain.Remocao) error {
+	args := m.Called(id, remocao)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreatePromptVersao(versao *domain.PromptVersao) error {
+	args := m.Called(versao)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetPromptVersao(promptID string, numero int) (*domain.PromptVersao, error) {
+	args := m.Called(promptID, numero)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.PromptVersao), args.Error(1)
+}
+
+func (m *MockRepository) ListPromptVersoes(promptID string) ([]domain.PromptVersao, error) {
+	args := m.Called(promptID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.PromptVersao), args.Error(1)
+}
+
+func (m *MockRepository) UpdatePromptEmbedding(id string, embedding *domain.Embedding) error {
+	args := m.Called(id, embedding)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateResposta(resposta *domain.Resposta) error {
+	args := m.Called(resposta)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetResposta(id string) (*domain.Resposta, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Resposta), args.Error(1)
+}
+
+func (m *MockRepository) ListRespostas(filtro domain.RespostaFiltro) ([]domain.Resposta, error) {
+	args := m.Called(filtro)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Resposta), args.Error(1)
+}
+
+func (m *MockRepository) SomarConsumo(filtro domain.ConsumoFiltro) (*domain.Consumo, error) {
+	args := m.Called(filtro)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Consumo), args.Error(1)
+}
+
+func (m *MockRepository) CreateUsoEmbeddings(uso *domain.UsoEmbeddings) error {
+	args := m.Called(uso)
+	return args.Error(0)
+}
+
+func (m *MockRepository) UpdateRespostaEmbedding(id string, embedding *domain.Embedding) error {
+	args := m.Called(id, embedding)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateConversa(conversa *domain.Conversa) error {
+	args := m.Called(conversa)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetConversa(id string) (*domain.Conversa, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Conversa), args.Error(1)
+}
+
+func (m *MockRepository) ListConversas(filtro domain.ConversaFiltro) ([]domain.Conversa, error) {
+	args := m.Called(filtro)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Conversa), args.Error(1)
+}
+
+func (m *MockRepository) AppendMensagensConversa(id string, mensagens []domain.MensagemConversa) error {
+	args := m.Called(id, mensagens)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateDocumento(documento *domain.Documento, trechos []domain.TrechoDocumento) error {
+	args := m.Called(documento, trechos)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetDocumento(id string) (*domain.Documento, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Documento), args.Error(1)
+}
+
+func (m *MockRepository) ListDocumentos(contextoID string) ([]domain.Documento, error) {
+	args := m.Called(contextoID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Documento), args.Error(1)
+}
+
+func (m *MockRepository) ListTrechosDocumento(contextoID string) ([]domain.TrechoDocumento, error) {
+	args := m.Called(contextoID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.TrechoDocumento), args.Error(1)
+}
+
+func (m *MockRepository) DeleteDocumento(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateJob(job *domain.Job) error {
+	args := m.Called(job)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetJob(id string) (*domain.Job, error) {
 	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Job), args.Error(1)
+}
+
+func (m *MockRepository) ListJobsPorStatus(status ...string) ([]domain.Job, error) {
+	args := m.Called(status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Job), args.Error(1)
+}
+
+func (m *MockRepository) UpdateJobStatus(id string, status string) error {
+	args := m.Called(id, status)
+	return args.Error(0)
+}
+
+func (m *MockRepository) UpdateJobItem(id string, i int, item domain.ItemJob) error {
+	args := m.Called(id, i, item)
+	return args.Error(0)
+}
+
+func (m *MockRepository) AddPessoaContexto(contextoID, pessoaID string) error {
+	args := m.Called(contextoID, pessoaID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) RemovePessoaContexto(contextoID, pessoaID string) error {
+	args := m.Called(contextoID, pessoaID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) BuscarTexto(filtro domain.BuscaTextoFiltro) ([]domain.ResultadoTexto, error) {
+	args := m.Called(filtro)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.ResultadoTexto), args.Error(1)
+}
+
+// Transacao roda fn no próprio mock, sem transação
+func (m *MockRepository) Transacao(fn func(repo usecase.Repository) error) error {
+	return fn(m)
+}
+
+func (m *MockRepository) ContarReferencias(relacao domain.Relacao, id string) (int64, error) {
+	args := m.Called(relacao, id)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) RemoverReferencias(relacao domain.Relacao, id string, remocao domain.Remocao) error {
+	args := m.Called(relacao, id, remocao)
+	return args.Error(0)
+}
+
+func (m *MockRepository) AnularReferencias(relacao domain.Relacao, id string) error {
+	args := m.Called(relacao, id)
 	return args.Error(0)
 }
 
+func (m *MockRepository) ListLixeira(filtro domain.LixeiraFiltro) ([]domain.ItemLixeira, int64, error) {
+	args := m.Called(filtro)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]domain.ItemLixeira), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockRepository) GetLixeira(entidade, id string) (*domain.ItemLixeira, error) {
+	args := m.Called(entidade, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ItemLixeira), args.Error(1)
+}
+
+func (m *MockRepository) Restaurar(entidade, id string) (*domain.Remocao, error) {
+	args := m.Called(entidade, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Remocao), args.Error(1)
+}
+
+func (m *MockRepository) RestaurarReferencias(relacao domain.Relacao, id string, em time.Time) error {
+	args := m.Called(relacao, id, em)
+	return args.Error(0)
+}
+
+func (m *MockRepository) PurgarLixeira(ate time.Time) (int64, error) {
+	args := m.Called(ate)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func TestCreatePessoa(t *testing.T) {
 	mockRepo := new(MockRepository)
 	useCase := usecase.NewPessoaUseCase(mockRepo)
@@ -158,15 +388,16 @@ func TestGetPessoa(t *testing.T) {
 	mockRepo := new(MockRepository)
 	useCase := usecase.NewPessoaUseCase(mockRepo)
 
+	id := primitive.NewObjectID()
 	expectedPessoa := &domain.Pessoa{
-		ID:    1,
+		ID:    id,
 		Nome:  "Teste",
 		Email: "teste@teste.com",
 	}
 
-	mockRepo.On("GetPessoa", uint(1)).Return(expectedPessoa, nil)
+	mockRepo.On("GetPessoa", id.Hex()).Return(expectedPessoa, nil)
 
-	pessoa, err := useCase.GetPessoa(1)
+	pessoa, err := useCase.GetPessoa(id.Hex())
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedPessoa, pessoa)
@@ -179,23 +410,25 @@ func TestListPessoas(t *testing.T) {
 
 	expectedPessoas := []domain.Pessoa{
 		{
-			ID:    1,
+			ID:    primitive.NewObjectID(),
 			Nome:  "Teste 1",
 			Email: "teste1@teste.com",
 		},
 		{
-			ID:    2,
+			ID:    primitive.NewObjectID(),
 			Nome:  "Teste 2",
 			Email: "teste2@teste.com",
 		},
 	}
 
-	mockRepo.On("ListPessoas").Return(expectedPessoas, nil)
+	mockRepo.On("ListPessoas", mock.Anything).Return(expectedPessoas, int64(2), nil)
 
-	pessoas, err := useCase.ListPessoas()
+	pagina, err := useCase.ListPessoas(domain.PessoaFiltro{}, usecase.ParametrosPagina{Total: true})
 
 	assert.NoError(t, err)
-	assert.Equal(t, expectedPessoas, pessoas)
+	assert.Equal(t, expectedPessoas, pagina.Itens)
+	assert.Equal(t, int64(2), *pagina.Total)
+	assert.Empty(t, pagina.ProximoCursor)
 	mockRepo.AssertExpectations(t)
 }
 
@@ -204,7 +437,7 @@ func TestUpdatePessoa(t *testing.T) {
 	useCase := usecase.NewPessoaUseCase(mockRepo)
 
 	pessoa := &domain.Pessoa{
-		ID:    1,
+		ID:    primitive.NewObjectID(),
 		Nome:  "Teste Atualizado",
 		Email: "teste.atualizado@teste.com",
 	}
@@ -221,10 +454,36 @@ func TestDeletePessoa(t *testing.T) {
 	mockRepo := new(MockRepository)
 	useCase := usecase.NewPessoaUseCase(mockRepo)
 
-	mockRepo.On("DeletePessoa", uint(1)).Return(nil)
+	id := primitive.NewObjectID().Hex()
+	var remocoes []domain.Remocao
+	guardar := func(args mock.Arguments) { remocoes = append(remocoes, args.Get(len(args)-1).(domain.Remocao)) }
+	mockRepo.On("RemoverReferencias", domain.RelacaoPessoaTelefones, id, mock.Anything).Run(guardar).Return(nil)
+	mockRepo.On("AnularReferencias", domain.RelacaoPessoaContextos, id).Return(nil)
+	mockRepo.On("DeletePessoa", id, mock.Anything).Run(guardar).Return(nil)
 
-	err := useCase.DeletePessoa(1)
+	err := useCase.DeletePessoa(id, "admin")
 
 	assert.NoError(t, err)
 	mockRepo.AssertExpectations(t)
+	// Os telefones vão para a lixeira com a mesma remoção da pessoa
+	assert.Len(t, remocoes, 2)
+	assert.Equal(t, remocoes[0], remocoes[1])
+	assert.Equal(t, "admin", remocoes[0].Por)
+}
+
+func TestDeletePessoaRestrita(t *testing.T) {
+	mockRepo := new(MockRepository)
+	useCase := usecase.NewPessoaUseCase(mockRepo)
+	regras, err := usecase.ParseRegrasRemocao("pessoa_telefones=restrict")
+	assert.NoError(t, err)
+	useCase.UsarRegrasRemocao(regras)
+
+	id := primitive.NewObjectID().Hex()
+	mockRepo.On("ContarReferencias", domain.RelacaoPessoaTelefones, id).Return(int64(2), nil)
+
+	err = useCase.DeletePessoa(id, "")
+
+	assert.ErrorIs(t, err, usecase.ErrRemocaoRestrita)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "DeletePessoa", id, mock.Anything)
 }
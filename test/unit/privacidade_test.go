@@ -0,0 +1,127 @@
+package unit
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"vend/internal/domain"
+	"vend/internal/infrastructure/privacidade"
+	"vend/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// providerEco responde com a última mensagem recebida; no streaming, em
+// trechos de 3 caracteres, que cortam os marcadores ao meio
+type providerEco struct {
+	requisicoes []domain.RequisicaoGeracao
+}
+
+func (p *providerEco) Generate(ctx context.Context, req domain.RequisicaoGeracao) (*domain.ResultadoGeracao, error) {
+	p.requisicoes = append(p.requisicoes, req)
+	return &domain.ResultadoGeracao{Conteudo: "Eco: " + req.Mensagens[len(req.Mensagens)-1].Conteudo}, nil
+}
+
+func (p *providerEco) GenerateStream(ctx context.Context, req domain.RequisicaoGeracao, onDelta func(string) error) (*domain.ResultadoGeracao, error) {
+	resultado, _ := p.Generate(ctx, req)
+	runas := []rune(resultado.Conteudo)
+	for i := 0; i < len(runas); i += 3 {
+		if err := onDelta(string(runas[i:min(i+3, len(runas))])); err != nil {
+			return nil, err
+		}
+	}
+	return resultado, nil
+}
+
+type moderadorSinalizador struct{}
+
+func (moderadorSinalizador) Moderar(ctx context.Context, texto string) (*domain.ResultadoModeracao, error) {
+	return &domain.ResultadoModeracao{Sinalizado: true, Categorias: []string{"harassment"}}, nil
+}
+
+const mensagemComDados = "Ligue para Ana Souza em (11) 98765-4321 ou ana@exemplo.com, CPF 123.456.789-09"
+
+func requisicaoComDados() domain.RequisicaoGeracao {
+	return domain.RequisicaoGeracao{Mensagens: []domain.Mensagem{
+		{Papel: domain.PapelSistema, Conteudo: "Contexto com Ana Souza (ana@exemplo.com)"},
+		{Papel: domain.PapelUsuario, Conteudo: mensagemComDados},
+	}}
+}
+
+func ctxComPrivacidade(modo string) context.Context {
+	return domain.ComPrivacidade(context.Background(), domain.Privacidade{
+		Politica: domain.PoliticaPrivacidade{Modo: modo},
+		Nomes:    []string{"Ana Souza"},
+	})
+}
+
+func TestPseudonimizarRestauraResposta(t *testing.T) {
+	eco := &providerEco{}
+	provider := privacidade.NewProvider(eco, nil, "")
+
+	resultado, err := provider.Generate(ctxComPrivacidade(domain.PrivacidadePseudonimizar), requisicaoComDados())
+
+	assert.NoError(t, err)
+	enviadas := eco.requisicoes[0].Mensagens
+	assert.Equal(t, "Contexto com <PESSOA_1> (<EMAIL_1>)", enviadas[0].Conteudo)
+	assert.Equal(t, "Ligue para <PESSOA_1> em <TELEFONE_1> ou <EMAIL_1>, CPF <CPF_1>", enviadas[1].Conteudo)
+	assert.Equal(t, "Eco: "+mensagemComDados, resultado.Conteudo)
+}
+
+func TestMascararNaoRestauraResposta(t *testing.T) {
+	eco := &providerEco{}
+	provider := privacidade.NewProvider(eco, nil, domain.PrivacidadeMascarar)
+
+	// Sem política no contexto vale o modo padrão
+	ctx := domain.ComPrivacidade(context.Background(), domain.Privacidade{Nomes: []string{"Ana Souza"}})
+	resultado, err := provider.Generate(ctx, requisicaoComDados())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Eco: Ligue para [PESSOA] em [TELEFONE] ou [EMAIL], CPF [CPF]", resultado.Conteudo)
+}
+
+func TestPrivacidadeRespeitaDadosDaPolitica(t *testing.T) {
+	eco := &providerEco{}
+	provider := privacidade.NewProvider(eco, nil, "")
+
+	ctx := domain.ComPrivacidade(context.Background(), domain.Privacidade{
+		Politica: domain.PoliticaPrivacidade{Modo: domain.PrivacidadeMascarar, Dados: []string{domain.DadoEmail}},
+		Nomes:    []string{"Ana Souza"},
+	})
+	_, err := provider.Generate(ctx, requisicaoComDados())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Ligue para Ana Souza em (11) 98765-4321 ou [EMAIL], CPF 123.456.789-09", eco.requisicoes[0].Mensagens[1].Conteudo)
+}
+
+func TestPseudonimizarRestauraStream(t *testing.T) {
+	provider := privacidade.NewProvider(&providerEco{}, nil, "")
+
+	var trechos []string
+	resultado, err := provider.GenerateStream(ctxComPrivacidade(domain.PrivacidadePseudonimizar), requisicaoComDados(), func(delta string) error {
+		trechos = append(trechos, delta)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Eco: "+mensagemComDados, strings.Join(trechos, ""))
+	assert.Equal(t, "Eco: "+mensagemComDados, resultado.Conteudo)
+}
+
+func TestModeracaoBloqueiaGeracao(t *testing.T) {
+	eco := &providerEco{}
+	provider := privacidade.NewProvider(eco, moderadorSinalizador{}, "")
+
+	ctx := domain.ComPrivacidade(context.Background(), domain.Privacidade{Politica: domain.PoliticaPrivacidade{Moderar: true}})
+	_, err := provider.Generate(ctx, requisicaoComDados())
+
+	assert.ErrorIs(t, err, domain.ErrConteudoBloqueado)
+	assert.Contains(t, err.Error(), "harassment")
+	assert.Empty(t, eco.requisicoes)
+}
+
+func TestValidarPrivacidade(t *testing.T) {
+	assert.NoError(t, usecase.ValidarPrivacidade(domain.PoliticaPrivacidade{Modo: domain.PrivacidadePseudonimizar, Dados: []string{domain.DadoCPF}}))
+	assert.ErrorIs(t, usecase.ValidarPrivacidade(domain.PoliticaPrivacidade{Modo: "apagar"}), usecase.ErrPrivacidadeInvalida)
+	assert.ErrorIs(t, usecase.ValidarPrivacidade(domain.PoliticaPrivacidade{Dados: []string{"endereco"}}), usecase.ErrPrivacidadeInvalida)
+}
@@ -0,0 +1,64 @@
+package unit
+
+import (
+	"testing"
+	"time"
+	"vend/internal/domain"
+	"vend/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderizarPrompt(t *testing.T) {
+	prompt := &domain.Prompt{
+		Conteudo: `Olá {{.Pessoa.Nome}}, {{.Vars.desconto}}% off até {{.Contexto.DataFim.Format "02/01/2006"}}{{if .Vars.vip}} (VIP){{end}}`,
+		Variaveis: []domain.VariavelPrompt{
+			{Nome: "desconto", Tipo: domain.TipoVariavelNumero, Obrigatoria: true},
+			{Nome: "vip", Tipo: domain.TipoVariavelBooleano, Padrao: "true"},
+		},
+	}
+	assert.NoError(t, usecase.ValidarPrompt(prompt))
+
+	pessoa := &domain.Pessoa{Nome: "Maria"}
+	contexto := &domain.Contexto{DataFim: time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC)}
+
+	conteudo, err := usecase.RenderizarPrompt(prompt, pessoa, contexto, map[string]any{"desconto": float64(15)})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Olá Maria, 15% off até 29/11/2024 (VIP)", conteudo)
+}
+
+func TestRenderizarPromptVariavelObrigatoria(t *testing.T) {
+	prompt := &domain.Prompt{
+		Conteudo:  "{{.Vars.desconto}}",
+		Variaveis: []domain.VariavelPrompt{{Nome: "desconto", Tipo: domain.TipoVariavelNumero, Obrigatoria: true}},
+	}
+
+	_, err := usecase.RenderizarPrompt(prompt, nil, nil, nil)
+	assert.ErrorIs(t, err, usecase.ErrVariavelInvalida)
+
+	_, err = usecase.RenderizarPrompt(prompt, nil, nil, map[string]any{"desconto": "muito"})
+	assert.ErrorIs(t, err, usecase.ErrVariavelInvalida)
+
+	_, err = usecase.RenderizarPrompt(prompt, nil, nil, map[string]any{"desconto": 10, "extra": "x"})
+	assert.ErrorIs(t, err, usecase.ErrVariavelInvalida)
+}
+
+func TestValidarPromptRejeitaTemplateInvalido(t *testing.T) {
+	casos := map[string]*domain.Prompt{
+		"sintaxe":                {Conteudo: "Olá {{.Pessoa.Nome"},
+		"campo inexistente":      {Conteudo: "{{.Pessoa.Idade}}"},
+		"variável não declarada": {Conteudo: "{{.Vars.cupom}}"},
+	}
+	for nome, prompt := range casos {
+		t.Run(nome, func(t *testing.T) {
+			assert.ErrorIs(t, usecase.ValidarPrompt(prompt), usecase.ErrTemplateInvalido)
+		})
+	}
+
+	err := usecase.ValidarPrompt(&domain.Prompt{
+		Conteudo:  "ok",
+		Variaveis: []domain.VariavelPrompt{{Nome: "valor", Tipo: "moeda"}},
+	})
+	assert.ErrorIs(t, err, usecase.ErrVariavelInvalida)
+}
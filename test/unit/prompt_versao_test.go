@@ -0,0 +1,74 @@
+package unit
+
+import (
+	"testing"
+	"vend/internal/domain"
+	"vend/internal/infrastructure/memoria"
+	"vend/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDiffLinhas(t *testing.T) {
+	diff := usecase.DiffLinhas("Olá\nCompre agora\nObrigado", "Olá\nAproveite o desconto\nObrigado")
+
+	assert.Equal(t, "  Olá\n- Compre agora\n+ Aproveite o desconto\n  Obrigado\n", diff)
+}
+
+func TestUpdatePromptRegistraNovaVersao(t *testing.T) {
+	mockRepo := new(MockRepository)
+	useCase := usecase.NewPromptUseCase(mockRepo, nil)
+
+	atual := &domain.Prompt{ID: primitive.NewObjectID(), Conteudo: "Compre agora", Versao: 2}
+	editado := &domain.Prompt{ID: atual.ID, Conteudo: "Aproveite o desconto"}
+
+	mockRepo.On("GetPrompt", atual.ID.Hex()).Return(atual, nil)
+	mockRepo.On("UpdatePrompt", editado).Return(nil)
+	mockRepo.On("CreatePromptVersao", mock.MatchedBy(func(v *domain.PromptVersao) bool {
+		return v.Numero == 3 && v.Autor == "ana" && v.Diff == "- Compre agora\n+ Aproveite o desconto\n"
+	})).Return(nil)
+
+	err := useCase.UpdatePrompt(editado, "ana")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, editado.Versao)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdatePromptSemMudancaNaoCriaVersao(t *testing.T) {
+	mockRepo := new(MockRepository)
+	useCase := usecase.NewPromptUseCase(mockRepo, nil)
+
+	atual := &domain.Prompt{ID: primitive.NewObjectID(), Conteudo: "Compre agora", Versao: 2}
+	editado := &domain.Prompt{ID: atual.ID, Conteudo: "Compre agora", ContextoID: primitive.NewObjectID()}
+
+	mockRepo.On("GetPrompt", atual.ID.Hex()).Return(atual, nil)
+	mockRepo.On("UpdatePrompt", editado).Return(nil)
+
+	err := useCase.UpdatePrompt(editado, "ana")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, editado.Versao)
+	mockRepo.AssertNotCalled(t, "CreatePromptVersao", mock.Anything)
+}
+
+// Se outra atualização já gravou o número da nova versão, a transação é
+// desfeita e o prompt continua como estava
+func TestUpdatePromptConcorrenteNaoGravaPrompt(t *testing.T) {
+	repo := memoria.NewRepository()
+	useCase := usecase.NewPromptUseCase(repo, nil)
+
+	prompt := &domain.Prompt{Conteudo: "Compre agora"}
+	assert.NoError(t, useCase.CreatePrompt(prompt, "ana"))
+	assert.NoError(t, repo.CreatePromptVersao(&domain.PromptVersao{PromptID: prompt.ID, Numero: 2, Conteudo: "Outra edição"}))
+
+	editado := &domain.Prompt{ID: prompt.ID, Conteudo: "Aproveite o desconto"}
+	assert.Error(t, useCase.UpdatePrompt(editado, "bia"))
+
+	recuperado, err := repo.GetPrompt(prompt.ID.Hex())
+	assert.NoError(t, err)
+	assert.Equal(t, "Compre agora", recuperado.Conteudo)
+	assert.Equal(t, 1, recuperado.Versao)
+}
@@ -0,0 +1,104 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	handlers "vend/internal/delivery/http"
+	"vend/internal/domain"
+	"vend/internal/infrastructure/memoria"
+	"vend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRelacoesContextoPorReferencia(t *testing.T) {
+	repo := memoria.NewRepository()
+	pessoaUseCase := usecase.NewPessoaUseCase(repo)
+	contextoUseCase := usecase.NewContextoUseCase(repo, nil)
+
+	ana := &domain.Pessoa{Nome: "Ana"}
+	bruno := &domain.Pessoa{Nome: "Bruno"}
+	assert.NoError(t, repo.CreatePessoa(ana))
+	assert.NoError(t, repo.CreatePessoa(bruno))
+
+	contexto := &domain.Contexto{Nome: "Campanha", PessoaIDs: []primitive.ObjectID{ana.ID, ana.ID}}
+	assert.NoError(t, contextoUseCase.CreateContexto(contexto))
+	assert.Equal(t, []primitive.ObjectID{ana.ID}, contexto.PessoaIDs)
+	assert.Len(t, contexto.Pessoas, 1)
+
+	err := contextoUseCase.CreateContexto(&domain.Contexto{Nome: "Outro", PessoaIDs: []primitive.ObjectID{primitive.NewObjectID()}})
+	assert.ErrorIs(t, err, usecase.ErrPessoaNaoEncontrada)
+
+	// Alterar a pessoa se reflete no contexto, que não guarda cópia
+	ana.Nome = "Ana Paula"
+	assert.NoError(t, pessoaUseCase.UpdatePessoa(ana))
+	recuperado, err := contextoUseCase.GetContexto(contexto.ID.Hex())
+	assert.NoError(t, err)
+	assert.Equal(t, "Ana Paula", recuperado.Pessoas[0].Nome)
+
+	// O PUT não altera as pessoas do contexto
+	recuperado.PessoaIDs = nil
+	recuperado.Descricao = "Nova descrição"
+	assert.NoError(t, contextoUseCase.UpdateContexto(recuperado))
+	assert.Equal(t, []primitive.ObjectID{ana.ID}, recuperado.PessoaIDs)
+
+	pagina, err := contextoUseCase.ListContextos(domain.ContextoFiltro{}, usecase.ParametrosPagina{})
+	assert.NoError(t, err)
+	assert.Empty(t, pagina.Itens[0].Pessoas)
+	pagina, err = contextoUseCase.ListContextos(domain.ContextoFiltro{Expandir: true}, usecase.ParametrosPagina{})
+	assert.NoError(t, err)
+	assert.Len(t, pagina.Itens[0].Pessoas, 1)
+}
+
+func TestRelacoesHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := memoria.NewRepository()
+	pessoa := &domain.Pessoa{Nome: "Ana"}
+	assert.NoError(t, repo.CreatePessoa(pessoa))
+	assert.NoError(t, repo.CreateTelefone(&domain.Telefone{PessoaID: pessoa.ID, Numero: "11999990000"}))
+	contexto := &domain.Contexto{Nome: "Campanha"}
+	assert.NoError(t, repo.CreateContexto(contexto))
+
+	h := handlers.NewHandler(usecase.NewPessoaUseCase(repo), nil, usecase.NewContextoUseCase(repo, nil), nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	r := gin.New()
+	r.GET("/pessoas/:id/telefones", h.ListTelefonesPessoa)
+	r.POST("/contextos/:id/pessoas/:pessoaId", h.AddPessoaContexto)
+	r.DELETE("/contextos/:id/pessoas/:pessoaId", h.RemovePessoaContexto)
+
+	requisitar := func(metodo, url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(metodo, url, nil))
+		return w
+	}
+	url := "/contextos/" + contexto.ID.Hex() + "/pessoas/" + pessoa.ID.Hex()
+
+	// Adicionar de novo não duplica a pessoa
+	for i := 0; i < 2; i++ {
+		w := requisitar(http.MethodPost, url)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var recuperado domain.Contexto
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &recuperado))
+		assert.Equal(t, []primitive.ObjectID{pessoa.ID}, recuperado.PessoaIDs)
+		assert.Equal(t, "Ana", recuperado.Pessoas[0].Nome)
+	}
+
+	w := requisitar(http.MethodPost, "/contextos/"+contexto.ID.Hex()+"/pessoas/"+primitive.NewObjectID().Hex())
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	assert.Equal(t, http.StatusOK, requisitar(http.MethodDelete, url).Code)
+	assert.Equal(t, http.StatusNotFound, requisitar(http.MethodDelete, url).Code)
+
+	w = requisitar(http.MethodGet, "/pessoas/"+pessoa.ID.Hex()+"/telefones?total=true")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var telefones domain.Pagina[domain.Telefone]
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &telefones))
+	assert.Equal(t, int64(1), *telefones.Total)
+	assert.Equal(t, "11999990000", telefones.Itens[0].Numero)
+
+	w = requisitar(http.MethodGet, "/pessoas/"+primitive.NewObjectID().Hex()+"/telefones")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,123 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+	"vend/internal/domain"
+	"vend/internal/infrastructure/resiliencia"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// providerRoteiro devolve os erros do roteiro em ordem e depois responde com sucesso
+type providerRoteiro struct {
+	erros    []error
+	chamadas int
+	trechos  []string
+}
+
+func (p *providerRoteiro) Generate(ctx context.Context, req domain.RequisicaoGeracao) (*domain.ResultadoGeracao, error) {
+	return p.GenerateStream(ctx, req, func(string) error { return nil })
+}
+
+func (p *providerRoteiro) GenerateStream(ctx context.Context, req domain.RequisicaoGeracao, onDelta func(string) error) (*domain.ResultadoGeracao, error) {
+	p.chamadas++
+	for _, trecho := range p.trechos {
+		if err := onDelta(trecho); err != nil {
+			return nil, err
+		}
+	}
+	if p.chamadas <= len(p.erros) {
+		return nil, p.erros[p.chamadas-1]
+	}
+	return &domain.ResultadoGeracao{Conteudo: "ok"}, nil
+}
+
+func configTeste() resiliencia.Config {
+	return resiliencia.Config{
+		Tentativas:     3,
+		BackoffInicial: time.Millisecond,
+		BackoffMaximo:  2 * time.Millisecond,
+		LimiteFalhas:   2,
+		TempoAberto:    time.Hour,
+	}
+}
+
+func TestResilienciaRepeteFalhasTransitorias(t *testing.T) {
+	roteiro := &providerRoteiro{erros: []error{
+		fmt.Errorf("%w: status 429", domain.ErrLLMLimiteRequisicoes),
+		fmt.Errorf("%w: status 502", domain.ErrLLMIndisponivel),
+	}}
+	provider := resiliencia.NewProvider(roteiro, configTeste())
+
+	resultado, err := provider.Generate(context.Background(), domain.RequisicaoGeracao{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", resultado.Conteudo)
+	assert.Equal(t, 3, roteiro.chamadas)
+}
+
+func TestResilienciaNaoRepeteRequisicaoInvalida(t *testing.T) {
+	roteiro := &providerRoteiro{erros: []error{fmt.Errorf("%w: status 400", domain.ErrLLMRequisicaoInvalida)}}
+	provider := resiliencia.NewProvider(roteiro, configTeste())
+
+	_, err := provider.Generate(context.Background(), domain.RequisicaoGeracao{})
+
+	assert.ErrorIs(t, err, domain.ErrLLMRequisicaoInvalida)
+	assert.Equal(t, 1, roteiro.chamadas)
+}
+
+func TestResilienciaStreamNaoRepeteAposEntregarTrechos(t *testing.T) {
+	roteiro := &providerRoteiro{
+		erros:   []error{fmt.Errorf("%w: conexão encerrada", domain.ErrLLMIndisponivel)},
+		trechos: []string{"olá "},
+	}
+	provider := resiliencia.NewProvider(roteiro, configTeste())
+
+	_, err := provider.GenerateStream(context.Background(), domain.RequisicaoGeracao{}, func(string) error { return nil })
+
+	assert.ErrorIs(t, err, domain.ErrLLMIndisponivel)
+	assert.Equal(t, 1, roteiro.chamadas)
+}
+
+func TestResilienciaCircuitoAbreAposFalhasConsecutivas(t *testing.T) {
+	indisponivel := fmt.Errorf("%w: status 503", domain.ErrLLMIndisponivel)
+	roteiro := &providerRoteiro{erros: []error{indisponivel, indisponivel, indisponivel}}
+	config := configTeste()
+	config.Tentativas = 1
+	provider := resiliencia.NewProvider(roteiro, config)
+
+	for i := 0; i < config.LimiteFalhas; i++ {
+		_, err := provider.Generate(context.Background(), domain.RequisicaoGeracao{})
+		assert.ErrorIs(t, err, domain.ErrLLMIndisponivel)
+	}
+
+	_, err := provider.Generate(context.Background(), domain.RequisicaoGeracao{})
+
+	assert.ErrorIs(t, err, domain.ErrLLMCircuitoAberto)
+	assert.Equal(t, config.LimiteFalhas, roteiro.chamadas)
+}
+
+func TestResilienciaTimeoutPorChamada(t *testing.T) {
+	config := configTeste()
+	config.Tentativas = 1
+	config.TimeoutChamada = 10 * time.Millisecond
+	provider := resiliencia.NewProvider(providerLento{}, config)
+
+	_, err := provider.Generate(context.Background(), domain.RequisicaoGeracao{})
+
+	assert.ErrorIs(t, err, domain.ErrLLMTempoEsgotado)
+}
+
+type providerLento struct{}
+
+func (providerLento) Generate(ctx context.Context, req domain.RequisicaoGeracao) (*domain.ResultadoGeracao, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (p providerLento) GenerateStream(ctx context.Context, req domain.RequisicaoGeracao, onDelta func(string) error) (*domain.ResultadoGeracao, error) {
+	return p.Generate(ctx, req)
+}
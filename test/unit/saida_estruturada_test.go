@@ -0,0 +1,131 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"vend/internal/domain"
+	"vend/internal/jsonschema"
+	"vend/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var schemaLead = map[string]any{
+	"type":     "object",
+	"required": []any{"score", "proxima_acao"},
+	"properties": map[string]any{
+		"score":        map[string]any{"type": "integer", "minimum": 0.0, "maximum": 100.0},
+		"proxima_acao": map[string]any{"type": "string", "enum": []any{"ligar", "enviar_email"}},
+		"objecoes":     map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+	},
+	"additionalProperties": false,
+}
+
+func TestLerSaidaValidaContraSchema(t *testing.T) {
+	saida, err := usecase.LerSaida(schemaLead, "```json\n{\"score\": 80, \"proxima_acao\": \"ligar\", \"objecoes\": [\"preço\"]}\n```")
+	assert.NoError(t, err)
+	assert.Equal(t, 80.0, saida.(map[string]any)["score"])
+
+	_, err = usecase.LerSaida(schemaLead, `{"score": 80.5, "proxima_acao": "ligar"}`)
+	assert.ErrorContains(t, err, "$.score")
+
+	_, err = usecase.LerSaida(schemaLead, `{"score": 10, "proxima_acao": "visitar"}`)
+	assert.ErrorContains(t, err, "$.proxima_acao")
+
+	_, err = usecase.LerSaida(schemaLead, `{"score": 10}`)
+	assert.ErrorContains(t, err, "proxima_acao ausente")
+
+	_, err = usecase.LerSaida(schemaLead, `{"score": 10, "proxima_acao": "ligar", "extra": true}`)
+	assert.ErrorContains(t, err, "extra não permitida")
+}
+
+func TestValidarPromptRejeitaSchemaInvalido(t *testing.T) {
+	prompt := &domain.Prompt{Conteudo: "Classifique", SchemaSaida: map[string]any{"type": "objeto"}}
+	assert.ErrorIs(t, usecase.ValidarPrompt(prompt), jsonschema.ErrSchemaInvalido)
+
+	prompt.SchemaSaida = map[string]any{"type": "array"}
+	assert.ErrorIs(t, usecase.ValidarPrompt(prompt), jsonschema.ErrSchemaInvalido)
+
+	prompt.SchemaSaida = schemaLead
+	assert.NoError(t, usecase.ValidarPrompt(prompt))
+}
+
+func TestExecutarPromptRepeteSaidaInvalida(t *testing.T) {
+	mockRepo := new(MockRepository)
+	// A primeira chamada devolve o texto do prompt, que não é JSON; a segunda
+	// recebe o erro e devolve um JSON válido
+	tmpl := `{{if gt (len .Mensagens) 2}}{"score": 42, "proxima_acao": "ligar"}{{else}}{{.Ultima}}{{end}}`
+	useCase := newGeracaoUseCase(t, mockRepo, tmpl)
+
+	contexto := &domain.Contexto{ID: primitive.NewObjectID()}
+	prompt := &domain.Prompt{ID: primitive.NewObjectID(), Conteudo: "Classifique o lead", SchemaSaida: schemaLead}
+
+	mockRepo.On("GetContexto", contexto.ID.Hex()).Return(contexto, nil)
+	mockRepo.On("GetPrompt", prompt.ID.Hex()).Return(prompt, nil)
+	mockRepo.On("CreateResposta", mock.AnythingOfType("*domain.Resposta")).Return(nil)
+
+	resposta, err := useCase.ExecutarPrompt(context.Background(), contexto.ID.Hex(), prompt.ID.Hex(), usecase.OpcoesExecucao{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"score": 42.0, "proxima_acao": "ligar"}, resposta.Saida)
+	assert.Len(t, resposta.Mensagens, 2)
+}
+
+func TestExecutarPromptDesisteAposTentativas(t *testing.T) {
+	mockRepo := new(MockRepository)
+	useCase := newGeracaoUseCase(t, mockRepo, "sem JSON")
+
+	contexto := &domain.Contexto{ID: primitive.NewObjectID()}
+	prompt := &domain.Prompt{ID: primitive.NewObjectID(), Conteudo: "Classifique o lead", SchemaSaida: schemaLead}
+
+	mockRepo.On("GetContexto", contexto.ID.Hex()).Return(contexto, nil)
+	mockRepo.On("GetPrompt", prompt.ID.Hex()).Return(prompt, nil)
+	mockRepo.On("CreateResposta", mock.AnythingOfType("*domain.Resposta")).Return(nil)
+
+	resposta, err := useCase.ExecutarPrompt(context.Background(), contexto.ID.Hex(), prompt.ID.Hex(), usecase.OpcoesExecucao{})
+
+	assert.ErrorIs(t, err, usecase.ErrSaidaInvalida)
+	assert.Nil(t, resposta.Saida)
+	// Os tokens das três tentativas ficam registrados
+	assert.Equal(t, 3*2, resposta.Uso.CompletionTokens)
+	mockRepo.AssertNumberOfCalls(t, "CreateResposta", 1)
+}
+
+// providerInstavel responde sem JSON na primeira chamada e falha nas seguintes
+type providerInstavel struct {
+	chamadas int
+}
+
+func (p *providerInstavel) Generate(ctx context.Context, req domain.RequisicaoGeracao) (*domain.ResultadoGeracao, error) {
+	p.chamadas++
+	if p.chamadas > 1 {
+		return nil, domain.ErrLLMIndisponivel
+	}
+	return &domain.ResultadoGeracao{Conteudo: "sem JSON", Uso: domain.UsoTokens{CompletionTokens: 7, TotalTokens: 7}}, nil
+}
+
+func (p *providerInstavel) GenerateStream(ctx context.Context, req domain.RequisicaoGeracao, onDelta func(string) error) (*domain.ResultadoGeracao, error) {
+	return p.Generate(ctx, req)
+}
+
+func TestExecutarPromptRegistraTentativasAntesDaFalha(t *testing.T) {
+	mockRepo := new(MockRepository)
+	useCase := usecase.NewGeracaoUseCase(mockRepo, &providerInstavel{}, usecase.NewConsumoUseCase(mockRepo, usecase.PrecosPadrao()), nil, nil, nil)
+
+	contexto := &domain.Contexto{ID: primitive.NewObjectID()}
+	prompt := &domain.Prompt{ID: primitive.NewObjectID(), Conteudo: "Classifique o lead", SchemaSaida: schemaLead}
+
+	mockRepo.On("GetContexto", contexto.ID.Hex()).Return(contexto, nil)
+	mockRepo.On("GetPrompt", prompt.ID.Hex()).Return(prompt, nil)
+	mockRepo.On("CreateResposta", mock.AnythingOfType("*domain.Resposta")).Return(nil)
+
+	resposta, err := useCase.ExecutarPrompt(context.Background(), contexto.ID.Hex(), prompt.ID.Hex(), usecase.OpcoesExecucao{})
+
+	// O erro é o do provedor, mas os tokens da primeira tentativa contam
+	assert.ErrorIs(t, err, domain.ErrLLMIndisponivel)
+	assert.NotErrorIs(t, err, usecase.ErrSaidaInvalida)
+	assert.Equal(t, 7, resposta.Uso.CompletionTokens)
+	mockRepo.AssertNumberOfCalls(t, "CreateResposta", 1)
+}